@@ -0,0 +1,50 @@
+package auth
+
+import "context"
+
+// realm role that allows managing duties
+const RoleAdmin = "admin"
+
+// realm role of crew leads, who can hand duties over to other crew members
+const RoleLead = "lead"
+
+// Identity is the authenticated caller of a request (taken from the JWT)
+type Identity struct {
+	Subject string   // JWT "sub" claim (Keycloak user ID)
+	Roles   []string // realm roles of the user
+	Locale  string   // JWT "locale" claim: the preferred language of the user's account (empty when not set)
+}
+
+// checks if the caller has a realm role
+func (i Identity) HasRole(role string) bool {
+	for _, r := range i.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// checks if the caller has the admin role
+func (i Identity) IsAdmin() bool {
+	return i.HasRole(RoleAdmin)
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of the context carrying the caller's identity
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext returns the caller's identity, if the request was authenticated
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+// SubjectFromContext returns the caller's user ID, or an empty string for anonymous requests
+func SubjectFromContext(ctx context.Context) string {
+	identity, _ := FromContext(ctx)
+	return identity.Subject
+}
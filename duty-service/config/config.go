@@ -0,0 +1,215 @@
+package config
+
+import (
+	"duty-service/models"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// default lifetime of the signed image URLs returned by the API
+const defaultImageURLExpiry = 15 * time.Minute
+
+// defaults of the duty analytics gauges: recalculated every 5 minutes over the last 4 weeks
+const (
+	defaultAnalyticsRefreshInterval = 5 * time.Minute
+	defaultAnalyticsWindow          = 28 * 24 * time.Hour
+)
+
+// defaults of the retention job: deleted duties and assignments are purged after 30 days, checked every hour
+const (
+	defaultSoftDeleteRetention = 30 * 24 * time.Hour
+	defaultRetentionInterval   = time.Hour
+)
+
+// language the duties are written in, unless DEFAULT_LANGUAGE says otherwise
+const defaultLanguage = "en"
+
+// page of the crew app where an employee completes a duty assignment, opened by the QR codes of printed checklists
+const defaultCompletionFormURL = "http://localhost:3000/duty-assignments/{ShiftId}/{DutyId}"
+
+// timezone of the restaurants, in which the weekday rules of the duty templates are read
+const defaultTimezone = "Europe/Amsterdam"
+
+// Where the duty assignment images are stored (IMAGE_STORE)
+const (
+	ImageStoreAzure  = "azure"  // private Azure Blob Storage container (default)
+	ImageStoreLocal  = "local"  // directory on the local filesystem, served by the duty service
+	ImageStoreMemory = "memory" // in memory, lost on restart
+)
+
+// Config holds the optional settings of the duty service (required settings are checked in main)
+type Config struct {
+	TemperatureRanges map[models.TemperatureUnitType]models.TemperatureRange // safe temperature range per unit type
+	ImageURLExpiry    time.Duration                                          // how long a signed image URL stays valid
+	ImageStore        string                                                 // where images are stored: azure, local or memory
+	LocalImageDir     string                                                 // directory of the local image store
+	PublicBaseURL     string                                                 // URL the duty service is reachable under (for local image URLs)
+	ImageSigningKey   string                                                 // key that signs local image URLs (random per start when empty)
+	AnalyticsInterval time.Duration                                          // how often the duty analytics gauges are recalculated
+	AnalyticsWindow   time.Duration                                          // period the duty analytics gauges cover
+	EventServiceURL   string                                                 // base URL of the event service (to select duties by event)
+	Retention         time.Duration                                          // how long deleted duties and assignments can be restored before they are purged
+	RetentionInterval time.Duration                                          // how often the retention job purges
+	DefaultLanguage   string                                                 // language the names and descriptions of the duties are written in
+	Languages         []string                                               // languages every duty should be translated into (for the missing translations report)
+	CompletionFormURL string                                                 // URL of the completion form of an assignment, with {ShiftId} and {DutyId} placeholders
+	Timezone          *time.Location                                         // local time of the clock-ins, for the weekday rules of the duty templates
+}
+
+// default food-safety ranges in °C (fridge at most 7, freezer at most -18, hot-holding at least 60)
+func defaultTemperatureRanges() map[models.TemperatureUnitType]models.TemperatureRange {
+	fridgeMax, freezerMax, hotHoldingMin := 7.0, -18.0, 60.0
+
+	return map[models.TemperatureUnitType]models.TemperatureRange{
+		models.UnitFridge:     {Max: &fridgeMax},
+		models.UnitFreezer:    {Max: &freezerMax},
+		models.UnitHotHolding: {Min: &hotHoldingMin},
+	}
+}
+
+// Load reads the optional settings from environment variables, falling back to defaults
+func Load() (*Config, error) {
+	timezone, err := time.LoadLocation(defaultTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the default timezone: %v", err)
+	}
+
+	cfg := &Config{
+		TemperatureRanges: defaultTemperatureRanges(),
+		ImageURLExpiry:    defaultImageURLExpiry,
+		ImageStore:        ImageStoreAzure,
+		LocalImageDir:     "./data/images",
+		PublicBaseURL:     "http://localhost:3004",
+		ImageSigningKey:   os.Getenv("IMAGE_SIGNING_KEY"),
+		AnalyticsInterval: defaultAnalyticsRefreshInterval,
+		AnalyticsWindow:   defaultAnalyticsWindow,
+		EventServiceURL:   "http://event-service:3001",
+		Retention:         defaultSoftDeleteRetention,
+		RetentionInterval: defaultRetentionInterval,
+		DefaultLanguage:   defaultLanguage,
+		CompletionFormURL: defaultCompletionFormURL,
+		Timezone:          timezone,
+	}
+
+	// TEMPERATURE_SAFE_RANGES overrides ranges per unit type, e.g. {"Fridge":{"Min":0,"Max":5}}
+	if rangesJSON := os.Getenv("TEMPERATURE_SAFE_RANGES"); rangesJSON != "" {
+		var ranges map[models.TemperatureUnitType]models.TemperatureRange
+		if err := json.Unmarshal([]byte(rangesJSON), &ranges); err != nil {
+			return nil, fmt.Errorf("invalid TEMPERATURE_SAFE_RANGES: %v", err)
+		}
+		for unitType, safeRange := range ranges {
+			if !models.ValidateTemperatureUnitType(unitType) {
+				return nil, fmt.Errorf("invalid TEMPERATURE_SAFE_RANGES: unknown unit type '%s'", unitType)
+			}
+			cfg.TemperatureRanges[unitType] = safeRange
+		}
+	}
+
+	// IMAGE_URL_EXPIRY is a Go duration, e.g. "15m" or "1h"
+	if expiry := os.Getenv("IMAGE_URL_EXPIRY"); expiry != "" {
+		duration, err := time.ParseDuration(expiry)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid IMAGE_URL_EXPIRY: '%s' is not a positive duration", expiry)
+		}
+		cfg.ImageURLExpiry = duration
+	}
+
+	if imageStore := os.Getenv("IMAGE_STORE"); imageStore != "" {
+		if imageStore != ImageStoreAzure && imageStore != ImageStoreLocal && imageStore != ImageStoreMemory {
+			return nil, fmt.Errorf("invalid IMAGE_STORE: '%s' (valid values are 'azure', 'local' or 'memory')", imageStore)
+		}
+		cfg.ImageStore = imageStore
+	}
+
+	if dir := os.Getenv("LOCAL_IMAGE_DIR"); dir != "" {
+		cfg.LocalImageDir = dir
+	}
+
+	if baseURL := os.Getenv("PUBLIC_BASE_URL"); baseURL != "" {
+		cfg.PublicBaseURL = baseURL
+	}
+
+	// ANALYTICS_REFRESH_INTERVAL and ANALYTICS_WINDOW are Go durations, e.g. "5m" and "672h"
+	if interval := os.Getenv("ANALYTICS_REFRESH_INTERVAL"); interval != "" {
+		duration, err := time.ParseDuration(interval)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid ANALYTICS_REFRESH_INTERVAL: '%s' is not a positive duration", interval)
+		}
+		cfg.AnalyticsInterval = duration
+	}
+
+	if window := os.Getenv("ANALYTICS_WINDOW"); window != "" {
+		duration, err := time.ParseDuration(window)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid ANALYTICS_WINDOW: '%s' is not a positive duration", window)
+		}
+		cfg.AnalyticsWindow = duration
+	}
+
+	if eventServiceURL := os.Getenv("EVENT_SERVICE_URL"); eventServiceURL != "" {
+		cfg.EventServiceURL = eventServiceURL
+	}
+
+	// SOFT_DELETE_RETENTION and RETENTION_JOB_INTERVAL are Go durations, e.g. "720h" and "1h"
+	if retention := os.Getenv("SOFT_DELETE_RETENTION"); retention != "" {
+		duration, err := time.ParseDuration(retention)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid SOFT_DELETE_RETENTION: '%s' is not a positive duration", retention)
+		}
+		cfg.Retention = duration
+	}
+
+	if interval := os.Getenv("RETENTION_JOB_INTERVAL"); interval != "" {
+		duration, err := time.ParseDuration(interval)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid RETENTION_JOB_INTERVAL: '%s' is not a positive duration", interval)
+		}
+		cfg.RetentionInterval = duration
+	}
+
+	// DEFAULT_LANGUAGE and TRANSLATION_LANGUAGES are language tags, e.g. "en" and "nl,pl,ro"
+	if language := os.Getenv("DEFAULT_LANGUAGE"); language != "" {
+		tag, err := models.NormalizeLanguageTag(language)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DEFAULT_LANGUAGE: '%s' is not a language tag", language)
+		}
+		cfg.DefaultLanguage = tag
+	}
+
+	if languages := os.Getenv("TRANSLATION_LANGUAGES"); languages != "" {
+		for _, language := range strings.Split(languages, ",") {
+			tag, err := models.NormalizeLanguageTag(language)
+			if err != nil {
+				return nil, fmt.Errorf("invalid TRANSLATION_LANGUAGES: '%s' is not a language tag", strings.TrimSpace(language))
+			}
+			cfg.Languages = append(cfg.Languages, tag)
+		}
+	}
+
+	// COMPLETION_FORM_URL is where the QR codes of printed checklists point, e.g. "https://crew.example.com/duties/{ShiftId}/{DutyId}"
+	if formURL := os.Getenv("COMPLETION_FORM_URL"); formURL != "" {
+		parsed, err := url.Parse(formURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid COMPLETION_FORM_URL: '%s' is not an http(s) URL", formURL)
+		}
+		if !strings.Contains(formURL, "{ShiftId}") || !strings.Contains(formURL, "{DutyId}") {
+			return nil, fmt.Errorf("invalid COMPLETION_FORM_URL: '%s' needs both {ShiftId} and {DutyId}", formURL)
+		}
+		cfg.CompletionFormURL = formURL
+	}
+
+	// TIMEZONE is an IANA timezone name, e.g. "Europe/Amsterdam"
+	if name := os.Getenv("TIMEZONE"); name != "" {
+		location, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TIMEZONE: '%s' is not a timezone", name)
+		}
+		cfg.Timezone = location
+	}
+
+	return cfg, nil
+}
@@ -3,8 +3,6 @@ package db
 import (
 	"context"
 	"errors"
-	"fmt"
-	"io"
 	"log"
 	"net/http"
 
@@ -20,7 +18,7 @@ var (
 
 // Models defines the list of tables to be created
 var Models = []string{
-	"duties", "dutyAssignments",
+	"duties", "dutyAssignments", "temperatureLogs", "dutyAssignmentPhotos", "dutyVersions", "dutySyncChanges", "dutyAssignmentHistory", "dutyAssignmentComments", "dutyPhotoHashes",
 }
 
 // InitAzureTables initializes Azure Table Storage connections for all models
@@ -68,10 +66,7 @@ func isResourceExistsError(err error) bool {
 
 //////////////////////////////////////
 
-// Azure Blob for Duty Assignment images:
-
-// Global Azure Blob Storage client
-var BlobServiceClient *azblob.Client
+// Azure Blob for Duty Assignment images (used through storage.AzureImageStore):
 
 // InitAzureBlobStorage initializes the Azure Blob Storage client
 func InitAzureBlobStorage(connectionString string) (*azblob.Client, error) {
@@ -81,35 +76,6 @@ func InitAzureBlobStorage(connectionString string) (*azblob.Client, error) {
 		return nil, err
 	}
 
-	BlobServiceClient = client //TODO check
 	log.Println("Successfully connected to Azure Blob Storage")
-	return BlobServiceClient, nil
-}
-
-// UploadImage uploads an image to the specified container and returns the URL
-func UploadImage(ctx context.Context, containerName string, blobName string, imageData io.Reader) (string, error) {
-	// Ensure the container exists (create if not)
-	_, err := BlobServiceClient.CreateContainer(ctx, containerName, nil)
-	if err != nil && !isContainerExistsError(err) {
-		return "", fmt.Errorf("failed to create blob container: %v", err)
-	}
-
-	// Upload the image to the blob storage
-	_, err = BlobServiceClient.UploadStream(ctx, containerName, blobName, imageData, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to upload image: %v", err)
-	}
-
-	// Construct the blob URL
-	blobURL := fmt.Sprintf("%s/%s/%s", BlobServiceClient.URL(), containerName, blobName)
-	return blobURL, nil
-}
-
-// isContainerExistsError checks if an error is due to the container already existing
-func isContainerExistsError(err error) bool {
-	var responseErr *azcore.ResponseError
-	if errors.As(err, &responseErr) {
-		return responseErr.StatusCode == http.StatusConflict // 409 Conflict indicates container exists
-	}
-	return false
+	return client, nil
 }
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"duty-service/services"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// period the analytics cover when no 'from' is given
+const defaultAnalyticsPeriod = 8 * 7 * 24 * time.Hour
+
+type DutyAnalyticsHandler struct {
+	service services.InterfaceDutyAnalyticsService
+}
+
+func NewDutyAnalyticsHandler(service services.InterfaceDutyAnalyticsService) *DutyAnalyticsHandler {
+	return &DutyAnalyticsHandler{service: service}
+}
+
+// analytics of the duty assignments created between ?from= and ?to= (the last 8 weeks by default)
+func (h *DutyAnalyticsHandler) GetAnalytics(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	to := time.Now().UTC()
+	if query.Get("to") != "" {
+		var err error
+		to, err = parseDateParam(query.Get("to"), true)
+		if err != nil {
+			http.Error(w, "Invalid 'to' date: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	from := to.Add(-defaultAnalyticsPeriod)
+	if query.Get("from") != "" {
+		var err error
+		from, err = parseDateParam(query.Get("from"), false)
+		if err != nil {
+			http.Error(w, "Invalid 'from' date: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if from.After(to) {
+		http.Error(w, "'from' must be before 'to'", http.StatusBadRequest)
+		return
+	}
+
+	analytics, err := h.service.GetAnalytics(context.Background(), from, to)
+	if err != nil {
+		http.Error(w, "Failed to calculate duty analytics: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(analytics)
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"duty-service/images"
+	"duty-service/models"
+	"duty-service/repositories"
+	"duty-service/services"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type DutyAssignmentCommentHandler struct {
+	service services.InterfaceDutyAssignmentCommentService
+}
+
+func NewDutyAssignmentCommentHandler(service services.InterfaceDutyAssignmentCommentService) *DutyAssignmentCommentHandler {
+	return &DutyAssignmentCommentHandler{service: service}
+}
+
+// fetch the comment thread of a duty assignment (oldest first)
+func (h *DutyAssignmentCommentHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	uuids, err := parseUUIDs(map[string]string{"ShiftId": vars["ShiftId"], "DutyId": vars["DutyId"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	comments, err := h.service.GetComments(r.Context(), uuids["ShiftId"], uuids["DutyId"])
+	if err != nil {
+		if errors.Is(err, repositories.ErrDutyAssignmentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve comments: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comments)
+}
+
+// fetch a single comment
+func (h *DutyAssignmentCommentHandler) GetComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	uuids, err := parseUUIDs(map[string]string{"ShiftId": vars["ShiftId"], "DutyId": vars["DutyId"], "CommentId": vars["CommentId"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.service.GetComment(r.Context(), uuids["ShiftId"], uuids["DutyId"], uuids["CommentId"])
+	if err != nil {
+		if errors.Is(err, repositories.ErrCommentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve comment: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comment)
+}
+
+// adds a comment to a duty assignment. Sent as JSON ({"Text": "..."}), or as multipart/form-data with the 'Text'
+// field and the photos as 'photos' file parts
+func (h *DutyAssignmentCommentHandler) AddComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	uuids, err := parseUUIDs(map[string]string{"ShiftId": vars["ShiftId"], "DutyId": vars["DutyId"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var text string
+	var files []multipart.File
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		// photos beyond 32MB are buffered on disk
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			http.Error(w, "Failed to parse multipart form: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		text = r.FormValue("Text")
+		for _, header := range r.MultipartForm.File["photos"] {
+			file, err := header.Open()
+			if err != nil {
+				http.Error(w, "Failed to read photo: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+			files = append(files, file)
+		}
+	} else {
+		var request struct {
+			Text string `json:"Text"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		text = request.Text
+	}
+
+	// the context carries the author
+	comment, err := h.service.AddComment(r.Context(), uuids["ShiftId"], uuids["DutyId"], text, files)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrDutyAssignmentNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, models.ErrInvalidComment), errors.Is(err, images.ErrInvalidImage):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, images.ErrUnsupportedImage):
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		default:
+			http.Error(w, "Failed to add comment: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// changes the text of a comment (only its author)
+func (h *DutyAssignmentCommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	uuids, err := parseUUIDs(map[string]string{"ShiftId": vars["ShiftId"], "DutyId": vars["DutyId"], "CommentId": vars["CommentId"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Text string `json:"Text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.service.UpdateComment(r.Context(), uuids["ShiftId"], uuids["DutyId"], uuids["CommentId"], request.Text)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrCommentNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, models.ErrInvalidComment):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrNotCommentAuthor):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, "Failed to update comment: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comment)
+}
+
+// deletes a comment with its photos (its author or an admin)
+func (h *DutyAssignmentCommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	uuids, err := parseUUIDs(map[string]string{"ShiftId": vars["ShiftId"], "DutyId": vars["DutyId"], "CommentId": vars["CommentId"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteComment(r.Context(), uuids["ShiftId"], uuids["DutyId"], uuids["CommentId"]); err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrCommentNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, services.ErrNotCommentAuthor):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, "Failed to delete comment: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	response := map[string]string{"message": "Comment deleted successfully"}
+	json.NewEncoder(w).Encode(response)
+}
@@ -2,11 +2,15 @@ package handlers
 
 import (
 	"context"
+	"duty-service/images"
 	"duty-service/models"
+	"duty-service/repositories"
 	"duty-service/services"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -20,24 +24,53 @@ func NewDutyAssignmentHandler(service services.InterfaceDutyAssignmentService) *
 	return &DutyAssignmentHandler{service: service}
 }
 
-// fetch all duty assignments by shiftId
+// fetch all duty assignments by shiftId, or an employee's own duty assignments by employeeId (optionally of a shift)
 func (h *DutyAssignmentHandler) GetAllDutyAssignmentsByShiftId(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	shiftIdStr := query.Get("shiftId") // Get the shiftId parameter from the query string
+	employeeId := query.Get("employeeId")
 
-	if shiftIdStr == "" { // check that the shiftId parameter is provided and is valid
-		http.Error(w, "Missing 'shiftId' query parameter", http.StatusBadRequest)
+	if shiftIdStr == "" && employeeId == "" { // check that the shiftId parameter is provided and is valid
+		http.Error(w, "Missing 'shiftId' or 'employeeId' query parameter", http.StatusBadRequest)
 		return
 	}
 
-	uuids, err := parseUUIDs(map[string]string{"shiftId": shiftIdStr})
+	var shiftId *uuid.UUID
+	if shiftIdStr != "" {
+		uuids, err := parseUUIDs(map[string]string{"shiftId": shiftIdStr})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		parsed := uuids["shiftId"]
+		shiftId = &parsed
+	}
+
+	// fetch duty assignments by shiftId or employeeId via service
+	var dutyAssignments []models.DutyAssignment
+	var err error
+	if employeeId != "" {
+		dutyAssignments, err = h.service.GetDutyAssignmentsByAssignee(context.Background(), employeeId, shiftId)
+	} else {
+		dutyAssignments, err = h.service.GetAllDutyAssignmentsByShiftId(context.Background(), *shiftId)
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, "Failed to retrieve duty assignments: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// fetch duty assignments by shiftId via service
-	dutyAssignments, err := h.service.GetAllDutyAssignmentsByShiftId(context.Background(), uuids["shiftId"])
+	// deleted assignments are left out, unless a shift's are asked for
+	if query.Get("includeDeleted") == "true" && employeeId == "" {
+		deleted, err := h.service.GetDeletedDutyAssignments(context.Background(), *shiftId)
+		if err != nil {
+			http.Error(w, "Failed to retrieve deleted duty assignments: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		dutyAssignments = append(dutyAssignments, deleted...)
+	}
+
+	// names and descriptions in the caller's language (Accept-Language or the language of their account)
+	dutyAssignments, err = h.service.LocalizeDutyAssignments(r.Context(), dutyAssignments)
 	if err != nil {
 		http.Error(w, "Failed to retrieve duty assignments: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -69,7 +102,8 @@ func (h *DutyAssignmentHandler) CreateDutyAssignments(w http.ResponseWriter, r *
 		return
 	}
 
-	if err := h.service.CreateDutyAssignments(context.Background(), shiftIdUUID, request.RoleId); err != nil {
+	// the caller is the employee who clocked in and gets the duties
+	if err := h.service.CreateDutyAssignments(r.Context(), shiftIdUUID, request.RoleId, time.Now().UTC()); err != nil {
 		http.Error(w, "Failed to create duty assignments: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -128,6 +162,14 @@ func (h *DutyAssignmentHandler) UpdateDutyAssignment(w http.ResponseWriter, r *h
 		dutyAssignment.DutyAssignmentNote = &note
 	}
 
+	// answers to the duty's form, sent as a JSON object (e.g. {"oilChanged": true, "stockCount": 12})
+	if formValues := r.FormValue("FormValues"); formValues != "" {
+		if err := json.Unmarshal([]byte(formValues), &dutyAssignment.FormValues); err != nil {
+			http.Error(w, "Invalid FormValues: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	//check ShiftId and DutyId
 	if dutyAssignment.PartitionKey != uuids["ShiftId"] || dutyAssignment.RowKey != uuids["DutyId"] {
 		http.Error(w, "Mismatched ShiftId or DutyId in request body", http.StatusBadRequest)
@@ -136,12 +178,33 @@ func (h *DutyAssignmentHandler) UpdateDutyAssignment(w http.ResponseWriter, r *h
 
 	// check DutyAssignmentStatus
 	if !models.ValidateDutyAssignmentStatus(dutyAssignment.DutyAssignmentStatus) {
-		http.Error(w, "Invalid DutyAssignmentStatus. Valid values are 'Completed' or 'Incomplete'.", http.StatusBadRequest)
+		http.Error(w, "Invalid DutyAssignmentStatus. Valid values are 'Completed', 'Incomplete' or 'Skipped'.", http.StatusBadRequest)
 		return
 	}
 
-	// Call the service to update the duty assignment
-	if err := h.service.UpdateDutyAssignment(context.Background(), dutyAssignment, file); err != nil {
+	// Call the service to update the duty assignment (the request context carries the uploader of a photo)
+	if err := h.service.UpdateDutyAssignment(r.Context(), dutyAssignment, file); err != nil {
+		var formErr *models.FormValidationError
+		if errors.As(err, &formErr) {
+			http.Error(w, formErr.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, images.ErrUnsupportedImage) {
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+			return
+		}
+		if errors.Is(err, images.ErrInvalidImage) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, repositories.ErrDutyAssignmentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, services.ErrDutyAssignmentFrozen) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, "Failed to update duty assignment: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -152,6 +215,76 @@ func (h *DutyAssignmentHandler) UpdateDutyAssignment(w http.ResponseWriter, r *h
 	json.NewEncoder(w).Encode(response)
 }
 
+// hands a duty assignment over to another crew member of the same event (leads and admins)
+func (h *DutyAssignmentHandler) ReassignDutyAssignment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	uuids, err := parseUUIDs(map[string]string{"ShiftId": vars["ShiftId"], "DutyId": vars["DutyId"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		AssigneeId string  `json:"AssigneeId"`
+		Note       *string `json:"Note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.AssigneeId == "" {
+		http.Error(w, "Missing AssigneeId", http.StatusBadRequest)
+		return
+	}
+
+	// the context carries who hands the duty over
+	dutyAssignment, err := h.service.ReassignDutyAssignment(r.Context(), uuids["ShiftId"], uuids["DutyId"], request.AssigneeId, request.Note)
+	if err != nil {
+		if errors.Is(err, repositories.ErrDutyAssignmentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, services.ErrAssigneeNotOnEvent) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, services.ErrDutyAssignmentFrozen) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Failed to reassign duty assignment: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dutyAssignment)
+}
+
+// fetch the history of a duty assignment (oldest first)
+func (h *DutyAssignmentHandler) GetDutyAssignmentHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	uuids, err := parseUUIDs(map[string]string{"ShiftId": vars["ShiftId"], "DutyId": vars["DutyId"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.service.GetDutyAssignmentHistory(context.Background(), uuids["ShiftId"], uuids["DutyId"])
+	if err != nil {
+		if errors.Is(err, repositories.ErrDutyAssignmentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve duty assignment history: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
 // deletes a duty assignment
 func (h *DutyAssignmentHandler) DeleteDutyAssignment(w http.ResponseWriter, r *http.Request) {
 	// get ShiftId and DutyId from path parameters
@@ -168,7 +301,15 @@ func (h *DutyAssignmentHandler) DeleteDutyAssignment(w http.ResponseWriter, r *h
 		return
 	}
 
-	if err := h.service.DeleteDutyAssignment(context.Background(), uuids["ShiftId"], uuids["DutyId"]); err != nil {
+	if err := h.service.DeleteDutyAssignment(r.Context(), uuids["ShiftId"], uuids["DutyId"]); err != nil { // the context tells if an admin deletes a frozen duty
+		if errors.Is(err, services.ErrDutyAssignmentFrozen) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, repositories.ErrDutyAssignmentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, "Failed to delete duty assignment: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -179,6 +320,198 @@ func (h *DutyAssignmentHandler) DeleteDutyAssignment(w http.ResponseWriter, r *h
 	json.NewEncoder(w).Encode(response)
 }
 
+// restores a deleted duty assignment
+func (h *DutyAssignmentHandler) RestoreDutyAssignment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if vars["ShiftId"] == "" || vars["DutyId"] == "" {
+		http.Error(w, "Missing 'ShiftId' or 'DutyId' path parameter", http.StatusBadRequest)
+		return
+	}
+
+	uuids, err := parseUUIDs(map[string]string{"ShiftId": vars["ShiftId"], "DutyId": vars["DutyId"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dutyAssignment, err := h.service.RestoreDutyAssignment(r.Context(), uuids["ShiftId"], uuids["DutyId"])
+	if err != nil {
+		if errors.Is(err, repositories.ErrDutyAssignmentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to restore duty assignment: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dutyAssignment)
+}
+
+// fetch all photos of a duty assignment (newest first)
+func (h *DutyAssignmentHandler) GetDutyAssignmentPhotos(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if vars["ShiftId"] == "" || vars["DutyId"] == "" {
+		http.Error(w, "Missing 'ShiftId' or 'DutyId' path parameter", http.StatusBadRequest)
+		return
+	}
+
+	uuids, err := parseUUIDs(map[string]string{"ShiftId": vars["ShiftId"], "DutyId": vars["DutyId"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	photos, err := h.service.GetDutyAssignmentPhotos(context.Background(), uuids["ShiftId"], uuids["DutyId"])
+	if err != nil {
+		http.Error(w, "Failed to retrieve duty assignment photos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(photos)
+	if err != nil {
+		http.Error(w, "Failed to encode response: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// deletes a single photo of a duty assignment
+func (h *DutyAssignmentHandler) DeleteDutyAssignmentPhoto(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if vars["ShiftId"] == "" || vars["DutyId"] == "" || vars["PhotoId"] == "" {
+		http.Error(w, "Missing 'ShiftId', 'DutyId' or 'PhotoId' path parameter", http.StatusBadRequest)
+		return
+	}
+
+	uuids, err := parseUUIDs(map[string]string{"ShiftId": vars["ShiftId"], "DutyId": vars["DutyId"], "PhotoId": vars["PhotoId"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteDutyAssignmentPhoto(r.Context(), uuids["ShiftId"], uuids["DutyId"], uuids["PhotoId"]); err != nil { // the context tells if an admin deletes a frozen duty's photo
+		if errors.Is(err, repositories.ErrPhotoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, services.ErrDutyAssignmentFrozen) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Failed to delete duty assignment photo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	response := map[string]string{"message": "Duty assignment photo deleted successfully"}
+	json.NewEncoder(w).Encode(response)
+}
+
+// lists the flagged photos that wait for review, oldest first
+func (h *DutyAssignmentHandler) GetPhotoReviewQueue(w http.ResponseWriter, r *http.Request) {
+	queue, err := h.service.GetPhotoReviewQueue(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to retrieve photo review queue: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(queue)
+}
+
+// approves or rejects a flagged photo of a duty assignment
+func (h *DutyAssignmentHandler) ReviewDutyAssignmentPhoto(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	uuids, err := parseUUIDs(map[string]string{"ShiftId": vars["ShiftId"], "DutyId": vars["DutyId"], "PhotoId": vars["PhotoId"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var review models.PhotoReview
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// the context carries the reviewer
+	photo, err := h.service.ReviewDutyAssignmentPhoto(r.Context(), uuids["ShiftId"], uuids["DutyId"], uuids["PhotoId"], review)
+	switch {
+	case errors.Is(err, models.ErrInvalidPhotoReview):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, repositories.ErrPhotoNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, services.ErrPhotoNotFlagged):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to review duty assignment photo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(photo)
+}
+
+// checks whether a shift can clock out, i.e. none of its mandatory duties is still incomplete
+func (h *DutyAssignmentHandler) CanClockOut(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	uuids, err := parseUUIDs(map[string]string{"ShiftId": vars["ShiftId"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.CanClockOut(context.Background(), uuids["ShiftId"])
+	if err != nil {
+		http.Error(w, "Failed to check clock-out: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// redirects to a freshly signed URL of the main photo of a duty assignment (?thumbnail=true for the thumbnail)
+func (h *DutyAssignmentHandler) RedirectToDutyAssignmentImage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if vars["ShiftId"] == "" || vars["DutyId"] == "" {
+		http.Error(w, "Missing 'ShiftId' or 'DutyId' path parameter", http.StatusBadRequest)
+		return
+	}
+
+	uuids, err := parseUUIDs(map[string]string{"ShiftId": vars["ShiftId"], "DutyId": vars["DutyId"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	thumbnail := r.URL.Query().Get("thumbnail") == "true"
+
+	imageUrl, err := h.service.GetDutyAssignmentImageUrl(context.Background(), uuids["ShiftId"], uuids["DutyId"], thumbnail)
+	if err != nil {
+		if errors.Is(err, services.ErrNoImage) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve duty assignment image: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// the link expires, so the redirect itself must not be cached
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, imageUrl, http.StatusFound)
+}
+
 // parses multiple UUID strings and returns them along with an error if any parsing fails.
 func parseUUIDs(uuidStrings map[string]string) (map[string]uuid.UUID, error) {
 	uuids := make(map[string]uuid.UUID)
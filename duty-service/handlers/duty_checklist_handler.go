@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"bytes"
+	"duty-service/models"
+	"duty-service/services"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/signintech/gopdf"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// size in pixels of the QR code images
+const checklistQRSize = 256
+
+type DutyChecklistHandler struct {
+	service services.InterfaceDutyChecklistService
+}
+
+func NewDutyChecklistHandler(service services.InterfaceDutyChecklistService) *DutyChecklistHandler {
+	return &DutyChecklistHandler{service: service}
+}
+
+// printable checklist of a shift (?shiftId=) as HTML (default) or PDF (?format=pdf)
+func (h *DutyChecklistHandler) PrintDutyChecklist(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if query.Get("shiftId") == "" {
+		http.Error(w, "Missing 'shiftId' query parameter", http.StatusBadRequest)
+		return
+	}
+
+	uuids, err := parseUUIDs(map[string]string{"shiftId": query.Get("shiftId")})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format := query.Get("format")
+	if format != "" && format != "html" && format != "pdf" {
+		http.Error(w, "Invalid 'format'. Valid values are 'html' or 'pdf'.", http.StatusBadRequest)
+		return
+	}
+
+	checklist, err := h.service.GetDutyChecklist(r.Context(), uuids["shiftId"]) // the context carries the caller's language
+	if err != nil {
+		http.Error(w, "Failed to create duty checklist: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// one QR code per assignment, opening its completion form
+	qrCodes := make([][]byte, len(checklist.Items))
+	for i, item := range checklist.Items {
+		qrCodes[i], err = qrcode.Encode(item.FormUrl, qrcode.Medium, checklistQRSize)
+		if err != nil {
+			http.Error(w, "Failed to create QR code: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if format == "pdf" {
+		var pdf bytes.Buffer
+		if err := writeDutyChecklistPDF(&pdf, checklist, qrCodes); err != nil {
+			http.Error(w, "Failed to render duty checklist: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `attachment; filename="duty-checklist_`+checklist.ShiftId.String()+`.pdf"`)
+		w.Write(pdf.Bytes())
+		return
+	}
+
+	items := make([]checklistPrintItem, len(checklist.Items))
+	for i, item := range checklist.Items {
+		items[i] = checklistPrintItem{
+			DutyChecklistItem: item,
+			QRCode:            template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(qrCodes[i])),
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dutyChecklistTemplate.Execute(w, struct {
+		*models.DutyChecklist
+		Items []checklistPrintItem
+	}{checklist, items}); err != nil {
+		http.Error(w, "Failed to render duty checklist: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// an item of the HTML checklist with its QR code inlined as a data URI
+type checklistPrintItem struct {
+	models.DutyChecklistItem
+	QRCode template.URL
+}
+
+// the blank to fill in for a form field, e.g. "____ °C (2–8)" or "[ ] yes   [ ] no"
+func formFieldBlank(field models.FormField) string {
+	switch field.Type {
+	case models.FieldCheckbox:
+		return "[ ] yes   [ ] no"
+	case models.FieldChoice:
+		options := make([]string, len(field.Options))
+		for i, option := range field.Options {
+			options[i] = "[ ] " + option
+		}
+		return strings.Join(options, "   ")
+	case models.FieldNumber:
+		blank := "________ " + field.Unit
+		if field.Min != nil || field.Max != nil {
+			blank += " (" + formatBound(field.Min) + "–" + formatBound(field.Max) + ")"
+		}
+		return strings.TrimSpace(blank)
+	case models.FieldPhoto:
+		return "photo: scan the QR code"
+	default:
+		return "______________________________"
+	}
+}
+
+func formatBound(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
+
+// the due time and whether the duty is mandatory, e.g. "Due 2024-05-01 18:00 UTC · mandatory"
+func checklistItemDetails(item models.DutyChecklistItem) string {
+	var details []string
+	if item.DueAt != nil {
+		details = append(details, "Due "+formatReportTime(item.DueAt)+" UTC")
+	}
+	if item.Mandatory {
+		details = append(details, "mandatory")
+	}
+	return strings.Join(details, " · ")
+}
+
+// printable checklist: a tick box per duty, the blanks of its form and the QR code of its completion form
+var dutyChecklistTemplate = template.Must(template.New("duty-checklist").Funcs(template.FuncMap{
+	"time":    func(value time.Time) string { return formatReportTime(&value) },
+	"blank":   formFieldBlank,
+	"details": checklistItemDetails,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Duty checklist</title>
+<style>
+body { font-family: sans-serif; font-size: 12px; margin: 24px; }
+section { display: flex; gap: 12px; border-bottom: 1px solid #ccc; padding: 8px 0; page-break-inside: avoid; }
+.box { width: 18px; height: 18px; border: 2px solid #000; flex-shrink: 0; }
+.text { flex-grow: 1; }
+h2 { font-size: 14px; margin: 0 0 4px; }
+p { margin: 0 0 4px; }
+.details { color: #555; }
+.Completed .box { background: #000; }
+img { width: 96px; height: 96px; flex-shrink: 0; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>Duty checklist</h1>
+<p>Shift {{.ShiftId}} &middot; printed {{time .GeneratedAt}} UTC</p>
+<p>Tick each duty when it is done, then scan its QR code to record it.</p>
+{{range .Items}}
+<section class="{{.Status}}">
+<div class="box"></div>
+<div class="text">
+<h2>{{.DutyName}}</h2>
+{{with .DutyDescription}}<p>{{.}}</p>{{end}}
+{{with details .DutyChecklistItem}}<p class="details">{{.}}</p>{{end}}
+{{range .FormFields}}<p>{{.Label}}{{if .Required}} *{{end}}: {{blank .}}</p>{{end}}
+</div>
+<img src="{{.QRCode}}" alt="QR code of the completion form">
+</section>
+{{else}}
+<p>No duty assignments.</p>
+{{end}}
+</body>
+</html>`))
+
+// writes the checklist as an A4 PDF; an item is never split over two pages
+func writeDutyChecklistPDF(w io.Writer, checklist *models.DutyChecklist, qrCodes [][]byte) error {
+	const (
+		margin     = 10.0
+		lineHeight = 5.0
+		boxSize    = 5.0
+		qrSize     = 30.0
+		gap        = 4.0
+	)
+
+	pdf := &gopdf.GoPdf{}
+	pdf.Start(gopdf.Config{Unit: gopdf.UnitMM, PageSize: *gopdf.PageSizeA4})
+	pdf.SetInfo(gopdf.PdfInfo{Title: "Duty checklist"})
+
+	// Unicode fonts, duty names may be translated. Characters the font has no glyph for (e.g. emoji) are left blank.
+	blank := func(r rune) rune { return ' ' }
+	if err := pdf.AddTTFFontDataWithOption("Go", goregular.TTF, gopdf.TtfOption{Style: gopdf.Regular, OnGlyphNotFoundSubstitute: blank}); err != nil {
+		return err
+	}
+	if err := pdf.AddTTFFontDataWithOption("Go", gobold.TTF, gopdf.TtfOption{Style: gopdf.Bold, OnGlyphNotFoundSubstitute: blank}); err != nil {
+		return err
+	}
+
+	pageWidth, pageHeight := 210.0, 297.0
+	textX := margin + boxSize + gap
+	textWidth := pageWidth - margin - qrSize - gap - textX
+
+	// writes a line of text at the left margin and moves to the next line
+	writeLine := func(style string, size float64, height float64, text string) error {
+		if err := pdf.SetFont("Go", style, size); err != nil {
+			return err
+		}
+		pdf.SetX(margin)
+		if err := pdf.Cell(&gopdf.Rect{W: pageWidth - 2*margin, H: height}, text); err != nil {
+			return err
+		}
+		pdf.SetY(pdf.GetY() + height)
+		return nil
+	}
+
+	pdf.AddPage()
+	pdf.SetY(margin)
+	if err := writeLine("B", 16, 8, "Duty checklist"); err != nil {
+		return err
+	}
+	if err := writeLine("", 10, lineHeight, fmt.Sprintf("Shift %s · printed %s UTC", checklist.ShiftId, formatReportTime(&checklist.GeneratedAt))); err != nil {
+		return err
+	}
+	if err := writeLine("", 10, lineHeight, "Tick each duty when it is done, then scan its QR code to record it."); err != nil {
+		return err
+	}
+	pdf.SetY(pdf.GetY() + gap)
+
+	if len(checklist.Items) == 0 {
+		if err := writeLine("", 10, lineHeight, "No duty assignments."); err != nil {
+			return err
+		}
+	}
+
+	for i, item := range checklist.Items {
+		// the lines of the item, in the font they're printed in
+		type line struct {
+			text  string
+			style string
+			size  float64
+		}
+		var lines []line
+		texts := []line{{item.DutyName, "B", 12}, {item.DutyDescription, "", 10}, {checklistItemDetails(item), "", 10}}
+		for _, field := range item.FormFields {
+			label := field.Label
+			if field.Required {
+				label += " *"
+			}
+			texts = append(texts, line{label + ": " + formFieldBlank(field), "", 10})
+		}
+		for _, text := range texts {
+			if strings.TrimSpace(text.text) == "" {
+				continue
+			}
+			if err := pdf.SetFont("Go", text.style, text.size); err != nil {
+				return err
+			}
+			split, err := pdf.SplitTextWithWordWrap(text.text, textWidth)
+			if err != nil {
+				return err
+			}
+			for _, part := range split {
+				lines = append(lines, line{part, text.style, text.size})
+			}
+		}
+
+		height := float64(len(lines)) * lineHeight
+		if height < qrSize {
+			height = qrSize
+		}
+		if pdf.GetY()+height > pageHeight-margin && pdf.GetY() > margin {
+			pdf.AddPage()
+			pdf.SetY(margin)
+		}
+
+		y := pdf.GetY()
+		pdf.SetLineWidth(0.5)
+		pdf.RectFromUpperLeftWithStyle(margin, y, boxSize, boxSize, "D")
+		if item.Status == models.StatusCompleted {
+			pdf.RectFromUpperLeftWithStyle(margin+1, y+1, boxSize-2, boxSize-2, "F")
+		}
+
+		for j, text := range lines {
+			if err := pdf.SetFont("Go", text.style, text.size); err != nil {
+				return err
+			}
+			pdf.SetXY(textX, y+float64(j)*lineHeight)
+			if err := pdf.Cell(&gopdf.Rect{W: textWidth, H: lineHeight}, text.text); err != nil {
+				return err
+			}
+		}
+
+		qrCode, err := gopdf.ImageHolderByBytes(qrCodes[i])
+		if err != nil {
+			return err
+		}
+		if err := pdf.ImageByHolder(qrCode, pageWidth-margin-qrSize, y, &gopdf.Rect{W: qrSize, H: qrSize}); err != nil {
+			return err
+		}
+
+		pdf.SetLineWidth(0.2)
+		pdf.Line(margin, y+height+gap/2, pageWidth-margin, y+height+gap/2)
+		pdf.SetXY(margin, y+height+gap)
+	}
+
+	return pdf.Write(w)
+}
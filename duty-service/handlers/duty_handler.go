@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"context"
+	"duty-service/images"
 	"duty-service/models"
+	"duty-service/repositories"
 	"duty-service/services"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -21,16 +27,26 @@ func NewDutyHandler(service services.InterfaceDutyService) *DutyHandler {
 }
 
 func (h *DutyHandler) GetAllDuties(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query()
-	name := query.Get("name") // Get the name parameter from the query string
+	search, err := parseDutySearch(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	duties, err := h.service.GetAllDuties(context.Background(), name)
+	result, err := h.service.GetAllDuties(context.Background(), search)
+	if errors.Is(err, models.ErrInvalidDutySearch) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to retrieve duties: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+
+	// the body stays a list of duties; the number of matches over all pages is a header
+	w.Header().Set("X-Total-Count", strconv.Itoa(result.Total))
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(duties)
+	json.NewEncoder(w).Encode(result.Duties)
 }
 
 func (h *DutyHandler) GetDutyById(w http.ResponseWriter, r *http.Request) {
@@ -44,6 +60,10 @@ func (h *DutyHandler) GetDutyById(w http.ResponseWriter, r *http.Request) {
 	}
 
 	duty, err := h.service.GetDutyById(context.Background(), partitionKey, rowKey)
+	if errors.Is(err, repositories.ErrDutyNotFound) {
+		http.Error(w, "Duty not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to retrieve duty: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -89,13 +109,41 @@ func (h *DutyHandler) CreateDuty(w http.ResponseWriter, r *http.Request) {
 		duty.PartitionKey = "Duty"
 	}
 
+	if duty.DueMinutes < 0 {
+		http.Error(w, "'DueMinutes' can't be negative", http.StatusBadRequest)
+		return
+	}
+
+	if err := duty.AppliesTo.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := models.ValidateDutyTags(duty.Tags); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the key keeps identifying the template for import/export when it is renamed later
+	if duty.DutyKey == "" {
+		duty.DutyKey = models.DeriveDutyKey(duty.RoleId, duty.DutyName)
+	} else if !models.ValidateDutyKey(duty.DutyKey) {
+		http.Error(w, "'DutyKey' must be lowercase letters, digits, '.', '_' or '-' (at most 100 characters)", http.StatusBadRequest)
+		return
+	}
+
 	// create a new UUID for the RowKey
 	duty.RowKey = uuid.New()
 	// I wanted to make these UUIDS so that they do not collide
 	// but i searched and it's EXTREMELY unlikely for them to colldie.
 	// source: https://stackoverflow.com/questions/24876188/how-big-is-the-chance-to-get-a-java-uuid-randomuuid-collision
 
-	if err := h.service.CreateDuty(context.Background(), duty); err != nil {
+	if err := h.service.CreateDuty(r.Context(), duty); err != nil { // the context identifies who created the first version
+		var formErr *models.FormValidationError
+		if errors.As(err, &formErr) {
+			http.Error(w, formErr.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to create duty: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -117,9 +165,37 @@ func (h *DutyHandler) UpdateDuty(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if duty.DueMinutes < 0 {
+		http.Error(w, "'DueMinutes' can't be negative", http.StatusBadRequest)
+		return
+	}
+
+	if err := duty.AppliesTo.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := models.ValidateDutyTags(duty.Tags); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 
-	if err := h.service.UpdateDuty(context.Background(), partitionKey, rowKey, duty); err != nil {
+	if err := h.service.UpdateDuty(r.Context(), partitionKey, rowKey, duty); err != nil { // the context identifies who created the version
+		var formErr *models.FormValidationError
+		if errors.As(err, &formErr) {
+			http.Error(w, formErr.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, repositories.ErrDutyNotFound) {
+			http.Error(w, "Duty not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, services.ErrDutyDeleted) || errors.Is(err, repositories.ErrDutyChanged) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, "Failed to update duty: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -135,9 +211,9 @@ func (h *DutyHandler) DeleteDuty(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	err := h.service.DeleteDuty(context.Background(), partitionKey, rowKey)
+	err := h.service.DeleteDuty(r.Context(), partitionKey, rowKey) // the context identifies who deleted the duty
 	if err != nil {
-		if err.Error() == "ResourceNotFound" {
+		if errors.Is(err, repositories.ErrDutyNotFound) {
 			http.Error(w, `{"error": "Duty not found"}`, http.StatusNotFound)
 			return
 		}
@@ -148,3 +224,133 @@ func (h *DutyHandler) DeleteDuty(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Duty deleted successfully"})
 }
+
+func (h *DutyHandler) RestoreDuty(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	partitionKey := vars["PartitionKey"]
+	rowKey := vars["RowKey"]
+
+	duty, err := h.service.RestoreDuty(r.Context(), partitionKey, rowKey)
+	switch {
+	case errors.Is(err, repositories.ErrDutyNotFound):
+		http.Error(w, "Duty not found", http.StatusNotFound)
+		return
+	case errors.Is(err, services.ErrDutyKeyInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to restore duty: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(duty)
+}
+
+// attaches an image, video or PDF (multipart field "file", optional "Title") to a duty
+func (h *DutyHandler) AddDutyAttachment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	// videos are the largest attachments; the rest of the form is small
+	r.Body = http.MaxBytesReader(w, r.Body, images.MaxAttachmentBytes+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "Failed to parse multipart form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	title := strings.TrimSpace(r.FormValue("Title"))
+	if err := models.ValidateDutyAttachmentTitle(title); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing 'file' in the form", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	// the context carries the uploader
+	attachment, err := h.service.AddDutyAttachment(r.Context(), vars["PartitionKey"], vars["RowKey"], title, header.Filename, file)
+	switch {
+	case errors.Is(err, repositories.ErrDutyNotFound):
+		http.Error(w, "Duty not found", http.StatusNotFound)
+		return
+	case errors.Is(err, services.ErrDutyDeleted):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, services.ErrTooManyDutyAttachments), errors.Is(err, images.ErrInvalidImage), errors.Is(err, images.ErrInvalidAttachment):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, images.ErrUnsupportedImage), errors.Is(err, images.ErrUnsupportedAttachment):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	case err != nil:
+		http.Error(w, "Failed to add attachment: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(attachment)
+}
+
+// deletes an attachment of a duty
+func (h *DutyHandler) DeleteDutyAttachment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	attachmentId, err := uuid.Parse(vars["AttachmentId"])
+	if err != nil {
+		http.Error(w, "Invalid AttachmentId", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.DeleteDutyAttachment(r.Context(), vars["PartitionKey"], vars["RowKey"], attachmentId)
+	switch {
+	case errors.Is(err, repositories.ErrDutyNotFound):
+		http.Error(w, "Duty not found", http.StatusNotFound)
+		return
+	case errors.Is(err, services.ErrDutyAttachmentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "Failed to delete attachment: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseDutySearch reads the search of GET /duties from the query string
+func parseDutySearch(query url.Values) (models.DutySearch, error) {
+	search := models.DutySearch{
+		Name:           query.Get("name"),
+		Query:          strings.TrimSpace(query.Get("q")),
+		NamePrefix:     strings.TrimSpace(query.Get("prefix")),
+		Tags:           query["tag"],
+		IncludeDeleted: query.Get("includeDeleted") == "true",
+		Sort:           query.Get("sort"),
+	}
+
+	if value := query.Get("roleId"); value != "" {
+		roleId, err := strconv.Atoi(value)
+		if err != nil || !models.ValidateRoleId(roleId) {
+			return models.DutySearch{}, fmt.Errorf("invalid 'roleId': '%s' (valid values are 0 = Admin, 1 = HeadTrucker, 2 = Chef, 3 = Staff)", value)
+		}
+		search.RoleId = &roleId
+	}
+
+	for name, target := range map[string]*int{"page": &search.Page, "pageSize": &search.PageSize} {
+		if value := query.Get(name); value != "" {
+			number, err := strconv.Atoi(value)
+			if err != nil || number < 1 {
+				return models.DutySearch{}, fmt.Errorf("invalid '%s': '%s' is not a positive number", name, value)
+			}
+			*target = number
+		}
+	}
+
+	return search, nil
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"duty-service/models"
+	"duty-service/services"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type DutyReportHandler struct {
+	service services.InterfaceDutyReportService
+}
+
+func NewDutyReportHandler(service services.InterfaceDutyReportService) *DutyReportHandler {
+	return &DutyReportHandler{service: service}
+}
+
+// report of a single shift
+func (h *DutyReportHandler) GetShiftReport(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if vars["ShiftId"] == "" {
+		http.Error(w, "Missing 'ShiftId' path parameter", http.StatusBadRequest)
+		return
+	}
+
+	uuids, err := parseUUIDs(map[string]string{"ShiftId": vars["ShiftId"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.GetShiftReport(context.Background(), uuids["ShiftId"])
+	if err != nil {
+		http.Error(w, "Failed to create duty report: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeReport(w, r, report, "duty-report_"+uuids["ShiftId"].String())
+}
+
+// report of a list of shifts (?shiftIds=a,b,c, e.g. all shifts of an event) or of a date range (?from=&to=)
+func (h *DutyReportHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var report *models.DutyReport
+	var filename string
+
+	switch {
+	case query.Get("shiftIds") != "" && (query.Get("from") != "" || query.Get("to") != ""):
+		http.Error(w, "Use either 'shiftIds' or 'from' and 'to', not both", http.StatusBadRequest)
+		return
+
+	case query.Get("shiftIds") != "":
+		var shiftIds []uuid.UUID
+		for _, value := range strings.Split(query.Get("shiftIds"), ",") {
+			shiftId, err := uuid.Parse(strings.TrimSpace(value))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid 'shiftIds' format: %v", err), http.StatusBadRequest)
+				return
+			}
+			shiftIds = append(shiftIds, shiftId)
+		}
+
+		var err error
+		report, err = h.service.GetShiftsReport(context.Background(), shiftIds)
+		if err != nil {
+			http.Error(w, "Failed to create duty report: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		filename = "duty-report"
+
+	case query.Get("from") != "" && query.Get("to") != "":
+		from, err := parseDateParam(query.Get("from"), false)
+		if err != nil {
+			http.Error(w, "Invalid 'from' date: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		to, err := parseDateParam(query.Get("to"), true)
+		if err != nil {
+			http.Error(w, "Invalid 'to' date: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err = h.service.GetDateRangeReport(context.Background(), from, to)
+		if err != nil {
+			http.Error(w, "Failed to create duty report: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		filename = fmt.Sprintf("duty-report_%s_%s", from.Format("2006-01-02"), to.Format("2006-01-02"))
+
+	default:
+		http.Error(w, "Missing 'shiftIds' or 'from' and 'to' query parameters", http.StatusBadRequest)
+		return
+	}
+
+	h.writeReport(w, r, report, filename)
+}
+
+// writes the report as JSON (default), CSV or printable HTML depending on ?format=
+func (h *DutyReportHandler) writeReport(w http.ResponseWriter, r *http.Request, report *models.DutyReport, filename string) {
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		writeDutyReportCSV(w, report)
+
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := dutyReportTemplate.Execute(w, report); err != nil {
+			http.Error(w, "Failed to render duty report: "+err.Error(), http.StatusInternalServerError)
+		}
+
+	default:
+		http.Error(w, "Invalid 'format'. Valid values are 'json', 'csv' or 'html'.", http.StatusBadRequest)
+	}
+}
+
+// writes one row per duty assignment
+func writeDutyReportCSV(w http.ResponseWriter, report *models.DutyReport) {
+	writer := csv.NewWriter(w)
+
+	writer.Write([]string{"Shift", "Duty", "Status", "Created (UTC)", "Due (UTC)", "Overdue", "Photo expected", "Photo missing", "Note"})
+
+	for _, shift := range report.Shifts {
+		for _, line := range shift.Assignments {
+			note := ""
+			if line.Note != nil {
+				note = *line.Note
+			}
+
+			writer.Write([]string{
+				shift.ShiftId.String(),
+				line.DutyName,
+				string(line.Status),
+				formatReportTime(&line.CreatedAt),
+				formatReportTime(line.DueAt),
+				strconv.FormatBool(line.Overdue),
+				strconv.FormatBool(line.ExpectsPhoto),
+				strconv.FormatBool(line.MissingPhoto),
+				note,
+			})
+		}
+	}
+
+	writer.Flush()
+}
+
+// formats a time for a report ("" when it isn't set)
+func formatReportTime(value *time.Time) string {
+	if value == nil || value.IsZero() {
+		return ""
+	}
+	return value.UTC().Format("2006-01-02 15:04")
+}
+
+// printable summary with the thumbnails of the photos
+var dutyReportTemplate = template.Must(template.New("duty-report").Funcs(template.FuncMap{
+	"time": func(value interface{}) string {
+		switch value := value.(type) {
+		case time.Time:
+			return formatReportTime(&value)
+		case *time.Time:
+			return formatReportTime(value)
+		}
+		return ""
+	},
+	"percent": func(value float64) string { return strconv.FormatFloat(value, 'f', 0, 64) + "%" },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Duty report</title>
+<style>
+body { font-family: sans-serif; font-size: 12px; margin: 24px; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 24px; }
+th, td { border: 1px solid #ccc; padding: 4px 6px; text-align: left; vertical-align: middle; }
+th { background: #f0f0f0; }
+img { max-width: 80px; max-height: 80px; }
+.Completed { color: #1b7a1b; }
+.Incomplete, .warning { color: #b00020; font-weight: bold; }
+.Skipped { color: #777; }
+section { page-break-inside: avoid; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>Duty report</h1>
+<p>Generated {{time .GeneratedAt}} UTC{{if .From}} &middot; {{time .From}} – {{time .To}}{{end}}</p>
+{{template "summary" .Summary}}
+{{range .Shifts}}
+<section>
+<h2>Shift {{.ShiftId}}</h2>
+{{template "summary" .Summary}}
+<table>
+<tr><th>Duty</th><th>Status</th><th>Due (UTC)</th><th>Photo</th><th>Note</th></tr>
+{{range .Assignments}}
+<tr>
+<td>{{.DutyName}}</td>
+<td class="{{.Status}}">{{.Status}}{{if .Overdue}} <span class="warning">(overdue)</span>{{end}}</td>
+<td>{{time .DueAt}}</td>
+<td>{{if .ThumbnailUrl}}<img src="{{.ThumbnailUrl}}" alt="photo">{{else if .MissingPhoto}}<span class="warning">missing</span>{{end}}</td>
+<td>{{with .Note}}{{.}}{{end}}</td>
+</tr>
+{{end}}
+</table>
+</section>
+{{else}}
+<p>No duty assignments.</p>
+{{end}}
+</body>
+</html>
+{{define "summary"}}
+<table>
+<tr><th>Completed</th><th>Duties</th><th>Done</th><th>Incomplete</th><th>Skipped</th><th>Overdue</th><th>Missing photos</th></tr>
+<tr><td>{{percent .CompletionPercentage}}</td><td>{{.Total}}</td><td>{{.Completed}}</td><td>{{.Incomplete}}</td><td>{{.Skipped}}</td><td>{{.Overdue}}</td><td>{{.MissingPhotos}}</td></tr>
+</table>
+{{end}}`))
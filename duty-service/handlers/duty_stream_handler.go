@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"duty-service/models"
+	"duty-service/services"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// how often a comment is sent on an idle stream, so proxies don't close the connection
+const dutyStreamKeepAlive = 15 * time.Second
+
+// how long a client waits before reconnecting (sent as the SSE retry field)
+const dutyStreamRetry = 3 * time.Second
+
+type DutyStreamHandler struct {
+	stream services.InterfaceDutyStream
+}
+
+func NewDutyStreamHandler(stream services.InterfaceDutyStream) *DutyStreamHandler {
+	return &DutyStreamHandler{stream: stream}
+}
+
+// streams the duty assignment changes of a shift (?shiftId=) or event (?eventId=) as Server-Sent Events
+func (h *DutyStreamHandler) StreamDutyAssignments(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var filter models.DutyStreamFilter
+	ids := map[string]string{}
+	for _, name := range []string{"shiftId", "eventId"} {
+		if value := query.Get(name); value != "" {
+			ids[name] = value
+		}
+	}
+	if len(ids) == 0 {
+		http.Error(w, "Missing 'shiftId' or 'eventId' query parameter", http.StatusBadRequest)
+		return
+	}
+
+	uuids, err := parseUUIDs(ids)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if shiftId, ok := uuids["shiftId"]; ok {
+		filter.ShiftId = &shiftId
+	}
+	if eventId, ok := uuids["eventId"]; ok {
+		filter.EventId = &eventId
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// browsers send the ID of the last event they received when they reconnect
+	subscription := h.stream.Subscribe(filter, r.Header.Get("Last-Event-ID"))
+	defer subscription.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // keep nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", dutyStreamRetry.Milliseconds())
+
+	// the events since the Last-Event-ID are gone: the client reloads the assignments and resumes from LastId
+	if subscription.Missed {
+		fmt.Fprintf(w, "id: %s\nevent: reset\ndata: {}\n\n", subscription.LastId)
+	}
+	for _, event := range subscription.Replay {
+		if err := writeDutyStreamEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(dutyStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return // fell behind, the client reconnects with its Last-Event-ID
+			}
+			if err := writeDutyStreamEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writes a duty stream event in the SSE format, named after its type
+func writeDutyStreamEvent(w http.ResponseWriter, event models.DutyStreamEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data)
+	return err
+}
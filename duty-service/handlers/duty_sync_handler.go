@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"duty-service/models"
+	"duty-service/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+type DutySyncHandler struct {
+	service services.InterfaceDutySyncService
+}
+
+func NewDutySyncHandler(service services.InterfaceDutySyncService) *DutySyncHandler {
+	return &DutySyncHandler{service: service}
+}
+
+// applies a batch of offline duty assignment changes and returns the assignments changed since the last sync.
+// Sent as JSON, or as multipart/form-data with the JSON in the 'sync' field and the photos as file parts.
+func (h *DutySyncHandler) SyncDutyAssignments(w http.ResponseWriter, r *http.Request) {
+	var request models.DutySyncRequest
+	photos := make(map[string]multipart.File)
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		// photos beyond 32MB are buffered on disk
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			http.Error(w, "Failed to parse multipart form: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		if err := json.Unmarshal([]byte(r.FormValue("sync")), &request); err != nil {
+			http.Error(w, "Invalid 'sync' field: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for name, files := range r.MultipartForm.File {
+			file, err := files[0].Open()
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to read photo '%s': %v", name, err), http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+			photos[name] = file
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(request.Changes) > services.MaxSyncChanges {
+		http.Error(w, fmt.Sprintf("Too many changes: at most %d per sync", services.MaxSyncChanges), http.StatusBadRequest)
+		return
+	}
+
+	if !models.ValidateSyncConflictMode(request.ConflictMode) {
+		http.Error(w, "Invalid ConflictMode. Valid values are 'last-writer-wins' or 'report'.", http.StatusBadRequest)
+		return
+	}
+
+	// the request context carries the uploader of the photos
+	response, err := h.service.Sync(r.Context(), request, photos)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidSyncToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to sync duty assignments: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		http.Error(w, "Failed to encode response: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"duty-service/models"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// largest duty template file that can be imported
+const maxDutyImportBytes = 5 << 20
+
+// columns of a duty template CSV (FormSchema is a JSON list, AppliesTo a JSON object, Tags are separated by commas)
+var dutyTemplateCSVHeader = []string{"DutyKey", "RoleId", "DutyName", "DutyDescription", "DueMinutes", "Mandatory", "FormSchema", "AppliesTo", "Tags"}
+
+// exports all duty templates as ?format=json (default), csv or yaml
+func (h *DutyHandler) ExportDuties(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" && format != "yaml" {
+		http.Error(w, "Invalid 'format'. Valid values are 'json', 'csv' or 'yaml'.", http.StatusBadRequest)
+		return
+	}
+
+	templates, err := h.service.ExportDuties(context.Background())
+	if err != nil {
+		http.Error(w, "Failed to export duties: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="duties.`+format+`"`)
+
+	switch format {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(templates)
+
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		writeDutyTemplatesCSV(w, templates)
+
+	case "yaml":
+		w.Header().Set("Content-Type", "application/yaml")
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		encoder.Encode(templates)
+		encoder.Close()
+	}
+}
+
+// imports duty templates from a JSON, CSV or YAML body (?format= or the Content-Type, JSON by default).
+// ?dryRun=true only returns the changes, ?prune=true also deletes the templates of the imported roles that aren't in the file.
+func (h *DutyHandler) ImportDuties(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	options := models.DutyImportOptions{}
+	for name, target := range map[string]*bool{"dryRun": &options.DryRun, "prune": &options.Prune} {
+		if value := query.Get(name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid '%s' value: %v", name, err), http.StatusBadRequest)
+				return
+			}
+			*target = parsed
+		}
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = importFormatFromContentType(r.Header.Get("Content-Type"))
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxDutyImportBytes)
+
+	var templates []models.DutyTemplate
+	var err error
+	switch format {
+	case "json":
+		decoder := json.NewDecoder(body)
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&templates)
+	case "csv":
+		templates, err = readDutyTemplatesCSV(body)
+	case "yaml":
+		decoder := yaml.NewDecoder(body)
+		decoder.KnownFields(true)
+		err = decoder.Decode(&templates)
+	default:
+		http.Error(w, "Invalid 'format'. Valid values are 'json', 'csv' or 'yaml'.", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Invalid duty templates: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(templates) == 0 {
+		http.Error(w, "The import contains no duty templates", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.ImportDuties(r.Context(), templates, options)
+	if err != nil {
+		var importErr *models.DutyImportError
+		if errors.As(err, &importErr) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string][]string{"errors": importErr.Problems})
+			return
+		}
+		http.Error(w, "Failed to import duties: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// importFormatFromContentType picks the import format from the Content-Type (JSON when it isn't CSV or YAML)
+func importFormatFromContentType(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return "csv"
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return "yaml"
+	}
+	return "json"
+}
+
+// writes one row per duty template
+func writeDutyTemplatesCSV(w io.Writer, templates []models.DutyTemplate) {
+	writer := csv.NewWriter(w)
+
+	writer.Write(dutyTemplateCSVHeader)
+
+	for _, template := range templates {
+		formSchema := ""
+		if len(template.FormSchema) > 0 {
+			schemaBytes, _ := json.Marshal(template.FormSchema)
+			formSchema = string(schemaBytes)
+		}
+		appliesTo := ""
+		if !template.AppliesTo.IsEmpty() {
+			rulesBytes, _ := json.Marshal(template.AppliesTo)
+			appliesTo = string(rulesBytes)
+		}
+
+		writer.Write([]string{
+			template.DutyKey,
+			strconv.Itoa(template.RoleId),
+			template.DutyName,
+			template.DutyDescription,
+			strconv.Itoa(template.DueMinutes),
+			strconv.FormatBool(template.Mandatory),
+			formSchema,
+			appliesTo,
+			strings.Join(template.Tags, ","),
+		})
+	}
+
+	writer.Flush()
+}
+
+// reads duty templates from a CSV with a header row (columns may be in any order, DutyKey, DueMinutes, Mandatory, FormSchema, AppliesTo and Tags are optional)
+func readDutyTemplatesCSV(r io.Reader) ([]models.DutyTemplate, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i // Excel starts the file with a byte order mark
+	}
+	for _, required := range []string{"RoleId", "DutyName"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing CSV column '%s'", required)
+		}
+	}
+
+	var templates []models.DutyTemplate
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %v", err)
+		}
+
+		value := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		template := models.DutyTemplate{
+			DutyKey:         value("DutyKey"),
+			DutyName:        value("DutyName"),
+			DutyDescription: value("DutyDescription"),
+		}
+
+		if template.RoleId, err = strconv.Atoi(value("RoleId")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid RoleId '%s'", line, value("RoleId"))
+		}
+		if dueMinutes := value("DueMinutes"); dueMinutes != "" {
+			if template.DueMinutes, err = strconv.Atoi(dueMinutes); err != nil {
+				return nil, fmt.Errorf("line %d: invalid DueMinutes '%s'", line, dueMinutes)
+			}
+		}
+		if mandatory := value("Mandatory"); mandatory != "" {
+			if template.Mandatory, err = strconv.ParseBool(mandatory); err != nil {
+				return nil, fmt.Errorf("line %d: invalid Mandatory '%s'", line, mandatory)
+			}
+		}
+		if formSchema := value("FormSchema"); formSchema != "" {
+			if err := json.Unmarshal([]byte(formSchema), &template.FormSchema); err != nil {
+				return nil, fmt.Errorf("line %d: invalid FormSchema: %v", line, err)
+			}
+		}
+		if appliesTo := value("AppliesTo"); appliesTo != "" {
+			if err := json.Unmarshal([]byte(appliesTo), &template.AppliesTo); err != nil {
+				return nil, fmt.Errorf("line %d: invalid AppliesTo: %v", line, err)
+			}
+		}
+		if tags := value("Tags"); tags != "" {
+			for _, tag := range strings.Split(tags, ",") {
+				template.Tags = append(template.Tags, strings.TrimSpace(tag))
+			}
+		}
+
+		templates = append(templates, template)
+	}
+
+	return templates, nil
+}
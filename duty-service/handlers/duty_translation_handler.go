@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"duty-service/models"
+	"duty-service/repositories"
+	"duty-service/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type DutyTranslationHandler struct {
+	service services.InterfaceDutyTranslationService
+}
+
+func NewDutyTranslationHandler(service services.InterfaceDutyTranslationService) *DutyTranslationHandler {
+	return &DutyTranslationHandler{service: service}
+}
+
+// adds or replaces the translation of a duty in the language of the path and returns the duty
+func (h *DutyTranslationHandler) SetDutyTranslation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var translation models.DutyTranslation
+	if err := json.NewDecoder(r.Body).Decode(&translation); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	duty, err := h.service.SetDutyTranslation(r.Context(), vars["PartitionKey"], vars["RowKey"], vars["Language"], translation)
+	if err != nil {
+		writeDutyTranslationError(w, "Failed to save translation: ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(duty)
+}
+
+// removes the translation of a duty in the language of the path
+func (h *DutyTranslationHandler) DeleteDutyTranslation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := h.service.DeleteDutyTranslation(r.Context(), vars["PartitionKey"], vars["RowKey"], vars["Language"]); err != nil {
+		writeDutyTranslationError(w, "Failed to delete translation: ", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lists the duties that aren't translated into the languages of ?languages=nl,pl (the configured ones by default)
+func (h *DutyTranslationHandler) GetMissingTranslations(w http.ResponseWriter, r *http.Request) {
+	var languages []string
+	for _, language := range strings.Split(r.URL.Query().Get("languages"), ",") {
+		if language = strings.TrimSpace(language); language != "" {
+			languages = append(languages, language)
+		}
+	}
+
+	missing, err := h.service.GetMissingTranslations(r.Context(), languages)
+	if err != nil {
+		writeDutyTranslationError(w, "Failed to find missing translations: ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(missing)
+}
+
+// writeDutyTranslationError maps the errors of the translation service to a status code
+func writeDutyTranslationError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidDutyTranslation):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repositories.ErrDutyNotFound):
+		http.Error(w, "Duty not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrDutyTranslationNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrDutyDeleted):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, repositories.ErrDutyChanged):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, message+err.Error(), http.StatusInternalServerError)
+	}
+}
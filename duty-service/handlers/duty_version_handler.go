@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"duty-service/repositories"
+	"duty-service/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// all versions of a duty template, oldest first
+func (h *DutyHandler) GetDutyVersions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	uuids, err := parseUUIDs(map[string]string{"RowKey": vars["RowKey"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	versions, err := h.service.GetDutyVersions(context.Background(), uuids["RowKey"])
+	if err != nil {
+		http.Error(w, "Failed to retrieve duty versions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+// a single version of a duty template (e.g. the one an assignment was created from)
+func (h *DutyHandler) GetDutyVersion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	uuids, err := parseUUIDs(map[string]string{"RowKey": vars["RowKey"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := parseVersion("Version", vars["Version"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dutyVersion, err := h.service.GetDutyVersion(context.Background(), uuids["RowKey"], version)
+	if errors.Is(err, repositories.ErrDutyVersionNotFound) {
+		http.Error(w, "Duty version not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve duty version: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dutyVersion)
+}
+
+// the differences between two versions of a duty template (?from=&to=)
+func (h *DutyHandler) DiffDutyVersions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+
+	uuids, err := parseUUIDs(map[string]string{"RowKey": vars["RowKey"]})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	from, err := parseVersion("from", query.Get("from"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	to, err := parseVersion("to", query.Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	diff, err := h.service.DiffDutyVersions(context.Background(), uuids["RowKey"], from, to)
+	if errors.Is(err, repositories.ErrDutyVersionNotFound) {
+		http.Error(w, "Duty version not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to compare duty versions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
+
+// makes an older version the current one again (stored as a new version)
+func (h *DutyHandler) RollbackDuty(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	partitionKey := vars["PartitionKey"]
+	rowKey := vars["RowKey"]
+
+	version, err := parseVersion("Version", vars["Version"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	duty, err := h.service.RollbackDuty(r.Context(), partitionKey, rowKey, version)
+	if errors.Is(err, repositories.ErrDutyVersionNotFound) {
+		http.Error(w, "Duty version not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repositories.ErrDutyNotFound) {
+		http.Error(w, "Duty not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, services.ErrDutyDeleted) || errors.Is(err, repositories.ErrDutyChanged) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to roll back duty: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(duty)
+}
+
+// parseVersion parses a version number (versions start at 1)
+func parseVersion(name string, value string) (int, error) {
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid '%s': '%s' is not a version number", name, value)
+	}
+	return version, nil
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"duty-service/storage"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type ImageHandler struct {
+	store *storage.LocalImageStore
+}
+
+func NewImageHandler(store *storage.LocalImageStore) *ImageHandler {
+	return &ImageHandler{store: store}
+}
+
+// serves an image of the local image store through its signed URL
+func (h *ImageHandler) ServeImage(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["Name"]
+	query := r.URL.Query()
+
+	file, contentType, err := h.store.Open(name, query.Get("expires"), query.Get("signature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInvalidImageURL), errors.Is(err, storage.ErrImageURLExpired):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, storage.ErrImageNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to read image: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		http.Error(w, "Failed to read image: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=60")
+	http.ServeContent(w, r, name, stat.ModTime(), file)
+}
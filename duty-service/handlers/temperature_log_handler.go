@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"duty-service/models"
+	"duty-service/services"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type TemperatureLogHandler struct {
+	service services.InterfaceTemperatureLogService
+}
+
+func NewTemperatureLogHandler(service services.InterfaceTemperatureLogService) *TemperatureLogHandler {
+	return &TemperatureLogHandler{service: service}
+}
+
+// fetch all temperature readings of a shift
+func (h *TemperatureLogHandler) GetReadingsByShiftId(w http.ResponseWriter, r *http.Request) {
+	shiftIdStr := r.URL.Query().Get("shiftId")
+	if shiftIdStr == "" {
+		http.Error(w, "Missing 'shiftId' query parameter", http.StatusBadRequest)
+		return
+	}
+
+	uuids, err := parseUUIDs(map[string]string{"shiftId": shiftIdStr})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	readings, err := h.service.GetReadingsByShiftId(context.Background(), uuids["shiftId"])
+	if err != nil {
+		http.Error(w, "Failed to retrieve temperature readings: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(readings)
+}
+
+// records a temperature reading
+func (h *TemperatureLogHandler) RecordReading(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ShiftId          string                     `json:"ShiftId"`
+		EventId          string                     `json:"EventId"`
+		UnitType         models.TemperatureUnitType `json:"UnitType"`
+		UnitName         string                     `json:"UnitName"`
+		Temperature      *float64                   `json:"Temperature"`
+		RecordedAt       *time.Time                 `json:"RecordedAt"`
+		EmployeeId       string                     `json:"EmployeeId"`
+		CorrectiveAction *string                    `json:"CorrectiveAction"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	uuids, err := parseUUIDs(map[string]string{"ShiftId": request.ShiftId, "EventId": request.EventId})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !models.ValidateTemperatureUnitType(request.UnitType) {
+		http.Error(w, "Invalid UnitType. Valid values are 'Fridge', 'Freezer' or 'HotHolding'.", http.StatusBadRequest)
+		return
+	}
+
+	if request.Temperature == nil || request.UnitName == "" || request.EmployeeId == "" {
+		http.Error(w, "Missing 'Temperature', 'UnitName' or 'EmployeeId'", http.StatusBadRequest)
+		return
+	}
+
+	reading := models.TemperatureReading{
+		PartitionKey:     uuids["ShiftId"],
+		EventId:          uuids["EventId"],
+		UnitType:         request.UnitType,
+		UnitName:         request.UnitName,
+		Temperature:      *request.Temperature,
+		EmployeeId:       request.EmployeeId,
+		CorrectiveAction: request.CorrectiveAction,
+	}
+	if request.RecordedAt != nil {
+		reading.RecordedAt = *request.RecordedAt
+	}
+
+	reading, err = h.service.RecordReading(context.Background(), reading)
+	if err != nil {
+		if errors.Is(err, services.ErrCorrectiveActionRequired) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to record temperature reading: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reading)
+}
+
+// exports the readings of an event within a date range as an inspection report (CSV by default, or JSON)
+func (h *TemperatureLogHandler) ExportReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if query.Get("eventId") == "" || query.Get("from") == "" || query.Get("to") == "" {
+		http.Error(w, "Missing 'eventId', 'from' or 'to' query parameter", http.StatusBadRequest)
+		return
+	}
+
+	uuids, err := parseUUIDs(map[string]string{"eventId": query.Get("eventId")})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	from, err := parseDateParam(query.Get("from"), false)
+	if err != nil {
+		http.Error(w, "Invalid 'from' date: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	to, err := parseDateParam(query.Get("to"), true)
+	if err != nil {
+		http.Error(w, "Invalid 'to' date: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	readings, err := h.service.GetReport(context.Background(), uuids["eventId"], from, to)
+	if err != nil {
+		http.Error(w, "Failed to retrieve temperature readings: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch query.Get("format") {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(readings)
+
+	case "", "csv":
+		filename := fmt.Sprintf("temperature-log_%s_%s_%s.csv", uuids["eventId"], from.Format("2006-01-02"), to.Format("2006-01-02"))
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		h.writeReportCSV(w, readings)
+
+	default:
+		http.Error(w, "Invalid 'format'. Valid values are 'csv' or 'json'.", http.StatusBadRequest)
+	}
+}
+
+// writes one row per reading with the safe range it was checked against
+func (h *TemperatureLogHandler) writeReportCSV(w http.ResponseWriter, readings []models.TemperatureReading) {
+	writer := csv.NewWriter(w)
+
+	writer.Write([]string{"Date", "Time (UTC)", "Shift", "Unit type", "Unit", "Temperature (°C)", "Safe range (°C)", "Status", "Corrective action", "Employee"})
+
+	for _, reading := range readings {
+		status := "OK"
+		if !reading.WithinRange {
+			status = "OUT OF RANGE"
+		}
+
+		correctiveAction := ""
+		if reading.CorrectiveAction != nil {
+			correctiveAction = *reading.CorrectiveAction
+		}
+
+		recordedAt := reading.RecordedAt.UTC()
+		writer.Write([]string{
+			recordedAt.Format("2006-01-02"),
+			recordedAt.Format("15:04:05"),
+			reading.PartitionKey.String(),
+			string(reading.UnitType),
+			reading.UnitName,
+			strconv.FormatFloat(reading.Temperature, 'f', 1, 64),
+			formatTemperatureRange(reading.SafeRange),
+			status,
+			correctiveAction,
+			reading.EmployeeId,
+		})
+	}
+
+	writer.Flush()
+}
+
+// formats a safe range like "≤ 7.0", "≥ 60.0" or "0.0 – 7.0" ("unknown" for readings from before the range was stored)
+func formatTemperatureRange(safeRange *models.TemperatureRange) string {
+	switch {
+	case safeRange == nil:
+		return "unknown"
+	case safeRange.Min != nil && safeRange.Max != nil:
+		return fmt.Sprintf("%.1f – %.1f", *safeRange.Min, *safeRange.Max)
+	case safeRange.Min != nil:
+		return fmt.Sprintf("≥ %.1f", *safeRange.Min)
+	case safeRange.Max != nil:
+		return fmt.Sprintf("≤ %.1f", *safeRange.Max)
+	default:
+		return "any"
+	}
+}
+
+// parses a date (2006-01-02) or an RFC3339 timestamp; a plain date as end of a range covers the whole day
+func parseDateParam(value string, endOfDay bool) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		if endOfDay {
+			return date.Add(24*time.Hour - time.Second), nil
+		}
+		return date, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,81 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Content types of the videos and documents that can be attached to a duty template
+const (
+	ContentTypeMP4  = "video/mp4"
+	ContentTypeWebM = "video/webm"
+	ContentTypePDF  = "application/pdf"
+)
+
+// Limits of an uploaded attachment (images have the limits of Process)
+const (
+	MaxVideoBytes      = 50 << 20 // 50MB, enough for a short instruction video
+	MaxDocumentBytes   = 20 << 20 // 20MB
+	MaxAttachmentBytes = MaxVideoBytes
+)
+
+// Kinds of attachments
+const (
+	AttachmentImage    = "image"
+	AttachmentVideo    = "video"
+	AttachmentDocument = "pdf"
+)
+
+var (
+	// ErrUnsupportedAttachment is returned when an attachment isn't an image, an MP4 or WebM video or a PDF
+	ErrUnsupportedAttachment = errors.New("unsupported attachment: only JPEG, PNG and WebP images, MP4 and WebM videos and PDFs are allowed")
+	// ErrInvalidAttachment is returned when a video or PDF is too large
+	ErrInvalidAttachment = errors.New("invalid attachment")
+)
+
+// an attachment after validation
+type ProcessedAttachment struct {
+	Kind      string        // AttachmentImage, AttachmentVideo or AttachmentDocument
+	File      EncodedImage  // what is stored: the re-encoded image, or the video or PDF as uploaded
+	Thumbnail *EncodedImage // thumbnail of an image (nil for videos and PDFs)
+}
+
+// ProcessAttachment checks the real type of an upload. Images are re-encoded like photos (see Process), videos and
+// PDFs are kept as they are once their type and size are checked.
+func ProcessAttachment(r io.Reader) (*ProcessedAttachment, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxAttachmentBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %v", err)
+	}
+
+	// sniff the real type instead of trusting the file name or the client's content type
+	sniffed := http.DetectContentType(data[:minInt(len(data), sniffLength)])
+
+	switch sniffed {
+	case ContentTypeJPEG, ContentTypePNG, ContentTypeWebP:
+		processed, err := Process(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return &ProcessedAttachment{Kind: AttachmentImage, File: processed.Original, Thumbnail: &processed.Thumbnail}, nil
+	case ContentTypeMP4, ContentTypeWebM:
+		if len(data) > MaxVideoBytes {
+			return nil, fmt.Errorf("%w: videos can't be larger than %dMB", ErrInvalidAttachment, MaxVideoBytes>>20)
+		}
+		extension := ".mp4"
+		if sniffed == ContentTypeWebM {
+			extension = ".webm"
+		}
+		return &ProcessedAttachment{Kind: AttachmentVideo, File: EncodedImage{Data: data, ContentType: sniffed, Extension: extension}}, nil
+	case ContentTypePDF:
+		if len(data) > MaxDocumentBytes {
+			return nil, fmt.Errorf("%w: PDFs can't be larger than %dMB", ErrInvalidAttachment, MaxDocumentBytes>>20)
+		}
+		return &ProcessedAttachment{Kind: AttachmentDocument, File: EncodedImage{Data: data, ContentType: sniffed, Extension: ".pdf"}}, nil
+	default:
+		return nil, ErrUnsupportedAttachment
+	}
+}
@@ -0,0 +1,192 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"time"
+)
+
+// EXIF tags the service reads from uploaded photos
+const (
+	tagOrientation        = 0x0112
+	tagExifIFDPointer     = 0x8769 // offset of the Exif IFD, which has the capture time
+	tagDateTimeOriginal   = 0x9003 // "2006:01:02 15:04:05" in the camera's local time
+	tagOffsetTimeOriginal = 0x9011 // "+02:00", the UTC offset of DateTimeOriginal (EXIF 2.31)
+)
+
+// TIFF field types the service reads
+const (
+	tiffTypeASCII = 2
+	tiffTypeLong  = 4
+)
+
+// exifDateTimeLayout is the layout of the EXIF date/time tags
+const exifDateTimeLayout = "2006:01:02 15:04:05"
+
+// readExifOrientation returns the EXIF orientation (1-8) of a JPEG, or 1 when it has none.
+// Only the orientation is read: the re-encoded image doesn't keep any EXIF data (GPS location included),
+// so the rotation has to be applied to the pixels instead.
+func readExifOrientation(data []byte) int {
+	tiff := findJPEGExif(data)
+	if tiff == nil {
+		return 1
+	}
+
+	value, ok := readIFD0Short(tiff, tagOrientation)
+	if !ok || value < 1 || value > 8 {
+		return 1
+	}
+
+	return int(value)
+}
+
+// readExifCaptureTime returns when a JPEG was taken according to its EXIF DateTimeOriginal. Without an
+// OffsetTimeOriginal the camera's UTC offset is unknown: the local time is then returned as UTC and local is true.
+// ok is false when the photo has no (valid) capture time.
+func readExifCaptureTime(data []byte) (capturedAt time.Time, local bool, ok bool) {
+	tiff := findJPEGExif(data)
+	if tiff == nil {
+		return time.Time{}, false, false
+	}
+	order, ok := tiffByteOrder(tiff)
+	if !ok {
+		return time.Time{}, false, false
+	}
+
+	exifOffset, ok := readIFDLong(tiff, order, int(order.Uint32(tiff[4:8])), tagExifIFDPointer)
+	if !ok {
+		return time.Time{}, false, false
+	}
+
+	dateTime, ok := readIFDASCII(tiff, order, int(exifOffset), tagDateTimeOriginal)
+	if !ok {
+		return time.Time{}, false, false
+	}
+
+	if offset, ok := readIFDASCII(tiff, order, int(exifOffset), tagOffsetTimeOriginal); ok {
+		if parsed, err := time.Parse(exifDateTimeLayout+"-07:00", dateTime+offset); err == nil {
+			return parsed, false, true
+		}
+	}
+
+	parsed, err := time.Parse(exifDateTimeLayout, dateTime)
+	if err != nil {
+		return time.Time{}, false, false
+	}
+	return parsed, true, true
+}
+
+// findJPEGExif returns the TIFF structure inside the APP1 "Exif" segment of a JPEG
+func findJPEGExif(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil
+	}
+
+	offset := 2
+	for offset+4 <= len(data) {
+		if data[offset] != 0xFF {
+			return nil
+		}
+		marker := data[offset+1]
+		if marker == 0xDA || marker == 0xD9 { // start of scan / end of image: no more metadata segments
+			return nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[offset+2 : offset+4]))
+		if length < 2 || offset+2+length > len(data) {
+			return nil
+		}
+		segment := data[offset+4 : offset+2+length]
+
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+
+		offset += 2 + length
+	}
+
+	return nil
+}
+
+// readIFD0Short reads a SHORT value of the first image file directory of a TIFF structure
+func readIFD0Short(tiff []byte, tag uint16) (uint16, bool) {
+	order, ok := tiffByteOrder(tiff)
+	if !ok {
+		return 0, false
+	}
+
+	entry, ok := findIFDEntry(tiff, order, int(order.Uint32(tiff[4:8])), tag)
+	if !ok {
+		return 0, false
+	}
+	return order.Uint16(entry[8:10]), true
+}
+
+// readIFDLong reads a LONG value (e.g. the offset of another IFD) of an image file directory
+func readIFDLong(tiff []byte, order binary.ByteOrder, ifdOffset int, tag uint16) (uint32, bool) {
+	entry, ok := findIFDEntry(tiff, order, ifdOffset, tag)
+	if !ok || order.Uint16(entry[2:4]) != tiffTypeLong {
+		return 0, false
+	}
+	return order.Uint32(entry[8:12]), true
+}
+
+// readIFDASCII reads an ASCII value of an image file directory, without its trailing NUL
+func readIFDASCII(tiff []byte, order binary.ByteOrder, ifdOffset int, tag uint16) (string, bool) {
+	entry, ok := findIFDEntry(tiff, order, ifdOffset, tag)
+	if !ok || order.Uint16(entry[2:4]) != tiffTypeASCII {
+		return "", false
+	}
+
+	// values of up to 4 bytes are stored in the entry itself, longer ones at an offset
+	count := int(order.Uint32(entry[4:8]))
+	value := entry[8:12]
+	if count > 4 {
+		offset := int(order.Uint32(entry[8:12]))
+		if offset < 0 || offset+count > len(tiff) {
+			return "", false
+		}
+		value = tiff[offset : offset+count]
+	} else {
+		value = value[:count]
+	}
+
+	return strings.TrimRight(string(value), "\x00 "), true
+}
+
+// tiffByteOrder returns the byte order of a TIFF structure ("II" little endian, "MM" big endian)
+func tiffByteOrder(tiff []byte) (binary.ByteOrder, bool) {
+	if len(tiff) < 8 {
+		return nil, false
+	}
+
+	switch string(tiff[:2]) {
+	case "II":
+		return binary.LittleEndian, true
+	case "MM":
+		return binary.BigEndian, true
+	default:
+		return nil, false
+	}
+}
+
+// findIFDEntry returns the 12-byte entry of a tag in the image file directory at ifdOffset
+func findIFDEntry(tiff []byte, order binary.ByteOrder, ifdOffset int, tag uint16) ([]byte, bool) {
+	if ifdOffset < 0 || ifdOffset+2 > len(tiff) {
+		return nil, false
+	}
+
+	entryCount := int(order.Uint16(tiff[ifdOffset : ifdOffset+2]))
+	for i := 0; i < entryCount; i++ {
+		entry := ifdOffset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return nil, false
+		}
+		if order.Uint16(tiff[entry:entry+2]) == tag {
+			return tiff[entry : entry+12], true
+		}
+	}
+
+	return nil, false
+}
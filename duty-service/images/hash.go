@@ -0,0 +1,32 @@
+package images
+
+import (
+	"image"
+	"math/bits"
+
+	"golang.org/x/image/draw"
+)
+
+// DifferenceHash returns the 64-bit difference hash (dHash) of an image: it is scaled down to 9x8 grey pixels and
+// every bit says whether a pixel is brighter than its right neighbour. Re-encoding, resizing and small edits barely
+// change the hash, so near-duplicates have hashes that differ in only a few bits.
+func DifferenceHash(img image.Image) uint64 {
+	small := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.CatmullRom.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small.GrayAt(x, y).Y > small.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HashDistance returns the number of bits two difference hashes differ in (0 = the same picture)
+func HashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
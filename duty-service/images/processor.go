@@ -0,0 +1,256 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// Content types of the images the service accepts and stores
+const (
+	ContentTypeJPEG = "image/jpeg"
+	ContentTypePNG  = "image/png"
+	ContentTypeWebP = "image/webp"
+)
+
+// Limits of an uploaded image
+const (
+	MaxImageBytes     = 10 << 20   // 10MB, same as the multipart form limit
+	MaxImagePixels    = 50_000_000 // guards against decompression bombs
+	ThumbnailMaxSize  = 320        // longest side of a thumbnail in pixels
+	jpegQuality       = 90
+	thumbnailQuality  = 80
+	sniffLength       = 512
+	thumbnailFileName = "_thumb"
+)
+
+var (
+	// ErrUnsupportedImage is returned when the upload isn't a JPEG, PNG or WebP image
+	ErrUnsupportedImage = errors.New("unsupported image format: only JPEG, PNG and WebP are allowed")
+	// ErrInvalidImage is returned when the upload claims to be an image but can't be decoded
+	ErrInvalidImage = errors.New("invalid image")
+)
+
+// an encoded image ready to be stored
+type EncodedImage struct {
+	Data        []byte
+	ContentType string
+	Extension   string // file extension including the dot, e.g. ".jpg"
+}
+
+// an upload after validation: the re-encoded original and its thumbnail, with what the authenticity checks need
+type ProcessedImage struct {
+	Original        EncodedImage
+	Thumbnail       EncodedImage
+	Hash            uint64     // difference hash of the picture (see DifferenceHash)
+	CapturedAt      *time.Time // EXIF capture time of a JPEG (nil when it has none)
+	CapturedAtLocal bool       // the camera didn't record its UTC offset: CapturedAt is its local time, written as UTC
+}
+
+// Process checks that the upload is a real JPEG, PNG or WebP image and re-encodes it without metadata
+// (which strips EXIF GPS data), applying the EXIF orientation first. WebP is stored as JPEG (or PNG when it
+// has transparency) because there is no WebP encoder. A JPEG thumbnail is generated next to it.
+func Process(r io.Reader) (*ProcessedImage, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %v", err)
+	}
+	if len(data) > MaxImageBytes {
+		return nil, fmt.Errorf("%w: larger than %dMB", ErrInvalidImage, MaxImageBytes>>20)
+	}
+
+	// sniff the real type instead of trusting the file name or the client's content type
+	sniffed := http.DetectContentType(data[:minInt(len(data), sniffLength)])
+
+	var decode func(io.Reader) (image.Image, error)
+	var decodeConfig func(io.Reader) (image.Config, error)
+	switch sniffed {
+	case ContentTypeJPEG:
+		decode, decodeConfig = jpeg.Decode, jpeg.DecodeConfig
+	case ContentTypePNG:
+		decode, decodeConfig = png.Decode, png.DecodeConfig
+	case ContentTypeWebP:
+		decode, decodeConfig = webp.Decode, webp.DecodeConfig
+	default:
+		return nil, ErrUnsupportedImage
+	}
+
+	// check the dimensions before decoding the whole image
+	config, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxImagePixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels is not allowed", ErrInvalidImage, config.Width, config.Height)
+	}
+
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	// the capture time is read before the metadata is dropped by re-encoding
+	var capturedAt *time.Time
+	var capturedAtLocal bool
+	if sniffed == ContentTypeJPEG {
+		img = applyOrientation(img, readExifOrientation(data))
+		if captured, local, ok := readExifCaptureTime(data); ok {
+			capturedAt, capturedAtLocal = &captured, local
+		}
+	}
+
+	// PNG stays PNG, JPEG stays JPEG, WebP becomes JPEG unless it uses transparency
+	var original EncodedImage
+	if sniffed == ContentTypePNG || (sniffed == ContentTypeWebP && !isOpaque(img)) {
+		original, err = encodePNG(img)
+	} else {
+		original, err = encodeJPEG(img, jpegQuality)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the hash is taken from the thumbnail's pixels: scaling down the full image again would only be slower
+	small := flatten(resize(img, ThumbnailMaxSize))
+	thumbnail, err := encodeJPEG(small, thumbnailQuality)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProcessedImage{
+		Original:        original,
+		Thumbnail:       thumbnail,
+		Hash:            DifferenceHash(small),
+		CapturedAt:      capturedAt,
+		CapturedAtLocal: capturedAtLocal,
+	}, nil
+}
+
+// ThumbnailName returns the name of a thumbnail stored next to an original (e.g. "a_b.png" -> "a_b_thumb.jpg")
+func ThumbnailName(baseName string, thumbnail EncodedImage) string {
+	return baseName + thumbnailFileName + thumbnail.Extension
+}
+
+// encodeJPEG encodes an image as JPEG
+func encodeJPEG(img image.Image, quality int) (EncodedImage, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return EncodedImage{}, fmt.Errorf("failed to encode JPEG: %v", err)
+	}
+	return EncodedImage{Data: buf.Bytes(), ContentType: ContentTypeJPEG, Extension: ".jpg"}, nil
+}
+
+// encodePNG encodes an image as PNG
+func encodePNG(img image.Image) (EncodedImage, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return EncodedImage{}, fmt.Errorf("failed to encode PNG: %v", err)
+	}
+	return EncodedImage{Data: buf.Bytes(), ContentType: ContentTypePNG, Extension: ".png"}, nil
+}
+
+// resize scales an image down so its longest side is at most maxSize (smaller images are kept as they are)
+func resize(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSize && height <= maxSize {
+		return img
+	}
+
+	if width >= height {
+		height = maxInt(1, height*maxSize/width)
+		width = maxSize
+	} else {
+		width = maxInt(1, width*maxSize/height)
+		height = maxSize
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// flatten draws an image on a white background (JPEG has no transparency)
+func flatten(img image.Image) image.Image {
+	if isOpaque(img) {
+		return img
+	}
+
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
+
+// isOpaque reports whether an image has no transparent pixels
+func isOpaque(img image.Image) bool {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok {
+		return opaque.Opaque()
+	}
+	return false
+}
+
+// applyOrientation rotates/flips an image according to its EXIF orientation (1 = as stored)
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 { // orientations 5-8 swap width and height
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = width-1-x, y
+			case 3: // rotated 180
+				dx, dy = width-1-x, height-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, height-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = height-1-y, x
+			case 7: // transversed
+				dx, dy = height-1-y, width-1-x
+			case 8: // rotated 90 counter-clockwise
+				dx, dy = y, width-1-x
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+
+	return dst
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,48 @@
+package locale
+
+import (
+	"context"
+
+	"golang.org/x/text/language"
+)
+
+// Preference is what a request asked for: the languages the caller reads, best first, and the language the duties
+// themselves are written in (used when none of the languages has a translation)
+type Preference struct {
+	Languages []string // canonical language tags, e.g. "pl" or "pt-BR"
+	Default   string
+}
+
+type preferenceKey struct{}
+
+// WithPreference returns a copy of the context carrying the caller's language preference
+func WithPreference(ctx context.Context, preference Preference) context.Context {
+	return context.WithValue(ctx, preferenceKey{}, preference)
+}
+
+// FromContext returns the caller's language preference (empty outside of a request)
+func FromContext(ctx context.Context) Preference {
+	preference, _ := ctx.Value(preferenceKey{}).(Preference)
+	return preference
+}
+
+// ParseAcceptLanguage returns the canonical tags of an Accept-Language header (or a single tag), best first.
+// Tags with q=0 are left out; an invalid header yields no tags, so it can't fail a request.
+func ParseAcceptLanguage(header string) []string {
+	if header == "" {
+		return nil
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil {
+		return nil
+	}
+
+	languages := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag != language.Und {
+			languages = append(languages, tag.String())
+		}
+	}
+	return languages
+}
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"crypto/rand"
+	"duty-service/config"
 	"duty-service/db"
 	"duty-service/metrics"
 	"duty-service/routes"
 	"duty-service/services"
+	"duty-service/storage"
 	"encoding/base64"
 	"log"
 	"net/http"
 	"os"
+	_ "time/tzdata" // the alpine image has no timezone database, needed for TIMEZONE
 
 	"github.com/joho/godotenv"
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -20,6 +24,33 @@ func failOnError(err error, msg string) {
 	}
 }
 
+// newImageStore creates the store of the duty assignment images selected with IMAGE_STORE
+func newImageStore(cfg *config.Config, blobConnectionString string) (storage.ImageStore, error) {
+	switch cfg.ImageStore {
+	case config.ImageStoreLocal:
+		signingKey := []byte(cfg.ImageSigningKey)
+		if len(signingKey) == 0 { // links then stop working after a restart, which is fine for local runs
+			signingKey = make([]byte, 32)
+			if _, err := rand.Read(signingKey); err != nil {
+				return nil, err
+			}
+		}
+		log.Printf("Storing images in %s", cfg.LocalImageDir)
+		return storage.NewLocalImageStore(cfg.LocalImageDir, cfg.PublicBaseURL+"/duties/images", signingKey)
+
+	case config.ImageStoreMemory:
+		log.Println("Storing images in memory, they are lost on restart")
+		return storage.NewMemoryImageStore(), nil
+
+	default:
+		blobServiceClient, err := db.InitAzureBlobStorage(blobConnectionString)
+		if err != nil {
+			return nil, err
+		}
+		return storage.NewAzureImageStore(blobServiceClient, "dutyassignmentimages"), nil
+	}
+}
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(".env"); err != nil {
@@ -59,10 +90,16 @@ func main() {
 		log.Fatal("Error initializing Azure Table Storage: ", err)
 	}
 
-	// Initialize Azure Blob Storage
-	blobServiceClient, err := db.InitAzureBlobStorage(blobConnectionString)
+	// Load optional settings (e.g. safe temperature ranges, image store)
+	cfg, err := config.Load()
 	if err != nil {
-		log.Fatal("Error initializing Azure Blob Storage: ", err)
+		log.Fatal("Error loading configuration: ", err)
+	}
+
+	// Initialize the store of the duty assignment images
+	imageStore, err := newImageStore(cfg, blobConnectionString)
+	if err != nil {
+		log.Fatal("Error initializing image store: ", err)
 	}
 
 	// Initialize RabbitMQ connection
@@ -75,18 +112,15 @@ func main() {
 	failOnError(err, "Failed to connect to RabbitMQ")
 	defer rabbitConn.Close()
 
-	// Initialize DutyAssignmentService
-	dutyService := &services.DutyAssignmentService{}
-
-	// Initialize RabbitMQService
-	rabbitMQService := services.NewRabbitMQService(dutyService, rabbitConn)
+	// Initialize RabbitMQService (starts consuming once the routes have built the DutyAssignmentService)
+	rabbitMQService := services.NewRabbitMQService(rabbitConn)
 	defer rabbitMQService.Close()
 
 	// Register the /metrics route for Prometheus to scrape
 	metrics.RegisterMetricsHandler()
 
 	// Register HTTP routes
-	router := routes.RegisterRoutes(tableClient, blobServiceClient, string(publicKeyPEM))
+	router := routes.RegisterRoutes(tableClient, imageStore, rabbitMQService, cfg, string(publicKeyPEM))
 
 	// Fixed port: 3004
 	port := "3004"
@@ -1,8 +1,10 @@
 package metrics
 
 import (
+	"duty-service/models"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -47,11 +49,50 @@ var (
 	)
 )
 
+// Duty analytics gauges (refreshed periodically from the duty assignments of the last weeks)
+var (
+	dutyCompletionRate = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "duty_completion_rate",
+			Help: "Completed out of all duty assignments that weren't skipped, per duty template (0-1)",
+		},
+		[]string{"duty_id", "duty_name"},
+	)
+
+	dutyMedianMinutesToComplete = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "duty_median_minutes_to_complete",
+			Help: "Median minutes from clock-in until a duty is completed, per duty template",
+		},
+		[]string{"duty_id", "duty_name"},
+	)
+
+	dutyLeftIncomplete = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "duty_left_incomplete",
+			Help: "Number of duty assignments left incomplete after the shift, per duty template",
+		},
+		[]string{"duty_id", "duty_name"},
+	)
+
+	dutyRoleCompletionRate = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "duty_role_completion_rate",
+			Help: "Completed out of all duty assignments that weren't skipped, per role (0-1)",
+		},
+		[]string{"role_id"},
+	)
+)
+
 func init() {
 	prometheus.MustRegister(httpRequests)
 	prometheus.MustRegister(httpRequestDuration)
 	prometheus.MustRegister(httpRequestErrors)
 	prometheus.MustRegister(applicationHealth)
+	prometheus.MustRegister(dutyCompletionRate)
+	prometheus.MustRegister(dutyMedianMinutesToComplete)
+	prometheus.MustRegister(dutyLeftIncomplete)
+	prometheus.MustRegister(dutyRoleCompletionRate)
 
 	log.Println("Prometheus metrics initialized")
 }
@@ -82,3 +123,24 @@ func CountError(method, status string) {
 func SetHealthStatus(service string, status float64) {
 	applicationHealth.WithLabelValues(service).Set(status)
 }
+
+// SetDutyAnalytics replaces the duty analytics gauges (templates and roles that are gone disappear)
+func SetDutyAnalytics(analytics *models.DutyAnalytics) {
+	dutyCompletionRate.Reset()
+	dutyMedianMinutesToComplete.Reset()
+	dutyLeftIncomplete.Reset()
+	dutyRoleCompletionRate.Reset()
+
+	for _, duty := range analytics.Duties {
+		dutyId := duty.DutyId.String()
+		dutyCompletionRate.WithLabelValues(dutyId, duty.DutyName).Set(duty.CompletionRate)
+		dutyLeftIncomplete.WithLabelValues(dutyId, duty.DutyName).Set(float64(duty.LeftIncomplete))
+		if duty.MedianMinutesToComplete != nil {
+			dutyMedianMinutesToComplete.WithLabelValues(dutyId, duty.DutyName).Set(*duty.MedianMinutesToComplete)
+		}
+	}
+
+	for _, role := range analytics.Roles {
+		dutyRoleCompletionRate.WithLabelValues(strconv.Itoa(role.RoleId)).Set(role.CompletionRate)
+	}
+}
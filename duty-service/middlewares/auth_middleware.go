@@ -3,7 +3,9 @@ package middlewares
 import (
 	"crypto/rsa"
 	"crypto/x509"
+	"duty-service/auth"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -12,6 +14,9 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// errCertificate is returned when the configured public key PEM can't be used
+var errCertificate = errors.New("failed to parse certificate")
+
 func JWTMiddleware(publicKeyPEM string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -20,53 +25,113 @@ func JWTMiddleware(publicKeyPEM string, next http.Handler) http.Handler {
 			return
 		}
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		// Parse the certificate
-		block, _ := pem.Decode([]byte(publicKeyPEM))
-		if block == nil {
-			log.Println("Failed to decode PEM block")
-			log.Println("Public Key PEM:", publicKeyPEM)
+		identity, err := parseToken(publicKeyPEM, tokenString)
+		if errors.Is(err, errCertificate) {
 			http.Error(w, "Failed to parse certificate", http.StatusInternalServerError)
 			return
 		}
-		cert, err := x509.ParseCertificate(block.Bytes)
 		if err != nil {
-			fmt.Println("Public Key PEM:")
-			fmt.Println(publicKeyPEM)
-			http.Error(w, "Failed to parse certificate", http.StatusInternalServerError)
+			http.Error(w, "Unauthorized: invalid token", http.StatusUnauthorized)
 			return
 		}
-		publicKey := cert.PublicKey.(*rsa.PublicKey)
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		// Check for admin role
+		if !identity.IsAdmin() {
+			http.Error(w, "Forbidden: admin role required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), identity)))
+	})
+}
+
+// IdentityMiddleware adds the caller's identity to the request context when a valid bearer token is sent.
+// Requests without (or with an invalid) token continue anonymously, so it can be used on public routes.
+func IdentityMiddleware(publicKeyPEM string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if strings.HasPrefix(authHeader, "Bearer ") {
+				identity, err := parseToken(publicKeyPEM, strings.TrimPrefix(authHeader, "Bearer "))
+				if err == nil {
+					r = r.WithContext(auth.WithIdentity(r.Context(), identity))
+				}
 			}
-			return publicKey, nil
+			next.ServeHTTP(w, r)
 		})
-		if err != nil || !token.Valid {
-			http.Error(w, "Unauthorized: invalid token", http.StatusUnauthorized)
-			return
+	}
+}
+
+// parseToken verifies a JWT with the public key of the certificate and returns the identity in its claims
+func parseToken(publicKeyPEM string, tokenString string) (auth.Identity, error) {
+	// Parse the certificate
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		log.Println("Failed to decode PEM block")
+		log.Println("Public Key PEM:", publicKeyPEM)
+		return auth.Identity{}, errCertificate
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		fmt.Println("Public Key PEM:")
+		fmt.Println(publicKeyPEM)
+		return auth.Identity{}, errCertificate
+	}
+	publicKey := cert.PublicKey.(*rsa.PublicKey)
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
-			return
+		return publicKey, nil
+	})
+	if err != nil || !token.Valid {
+		return auth.Identity{}, fmt.Errorf("invalid token: %v", err)
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return auth.Identity{}, errors.New("invalid token claims")
+	}
+
+	identity := auth.Identity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Locale, _ = claims["locale"].(string)
+	if realmAccess, ok := claims["realm_access"].(map[string]interface{}); ok {
+		if roles, ok := realmAccess["roles"].([]interface{}); ok {
+			for _, role := range roles {
+				if roleStr, ok := role.(string); ok {
+					identity.Roles = append(identity.Roles, roleStr)
+				}
+			}
 		}
-		// Check for admin role
-		hasAdminRole := false
-		if realmAccess, ok := claims["realm_access"].(map[string]interface{}); ok {
-			if roles, ok := realmAccess["roles"].([]interface{}); ok {
+	}
+
+	return identity, nil
+}
+
+// RequireIdentity only lets requests through whose caller was identified by IdentityMiddleware and, when roles are
+// given, has one of them
+func RequireIdentity(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, ok := auth.FromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized: No token provided", http.StatusUnauthorized)
+				return
+			}
+
+			if len(roles) > 0 {
+				allowed := false
 				for _, role := range roles {
-					if roleStr, ok := role.(string); ok && roleStr == "admin" {
-						hasAdminRole = true
+					if identity.HasRole(role) {
+						allowed = true
 						break
 					}
 				}
+				if !allowed {
+					http.Error(w, "Forbidden: one of the roles "+strings.Join(roles, ", ")+" required", http.StatusForbidden)
+					return
+				}
 			}
-		}
-		if !hasAdminRole {
-			http.Error(w, "Forbidden: admin role required", http.StatusForbidden)
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"duty-service/auth"
+	"duty-service/locale"
+	"net/http"
+)
+
+// LanguageMiddleware adds the caller's language preference to the request context: the languages of the
+// Accept-Language header first, then the preferred language of the employee's account (the token's "locale" claim,
+// so it has to run after IdentityMiddleware). defaultLanguage is the language the duties are written in.
+func LanguageMiddleware(defaultLanguage string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			languages := locale.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+			if identity, ok := auth.FromContext(r.Context()); ok {
+				languages = append(languages, locale.ParseAcceptLanguage(identity.Locale)...)
+			}
+
+			preference := locale.Preference{Languages: languages, Default: defaultLanguage}
+			next.ServeHTTP(w, r.WithContext(locale.WithPreference(r.Context(), preference)))
+		})
+	}
+}
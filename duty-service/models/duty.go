@@ -1,6 +1,10 @@
 package models
 
 import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,15 +12,120 @@ import (
 
 // represents a duty assigned to a role
 type Duty struct {
-	PartitionKey    string    `json:"PartitionKey"`    // Azure Table Storage PartitionKey
-	RowKey          uuid.UUID `json:"RowKey"`          // THIS IS ID OF THE TASK Rowkey - Primary Key (string representation of UUID)
-	RoleId          int       `json:"RoleId"`          // ID of the associated role (now an int because it is an enum in Employee ms)
-	DutyName        string    `json:"DutyName"`        // Name of the duty
-	DutyDescription string    `json:"DutyDescription"` // Detailed description
+	PartitionKey    string             `json:"PartitionKey"`    // Azure Table Storage PartitionKey
+	RowKey          uuid.UUID          `json:"RowKey"`          // THIS IS ID OF THE TASK Rowkey - Primary Key (string representation of UUID)
+	RoleId          int                `json:"RoleId"`          // ID of the associated role (now an int because it is an enum in Employee ms)
+	DutyName        string             `json:"DutyName"`        // Name of the duty
+	DutyDescription string             `json:"DutyDescription"` // Detailed description
+	FormSchema      []FormField        `json:"FormSchema"`      // Inputs the employee fills in when doing the duty (optional)
+	DueMinutes      int                `json:"DueMinutes"`      // Minutes after clock-in the duty has to be done (0 = no deadline)
+	DutyKey         string             `json:"DutyKey"`         // stable key that identifies the template across environments (used by import/export)
+	Version         int                `json:"Version"`         // current version of the template (0 for duties from before versioning)
+	AppliesTo       *DutyApplicability `json:"AppliesTo"`       // optional rules for the events/days the duty applies to (null = every shift of the role)
+	Mandatory       bool               `json:"Mandatory"`       // has to be completed or skipped before the employee can clock out
+	Tags            []string           `json:"Tags"`            // labels to find the duty by, e.g. "cleaning" or "closing" (optional)
+	Translations    DutyTranslations   `json:"Translations"`    // name and description in other languages, keyed by language tag (managed with the translation endpoints)
+	Attachments     []DutyAttachment   `json:"Attachments"`     // images, videos and PDFs that show how to do the duty, oldest first (managed with the attachment endpoints)
+	DeletedAt       *time.Time         `json:"DeletedAt"`       // when the duty was deleted (null = not deleted); purged after the retention period
+	DeletedBy       string             `json:"DeletedBy"`       // user ID of the admin who deleted the duty
+	ETag            string             `json:"-"`               // ETag of the stored duty when it was read, so an update can't overwrite a concurrent one
 }
+
+// duty keys are short slugs, e.g. "chef-clean-grill"
+var dutyKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,99}$`)
+
+// characters that are replaced by a dash when a key is derived from a duty name
+var nonKeyCharacters = regexp.MustCompile(`[^a-z0-9]+`)
+
+// checks if a duty key is a valid slug
+func ValidateDutyKey(key string) bool {
+	return dutyKeyPattern.MatchString(key)
+}
+
+// returns the DutyKey, or a key derived from the role and name for duties created before keys existed
+func (d Duty) Key() string {
+	if d.DutyKey != "" {
+		return d.DutyKey
+	}
+	return DeriveDutyKey(d.RoleId, d.DutyName)
+}
+
+// derives a duty key from the role and name, e.g. (2, "Clean grill") -> "chef-clean-grill"
+func DeriveDutyKey(roleId int, dutyName string) string {
+	role := strings.ToLower(Role(roleId).String())
+	if role == "" {
+		role = "role" + strconv.Itoa(roleId)
+	}
+	name := strings.Trim(nonKeyCharacters.ReplaceAllString(strings.ToLower(dutyName), "-"), "-")
+	key := role + "-" + name
+	if len(key) > 100 {
+		key = strings.TrimRight(key[:100], "-")
+	}
+	return key
+}
+
+// limits of a duty's tags
+const (
+	MaxDutyTags      = 20
+	MaxDutyTagLength = 50
+)
+
+// checks the tags of a duty for empty, too long and too many values
+func ValidateDutyTags(tags []string) error {
+	if len(tags) > MaxDutyTags {
+		return fmt.Errorf("invalid Tags: at most %d tags are allowed", MaxDutyTags)
+	}
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" {
+			return fmt.Errorf("invalid Tags: tags can't be empty")
+		}
+		if len(tag) > MaxDutyTagLength {
+			return fmt.Errorf("invalid Tags: '%s' is longer than %d characters", tag, MaxDutyTagLength)
+		}
+	}
+	return nil
+}
+
+// compares two lists of tags in order
+func EqualTags(a, b []string) bool {
+	return equalStrings(a, b)
+}
+
+// checks if the duty has the tag (case-insensitive)
+func (d Duty) HasTag(tag string) bool {
+	return containsAnyFold(d.Tags, tag)
+}
+
+// checks if the duty was (soft) deleted
+func (d Duty) IsDeleted() bool {
+	return d.DeletedAt != nil
+}
+
+// returns the duties that aren't deleted
+func ActiveDuties(duties []Duty) []Duty {
+	active := make([]Duty, 0, len(duties))
+	for _, duty := range duties {
+		if !duty.IsDeleted() {
+			active = append(active, duty)
+		}
+	}
+	return active
+}
+
+// checks if the duty asks for a photo
+func (d Duty) ExpectsPhoto() bool {
+	for _, field := range d.FormSchema {
+		if field.Type == FieldPhoto {
+			return true
+		}
+	}
+	return false
+}
+
 type ClockInMessage struct {
 	ShiftID     uuid.UUID `json:"shift_id"`
 	ClockInTime time.Time `json:"clock_in_time"`
 	//RoleId      uuid.UUID `json:"role_id"` //or roleID?? - //Beth: changed this to int but commented out the original for Myrthe (delete this comment later)
-	RoleId int `json:"role_id"`
+	RoleId     int    `json:"role_id"`
+	EmployeeId string `json:"employee_id"` // employee who clocked in (optional, used in the duty events)
 }
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Analytics over the duty assignments created in a period
+type DutyAnalytics struct {
+	GeneratedAt        time.Time               `json:"GeneratedAt"`
+	From               time.Time               `json:"From"`
+	To                 time.Time               `json:"To"`
+	Totals             DutyAnalyticsCounts     `json:"Totals"`
+	Duties             []DutyTemplateAnalytics `json:"Duties"`             // per duty template, by name
+	Roles              []RoleDutyAnalytics     `json:"Roles"`              // per role the duties were assigned for
+	MostLeftIncomplete []DutyTemplateAnalytics `json:"MostLeftIncomplete"` // templates most often left incomplete after the shift, worst first
+	Weeks              []WeeklyDutyAnalytics   `json:"Weeks"`              // per ISO week, oldest first
+}
+
+// the counts that every analytics group shares
+type DutyAnalyticsCounts struct {
+	Assignments    int     `json:"Assignments"`
+	Completed      int     `json:"Completed"`
+	Skipped        int     `json:"Skipped"`
+	LeftIncomplete int     `json:"LeftIncomplete"` // still incomplete when the shift was clocked out (even if done later)
+	CompletionRate float64 `json:"CompletionRate"` // completed out of all assignments that weren't skipped (0-1)
+}
+
+// analytics of a single duty template
+type DutyTemplateAnalytics struct {
+	DutyId   uuid.UUID `json:"DutyId"`
+	DutyName string    `json:"DutyName"`
+	DutyAnalyticsCounts
+	MedianMinutesToComplete *float64 `json:"MedianMinutesToComplete"` // from clock-in until completed (null without completed assignments)
+	MedianMinutesWorking    *float64 `json:"MedianMinutesWorking"`    // from the first update until completed
+}
+
+// analytics of the duties of a role
+type RoleDutyAnalytics struct {
+	RoleId int `json:"RoleId"`
+	DutyAnalyticsCounts
+}
+
+// analytics of a week
+type WeeklyDutyAnalytics struct {
+	Week      string    `json:"Week"`      // ISO week, e.g. 2024-W07
+	WeekStart time.Time `json:"WeekStart"` // Monday 00:00 UTC
+	DutyAnalyticsCounts
+	MedianMinutesToComplete *float64 `json:"MedianMinutesToComplete"`
+}
+
+// adds a duty assignment to the counts. leftIncomplete tells whether it was still incomplete at clock-out.
+func (c *DutyAnalyticsCounts) Add(status DutyAssignmentStatus, leftIncomplete bool) {
+	c.Assignments++
+	switch status {
+	case StatusCompleted:
+		c.Completed++
+	case StatusSkipped:
+		c.Skipped++
+	}
+	if leftIncomplete {
+		c.LeftIncomplete++
+	}
+
+	if counted := c.Assignments - c.Skipped; counted > 0 {
+		c.CompletionRate = float64(c.Completed) / float64(counted)
+	} else {
+		c.CompletionRate = 1
+	}
+}
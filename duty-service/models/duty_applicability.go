@@ -0,0 +1,144 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Optional rules that limit which shifts a duty template is assigned to. Every rule that is set has to match;
+// within a rule one of the values is enough. Templates without rules are assigned to every shift of their role.
+type DutyApplicability struct {
+	EventTags []string `json:"EventTags,omitempty" yaml:"EventTags,omitempty"` // the event has one of these tags
+	Venues    []string `json:"Venues,omitempty" yaml:"Venues,omitempty"`       // the event takes place at one of these venues
+	TruckIds  []string `json:"TruckIds,omitempty" yaml:"TruckIds,omitempty"`   // the event uses one of these trucks
+	Weekdays  []string `json:"Weekdays,omitempty" yaml:"Weekdays,omitempty"`   // the clock-in is on one of these days, in local time ("Monday" ... "Sunday")
+}
+
+// checks if no rule is set
+func (a *DutyApplicability) IsEmpty() bool {
+	return a == nil || (len(a.EventTags) == 0 && len(a.Venues) == 0 && len(a.TruckIds) == 0 && len(a.Weekdays) == 0)
+}
+
+// checks if a rule needs the shift's event (tags, venue or truck)
+func (a *DutyApplicability) NeedsEvent() bool {
+	return a != nil && (len(a.EventTags) > 0 || len(a.Venues) > 0 || len(a.TruckIds) > 0)
+}
+
+// checks the rules for empty values and unknown weekdays
+func (a *DutyApplicability) Validate() error {
+	if a == nil {
+		return nil
+	}
+
+	for rule, values := range map[string][]string{"EventTags": a.EventTags, "Venues": a.Venues, "TruckIds": a.TruckIds, "Weekdays": a.Weekdays} {
+		for _, value := range values {
+			if strings.TrimSpace(value) == "" {
+				return fmt.Errorf("invalid AppliesTo: %s can't contain empty values", rule)
+			}
+		}
+	}
+
+	for _, weekday := range a.Weekdays {
+		if _, ok := parseWeekday(weekday); !ok {
+			return fmt.Errorf("invalid AppliesTo: unknown weekday '%s' (use Monday ... Sunday)", weekday)
+		}
+	}
+
+	return nil
+}
+
+// checks if a template with these rules applies to a shift. event is nil when the shift's event is unknown;
+// rules that need the event don't match then.
+func (a *DutyApplicability) Matches(event *Event, clockInTime time.Time) bool {
+	if a.IsEmpty() {
+		return true
+	}
+
+	if len(a.Weekdays) > 0 {
+		matches := false
+		for _, value := range a.Weekdays {
+			if weekday, _ := parseWeekday(value); weekday == clockInTime.Weekday() {
+				matches = true
+				break
+			}
+		}
+		if !matches {
+			return false
+		}
+	}
+
+	if !a.NeedsEvent() {
+		return true
+	}
+	if event == nil {
+		return false
+	}
+
+	if len(a.EventTags) > 0 && !containsAnyFold(a.EventTags, event.Tags...) {
+		return false
+	}
+	if len(a.Venues) > 0 && !containsAnyFold(a.Venues, event.Venue) {
+		return false
+	}
+	if len(a.TruckIds) > 0 && !containsAnyFold(a.TruckIds, event.TruckID) {
+		return false
+	}
+
+	return true
+}
+
+// checks if two sets of rules are the same
+func (a *DutyApplicability) Equal(other *DutyApplicability) bool {
+	if a.IsEmpty() || other.IsEmpty() {
+		return a.IsEmpty() == other.IsEmpty()
+	}
+	return equalStrings(a.EventTags, other.EventTags) && equalStrings(a.Venues, other.Venues) &&
+		equalStrings(a.TruckIds, other.TruckIds) && equalStrings(a.Weekdays, other.Weekdays)
+}
+
+// returns the duties that apply to a shift of the given event that was clocked in at clockInTime
+func ApplicableDuties(duties []Duty, event *Event, clockInTime time.Time) []Duty {
+	var applicable []Duty
+	for _, duty := range duties {
+		if duty.AppliesTo.Matches(event, clockInTime) {
+			applicable = append(applicable, duty)
+		}
+	}
+	return applicable
+}
+
+// parseWeekday parses an English weekday name (case-insensitive)
+func parseWeekday(value string) (time.Weekday, bool) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(weekday.String(), strings.TrimSpace(value)) {
+			return weekday, true
+		}
+	}
+	return 0, false
+}
+
+// containsAnyFold checks if one of the values is in the list (case-insensitive, ignoring surrounding spaces)
+func containsAnyFold(list []string, values ...string) bool {
+	for _, item := range list {
+		for _, value := range values {
+			if strings.EqualFold(strings.TrimSpace(item), strings.TrimSpace(value)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// equalStrings compares two lists in order
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,14 +1,50 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // A duty assigned to a Shift (unique for each employee and for each shift)
 type DutyAssignment struct {
-	PartitionKey           uuid.UUID            `json:"PartitionKey"`           // ShiftID (used as PartitionKey in Azure Table Storage)
-	RowKey                 uuid.UUID            `json:"RowKey"`                 // DutyID (used as RowKey in Azure Table Storage)
-	DutyAssignmentStatus   DutyAssignmentStatus `json:"DutyAssignmentStatus"`   // DutyAssignmentStatus (e.g., "Completed", "Incomplete")
-	DutyAssignmentImageUrl *string              `json:"DutyAssignmentImageUrl"` // URL to an image (optional, nullable)
-	DutyAssignmentNote     *string              `json:"DutyAssignmentNote"`     // Additional note (optional, nullable)
+	PartitionKey               uuid.UUID              `json:"PartitionKey"`               // ShiftID (used as PartitionKey in Azure Table Storage)
+	RowKey                     uuid.UUID              `json:"RowKey"`                     // DutyID (used as RowKey in Azure Table Storage)
+	DutyAssignmentStatus       DutyAssignmentStatus   `json:"DutyAssignmentStatus"`       // DutyAssignmentStatus (e.g., "Completed", "Incomplete")
+	DutyAssignmentImageUrl     *string                `json:"DutyAssignmentImageUrl"`     // short-lived signed URL to the main photo (optional, nullable, generated on read)
+	DutyAssignmentThumbnailUrl *string                `json:"DutyAssignmentThumbnailUrl"` // short-lived signed URL to a small version of the main photo (optional, nullable)
+	ImageBlobName              string                 `json:"-"`                          // name of the main photo's blob (what is actually stored)
+	ThumbnailBlobName          string                 `json:"-"`                          // name of the main photo's thumbnail blob
+	PhotoFlags                 []PhotoFlag            `json:"PhotoFlags"`                 // flags of the assignment's photos that wait for review (empty when there are none)
+	DutyAssignmentNote         *string                `json:"DutyAssignmentNote"`         // Additional note (optional, nullable)
+	DutyPartitionKey           string                 `json:"DutyPartitionKey"`           // PartitionKey of the duty template this assignment was created from
+	DutyRowKey                 uuid.UUID              `json:"DutyRowKey"`                 // RowKey of the duty template this assignment was created from
+	DutyVersion                int                    `json:"DutyVersion"`                // version of the template that was active at clock-in (0 for older assignments)
+	DutyName                   string                 `json:"DutyName"`                   // name of the template in the caller's language (resolved on read, not stored)
+	DutyDescription            string                 `json:"DutyDescription"`            // description of the template in the caller's language (resolved on read)
+	Language                   string                 `json:"Language"`                   // language tag of DutyName and DutyDescription (resolved on read)
+	Attachments                []DutyAttachment       `json:"Attachments"`                // instructions attached to the template, with signed URLs (resolved on read)
+	FormValues                 map[string]interface{} `json:"FormValues"`                 // Answers to the duty template's form, keyed by FormField.Key
+	CreatedAt                  time.Time              `json:"CreatedAt"`                  // when the assignment was created (at clock-in)
+	DueAt                      *time.Time             `json:"DueAt"`                      // deadline of the duty (optional, nullable)
+	StartedAt                  *time.Time             `json:"StartedAt"`                  // first time the assignment was updated (nullable)
+	CompletedAt                *time.Time             `json:"CompletedAt"`                // when it was marked Completed (nullable, cleared when reopened)
+	RoleId                     *int                   `json:"RoleId"`                     // role the duty was assigned for at clock-in (nullable for older assignments)
+	EventId                    *uuid.UUID             `json:"EventId"`                    // event of the shift the duties were selected for (nullable when it was unknown at clock-in)
+	Mandatory                  bool                   `json:"Mandatory"`                  // has to be completed or skipped before clock-out (copied from the template at clock-in)
+	FrozenAt                   *time.Time             `json:"FrozenAt"`                   // when the shift was clocked out; only admins can change it afterwards (nullable)
+	IncompleteAtClockOut       bool                   `json:"IncompleteAtClockOut"`       // the duty was still incomplete at clock-out
+	AssigneeId                 string                 `json:"AssigneeId"`                 // employee who owns the duty: who clocked in, or whom it was handed over to (empty when unknown)
+	UpdatedAt                  *time.Time             `json:"UpdatedAt"`                  // when it was last updated, on the device for synced offline changes (nullable)
+	ModifiedAt                 time.Time              `json:"-"`                          // Timestamp of the last write to Table Storage (for sync tokens)
+	DeletedAt                  *time.Time             `json:"DeletedAt"`                  // when the assignment was deleted (null = not deleted); purged after the retention period
+	DeletedBy                  string                 `json:"DeletedBy"`                  // user ID of who deleted the assignment (empty when anonymous)
+}
+
+// returns the RowKey of a template's assignment in a shift. It is derived from both IDs, so creating the
+// assignments of a clock-in again yields the same keys and can't duplicate them.
+func DutyAssignmentRowKey(shiftId uuid.UUID, dutyId uuid.UUID) uuid.UUID {
+	return uuid.NewSHA1(shiftId, dutyId[:])
 }
 
 ////////////////////////////////////////
@@ -20,12 +56,24 @@ type DutyAssignmentStatus string
 const (
 	StatusCompleted  DutyAssignmentStatus = "Completed"
 	StatusIncomplete DutyAssignmentStatus = "Incomplete"
+	StatusSkipped    DutyAssignmentStatus = "Skipped" // deliberately not done (e.g. not needed on this shift)
 )
 
 // contains all valid status values
 var ValidDutyAssignmentStatuses = map[DutyAssignmentStatus]struct{}{
 	StatusCompleted:  {},
 	StatusIncomplete: {},
+	StatusSkipped:    {},
+}
+
+// checks if the duty is past its deadline and still has to be done
+func (a DutyAssignment) IsOverdue(now time.Time) bool {
+	return a.DutyAssignmentStatus == StatusIncomplete && a.DueAt != nil && now.After(*a.DueAt)
+}
+
+// checks if the assignment was (soft) deleted
+func (a DutyAssignment) IsDeleted() bool {
+	return a.DeletedAt != nil
 }
 
 // checks if the status is valid
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidComment is returned for a comment without text and photos, or with too long a text or too many photos
+var ErrInvalidComment = errors.New("invalid comment")
+
+// limits of a single comment
+const (
+	MaxCommentLength = 2000 // characters
+	MaxCommentPhotos = 4
+)
+
+// A comment in the thread of a duty assignment
+type DutyAssignmentComment struct {
+	PartitionKey string                       `json:"PartitionKey"` // [ShiftId]_[DutyId] (the thread of an assignment shares a partition)
+	RowKey       uuid.UUID                    `json:"RowKey"`       // CommentId
+	ShiftId      uuid.UUID                    `json:"ShiftId"`      // PartitionKey of the duty assignment
+	DutyId       uuid.UUID                    `json:"DutyId"`       // RowKey of the duty assignment
+	AuthorId     string                       `json:"AuthorId"`     // user ID of the author (JWT subject)
+	Text         string                       `json:"Text"`
+	Mentions     []string                     `json:"Mentions"` // user IDs mentioned with @ in the text
+	Photos       []DutyAssignmentCommentPhoto `json:"Photos"`
+	CreatedAt    time.Time                    `json:"CreatedAt"`
+	UpdatedAt    *time.Time                   `json:"UpdatedAt"` // when the text was last edited (nullable)
+}
+
+// A photo attached to a comment
+type DutyAssignmentCommentPhoto struct {
+	Id                uuid.UUID `json:"Id"`
+	ImageUrl          string    `json:"ImageUrl"`     // short-lived signed URL to the re-encoded image (generated on read)
+	ThumbnailUrl      string    `json:"ThumbnailUrl"` // short-lived signed URL to the small version of the image
+	BlobName          string    `json:"-"`            // name of the image blob (what is actually stored)
+	ThumbnailBlobName string    `json:"-"`            // name of the thumbnail blob
+	ContentType       string    `json:"ContentType"`
+}
+
+// returns the PartitionKey of the comments of a duty assignment
+func DutyAssignmentCommentPartitionKey(shiftId uuid.UUID, dutyId uuid.UUID) string {
+	return shiftId.String() + "_" + dutyId.String()
+}
+
+// an @ at the start of the text or after a character that can't be part of an email address, followed by a user ID
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.@-])@([\w-]+(?:\.[\w-]+)*)`)
+
+// returns the user IDs mentioned in a comment text, each once and in order of appearance
+func ParseMentions(text string) []string {
+	mentions := []string{}
+	seen := make(map[string]struct{})
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		if _, exists := seen[match[1]]; !exists {
+			seen[match[1]] = struct{}{}
+			mentions = append(mentions, match[1])
+		}
+	}
+	return mentions
+}
+
+// checks that a comment has text or photos and stays within the limits
+func ValidateComment(text string, photoCount int) error {
+	if strings.TrimSpace(text) == "" && photoCount == 0 {
+		return fmt.Errorf("%w: Text or a photo is required", ErrInvalidComment)
+	}
+	if utf8.RuneCountInString(text) > MaxCommentLength {
+		return fmt.Errorf("%w: Text is longer than %d characters", ErrInvalidComment, MaxCommentLength)
+	}
+	if photoCount > MaxCommentPhotos {
+		return fmt.Errorf("%w: at most %d photos per comment", ErrInvalidComment, MaxCommentPhotos)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ENUM for what happened to a duty assignment in its history
+type DutyAssignmentHistoryAction string
+
+const (
+	HistoryReassigned DutyAssignmentHistoryAction = "Reassigned" // handed over to another crew member
+)
+
+// An entry in the history of a duty assignment
+type DutyAssignmentHistoryEntry struct {
+	PartitionKey   string                      `json:"PartitionKey"`   // [ShiftId]_[DutyId] (the history of an assignment shares a partition)
+	RowKey         uuid.UUID                   `json:"RowKey"`         // EntryId
+	ShiftId        uuid.UUID                   `json:"ShiftId"`        // PartitionKey of the duty assignment
+	DutyId         uuid.UUID                   `json:"DutyId"`         // RowKey of the duty assignment
+	Action         DutyAssignmentHistoryAction `json:"Action"`         // what happened
+	ChangedBy      string                      `json:"ChangedBy"`      // user ID of who did it
+	ChangedAt      time.Time                   `json:"ChangedAt"`      // when it happened
+	FromAssigneeId string                      `json:"FromAssigneeId"` // owner before a handover
+	ToAssigneeId   string                      `json:"ToAssigneeId"`   // owner after a handover
+	Note           *string                     `json:"Note"`           // why (optional, nullable)
+}
+
+// returns the PartitionKey of the history of a duty assignment
+func DutyAssignmentHistoryPartitionKey(shiftId uuid.UUID, dutyId uuid.UUID) string {
+	return shiftId.String() + "_" + dutyId.String()
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A photo uploaded for a duty assignment (every upload is kept as a new version)
+type DutyAssignmentPhoto struct {
+	PartitionKey      string    `json:"PartitionKey"` // [ShiftId]_[DutyId] (all photos of an assignment share a partition)
+	RowKey            uuid.UUID `json:"RowKey"`       // PhotoId
+	ShiftId           uuid.UUID `json:"ShiftId"`      // PartitionKey of the duty assignment
+	DutyId            uuid.UUID `json:"DutyId"`       // RowKey of the duty assignment
+	ImageUrl          string    `json:"ImageUrl"`     // short-lived signed URL to the re-encoded image (generated on read)
+	ThumbnailUrl      string    `json:"ThumbnailUrl"` // short-lived signed URL to the small version of the image
+	BlobName          string    `json:"-"`            // name of the image blob (what is actually stored)
+	ThumbnailBlobName string    `json:"-"`            // name of the thumbnail blob
+	ContentType       string    `json:"ContentType"`  // content type of the stored image
+	UploadedAt        time.Time `json:"UploadedAt"`   // when the photo was uploaded
+	UploadedBy        string    `json:"UploadedBy"`   // user ID of the uploader (empty when uploaded anonymously)
+	DutyRowKey        uuid.UUID `json:"DutyRowKey"`   // duty template of the assignment (uuid.Nil for photos from before the authenticity checks)
+	Hash              uint64    `json:"-"`            // difference hash of the picture, to find near-duplicates
+
+	// results of the authenticity checks at upload (see CheckPhotoCapture) and their review
+	CapturedAt      *time.Time        `json:"CapturedAt"`      // EXIF capture time (nullable: PNG, WebP and most edited photos have none)
+	CapturedAtLocal bool              `json:"CapturedAtLocal"` // the camera didn't record its UTC offset: CapturedAt is its local time, written as UTC
+	Flags           []PhotoFlag       `json:"Flags"`           // why the photo looks suspicious (empty when it passed the checks)
+	DuplicateOf     *PhotoReference   `json:"DuplicateOf"`     // the earlier photo it is a near-duplicate of (nullable)
+	ReviewStatus    PhotoReviewStatus `json:"ReviewStatus"`    // Pending while a flagged photo waits for review (empty when it wasn't flagged)
+	ReviewedBy      string            `json:"ReviewedBy"`      // user ID of the reviewer
+	ReviewedAt      *time.Time        `json:"ReviewedAt"`      // when it was reviewed (nullable)
+	ReviewNote      string            `json:"ReviewNote"`      // why it was approved or rejected
+}
+
+// returns the PartitionKey of the photos of a duty assignment
+func DutyAssignmentPhotoPartitionKey(shiftId uuid.UUID, dutyId uuid.UUID) string {
+	return shiftId.String() + "_" + dutyId.String()
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidPhotoReview is returned for a review with an unknown decision or too long a note
+var ErrInvalidPhotoReview = errors.New("invalid photo review")
+
+// Why an uploaded photo looks suspicious
+type PhotoFlag string
+
+const (
+	PhotoFlagDuplicate            PhotoFlag = "Duplicate"            // near-duplicate of an earlier photo of the same duty
+	PhotoFlagCapturedOutsideShift PhotoFlag = "CapturedOutsideShift" // EXIF capture time outside the shift's clock-in window
+)
+
+// Review state of a flagged photo
+type PhotoReviewStatus string
+
+const (
+	PhotoReviewPending  PhotoReviewStatus = "Pending"
+	PhotoReviewApproved PhotoReviewStatus = "Approved" // the photo is fine after all
+	PhotoReviewRejected PhotoReviewStatus = "Rejected" // the photo isn't evidence of the duty
+)
+
+// limits of the authenticity checks
+const (
+	PhotoDuplicateMaxDistance = 10                  // hashes differing in at most this many of 64 bits are the same picture
+	PhotoDuplicateLookback    = 90 * 24 * time.Hour // how far back earlier photos of a duty are compared
+	PhotoCaptureTolerance     = 15 * time.Minute    // clock difference allowed between the camera and the server
+	MaxPhotoReviewNoteLength  = 1000                // characters
+
+	// UTC offsets a camera's local time can be in, for capture times without an offset
+	minUTCOffset = -12 * time.Hour
+	maxUTCOffset = 14 * time.Hour
+)
+
+// points to a photo of a duty assignment
+type PhotoReference struct {
+	ShiftId uuid.UUID `json:"ShiftId"`
+	DutyId  uuid.UUID `json:"DutyId"`
+	PhotoId uuid.UUID `json:"PhotoId"`
+}
+
+// A flagged photo in the review queue, with the earlier photo it duplicates (when it was flagged as a duplicate and
+// that photo still exists)
+type PhotoReviewItem struct {
+	Photo       DutyAssignmentPhoto  `json:"Photo"`
+	DuplicateOf *DutyAssignmentPhoto `json:"DuplicateOf"`
+}
+
+// The decision of a reviewer on a flagged photo
+type PhotoReview struct {
+	Status PhotoReviewStatus `json:"Status"` // Approved or Rejected
+	Note   string            `json:"Note"`   // optional
+}
+
+// checks that a review approves or rejects the photo
+func (r PhotoReview) Validate() error {
+	if r.Status != PhotoReviewApproved && r.Status != PhotoReviewRejected {
+		return fmt.Errorf("%w: Status must be '%s' or '%s'", ErrInvalidPhotoReview, PhotoReviewApproved, PhotoReviewRejected)
+	}
+	if utf8.RuneCountInString(strings.TrimSpace(r.Note)) > MaxPhotoReviewNoteLength {
+		return fmt.Errorf("%w: Note can't be longer than %d characters", ErrInvalidPhotoReview, MaxPhotoReviewNoteLength)
+	}
+	return nil
+}
+
+// CheckPhotoCapture reports whether a photo taken at capturedAt can have been taken between clock-in and to. A
+// capture time without a UTC offset (local) is the camera's wall clock, so every offset from -12 to +14 hours is
+// tried: this still catches photos from an earlier day, but not from a few hours before the shift.
+func CheckPhotoCapture(capturedAt time.Time, local bool, clockIn time.Time, to time.Time) bool {
+	earliest, latest := capturedAt, capturedAt
+	if local {
+		// the instant is the wall clock minus the camera's offset
+		earliest, latest = capturedAt.Add(-maxUTCOffset), capturedAt.Add(-minUTCOffset)
+	}
+
+	return !latest.Before(clockIn.Add(-PhotoCaptureTolerance)) && !earliest.After(to.Add(PhotoCaptureTolerance))
+}
+
+// returns the flags of the photos of an assignment that still wait for review, each once and in the order of PhotoFlag
+func PendingPhotoFlags(photos []DutyAssignmentPhoto) []PhotoFlag {
+	var pending [][]PhotoFlag
+	for _, photo := range photos {
+		if photo.ReviewStatus == PhotoReviewPending {
+			pending = append(pending, photo.Flags)
+		}
+	}
+	return MergePhotoFlags(pending...)
+}
+
+// returns the flags that are in any of the lists, each once and in the order of PhotoFlag
+func MergePhotoFlags(lists ...[]PhotoFlag) []PhotoFlag {
+	seen := map[PhotoFlag]bool{}
+	for _, list := range lists {
+		for _, flag := range list {
+			seen[flag] = true
+		}
+	}
+
+	flags := []PhotoFlag{}
+	for _, flag := range []PhotoFlag{PhotoFlagDuplicate, PhotoFlagCapturedOutsideShift} {
+		if seen[flag] {
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// limits of the attachments of a duty template
+const (
+	MaxDutyAttachments         = 10
+	MaxDutyAttachmentTitleSize = 200
+)
+
+// an image, video or PDF that shows how to do a duty, attached to its template
+type DutyAttachment struct {
+	Id                uuid.UUID `json:"Id"`
+	Kind              string    `json:"Kind"`         // "image", "video" or "pdf"
+	Title             string    `json:"Title"`        // caption shown with the attachment (optional)
+	FileName          string    `json:"FileName"`     // name of the uploaded file
+	ContentType       string    `json:"ContentType"`  // content type of the stored file
+	Size              int       `json:"Size"`         // size of the stored file in bytes
+	Url               string    `json:"Url"`          // short-lived signed URL to the file (generated on read)
+	ThumbnailUrl      *string   `json:"ThumbnailUrl"` // short-lived signed URL to a thumbnail (images only, nullable)
+	BlobName          string    `json:"-"`            // name of the file's blob (what is actually stored)
+	ThumbnailBlobName string    `json:"-"`            // name of the thumbnail blob (empty for videos and PDFs)
+	UploadedAt        time.Time `json:"UploadedAt"`
+	UploadedBy        string    `json:"UploadedBy"` // user ID of the admin who uploaded it
+}
+
+// checks the title of an attachment
+func ValidateDutyAttachmentTitle(title string) error {
+	if len(title) > MaxDutyAttachmentTitleSize {
+		return fmt.Errorf("invalid Title: longer than %d characters", MaxDutyAttachmentTitleSize)
+	}
+	return nil
+}
+
+// returns the attachment of the duty with the given ID
+func (d Duty) Attachment(id uuid.UUID) (DutyAttachment, bool) {
+	for _, attachment := range d.Attachments {
+		if attachment.Id == id {
+			return attachment, true
+		}
+	}
+	return DutyAttachment{}, false
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A printable checklist of the duties of a shift, for venues where phones aren't allowed behind the counter
+type DutyChecklist struct {
+	ShiftId     uuid.UUID           `json:"ShiftId"`
+	GeneratedAt time.Time           `json:"GeneratedAt"`
+	Items       []DutyChecklistItem `json:"Items"` // by deadline, then by name
+}
+
+// One duty assignment on a printed checklist
+type DutyChecklistItem struct {
+	DutyId          uuid.UUID            `json:"DutyId"`          // RowKey of the duty assignment
+	DutyName        string               `json:"DutyName"`        // in the caller's language
+	DutyDescription string               `json:"DutyDescription"` // in the caller's language
+	Status          DutyAssignmentStatus `json:"Status"`          // status when the checklist was printed
+	Mandatory       bool                 `json:"Mandatory"`
+	DueAt           *time.Time           `json:"DueAt"`
+	FormFields      []FormField          `json:"FormFields"` // questions of the template's form, printed as blanks to fill in
+	FormUrl         string               `json:"FormUrl"`    // completion form of the assignment, encoded in its QR code
+}
+
+// returns the URL of the completion form of a duty assignment by filling in the {ShiftId} and {DutyId} of a URL template
+func CompletionFormURL(template string, shiftId uuid.UUID, dutyId uuid.UUID) string {
+	return strings.NewReplacer(
+		"{ShiftId}", url.PathEscape(shiftId.String()),
+		"{DutyId}", url.PathEscape(dutyId.String()),
+	).Replace(template)
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// message of the clockOut queue
+type ClockOutMessage struct {
+	ShiftID      uuid.UUID `json:"shift_id"`
+	ClockOutTime time.Time `json:"clock_out_time"`
+	EmployeeId   string    `json:"employee_id"` // employee who clocked out (optional)
+}
+
+// request of the canClockOut RPC queue
+type CanClockOutRequest struct {
+	ShiftID uuid.UUID `json:"shift_id"`
+}
+
+// a duty of a shift that is still open
+type OpenDuty struct {
+	DutyId         uuid.UUID  `json:"DutyId"`         // RowKey of the duty assignment
+	DutyTemplateId uuid.UUID  `json:"DutyTemplateId"` // RowKey of the duty template
+	DutyName       string     `json:"DutyName"`
+	Mandatory      bool       `json:"Mandatory"`
+	DueAt          *time.Time `json:"DueAt"`
+}
+
+// whether a shift can clock out: only when no mandatory duty is still incomplete
+type CanClockOutResult struct {
+	ShiftId             uuid.UUID  `json:"ShiftId"`
+	CanClockOut         bool       `json:"CanClockOut"`
+	OpenMandatoryDuties []OpenDuty `json:"OpenMandatoryDuties"`
+}
+
+// message published on the duty.events exchange when a shift is clocked out and its duties are frozen
+type ShiftDutySummaryMessage struct {
+	ShiftID             uuid.UUID  `json:"shift_id"`
+	EmployeeID          string     `json:"employee_id"`
+	ClockOutTime        time.Time  `json:"clock_out_time"`
+	Total               int        `json:"total"`
+	Completed           int        `json:"completed"`
+	Skipped             int        `json:"skipped"`
+	Incomplete          int        `json:"incomplete"`
+	IncompleteMandatory int        `json:"incomplete_mandatory"`
+	IncompleteDuties    []OpenDuty `json:"incomplete_duties"`
+}
+
+// checks if a duty assignment is frozen (its shift was clocked out)
+func (a DutyAssignment) IsFrozen() bool {
+	return a.FrozenAt != nil
+}
+
+// returns the open duty of an assignment
+func (a DutyAssignment) OpenDuty(dutyName string) OpenDuty {
+	return OpenDuty{
+		DutyId:         a.RowKey,
+		DutyTemplateId: a.DutyRowKey,
+		DutyName:       dutyName,
+		Mandatory:      a.Mandatory,
+		DueAt:          a.DueAt,
+	}
+}
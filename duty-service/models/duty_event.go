@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// message published on the duty.events exchange when a duty assignment is created, completed or skipped
+type DutyAssignmentEventMessage struct {
+	ShiftID            uuid.UUID            `json:"shift_id"`
+	DutyID             uuid.UUID            `json:"duty_id"`          // RowKey of the duty assignment
+	DutyTemplateID     uuid.UUID            `json:"duty_template_id"` // RowKey of the duty template
+	DutyName           string               `json:"duty_name"`
+	RoleID             *int                 `json:"role_id"`
+	EventID            *uuid.UUID           `json:"event_id"`
+	EmployeeID         string               `json:"employee_id"`                    // employee who clocked in or updated the duty (empty when unknown)
+	AssigneeID         string               `json:"assignee_id"`                    // employee who owns the duty
+	PreviousAssigneeID string               `json:"previous_assignee_id,omitempty"` // owner before a handover (only on reassigned events)
+	Status             DutyAssignmentStatus `json:"status"`
+	CreatedAt          time.Time            `json:"created_at"`
+	DueAt              *time.Time           `json:"due_at"`
+	StartedAt          *time.Time           `json:"started_at"`
+	CompletedAt        *time.Time           `json:"completed_at"`
+	OccurredAt         time.Time            `json:"occurred_at"`
+}
+
+// message published on the duty.events exchange once every duty of a shift is completed or skipped
+type ShiftDutiesCompletedMessage struct {
+	ShiftID     uuid.UUID `json:"shift_id"`
+	EmployeeID  string    `json:"employee_id"` // employee who finished the last duty
+	Total       int       `json:"total"`
+	Completed   int       `json:"completed"`
+	Skipped     int       `json:"skipped"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// returns the event message of a duty assignment
+func (a DutyAssignment) EventMessage(dutyName string, employeeId string, occurredAt time.Time) DutyAssignmentEventMessage {
+	return DutyAssignmentEventMessage{
+		ShiftID:        a.PartitionKey,
+		DutyID:         a.RowKey,
+		DutyTemplateID: a.DutyRowKey,
+		DutyName:       dutyName,
+		RoleID:         a.RoleId,
+		EventID:        a.EventId,
+		EmployeeID:     employeeId,
+		AssigneeID:     a.AssigneeId,
+		Status:         a.DutyAssignmentStatus,
+		CreatedAt:      a.CreatedAt,
+		DueAt:          a.DueAt,
+		StartedAt:      a.StartedAt,
+		CompletedAt:    a.CompletedAt,
+		OccurredAt:     occurredAt,
+	}
+}
+
+// message published on the duty.events exchange for every employee mentioned in a comment of a duty assignment
+type DutyCommentMentionMessage struct {
+	CommentID   uuid.UUID `json:"comment_id"`
+	ShiftID     uuid.UUID `json:"shift_id"`
+	DutyID      uuid.UUID `json:"duty_id"` // RowKey of the duty assignment
+	AuthorID    string    `json:"author_id"`
+	MentionedID string    `json:"mentioned_id"` // employee to notify
+	Text        string    `json:"text"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
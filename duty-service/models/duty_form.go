@@ -0,0 +1,206 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ENUM for the input type of a duty form field
+type FormFieldType string
+
+// List of possible form field types
+const (
+	FieldCheckbox FormFieldType = "Checkbox" // yes/no, e.g. "oil changed?"
+	FieldNumber   FormFieldType = "Number"   // numeric reading with optional unit and min/max, e.g. "stock count"
+	FieldChoice   FormFieldType = "Choice"   // single choice out of Options
+	FieldText     FormFieldType = "Text"     // free text
+	FieldPhoto    FormFieldType = "Photo"    // satisfied by the image uploaded with the assignment
+)
+
+// contains all valid form field types
+var ValidFormFieldTypes = map[FormFieldType]struct{}{
+	FieldCheckbox: {},
+	FieldNumber:   {},
+	FieldChoice:   {},
+	FieldText:     {},
+	FieldPhoto:    {},
+}
+
+// maximum length of a free text answer
+const MaxFormTextLength = 1000
+
+// form keys are stored as Azure Table properties (Form_<key>), so they must be valid property names
+var formFieldKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+
+// one input of a duty template's form
+type FormField struct {
+	Key      string        `json:"Key" yaml:"Key"`                             // unique key of the field within the form, used to store the value
+	Label    string        `json:"Label" yaml:"Label"`                         // question shown to the employee
+	Type     FormFieldType `json:"Type" yaml:"Type"`                           // input type
+	Required bool          `json:"Required" yaml:"Required"`                   // must be filled in before the assignment can be completed
+	Unit     string        `json:"Unit,omitempty" yaml:"Unit,omitempty"`       // unit of a numeric reading (e.g. "°C", "pcs")
+	Min      *float64      `json:"Min,omitempty" yaml:"Min,omitempty"`         // lower bound of a numeric reading (optional)
+	Max      *float64      `json:"Max,omitempty" yaml:"Max,omitempty"`         // upper bound of a numeric reading (optional)
+	Options  []string      `json:"Options,omitempty" yaml:"Options,omitempty"` // possible answers of a single choice field
+}
+
+// FormValidationError is returned when a form schema or submitted form values are invalid
+type FormValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FormValidationError) Error() string {
+	if e.Field == "" {
+		return "invalid form: " + e.Reason
+	}
+	return fmt.Sprintf("invalid form field '%s': %s", e.Field, e.Reason)
+}
+
+// checks that a duty template's form schema is well-formed
+func ValidateFormSchema(schema []FormField) error {
+	seen := make(map[string]struct{}, len(schema))
+
+	for _, field := range schema {
+		if !formFieldKeyPattern.MatchString(field.Key) {
+			return &FormValidationError{Field: field.Key, Reason: "key must start with a letter and contain only letters, digits or underscores (max 64)"}
+		}
+		lowerKey := strings.ToLower(field.Key) // table property names are case-insensitive
+		if _, exists := seen[lowerKey]; exists {
+			return &FormValidationError{Field: field.Key, Reason: "duplicate key"}
+		}
+		seen[lowerKey] = struct{}{}
+
+		if _, valid := ValidFormFieldTypes[field.Type]; !valid {
+			return &FormValidationError{Field: field.Key, Reason: fmt.Sprintf("unknown type '%s'", field.Type)}
+		}
+
+		switch field.Type {
+		case FieldNumber:
+			if field.Min != nil && field.Max != nil && *field.Min > *field.Max {
+				return &FormValidationError{Field: field.Key, Reason: "Min is greater than Max"}
+			}
+		case FieldChoice:
+			if len(field.Options) == 0 {
+				return &FormValidationError{Field: field.Key, Reason: "a choice field needs at least one option"}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validates submitted values against the schema and returns them normalised to their stored types
+// (bool for checkboxes, float64 for numbers, string for choices and text)
+func ValidateFormValues(schema []FormField, values map[string]interface{}) (map[string]interface{}, error) {
+	fields := make(map[string]FormField, len(schema))
+	for _, field := range schema {
+		fields[field.Key] = field
+	}
+
+	normalised := make(map[string]interface{}, len(values))
+
+	for key, value := range values {
+		field, ok := fields[key]
+		if !ok {
+			return nil, &FormValidationError{Field: key, Reason: "not part of this duty's form"}
+		}
+
+		switch field.Type {
+		case FieldCheckbox:
+			checked, ok := value.(bool)
+			if !ok {
+				return nil, &FormValidationError{Field: key, Reason: "expected true or false"}
+			}
+			normalised[key] = checked
+
+		case FieldNumber:
+			number, ok := value.(float64)
+			if !ok {
+				return nil, &FormValidationError{Field: key, Reason: "expected a number"}
+			}
+			if field.Min != nil && number < *field.Min {
+				return nil, &FormValidationError{Field: key, Reason: fmt.Sprintf("must be at least %g%s", *field.Min, field.Unit)}
+			}
+			if field.Max != nil && number > *field.Max {
+				return nil, &FormValidationError{Field: key, Reason: fmt.Sprintf("must be at most %g%s", *field.Max, field.Unit)}
+			}
+			normalised[key] = number
+
+		case FieldChoice:
+			choice, ok := value.(string)
+			if !ok || !containsString(field.Options, choice) {
+				return nil, &FormValidationError{Field: key, Reason: "expected one of: " + strings.Join(field.Options, ", ")}
+			}
+			normalised[key] = choice
+
+		case FieldText:
+			text, ok := value.(string)
+			if !ok {
+				return nil, &FormValidationError{Field: key, Reason: "expected text"}
+			}
+			if len(text) > MaxFormTextLength {
+				return nil, &FormValidationError{Field: key, Reason: fmt.Sprintf("text is longer than %d characters", MaxFormTextLength)}
+			}
+			normalised[key] = text
+
+		case FieldPhoto:
+			return nil, &FormValidationError{Field: key, Reason: "photo fields are filled in by uploading an image"}
+		}
+	}
+
+	return normalised, nil
+}
+
+// checks that every required field has a value (hasPhoto tells whether the assignment has an image)
+func CheckRequiredFormFields(schema []FormField, values map[string]interface{}, hasPhoto bool) error {
+	for _, field := range schema {
+		if !field.Required {
+			continue
+		}
+
+		if field.Type == FieldPhoto {
+			if !hasPhoto {
+				return &FormValidationError{Field: field.Key, Reason: "a photo is required"}
+			}
+			continue
+		}
+
+		value, ok := values[field.Key]
+		if !ok || value == nil {
+			return &FormValidationError{Field: field.Key, Reason: "is required"}
+		}
+		if text, isText := value.(string); isText && strings.TrimSpace(text) == "" {
+			return &FormValidationError{Field: field.Key, Reason: "is required"}
+		}
+	}
+
+	return nil
+}
+
+// checks if a string is in a list
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// checks if two form fields are the same (bounds and options by value)
+func (f FormField) Equal(other FormField) bool {
+	if f.Key != other.Key || f.Label != other.Label || f.Type != other.Type || f.Required != other.Required || f.Unit != other.Unit {
+		return false
+	}
+	return equalBound(f.Min, other.Min) && equalBound(f.Max, other.Max) && equalStrings(f.Options, other.Options)
+}
+
+// equalBound compares two optional numeric bounds
+func equalBound(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
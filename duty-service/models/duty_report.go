@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A completion report of duty assignments, per shift and in total
+type DutyReport struct {
+	GeneratedAt time.Time         `json:"GeneratedAt"`
+	From        *time.Time        `json:"From,omitempty"` // set for date range reports
+	To          *time.Time        `json:"To,omitempty"`
+	Summary     DutyReportSummary `json:"Summary"` // totals over all shifts
+	Shifts      []ShiftDutyReport `json:"Shifts"`
+}
+
+// the counts of a report (for a single shift or in total)
+type DutyReportSummary struct {
+	Total                int     `json:"Total"`
+	Completed            int     `json:"Completed"`
+	Incomplete           int     `json:"Incomplete"`
+	Skipped              int     `json:"Skipped"`
+	Overdue              int     `json:"Overdue"`              // incomplete and past their deadline
+	MissingPhotos        int     `json:"MissingPhotos"`        // the duty asks for a photo but none was uploaded
+	CompletionPercentage float64 `json:"CompletionPercentage"` // completed out of all duties that weren't skipped
+}
+
+// the report of a single shift
+type ShiftDutyReport struct {
+	ShiftId     uuid.UUID         `json:"ShiftId"`
+	Summary     DutyReportSummary `json:"Summary"`
+	Assignments []DutyReportLine  `json:"Assignments"`
+}
+
+// a duty assignment in a report
+type DutyReportLine struct {
+	DutyId       uuid.UUID            `json:"DutyId"`
+	DutyName     string               `json:"DutyName"`
+	Status       DutyAssignmentStatus `json:"Status"`
+	Note         *string              `json:"Note"`
+	CreatedAt    time.Time            `json:"CreatedAt"`
+	DueAt        *time.Time           `json:"DueAt"`
+	Overdue      bool                 `json:"Overdue"`
+	ExpectsPhoto bool                 `json:"ExpectsPhoto"`
+	MissingPhoto bool                 `json:"MissingPhoto"`
+	ThumbnailUrl *string              `json:"ThumbnailUrl"` // short-lived signed URL
+}
+
+// adds a duty assignment to the counts
+func (s *DutyReportSummary) Add(line DutyReportLine) {
+	s.Total++
+	switch line.Status {
+	case StatusCompleted:
+		s.Completed++
+	case StatusSkipped:
+		s.Skipped++
+	default:
+		s.Incomplete++
+	}
+	if line.Overdue {
+		s.Overdue++
+	}
+	if line.MissingPhoto {
+		s.MissingPhotos++
+	}
+
+	if counted := s.Total - s.Skipped; counted > 0 {
+		s.CompletionPercentage = float64(s.Completed) * 100 / float64(counted)
+	} else {
+		s.CompletionPercentage = 100 // everything was skipped, so nothing was left undone
+	}
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidDutySearch is wrapped by the errors of an invalid duty search
+var ErrInvalidDutySearch = errors.New("invalid duty search")
+
+// the largest page of the duty search
+const MaxDutyPageSize = 200
+
+// fields duties can be sorted by (prefixed with "-" for descending order)
+var dutySortFields = map[string]func(a, b Duty) int{
+	"DutyName":   func(a, b Duty) int { return strings.Compare(strings.ToLower(a.DutyName), strings.ToLower(b.DutyName)) },
+	"DutyKey":    func(a, b Duty) int { return strings.Compare(a.Key(), b.Key()) },
+	"RoleId":     func(a, b Duty) int { return a.RoleId - b.RoleId },
+	"DueMinutes": func(a, b Duty) int { return a.DueMinutes - b.DueMinutes },
+	"Version":    func(a, b Duty) int { return a.Version - b.Version },
+}
+
+// the criteria of GET /duties. Every criterion that is set has to match.
+type DutySearch struct {
+	Name           string   // the exact name
+	Query          string   // part of the name or description (case-insensitive)
+	NamePrefix     string   // start of the name (case-insensitive)
+	RoleId         *int     // only duties of this role
+	Tags           []string // only duties with all of these tags (case-insensitive)
+	IncludeDeleted bool     // also return soft-deleted duties
+	Sort           string   // field to sort by, "-" in front for descending order (by role and name when empty)
+	Page           int      // 1-based page, with PageSize
+	PageSize       int      // duties per page (0 = all of them)
+}
+
+// a page of duties found by a search
+type DutySearchResult struct {
+	Duties []Duty
+	Total  int // number of duties found, over all pages
+}
+
+// checks the sort field and the page
+func (s DutySearch) Validate() error {
+	if s.Sort != "" {
+		if _, ok := dutySortFields[strings.TrimPrefix(s.Sort, "-")]; !ok {
+			return fmt.Errorf("%w: can't sort by '%s' (use DutyName, DutyKey, RoleId, DueMinutes or Version)", ErrInvalidDutySearch, s.Sort)
+		}
+	}
+	if s.PageSize < 0 || s.PageSize > MaxDutyPageSize {
+		return fmt.Errorf("%w: pageSize must be between 1 and %d", ErrInvalidDutySearch, MaxDutyPageSize)
+	}
+	if s.Page < 0 {
+		return fmt.Errorf("%w: page must be at least 1", ErrInvalidDutySearch)
+	}
+	if s.Page > 0 && s.PageSize == 0 {
+		return fmt.Errorf("%w: page needs a pageSize", ErrInvalidDutySearch)
+	}
+	return nil
+}
+
+// checks the criteria that can't be part of a Table Storage filter
+func (s DutySearch) Matches(duty Duty) bool {
+	if !s.IncludeDeleted && duty.IsDeleted() {
+		return false
+	}
+	if s.Name != "" && duty.DutyName != s.Name {
+		return false
+	}
+	if s.RoleId != nil && duty.RoleId != *s.RoleId {
+		return false
+	}
+	if s.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(duty.DutyName), strings.ToLower(s.NamePrefix)) {
+		return false
+	}
+	if s.Query != "" {
+		query := strings.ToLower(s.Query)
+		if !strings.Contains(strings.ToLower(duty.DutyName), query) && !strings.Contains(strings.ToLower(duty.DutyDescription), query) {
+			return false
+		}
+	}
+	for _, tag := range s.Tags {
+		if !duty.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// filters, sorts and pages the duties
+func (s DutySearch) Apply(duties []Duty) DutySearchResult {
+	found := []Duty{}
+	for _, duty := range duties {
+		if s.Matches(duty) {
+			found = append(found, duty)
+		}
+	}
+
+	s.sort(found)
+	result := DutySearchResult{Duties: found, Total: len(found)}
+
+	if s.PageSize > 0 {
+		page := s.Page
+		if page < 1 {
+			page = 1
+		}
+		start := (page - 1) * s.PageSize
+		if start > len(found) {
+			start = len(found)
+		}
+		end := start + s.PageSize
+		if end > len(found) {
+			end = len(found)
+		}
+		result.Duties = found[start:end]
+	}
+
+	return result
+}
+
+// sort orders the duties by the sort field, then by role and name, then by RowKey, so pages are stable
+func (s DutySearch) sort(duties []Duty) {
+	field, descending := strings.TrimPrefix(s.Sort, "-"), strings.HasPrefix(s.Sort, "-")
+	compare := dutySortFields[field]
+
+	sort.SliceStable(duties, func(i, j int) bool {
+		if compare != nil {
+			if c := compare(duties[i], duties[j]); c != 0 {
+				return (c < 0) != descending
+			}
+		}
+		if duties[i].RoleId != duties[j].RoleId {
+			return duties[i].RoleId < duties[j].RoleId
+		}
+		if duties[i].DutyName != duties[j].DutyName {
+			return duties[i].DutyName < duties[j].DutyName
+		}
+		return duties[i].RowKey.String() < duties[j].RowKey.String()
+	})
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ENUM for the kind of change of a duty stream event (sent as the SSE event name)
+type DutyStreamEventType string
+
+const (
+	DutyStreamCreated DutyStreamEventType = "created" // assigned at clock-in
+	DutyStreamUpdated DutyStreamEventType = "updated" // status, note, form values or photo changed
+	DutyStreamDeleted DutyStreamEventType = "deleted"
+	DutyStreamFrozen  DutyStreamEventType = "frozen" // the shift was clocked out
+)
+
+// A change of a duty assignment, pushed to the clients of GET /duties/stream
+type DutyStreamEvent struct {
+	Id             string              `json:"Id"` // also sent as the SSE id, so a reconnecting client can resume with Last-Event-ID
+	Type           DutyStreamEventType `json:"Type"`
+	ShiftId        uuid.UUID           `json:"ShiftId"`
+	EventId        *uuid.UUID          `json:"EventId"`        // event of the shift (nullable when it was unknown at clock-in)
+	DutyName       string              `json:"DutyName"`       // name of the duty template (empty for deleted assignments)
+	DutyAssignment DutyAssignment      `json:"DutyAssignment"` // state after the change (without image URLs, they are signed on read)
+	OccurredAt     time.Time           `json:"OccurredAt"`
+}
+
+// Which duty stream events a client receives (at least one of both is set)
+type DutyStreamFilter struct {
+	ShiftId *uuid.UUID
+	EventId *uuid.UUID
+}
+
+// returns the stream event of a duty assignment change (the Id is set when it is published)
+func (a DutyAssignment) StreamEvent(eventType DutyStreamEventType, dutyName string, occurredAt time.Time) DutyStreamEvent {
+	a.DutyAssignmentImageUrl = nil
+	a.DutyAssignmentThumbnailUrl = nil
+
+	return DutyStreamEvent{
+		Type:           eventType,
+		ShiftId:        a.PartitionKey,
+		EventId:        a.EventId,
+		DutyName:       dutyName,
+		DutyAssignment: a,
+		OccurredAt:     occurredAt,
+	}
+}
+
+// checks if a stream event is for the shift and event of the filter
+func (f DutyStreamFilter) Matches(event DutyStreamEvent) bool {
+	if f.ShiftId != nil && *f.ShiftId != event.ShiftId {
+		return false
+	}
+	if f.EventId != nil && (event.EventId == nil || *f.EventId != *event.EventId) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidSyncToken is returned for a sync token that wasn't issued by a sync
+var ErrInvalidSyncToken = errors.New("invalid SyncToken")
+
+// format of the sync token: Table Storage timestamps have a precision of 100ns
+const syncTokenFormat = "2006-01-02T15:04:05.0000000Z"
+
+// ENUM for how a sync handles a change of an assignment that was also changed on the server
+type SyncConflictMode string
+
+const (
+	SyncLastWriterWins  SyncConflictMode = "last-writer-wins" // the change with the later timestamp wins (default)
+	SyncReportConflicts SyncConflictMode = "report"           // conflicting changes aren't applied but reported
+)
+
+// ENUM for the outcome of a synced change
+type SyncChangeStatus string
+
+const (
+	SyncApplied  SyncChangeStatus = "Applied"
+	SyncStale    SyncChangeStatus = "Stale"    // last-writer-wins: the assignment has a later change, this one was discarded
+	SyncConflict SyncChangeStatus = "Conflict" // report: the assignment was changed on the server, this one wasn't applied
+	SyncRejected SyncChangeStatus = "Rejected" // invalid change (e.g. form values, photo, frozen or unknown assignment)
+	SyncFailed   SyncChangeStatus = "Failed"   // server error, the change can be sent again
+	SyncPending  SyncChangeStatus = "Pending"  // stored while the change is being applied, never returned
+)
+
+// A batch of duty assignment changes the crew app queued while offline (POST /duties/duty-assignments/sync)
+type DutySyncRequest struct {
+	SyncToken    string           `json:"SyncToken"`    // token of the previous sync (empty on the first sync)
+	ShiftIds     []uuid.UUID      `json:"ShiftIds"`     // shifts to return the changed assignments of (the shifts of the changes are added)
+	ConflictMode SyncConflictMode `json:"ConflictMode"` // "last-writer-wins" (default) or "report"
+	Changes      []DutySyncChange `json:"Changes"`      // applied in order
+}
+
+// A change of a duty assignment made on the device
+type DutySyncChange struct {
+	ClientChangeId       uuid.UUID              `json:"ClientChangeId"` // generated on the device, a change is applied only once
+	ShiftId              uuid.UUID              `json:"ShiftId"`
+	DutyId               uuid.UUID              `json:"DutyId"`
+	ClientTimestamp      time.Time              `json:"ClientTimestamp"`      // when the change was made on the device
+	BaseUpdatedAt        *time.Time             `json:"BaseUpdatedAt"`        // UpdatedAt of the assignment the device last saw (optional, for conflict reporting)
+	DutyAssignmentStatus DutyAssignmentStatus   `json:"DutyAssignmentStatus"` // optional, the status stays the same when empty
+	DutyAssignmentNote   *string                `json:"DutyAssignmentNote"`   // optional
+	FormValues           map[string]interface{} `json:"FormValues"`           // optional, merged with the stored answers
+	Photo                string                 `json:"Photo"`                // name of the multipart file part with the photo (optional)
+}
+
+// The outcome of a synced change
+type DutySyncChangeResult struct {
+	ClientChangeId uuid.UUID        `json:"ClientChangeId"`
+	ShiftId        uuid.UUID        `json:"ShiftId"`
+	DutyId         uuid.UUID        `json:"DutyId"`
+	Status         SyncChangeStatus `json:"Status"`
+	Error          string           `json:"Error,omitempty"`
+	Duplicate      bool             `json:"Duplicate"`      // the change was synced before, this is the result of then
+	DutyAssignment *DutyAssignment  `json:"DutyAssignment"` // the server's state of a Stale or Conflict change (nullable)
+	SyncedAt       time.Time        `json:"SyncedAt"`
+}
+
+// The response of a sync
+type DutySyncResponse struct {
+	Results   []DutySyncChangeResult `json:"Results"`   // one per change, in the order of the request
+	Changed   []DutyAssignment       `json:"Changed"`   // assignments of the shifts changed since the SyncToken (all of them on the first sync); deleted ones have DeletedAt set
+	SyncToken string                 `json:"SyncToken"` // to send with the next sync
+}
+
+// checks if the result is final, i.e. sending the change again gives the same result
+func (r DutySyncChangeResult) IsFinal() bool {
+	return r.Status != SyncFailed
+}
+
+// returns the sync token of the last change to Table Storage that was returned
+func NewSyncToken(timestamp time.Time) string {
+	if timestamp.IsZero() {
+		return ""
+	}
+	return timestamp.UTC().Format(syncTokenFormat)
+}
+
+// returns the time of a sync token (zero for an empty token)
+func ParseSyncToken(token string) (time.Time, error) {
+	if token == "" {
+		return time.Time{}, nil
+	}
+
+	timestamp, err := time.Parse(syncTokenFormat, token)
+	if err != nil {
+		return time.Time{}, ErrInvalidSyncToken
+	}
+	return timestamp, nil
+}
+
+// checks if the conflict mode is valid (empty means the default)
+func ValidateSyncConflictMode(mode SyncConflictMode) bool {
+	return mode == "" || mode == SyncLastWriterWins || mode == SyncReportConflicts
+}
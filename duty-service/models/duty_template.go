@@ -0,0 +1,102 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// a duty template as it is exported and imported: without the table keys, so it can move between environments
+type DutyTemplate struct {
+	DutyKey         string             `json:"DutyKey" yaml:"DutyKey"` // identifies the template; derived from RoleId and DutyName when empty
+	RoleId          int                `json:"RoleId" yaml:"RoleId"`
+	DutyName        string             `json:"DutyName" yaml:"DutyName"`
+	DutyDescription string             `json:"DutyDescription" yaml:"DutyDescription"`
+	DueMinutes      int                `json:"DueMinutes" yaml:"DueMinutes"`
+	Mandatory       bool               `json:"Mandatory,omitempty" yaml:"Mandatory,omitempty"`
+	FormSchema      []FormField        `json:"FormSchema,omitempty" yaml:"FormSchema,omitempty"`
+	AppliesTo       *DutyApplicability `json:"AppliesTo,omitempty" yaml:"AppliesTo,omitempty"`
+	Tags            []string           `json:"Tags,omitempty" yaml:"Tags,omitempty"`
+}
+
+// options of a duty template import
+type DutyImportOptions struct {
+	DryRun bool // only report what would change
+	Prune  bool // delete the templates of the imported roles that aren't in the import
+}
+
+// a template that an import creates, updates or deletes
+type DutyImportChange struct {
+	DutyKey  string     `json:"DutyKey"`
+	DutyName string     `json:"DutyName"`
+	RowKey   *uuid.UUID `json:"RowKey,omitempty"` // not known yet for templates a dry run would create
+	Fields   []string   `json:"Fields,omitempty"` // changed fields of an updated template
+}
+
+// what an import did (or, for a dry run, would do)
+type DutyImportResult struct {
+	DryRun    bool               `json:"DryRun"`
+	Created   []DutyImportChange `json:"Created"`
+	Updated   []DutyImportChange `json:"Updated"`
+	Deleted   []DutyImportChange `json:"Deleted"`
+	Unchanged int                `json:"Unchanged"`
+}
+
+// DutyImportError is returned when an import contains invalid templates (nothing is imported then)
+type DutyImportError struct {
+	Problems []string
+}
+
+func (e *DutyImportError) Error() string {
+	return "invalid duty import: " + strings.Join(e.Problems, "; ")
+}
+
+// returns the template of a duty
+func (d Duty) Template() DutyTemplate {
+	return DutyTemplate{
+		DutyKey:         d.Key(),
+		RoleId:          d.RoleId,
+		DutyName:        d.DutyName,
+		DutyDescription: d.DutyDescription,
+		DueMinutes:      d.DueMinutes,
+		Mandatory:       d.Mandatory,
+		FormSchema:      d.FormSchema,
+		AppliesTo:       d.AppliesTo,
+		Tags:            d.Tags,
+	}
+}
+
+// checks a template and returns the problems found (empty when it is valid)
+func (t DutyTemplate) Validate() []string {
+	var problems []string
+
+	name := t.DutyKey
+	if name == "" {
+		name = t.DutyName
+	}
+
+	if t.DutyKey != "" && !ValidateDutyKey(t.DutyKey) {
+		problems = append(problems, fmt.Sprintf("'%s': DutyKey must be lowercase letters, digits, '.', '_' or '-' (at most 100 characters)", name))
+	}
+	if strings.TrimSpace(t.DutyName) == "" {
+		problems = append(problems, fmt.Sprintf("'%s': DutyName is required", name))
+	}
+	if !ValidateRoleId(t.RoleId) {
+		problems = append(problems, fmt.Sprintf("'%s': invalid RoleId %d (valid values are 0 = Admin, 1 = HeadTrucker, 2 = Chef, 3 = Staff)", name, t.RoleId))
+	}
+	if t.DueMinutes < 0 {
+		problems = append(problems, fmt.Sprintf("'%s': DueMinutes can't be negative", name))
+	}
+	if err := ValidateFormSchema(t.FormSchema); err != nil {
+		problems = append(problems, fmt.Sprintf("'%s': %v", name, err))
+	}
+	if err := t.AppliesTo.Validate(); err != nil {
+		problems = append(problems, fmt.Sprintf("'%s': %v", name, err))
+	}
+	if err := ValidateDutyTags(t.Tags); err != nil {
+		problems = append(problems, fmt.Sprintf("'%s': %v", name, err))
+	}
+
+	return problems
+}
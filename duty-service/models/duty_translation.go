@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/language"
+)
+
+// ErrInvalidDutyTranslation is returned for a translation with an invalid language tag or without a name
+var ErrInvalidDutyTranslation = errors.New("invalid duty translation")
+
+// limits of a translation (the same as the texts of a duty are expected to be)
+const (
+	MaxTranslatedNameLength        = 200
+	MaxTranslatedDescriptionLength = 4000
+)
+
+// the name and description of a duty in another language
+type DutyTranslation struct {
+	DutyName        string `json:"DutyName"`
+	DutyDescription string `json:"DutyDescription"` // empty = the duty's own description is shown
+}
+
+// translations of a duty keyed by canonical language tag, e.g. "pl" or "pt-BR"
+type DutyTranslations map[string]DutyTranslation
+
+// a duty that lacks translations, reported to admins
+type MissingDutyTranslation struct {
+	DutyPartitionKey string    `json:"DutyPartitionKey"`
+	DutyRowKey       uuid.UUID `json:"DutyRowKey"`
+	DutyKey          string    `json:"DutyKey"`
+	DutyName         string    `json:"DutyName"`
+	RoleId           int       `json:"RoleId"`
+	Languages        []string  `json:"Languages"` // languages without a translation, or whose translation has no description while the duty has one
+}
+
+// returns the canonical form of a BCP 47 language tag, e.g. "PT-br" -> "pt-BR"
+func NormalizeLanguageTag(tag string) (string, error) {
+	parsed, err := language.Parse(strings.TrimSpace(tag))
+	if err != nil || parsed == language.Und {
+		return "", fmt.Errorf("%w: '%s' is not a language tag (e.g. 'nl' or 'pt-BR')", ErrInvalidDutyTranslation, tag)
+	}
+	return parsed.String(), nil
+}
+
+// checks that a translation has a name and isn't too long
+func (t DutyTranslation) Validate() error {
+	if strings.TrimSpace(t.DutyName) == "" {
+		return fmt.Errorf("%w: DutyName is required", ErrInvalidDutyTranslation)
+	}
+	if len(t.DutyName) > MaxTranslatedNameLength {
+		return fmt.Errorf("%w: DutyName is longer than %d characters", ErrInvalidDutyTranslation, MaxTranslatedNameLength)
+	}
+	if len(t.DutyDescription) > MaxTranslatedDescriptionLength {
+		return fmt.Errorf("%w: DutyDescription is longer than %d characters", ErrInvalidDutyTranslation, MaxTranslatedDescriptionLength)
+	}
+	return nil
+}
+
+// Localize returns the name and description in the first of the languages the duty has text in, and that language.
+// The duty's own text is in defaultLanguage; a regional language falls back to its base language ("nl-BE" -> "nl").
+// Without a match the duty's own text is returned.
+func (d Duty) Localize(languages []string, defaultLanguage string) (DutyTranslation, string) {
+	return localize(DutyTranslation{DutyName: d.DutyName, DutyDescription: d.DutyDescription}, d.Translations, languages, defaultLanguage)
+}
+
+// Localize returns the name and description of the version in the first of the languages it has text in, like
+// Duty.Localize
+func (v DutyVersion) Localize(languages []string, defaultLanguage string) (DutyTranslation, string) {
+	return localize(DutyTranslation{DutyName: v.DutyName, DutyDescription: v.DutyDescription}, v.Translations, languages, defaultLanguage)
+}
+
+// localize picks the text in the first of the languages there is text in (own is the text in defaultLanguage)
+func localize(own DutyTranslation, translations DutyTranslations, languages []string, defaultLanguage string) (DutyTranslation, string) {
+	for _, lang := range languages {
+		for _, candidate := range []string{lang, baseLanguage(lang)} {
+			if candidate == defaultLanguage {
+				return own, defaultLanguage
+			}
+			if translation, ok := translations[candidate]; ok {
+				if translation.DutyDescription == "" {
+					translation.DutyDescription = own.DutyDescription
+				}
+				return translation, candidate
+			}
+		}
+	}
+	return own, defaultLanguage
+}
+
+// returns the languages the duty has no complete translation in (the default language never misses one)
+func (d Duty) MissingLanguages(languages []string, defaultLanguage string) []string {
+	missing := []string{}
+	for _, lang := range languages {
+		if lang == defaultLanguage {
+			continue
+		}
+		translation, ok := d.Translations[lang]
+		if !ok || (translation.DutyDescription == "" && d.DutyDescription != "") {
+			missing = append(missing, lang)
+		}
+	}
+	return missing
+}
+
+// baseLanguage returns the language of a tag without its region or script ("pt-BR" -> "pt")
+func baseLanguage(tag string) string {
+	base, _, _ := strings.Cut(tag, "-")
+	return base
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// An immutable version of a duty template (every create, update and rollback adds one)
+type DutyVersion struct {
+	DutyId          uuid.UUID          `json:"DutyId"`  // RowKey of the duty (PartitionKey of the version)
+	Version         int                `json:"Version"` // 1 for the first version (RowKey, zero-padded so versions sort)
+	RoleId          int                `json:"RoleId"`
+	DutyName        string             `json:"DutyName"`
+	DutyDescription string             `json:"DutyDescription"`
+	FormSchema      []FormField        `json:"FormSchema"`
+	DueMinutes      int                `json:"DueMinutes"`
+	Mandatory       bool               `json:"Mandatory"`
+	DutyKey         string             `json:"DutyKey"`
+	AppliesTo       *DutyApplicability `json:"AppliesTo"`
+	Tags            []string           `json:"Tags"`
+	Translations    DutyTranslations   `json:"Translations"` // name and description of this version in other languages (kept up to date while it is current)
+	CreatedAt       time.Time          `json:"CreatedAt"`
+	CreatedBy       string             `json:"CreatedBy"`                // user ID of the admin who made the change (empty when unknown)
+	RolledBackFrom  *int               `json:"RolledBackFrom,omitempty"` // the older version this one restores
+}
+
+// a changed field between two versions of a duty template
+type DutyFieldChange struct {
+	Field string      `json:"Field"` // e.g. "DutyName", or "FormSchema.<key>" for a single form field
+	From  interface{} `json:"From"`  // null when a form field was added
+	To    interface{} `json:"To"`    // null when a form field was removed
+}
+
+// the differences between two versions of a duty template
+type DutyVersionDiff struct {
+	DutyId  uuid.UUID         `json:"DutyId"`
+	From    int               `json:"From"`
+	To      int               `json:"To"`
+	Changes []DutyFieldChange `json:"Changes"`
+}
+
+// the RowKey of a version: zero-padded, so the versions of a duty are listed in order
+func DutyVersionRowKey(version int) string {
+	return fmt.Sprintf("%010d", version)
+}
+
+// returns the version of the duty's current content
+func (d Duty) NewVersion(version int, createdAt time.Time, createdBy string) DutyVersion {
+	return DutyVersion{
+		DutyId:          d.RowKey,
+		Version:         version,
+		RoleId:          d.RoleId,
+		DutyName:        d.DutyName,
+		DutyDescription: d.DutyDescription,
+		FormSchema:      d.FormSchema,
+		DueMinutes:      d.DueMinutes,
+		Mandatory:       d.Mandatory,
+		DutyKey:         d.DutyKey,
+		AppliesTo:       d.AppliesTo,
+		Tags:            d.Tags,
+		Translations:    d.Translations,
+		CreatedAt:       createdAt,
+		CreatedBy:       createdBy,
+	}
+}
+
+// compares two versions field by field (form fields by key)
+func DiffDutyVersions(from, to DutyVersion) DutyVersionDiff {
+	diff := DutyVersionDiff{DutyId: to.DutyId, From: from.Version, To: to.Version, Changes: []DutyFieldChange{}}
+
+	addChange := func(field string, fromValue, toValue interface{}) {
+		diff.Changes = append(diff.Changes, DutyFieldChange{Field: field, From: fromValue, To: toValue})
+	}
+
+	if from.DutyKey != to.DutyKey {
+		addChange("DutyKey", from.DutyKey, to.DutyKey)
+	}
+	if from.RoleId != to.RoleId {
+		addChange("RoleId", from.RoleId, to.RoleId)
+	}
+	if from.DutyName != to.DutyName {
+		addChange("DutyName", from.DutyName, to.DutyName)
+	}
+	if from.DutyDescription != to.DutyDescription {
+		addChange("DutyDescription", from.DutyDescription, to.DutyDescription)
+	}
+	if from.DueMinutes != to.DueMinutes {
+		addChange("DueMinutes", from.DueMinutes, to.DueMinutes)
+	}
+	if from.Mandatory != to.Mandatory {
+		addChange("Mandatory", from.Mandatory, to.Mandatory)
+	}
+	if !from.AppliesTo.Equal(to.AppliesTo) {
+		addChange("AppliesTo", from.AppliesTo, to.AppliesTo)
+	}
+	if !equalStrings(from.Tags, to.Tags) {
+		addChange("Tags", from.Tags, to.Tags)
+	}
+
+	fromFields := make(map[string]FormField, len(from.FormSchema))
+	for _, field := range from.FormSchema {
+		fromFields[field.Key] = field
+	}
+	toFields := make(map[string]FormField, len(to.FormSchema))
+	for _, field := range to.FormSchema {
+		toFields[field.Key] = field
+	}
+
+	// removed and changed fields in the order of the old form, then added fields in the order of the new one
+	for _, field := range from.FormSchema {
+		newField, exists := toFields[field.Key]
+		switch {
+		case !exists:
+			addChange("FormSchema."+field.Key, field, nil)
+		case !field.Equal(newField):
+			addChange("FormSchema."+field.Key, field, newField)
+		}
+	}
+	for _, field := range to.FormSchema {
+		if _, exists := fromFields[field.Key]; !exists {
+			addChange("FormSchema."+field.Key, nil, field)
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// the event of a shift, as returned by the event service (only the fields the duty service uses)
+type Event struct {
+	PartitionKey string    `json:"partitionKey"`
+	RowKey       uuid.UUID `json:"rowKey"`
+	StartTime    time.Time `json:"startTime"`
+	EndTime      time.Time `json:"endTime"`
+	Venue        string    `json:"venue"`
+	Tags         []string  `json:"tags"`    // event type tags, e.g. "festival", "outdoor"
+	TruckID      string    `json:"truckID"` // truck used at the event
+}
@@ -0,0 +1,10 @@
+package models
+
+// The number of soft-deleted rows a retention run purged
+type PurgeResult struct {
+	Duties          int `json:"Duties"`
+	DutyAttachments int `json:"DutyAttachments"` // attachments of the purged duties
+	DutyAssignments int `json:"DutyAssignments"`
+	Photos          int `json:"Photos"`   // photos of the purged assignments
+	Comments        int `json:"Comments"` // comments of the purged assignments
+}
@@ -0,0 +1,31 @@
+package models
+
+// ENUM of the employee roles (same order as EmployeeRole in the employee service, which stores them as ints)
+type Role int
+
+// List of possible roles
+const (
+	RoleAdmin       Role = 0
+	RoleHeadTrucker Role = 1
+	RoleChef        Role = 2
+	RoleStaff       Role = 3
+)
+
+// names of the roles, as used by the employee service
+var roleNames = map[Role]string{
+	RoleAdmin:       "Admin",
+	RoleHeadTrucker: "HeadTrucker",
+	RoleChef:        "Chef",
+	RoleStaff:       "Staff",
+}
+
+// checks if the RoleId is one of the employee roles
+func ValidateRoleId(roleId int) bool {
+	_, ok := roleNames[Role(roleId)]
+	return ok
+}
+
+// name of the role (empty for an unknown role)
+func (r Role) String() string {
+	return roleNames[r]
+}
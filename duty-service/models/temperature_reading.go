@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A temperature reading of a fridge, freezer or hot-holding unit during a shift (food-safety log)
+type TemperatureReading struct {
+	PartitionKey     uuid.UUID           `json:"PartitionKey"`     // ShiftID (used as PartitionKey in Azure Table Storage)
+	RowKey           uuid.UUID           `json:"RowKey"`           // ID of the reading (used as RowKey in Azure Table Storage)
+	EventId          uuid.UUID           `json:"EventId"`          // Event the shift belongs to (used for inspection reports)
+	UnitType         TemperatureUnitType `json:"UnitType"`         // Type of the unit (e.g., "Fridge", "Freezer")
+	UnitName         string              `json:"UnitName"`         // Name of the unit on the truck (e.g., "Fridge 1")
+	Temperature      float64             `json:"Temperature"`      // Measured temperature in °C
+	RecordedAt       time.Time           `json:"RecordedAt"`       // When the temperature was measured
+	EmployeeId       string              `json:"EmployeeId"`       // Employee who took the reading
+	WithinRange      bool                `json:"WithinRange"`      // Whether the reading is within the unit's safe range (set by the service)
+	SafeRange        *TemperatureRange   `json:"SafeRange"`        // The safe range the reading was checked against (null for readings from before it was stored)
+	CorrectiveAction *string             `json:"CorrectiveAction"` // What was done about an out-of-range reading (required when out of range)
+}
+
+////////////////////////////////////////
+
+// ENUM for the type of unit a temperature is measured in
+type TemperatureUnitType string
+
+// List of possible unit types
+const (
+	UnitFridge     TemperatureUnitType = "Fridge"
+	UnitFreezer    TemperatureUnitType = "Freezer"
+	UnitHotHolding TemperatureUnitType = "HotHolding"
+)
+
+// contains all valid unit types
+var ValidTemperatureUnitTypes = map[TemperatureUnitType]struct{}{
+	UnitFridge:     {},
+	UnitFreezer:    {},
+	UnitHotHolding: {},
+}
+
+// checks if the unit type is valid
+func ValidateTemperatureUnitType(unitType TemperatureUnitType) bool {
+	_, valid := ValidTemperatureUnitTypes[unitType]
+	return valid
+}
+
+////////////////////////////////////////
+
+// safe temperature range of a unit type in °C (a nil bound means unbounded)
+type TemperatureRange struct {
+	Min *float64 `json:"Min"`
+	Max *float64 `json:"Max"`
+}
+
+// checks if a temperature is within the range
+func (r TemperatureRange) Contains(temperature float64) bool {
+	if r.Min != nil && temperature < *r.Min {
+		return false
+	}
+	if r.Max != nil && temperature > *r.Max {
+		return false
+	}
+	return true
+}
+
+// message published when a reading is out of its safe range
+type TemperatureAlertMessage struct {
+	ShiftID          uuid.UUID           `json:"shift_id"`
+	EventID          uuid.UUID           `json:"event_id"`
+	ReadingID        uuid.UUID           `json:"reading_id"`
+	UnitType         TemperatureUnitType `json:"unit_type"`
+	UnitName         string              `json:"unit_name"`
+	Temperature      float64             `json:"temperature"`
+	SafeRange        TemperatureRange    `json:"safe_range"`
+	RecordedAt       time.Time           `json:"recorded_at"`
+	EmployeeID       string              `json:"employee_id"`
+	CorrectiveAction string              `json:"corrective_action"`
+}
@@ -0,0 +1,324 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"duty-service/images"
+	"duty-service/models"
+	"duty-service/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+	"github.com/google/uuid"
+)
+
+// ErrCommentNotFound is returned when a duty assignment has no comment with the given ID
+var ErrCommentNotFound = errors.New("comment not found")
+
+// storedCommentPhoto is how a comment photo is kept in the Photos property (the blob names aren't part of the API)
+type storedCommentPhoto struct {
+	Id                uuid.UUID `json:"Id"`
+	BlobName          string    `json:"BlobName"`
+	ThumbnailBlobName string    `json:"ThumbnailBlobName"`
+	ContentType       string    `json:"ContentType"`
+}
+
+type DutyAssignmentCommentRepository struct {
+	imageStore     storage.ImageStore
+	serviceClient  *aztables.ServiceClient
+	tableName      string
+	imageURLExpiry time.Duration // lifetime of the signed image URLs
+}
+
+func NewDutyAssignmentCommentRepository(serviceClient *aztables.ServiceClient, imageStore storage.ImageStore, imageURLExpiry time.Duration) *DutyAssignmentCommentRepository {
+	return &DutyAssignmentCommentRepository{
+		imageStore:     imageStore,
+		serviceClient:  serviceClient,
+		tableName:      "dutyAssignmentComments",
+		imageURLExpiry: imageURLExpiry,
+	}
+}
+
+// GET ALL COMMENTS OF A DUTY ASSIGNMENT (oldest first)
+func (r *DutyAssignmentCommentRepository) GetComments(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentComment, error) {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	filter := fmt.Sprintf("PartitionKey eq '%s'", models.DutyAssignmentCommentPartitionKey(shiftId, dutyId))
+	pager := tableClient.NewListEntitiesPager(&aztables.ListEntitiesOptions{Filter: &filter})
+
+	comments := []models.DutyAssignmentComment{}
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list duty assignment comments: %v", err)
+		}
+
+		for _, entity := range page.Entities {
+			var commentData map[string]interface{}
+			if err := json.Unmarshal(entity, &commentData); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal duty assignment comment: %v", err)
+			}
+
+			comment, err := parseDutyAssignmentComment(commentData)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := r.signImageURLs(&comment); err != nil {
+				return nil, err
+			}
+
+			comments = append(comments, comment)
+		}
+	}
+
+	// RowKeys are random, so the order has to come from the time
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+	})
+
+	return comments, nil
+}
+
+// GET a single comment of a duty assignment
+func (r *DutyAssignmentCommentRepository) GetComment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, commentId uuid.UUID) (*models.DutyAssignmentComment, error) {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	resp, err := tableClient.GetEntity(ctx, models.DutyAssignmentCommentPartitionKey(shiftId, dutyId), commentId.String(), nil)
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get duty assignment comment: %v", err)
+	}
+
+	var commentData map[string]interface{}
+	if err := json.Unmarshal(resp.Value, &commentData); err != nil {
+		return nil, fmt.Errorf("failed to decode duty assignment comment: %v", err)
+	}
+
+	comment, err := parseDutyAssignmentComment(commentData)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.signImageURLs(&comment); err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+// POST - uploads the photos as new blobs and stores the comment
+func (r *DutyAssignmentCommentRepository) AddComment(ctx context.Context, comment models.DutyAssignmentComment, photos []*images.ProcessedImage) (models.DutyAssignmentComment, error) {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	comment.PartitionKey = models.DutyAssignmentCommentPartitionKey(comment.ShiftId, comment.DutyId)
+
+	// [shiftid]_[dutyid]_[commentid]_[photoid].[ext]
+	comment.Photos = make([]models.DutyAssignmentCommentPhoto, 0, len(photos))
+	stored := make([]storedCommentPhoto, 0, len(photos))
+	for _, image := range photos {
+		photo := models.DutyAssignmentCommentPhoto{Id: uuid.New(), ContentType: image.Original.ContentType}
+		baseName := fmt.Sprintf("%s_%s_%s", comment.PartitionKey, comment.RowKey.String(), photo.Id.String())
+		photo.BlobName = baseName + image.Original.Extension
+		photo.ThumbnailBlobName = images.ThumbnailName(baseName, image.Thumbnail)
+
+		if err := r.imageStore.Upload(ctx, photo.BlobName, image.Original.ContentType, bytes.NewReader(image.Original.Data)); err != nil {
+			return models.DutyAssignmentComment{}, fmt.Errorf("failed to upload image: %v", err)
+		}
+		if err := r.imageStore.Upload(ctx, photo.ThumbnailBlobName, image.Thumbnail.ContentType, bytes.NewReader(image.Thumbnail.Data)); err != nil {
+			return models.DutyAssignmentComment{}, fmt.Errorf("failed to upload thumbnail: %v", err)
+		}
+
+		comment.Photos = append(comment.Photos, photo)
+		stored = append(stored, storedCommentPhoto{Id: photo.Id, BlobName: photo.BlobName, ThumbnailBlobName: photo.ThumbnailBlobName, ContentType: photo.ContentType})
+	}
+
+	// Mentions and Photos are stored as JSON strings because table properties can't hold lists
+	mentionsJSON, err := json.Marshal(comment.Mentions)
+	if err != nil {
+		return models.DutyAssignmentComment{}, fmt.Errorf("failed to marshal Mentions: %v", err)
+	}
+	photosJSON, err := json.Marshal(stored)
+	if err != nil {
+		return models.DutyAssignmentComment{}, fmt.Errorf("failed to marshal Photos: %v", err)
+	}
+
+	entity := map[string]interface{}{
+		"PartitionKey": comment.PartitionKey,
+		"RowKey":       comment.RowKey.String(),
+		"ShiftId":      comment.ShiftId.String(),
+		"DutyId":       comment.DutyId.String(),
+		"AuthorId":     comment.AuthorId,
+		"Text":         comment.Text,
+		"Mentions":     string(mentionsJSON),
+		"Photos":       string(photosJSON),
+		"CreatedAt":    comment.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return models.DutyAssignmentComment{}, fmt.Errorf("failed to marshal duty assignment comment: %v", err)
+	}
+
+	if _, err := tableClient.AddEntity(ctx, entityBytes, nil); err != nil {
+		return models.DutyAssignmentComment{}, fmt.Errorf("failed to insert duty assignment comment: %v", err)
+	}
+
+	if err := r.signImageURLs(&comment); err != nil {
+		return models.DutyAssignmentComment{}, err
+	}
+
+	return comment, nil
+}
+
+// PUT updates the text of a comment (with its mentions and UpdatedAt)
+func (r *DutyAssignmentCommentRepository) UpdateComment(ctx context.Context, comment models.DutyAssignmentComment) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	mentionsJSON, err := json.Marshal(comment.Mentions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Mentions: %v", err)
+	}
+
+	entity := map[string]interface{}{
+		"PartitionKey": models.DutyAssignmentCommentPartitionKey(comment.ShiftId, comment.DutyId),
+		"RowKey":       comment.RowKey.String(),
+		"Text":         comment.Text,
+		"Mentions":     string(mentionsJSON),
+		"UpdatedAt":    formatOptionalTime(comment.UpdatedAt),
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal duty assignment comment: %v", err)
+	}
+
+	// merge, so the photos and the author stay as they are
+	_, err = tableClient.UpdateEntity(ctx, entityBytes, &aztables.UpdateEntityOptions{UpdateMode: aztables.UpdateModeMerge})
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+		return ErrCommentNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update duty assignment comment: %v", err)
+	}
+
+	return nil
+}
+
+// DELETE a comment and the blobs of its photos
+func (r *DutyAssignmentCommentRepository) DeleteComment(ctx context.Context, comment models.DutyAssignmentComment) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	for _, photo := range comment.Photos {
+		if err := r.imageStore.Delete(ctx, photo.BlobName); err != nil {
+			return err
+		}
+		if err := r.imageStore.Delete(ctx, photo.ThumbnailBlobName); err != nil {
+			return err
+		}
+	}
+
+	_, err := tableClient.DeleteEntity(ctx, models.DutyAssignmentCommentPartitionKey(comment.ShiftId, comment.DutyId), comment.RowKey.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete duty assignment comment: %v", err)
+	}
+
+	return nil
+}
+
+// signImageURLs fills in short-lived signed URLs for the photos of a comment
+func (r *DutyAssignmentCommentRepository) signImageURLs(comment *models.DutyAssignmentComment) error {
+	for i := range comment.Photos {
+		imageURL, err := r.imageStore.URL(comment.Photos[i].BlobName, r.imageURLExpiry)
+		if err != nil {
+			return err
+		}
+		comment.Photos[i].ImageUrl = imageURL
+
+		thumbnailURL, err := r.imageStore.URL(comment.Photos[i].ThumbnailBlobName, r.imageURLExpiry)
+		if err != nil {
+			return err
+		}
+		comment.Photos[i].ThumbnailUrl = thumbnailURL
+	}
+
+	return nil
+}
+
+// parseDutyAssignmentComment parses a comment entity into a models.DutyAssignmentComment
+func parseDutyAssignmentComment(commentData map[string]interface{}) (models.DutyAssignmentComment, error) {
+	commentId, err := uuid.Parse(fmt.Sprint(commentData["RowKey"]))
+	if err != nil {
+		return models.DutyAssignmentComment{}, fmt.Errorf("failed to parse RowKey as UUID: %v", err)
+	}
+
+	shiftId, err := uuid.Parse(fmt.Sprint(commentData["ShiftId"]))
+	if err != nil {
+		return models.DutyAssignmentComment{}, fmt.Errorf("failed to parse ShiftId as UUID: %v", err)
+	}
+
+	dutyId, err := uuid.Parse(fmt.Sprint(commentData["DutyId"]))
+	if err != nil {
+		return models.DutyAssignmentComment{}, fmt.Errorf("failed to parse DutyId as UUID: %v", err)
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, fmt.Sprint(commentData["CreatedAt"]))
+	if err != nil {
+		return models.DutyAssignmentComment{}, fmt.Errorf("failed to parse CreatedAt: %v", err)
+	}
+
+	updatedAt, err := parseOptionalTime(commentData, "UpdatedAt")
+	if err != nil {
+		return models.DutyAssignmentComment{}, err
+	}
+
+	mentions := []string{}
+	if mentionsJSON, ok := commentData["Mentions"].(string); ok && mentionsJSON != "" {
+		if err := json.Unmarshal([]byte(mentionsJSON), &mentions); err != nil {
+			return models.DutyAssignmentComment{}, fmt.Errorf("failed to parse Mentions: %v", err)
+		}
+	}
+
+	photos := []models.DutyAssignmentCommentPhoto{}
+	if photosJSON, ok := commentData["Photos"].(string); ok && photosJSON != "" {
+		var stored []storedCommentPhoto
+		if err := json.Unmarshal([]byte(photosJSON), &stored); err != nil {
+			return models.DutyAssignmentComment{}, fmt.Errorf("failed to parse Photos: %v", err)
+		}
+		for _, photo := range stored {
+			photos = append(photos, models.DutyAssignmentCommentPhoto{
+				Id:                photo.Id,
+				BlobName:          photo.BlobName,
+				ThumbnailBlobName: photo.ThumbnailBlobName,
+				ContentType:       photo.ContentType,
+			})
+		}
+	}
+
+	partitionKey, _ := commentData["PartitionKey"].(string)
+	authorId, _ := commentData["AuthorId"].(string)
+	text, _ := commentData["Text"].(string)
+
+	return models.DutyAssignmentComment{
+		PartitionKey: partitionKey,
+		RowKey:       commentId,
+		ShiftId:      shiftId,
+		DutyId:       dutyId,
+		AuthorId:     authorId,
+		Text:         text,
+		Mentions:     mentions,
+		Photos:       photos,
+		CreatedAt:    createdAt,
+		UpdatedAt:    updatedAt,
+	}, nil
+}
@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"context"
+	"duty-service/models"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+	"github.com/google/uuid"
+)
+
+type DutyAssignmentHistoryRepository struct {
+	serviceClient *aztables.ServiceClient
+	tableName     string
+}
+
+func NewDutyAssignmentHistoryRepository(serviceClient *aztables.ServiceClient) *DutyAssignmentHistoryRepository {
+	return &DutyAssignmentHistoryRepository{
+		serviceClient: serviceClient,
+		tableName:     "dutyAssignmentHistory",
+	}
+}
+
+// GET THE HISTORY OF A DUTY ASSIGNMENT (oldest first)
+func (r *DutyAssignmentHistoryRepository) GetHistory(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentHistoryEntry, error) {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	filter := fmt.Sprintf("PartitionKey eq '%s'", models.DutyAssignmentHistoryPartitionKey(shiftId, dutyId))
+	pager := tableClient.NewListEntitiesPager(&aztables.ListEntitiesOptions{Filter: &filter})
+
+	entries := []models.DutyAssignmentHistoryEntry{}
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list duty assignment history: %v", err)
+		}
+
+		for _, entity := range page.Entities {
+			var entryData map[string]interface{}
+			if err := json.Unmarshal(entity, &entryData); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal duty assignment history entry: %v", err)
+			}
+
+			entry, err := parseDutyAssignmentHistoryEntry(entryData)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	// RowKeys are random, so the order has to come from the time
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ChangedAt.Before(entries[j].ChangedAt)
+	})
+
+	return entries, nil
+}
+
+// POST adds an entry to the history of a duty assignment
+func (r *DutyAssignmentHistoryRepository) AddEntry(ctx context.Context, entry models.DutyAssignmentHistoryEntry) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	entity := map[string]interface{}{
+		"PartitionKey":   models.DutyAssignmentHistoryPartitionKey(entry.ShiftId, entry.DutyId),
+		"RowKey":         entry.RowKey.String(),
+		"ShiftId":        entry.ShiftId.String(),
+		"DutyId":         entry.DutyId.String(),
+		"Action":         string(entry.Action),
+		"ChangedBy":      entry.ChangedBy,
+		"ChangedAt":      entry.ChangedAt.UTC().Format(time.RFC3339Nano),
+		"FromAssigneeId": entry.FromAssigneeId,
+		"ToAssigneeId":   entry.ToAssigneeId,
+		"Note":           entry.Note,
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal duty assignment history entry: %v", err)
+	}
+
+	if _, err := tableClient.AddEntity(ctx, entityBytes, nil); err != nil {
+		return fmt.Errorf("failed to insert duty assignment history entry: %v", err)
+	}
+
+	return nil
+}
+
+// parseDutyAssignmentHistoryEntry parses a history entity into a models.DutyAssignmentHistoryEntry
+func parseDutyAssignmentHistoryEntry(entryData map[string]interface{}) (models.DutyAssignmentHistoryEntry, error) {
+	entryId, err := uuid.Parse(fmt.Sprint(entryData["RowKey"]))
+	if err != nil {
+		return models.DutyAssignmentHistoryEntry{}, fmt.Errorf("failed to parse RowKey as UUID: %v", err)
+	}
+
+	shiftId, err := uuid.Parse(fmt.Sprint(entryData["ShiftId"]))
+	if err != nil {
+		return models.DutyAssignmentHistoryEntry{}, fmt.Errorf("failed to parse ShiftId as UUID: %v", err)
+	}
+
+	dutyId, err := uuid.Parse(fmt.Sprint(entryData["DutyId"]))
+	if err != nil {
+		return models.DutyAssignmentHistoryEntry{}, fmt.Errorf("failed to parse DutyId as UUID: %v", err)
+	}
+
+	changedAt, err := time.Parse(time.RFC3339Nano, fmt.Sprint(entryData["ChangedAt"]))
+	if err != nil {
+		return models.DutyAssignmentHistoryEntry{}, fmt.Errorf("failed to parse ChangedAt: %v", err)
+	}
+
+	var note *string
+	if value, ok := entryData["Note"].(string); ok && value != "" {
+		note = &value
+	}
+
+	action, _ := entryData["Action"].(string)
+	changedBy, _ := entryData["ChangedBy"].(string)
+	fromAssigneeId, _ := entryData["FromAssigneeId"].(string)
+	toAssigneeId, _ := entryData["ToAssigneeId"].(string)
+
+	return models.DutyAssignmentHistoryEntry{
+		PartitionKey:   fmt.Sprint(entryData["PartitionKey"]),
+		RowKey:         entryId,
+		ShiftId:        shiftId,
+		DutyId:         dutyId,
+		Action:         models.DutyAssignmentHistoryAction(action),
+		ChangedBy:      changedBy,
+		ChangedAt:      changedAt,
+		FromAssigneeId: fromAssigneeId,
+		ToAssigneeId:   toAssigneeId,
+		Note:           note,
+	}, nil
+}
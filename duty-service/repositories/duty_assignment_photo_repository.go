@@ -0,0 +1,536 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"duty-service/images"
+	"duty-service/models"
+	"duty-service/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+	"github.com/google/uuid"
+)
+
+// ErrPhotoNotFound is returned when a duty assignment has no photo with the given ID
+var ErrPhotoNotFound = errors.New("photo not found")
+
+// layout of the upload time in the RowKeys of the hash index: fixed width, so the keys sort like the times they hold
+const photoHashTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+type DutyAssignmentPhotoRepository struct {
+	imageStore     storage.ImageStore
+	serviceClient  *aztables.ServiceClient
+	tableName      string
+	hashTableName  string        // index of the photo hashes, partitioned by duty template, for the duplicate checks
+	imageURLExpiry time.Duration // lifetime of the signed image URLs
+}
+
+func NewDutyAssignmentPhotoRepository(serviceClient *aztables.ServiceClient, imageStore storage.ImageStore, imageURLExpiry time.Duration) *DutyAssignmentPhotoRepository {
+	return &DutyAssignmentPhotoRepository{
+		imageStore:     imageStore,
+		serviceClient:  serviceClient,
+		tableName:      "dutyAssignmentPhotos",
+		hashTableName:  "dutyPhotoHashes",
+		imageURLExpiry: imageURLExpiry,
+	}
+}
+
+// GET ALL PHOTOS OF A DUTY ASSIGNMENT (newest first)
+func (r *DutyAssignmentPhotoRepository) GetPhotos(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentPhoto, error) {
+	filter := fmt.Sprintf("PartitionKey eq '%s'", models.DutyAssignmentPhotoPartitionKey(shiftId, dutyId))
+
+	photos, err := r.listPhotos(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// RowKeys are random, so the order has to come from the upload time
+	sort.SliceStable(photos, func(i, j int) bool {
+		return photos[i].UploadedAt.After(photos[j].UploadedAt)
+	})
+
+	return photos, nil
+}
+
+// GET the photos of all assignments of a duty template uploaded since a given time (to find near-duplicates). They
+// come from the hash index, a single partition per template, so only ShiftId, DutyId, RowKey, DutyRowKey, Hash and
+// UploadedAt are set. Photos from before the index have no entry and are never returned.
+func (r *DutyAssignmentPhotoRepository) GetPhotosByDuty(ctx context.Context, dutyRowKey uuid.UUID, since time.Time) ([]models.DutyAssignmentPhoto, error) {
+	tableClient := r.serviceClient.NewClient(r.hashTableName)
+
+	filter := NewODataFilter().Eq("PartitionKey", dutyRowKey.String()).Ge("RowKey", since.UTC().Format(photoHashTimeLayout)).String()
+	pager := tableClient.NewListEntitiesPager(&aztables.ListEntitiesOptions{Filter: &filter})
+
+	var photos []models.DutyAssignmentPhoto
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list photo hashes: %v", err)
+		}
+
+		for _, entity := range page.Entities {
+			var hashData map[string]interface{}
+			if err := json.Unmarshal(entity, &hashData); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal photo hash: %v", err)
+			}
+
+			photo, err := parsePhotoHash(dutyRowKey, hashData)
+			if err != nil {
+				return nil, err
+			}
+			photos = append(photos, photo)
+		}
+	}
+
+	return photos, nil
+}
+
+// GET the flagged photos that wait for review, oldest first
+func (r *DutyAssignmentPhotoRepository) GetPhotosForReview(ctx context.Context) ([]models.DutyAssignmentPhoto, error) {
+	filter := NewODataFilter().Eq("ReviewStatus", string(models.PhotoReviewPending)).String()
+
+	photos, err := r.listPhotos(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(photos, func(i, j int) bool {
+		return photos[i].UploadedAt.Before(photos[j].UploadedAt)
+	})
+
+	return photos, nil
+}
+
+// GET a single photo of a duty assignment
+func (r *DutyAssignmentPhotoRepository) GetPhoto(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, photoId uuid.UUID) (*models.DutyAssignmentPhoto, error) {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	resp, err := tableClient.GetEntity(ctx, models.DutyAssignmentPhotoPartitionKey(shiftId, dutyId), photoId.String(), nil)
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+		return nil, ErrPhotoNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get duty assignment photo: %v", err)
+	}
+
+	var photoData map[string]interface{}
+	if err := json.Unmarshal(resp.Value, &photoData); err != nil {
+		return nil, fmt.Errorf("failed to decode duty assignment photo: %v", err)
+	}
+
+	photo, err := parseDutyAssignmentPhoto(photoData)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.signImageURLs(&photo); err != nil {
+		return nil, err
+	}
+
+	return &photo, nil
+}
+
+// POST - uploads the image and its thumbnail as new blobs and stores the photo
+func (r *DutyAssignmentPhotoRepository) AddPhoto(ctx context.Context, photo models.DutyAssignmentPhoto, image *images.ProcessedImage) (models.DutyAssignmentPhoto, error) {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	photo.PartitionKey = models.DutyAssignmentPhotoPartitionKey(photo.ShiftId, photo.DutyId)
+
+	// every upload gets its own blob, so earlier photos are never overwritten. [shiftid]_[dutyid]_[photoid].[ext]
+	baseName := fmt.Sprintf("%s_%s", photo.PartitionKey, photo.RowKey.String())
+	photo.BlobName = baseName + image.Original.Extension
+	photo.ThumbnailBlobName = images.ThumbnailName(baseName, image.Thumbnail)
+	photo.ContentType = image.Original.ContentType
+
+	if err := r.imageStore.Upload(ctx, photo.BlobName, image.Original.ContentType, bytes.NewReader(image.Original.Data)); err != nil {
+		return models.DutyAssignmentPhoto{}, fmt.Errorf("failed to upload image: %v", err)
+	}
+
+	if err := r.imageStore.Upload(ctx, photo.ThumbnailBlobName, image.Thumbnail.ContentType, bytes.NewReader(image.Thumbnail.Data)); err != nil {
+		r.deleteBlobs(ctx, photo.BlobName)
+		return models.DutyAssignmentPhoto{}, fmt.Errorf("failed to upload thumbnail: %v", err)
+	}
+
+	entity := map[string]interface{}{
+		"PartitionKey":      photo.PartitionKey,
+		"RowKey":            photo.RowKey.String(),
+		"ShiftId":           photo.ShiftId.String(),
+		"DutyId":            photo.DutyId.String(),
+		"BlobName":          photo.BlobName,
+		"ThumbnailBlobName": photo.ThumbnailBlobName,
+		"ContentType":       photo.ContentType,
+		"UploadedAt":        photo.UploadedAt.UTC().Format(time.RFC3339Nano),
+		"UploadedBy":        photo.UploadedBy,
+		"CapturedAt":        formatOptionalTime(photo.CapturedAt),
+		"CapturedAtLocal":   photo.CapturedAtLocal,
+		"ReviewStatus":      string(photo.ReviewStatus),
+	}
+
+	// only photos of a known template take part in the duplicate checks
+	if photo.DutyRowKey != uuid.Nil {
+		entity["DutyRowKey"] = photo.DutyRowKey.String()
+		entity["Hash"] = strconv.FormatUint(photo.Hash, 16)
+	}
+
+	if err := addPhotoFlags(entity, photo.Flags, photo.DuplicateOf); err != nil {
+		r.deleteBlobs(ctx, photo.BlobName, photo.ThumbnailBlobName)
+		return models.DutyAssignmentPhoto{}, err
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		r.deleteBlobs(ctx, photo.BlobName, photo.ThumbnailBlobName)
+		return models.DutyAssignmentPhoto{}, fmt.Errorf("failed to marshal duty assignment photo: %v", err)
+	}
+
+	// without a row the retention job would never find the blobs, so they are deleted again
+	_, err = tableClient.AddEntity(ctx, entityBytes, nil)
+	if err != nil {
+		r.deleteBlobs(ctx, photo.BlobName, photo.ThumbnailBlobName)
+		return models.DutyAssignmentPhoto{}, fmt.Errorf("failed to insert duty assignment photo: %v", err)
+	}
+
+	// the photo is stored, so a missing index entry only leaves it out of later duplicate checks
+	if photo.DutyRowKey != uuid.Nil {
+		if err := r.addPhotoHash(ctx, photo); err != nil {
+			log.Printf("Failed to index the hash of photo %s: %v", photo.RowKey, err)
+		}
+	}
+
+	if err := r.signImageURLs(&photo); err != nil {
+		return models.DutyAssignmentPhoto{}, err
+	}
+
+	return photo, nil
+}
+
+// deleteBlobs deletes the blobs of a photo that couldn't be stored (failures are only logged, the upload failed anyway)
+func (r *DutyAssignmentPhotoRepository) deleteBlobs(ctx context.Context, names ...string) {
+	for _, name := range names {
+		if err := r.imageStore.Delete(ctx, name); err != nil {
+			log.Printf("Failed to delete blob %s of a photo that wasn't stored: %v", name, err)
+		}
+	}
+}
+
+// PUT the review of a flagged photo
+func (r *DutyAssignmentPhotoRepository) UpdatePhotoReview(ctx context.Context, photo models.DutyAssignmentPhoto) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	entity := map[string]interface{}{
+		"PartitionKey": photo.PartitionKey,
+		"RowKey":       photo.RowKey.String(),
+		"ReviewStatus": string(photo.ReviewStatus),
+		"ReviewedBy":   photo.ReviewedBy,
+		"ReviewedAt":   formatOptionalTime(photo.ReviewedAt),
+		"ReviewNote":   photo.ReviewNote,
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal duty assignment photo: %v", err)
+	}
+
+	_, err = tableClient.UpdateEntity(ctx, entityBytes, &aztables.UpdateEntityOptions{UpdateMode: aztables.UpdateModeMerge})
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+		return ErrPhotoNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update review of duty assignment photo: %v", err)
+	}
+
+	return nil
+}
+
+// DELETE a photo and its blobs
+func (r *DutyAssignmentPhotoRepository) DeletePhoto(ctx context.Context, photo models.DutyAssignmentPhoto) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	if err := r.imageStore.Delete(ctx, photo.BlobName); err != nil {
+		return err
+	}
+	if err := r.imageStore.Delete(ctx, photo.ThumbnailBlobName); err != nil {
+		return err
+	}
+
+	_, err := tableClient.DeleteEntity(ctx, photo.PartitionKey, photo.RowKey.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete duty assignment photo: %v", err)
+	}
+
+	// photos from before the hash index have no entry
+	if photo.DutyRowKey != uuid.Nil {
+		hashClient := r.serviceClient.NewClient(r.hashTableName)
+		_, err := hashClient.DeleteEntity(ctx, photo.DutyRowKey.String(), photoHashRowKey(photo), nil)
+		var responseErr *azcore.ResponseError
+		if err != nil && !(errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound) {
+			return fmt.Errorf("failed to delete photo hash: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// addPhotoHash adds a photo to the hash index of its duty template
+func (r *DutyAssignmentPhotoRepository) addPhotoHash(ctx context.Context, photo models.DutyAssignmentPhoto) error {
+	tableClient := r.serviceClient.NewClient(r.hashTableName)
+
+	entity := map[string]interface{}{
+		"PartitionKey": photo.DutyRowKey.String(),
+		"RowKey":       photoHashRowKey(photo),
+		"ShiftId":      photo.ShiftId.String(),
+		"DutyId":       photo.DutyId.String(),
+		"PhotoId":      photo.RowKey.String(),
+		"Hash":         strconv.FormatUint(photo.Hash, 16),
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal photo hash: %v", err)
+	}
+
+	if _, err := tableClient.AddEntity(ctx, entityBytes, nil); err != nil {
+		return fmt.Errorf("failed to insert photo hash: %v", err)
+	}
+
+	return nil
+}
+
+// photoHashRowKey returns the RowKey of a photo in the hash index: [uploadedAt]_[photoid], so a partition sorts by
+// upload time
+func photoHashRowKey(photo models.DutyAssignmentPhoto) string {
+	return photo.UploadedAt.UTC().Format(photoHashTimeLayout) + "_" + photo.RowKey.String()
+}
+
+// parsePhotoHash is a helper function to parse an entry of the hash index into a models.DutyAssignmentPhoto object.
+func parsePhotoHash(dutyRowKey uuid.UUID, hashData map[string]interface{}) (models.DutyAssignmentPhoto, error) {
+	rowKey := fmt.Sprint(hashData["RowKey"])
+	uploadedAt, err := time.Parse(photoHashTimeLayout, strings.SplitN(rowKey, "_", 2)[0])
+	if err != nil {
+		return models.DutyAssignmentPhoto{}, fmt.Errorf("failed to parse the upload time of photo hash %s: %v", rowKey, err)
+	}
+
+	ids := make(map[string]uuid.UUID, 3)
+	for _, key := range []string{"ShiftId", "DutyId", "PhotoId"} {
+		id, err := uuid.Parse(fmt.Sprint(hashData[key]))
+		if err != nil {
+			return models.DutyAssignmentPhoto{}, fmt.Errorf("failed to parse %s of photo hash as UUID: %v", key, err)
+		}
+		ids[key] = id
+	}
+
+	hash, err := strconv.ParseUint(fmt.Sprint(hashData["Hash"]), 16, 64)
+	if err != nil {
+		return models.DutyAssignmentPhoto{}, fmt.Errorf("failed to parse Hash of photo hash: %v", err)
+	}
+
+	return models.DutyAssignmentPhoto{
+		PartitionKey: models.DutyAssignmentPhotoPartitionKey(ids["ShiftId"], ids["DutyId"]),
+		RowKey:       ids["PhotoId"],
+		ShiftId:      ids["ShiftId"],
+		DutyId:       ids["DutyId"],
+		DutyRowKey:   dutyRowKey,
+		Hash:         hash,
+		UploadedAt:   uploadedAt,
+	}, nil
+}
+
+// listPhotos returns the photos matching a filter, with signed URLs
+func (r *DutyAssignmentPhotoRepository) listPhotos(ctx context.Context, filter string) ([]models.DutyAssignmentPhoto, error) {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	listOptions := &aztables.ListEntitiesOptions{
+		Filter: &filter,
+	}
+
+	pager := tableClient.NewListEntitiesPager(listOptions)
+
+	var photos []models.DutyAssignmentPhoto
+
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list duty assignment photos: %v", err)
+		}
+
+		for _, entity := range page.Entities {
+			var photoData map[string]interface{}
+
+			if err := json.Unmarshal(entity, &photoData); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal duty assignment photo: %v", err)
+			}
+
+			photo, err := parseDutyAssignmentPhoto(photoData)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := r.signImageURLs(&photo); err != nil {
+				return nil, err
+			}
+
+			photos = append(photos, photo)
+		}
+	}
+
+	return photos, nil
+}
+
+// signImageURLs fills in short-lived signed URLs for a photo and its thumbnail
+func (r *DutyAssignmentPhotoRepository) signImageURLs(photo *models.DutyAssignmentPhoto) error {
+	imageURL, err := r.imageStore.URL(photo.BlobName, r.imageURLExpiry)
+	if err != nil {
+		return err
+	}
+	photo.ImageUrl = imageURL
+
+	thumbnailURL, err := r.imageStore.URL(photo.ThumbnailBlobName, r.imageURLExpiry)
+	if err != nil {
+		return err
+	}
+	photo.ThumbnailUrl = thumbnailURL
+
+	return nil
+}
+
+// parseDutyAssignmentPhoto is a helper function to parse photo data into a models.DutyAssignmentPhoto object.
+func parseDutyAssignmentPhoto(photoData map[string]interface{}) (models.DutyAssignmentPhoto, error) {
+	photoId, err := uuid.Parse(fmt.Sprint(photoData["RowKey"]))
+	if err != nil {
+		return models.DutyAssignmentPhoto{}, fmt.Errorf("failed to parse RowKey as UUID: %v", err)
+	}
+
+	shiftId, err := uuid.Parse(fmt.Sprint(photoData["ShiftId"]))
+	if err != nil {
+		return models.DutyAssignmentPhoto{}, fmt.Errorf("failed to parse ShiftId as UUID: %v", err)
+	}
+
+	dutyId, err := uuid.Parse(fmt.Sprint(photoData["DutyId"]))
+	if err != nil {
+		return models.DutyAssignmentPhoto{}, fmt.Errorf("failed to parse DutyId as UUID: %v", err)
+	}
+
+	uploadedAt, err := time.Parse(time.RFC3339Nano, fmt.Sprint(photoData["UploadedAt"]))
+	if err != nil {
+		return models.DutyAssignmentPhoto{}, fmt.Errorf("failed to parse UploadedAt: %v", err)
+	}
+
+	partitionKey, _ := photoData["PartitionKey"].(string)
+	blobName, _ := photoData["BlobName"].(string)
+	thumbnailBlobName, _ := photoData["ThumbnailBlobName"].(string)
+	contentType, _ := photoData["ContentType"].(string)
+	uploadedBy, _ := photoData["UploadedBy"].(string)
+
+	// photos from before the authenticity checks have none of these
+	var dutyRowKey uuid.UUID
+	if value, ok := photoData["DutyRowKey"].(string); ok && value != "" {
+		dutyRowKey, err = uuid.Parse(value)
+		if err != nil {
+			return models.DutyAssignmentPhoto{}, fmt.Errorf("failed to parse DutyRowKey as UUID: %v", err)
+		}
+	}
+
+	var hash uint64
+	if value, ok := photoData["Hash"].(string); ok && value != "" {
+		hash, err = strconv.ParseUint(value, 16, 64)
+		if err != nil {
+			return models.DutyAssignmentPhoto{}, fmt.Errorf("failed to parse Hash: %v", err)
+		}
+	}
+
+	capturedAt, err := parseOptionalTime(photoData, "CapturedAt")
+	if err != nil {
+		return models.DutyAssignmentPhoto{}, err
+	}
+	capturedAtLocal, _ := photoData["CapturedAtLocal"].(bool)
+
+	flags, duplicateOf, err := parsePhotoFlags(photoData)
+	if err != nil {
+		return models.DutyAssignmentPhoto{}, err
+	}
+
+	reviewStatus, _ := photoData["ReviewStatus"].(string)
+	reviewedBy, _ := photoData["ReviewedBy"].(string)
+	reviewNote, _ := photoData["ReviewNote"].(string)
+	reviewedAt, err := parseOptionalTime(photoData, "ReviewedAt")
+	if err != nil {
+		return models.DutyAssignmentPhoto{}, err
+	}
+
+	return models.DutyAssignmentPhoto{
+		PartitionKey:      partitionKey,
+		RowKey:            photoId,
+		ShiftId:           shiftId,
+		DutyId:            dutyId,
+		BlobName:          blobName,
+		ThumbnailBlobName: thumbnailBlobName,
+		ContentType:       contentType,
+		UploadedAt:        uploadedAt,
+		UploadedBy:        uploadedBy,
+		DutyRowKey:        dutyRowKey,
+		Hash:              hash,
+		CapturedAt:        capturedAt,
+		CapturedAtLocal:   capturedAtLocal,
+		Flags:             flags,
+		DuplicateOf:       duplicateOf,
+		ReviewStatus:      models.PhotoReviewStatus(reviewStatus),
+		ReviewedBy:        reviewedBy,
+		ReviewedAt:        reviewedAt,
+		ReviewNote:        reviewNote,
+	}, nil
+}
+
+// addPhotoFlags stores the flags of a photo and the photo it duplicates on the entity as JSON strings
+func addPhotoFlags(entity map[string]interface{}, flags []models.PhotoFlag, duplicateOf *models.PhotoReference) error {
+	entity["Flags"] = ""
+	if len(flags) > 0 {
+		flagsBytes, err := json.Marshal(flags)
+		if err != nil {
+			return fmt.Errorf("failed to marshal Flags: %v", err)
+		}
+		entity["Flags"] = string(flagsBytes)
+	}
+
+	entity["DuplicateOf"] = ""
+	if duplicateOf != nil {
+		duplicateOfBytes, err := json.Marshal(duplicateOf)
+		if err != nil {
+			return fmt.Errorf("failed to marshal DuplicateOf: %v", err)
+		}
+		entity["DuplicateOf"] = string(duplicateOfBytes)
+	}
+
+	return nil
+}
+
+// parsePhotoFlags reads the flags of a photo and the photo it duplicates (empty when it wasn't flagged)
+func parsePhotoFlags(data map[string]interface{}) ([]models.PhotoFlag, *models.PhotoReference, error) {
+	flags := []models.PhotoFlag{}
+	if flagsJSON, ok := data["Flags"].(string); ok && flagsJSON != "" {
+		if err := json.Unmarshal([]byte(flagsJSON), &flags); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse Flags: %v", err)
+		}
+	}
+
+	var duplicateOf *models.PhotoReference
+	if duplicateOfJSON, ok := data["DuplicateOf"].(string); ok && duplicateOfJSON != "" {
+		duplicateOf = &models.PhotoReference{}
+		if err := json.Unmarshal([]byte(duplicateOfJSON), duplicateOf); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse DuplicateOf: %v", err)
+		}
+	}
+
+	return flags, duplicateOf, nil
+}
@@ -2,138 +2,338 @@ package repositories
 
 import (
 	"context"
-	"duty-service/db"
 	"duty-service/models"
+	"duty-service/storage"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
-	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/google/uuid"
 )
 
+// ErrDutyAssignmentNotFound is returned when a shift has no duty assignment with the given ID
+var ErrDutyAssignmentNotFound = errors.New("duty assignment not found")
+
 type DutyAssignmentRepository struct {
-	blobClient    *azblob.Client
-	serviceClient *aztables.ServiceClient
-	tableName     string
+	imageStore     storage.ImageStore
+	serviceClient  *aztables.ServiceClient
+	tableName      string
+	imageURLExpiry time.Duration // lifetime of the signed image URLs
 }
 
-func NewDutyAssignmentRepository(serviceClient *aztables.ServiceClient, blobClient *azblob.Client) *DutyAssignmentRepository {
+func NewDutyAssignmentRepository(serviceClient *aztables.ServiceClient, imageStore storage.ImageStore, imageURLExpiry time.Duration) *DutyAssignmentRepository {
 	return &DutyAssignmentRepository{
-		blobClient:    blobClient,
-		serviceClient: serviceClient,
-		tableName:     "dutyAssignments",
+		imageStore:     imageStore,
+		serviceClient:  serviceClient,
+		tableName:      "dutyAssignments",
+		imageURLExpiry: imageURLExpiry,
 	}
 }
 
 // GET ALL DUTY ASSIGNMENTS BY SHIFT ID
 func (r *DutyAssignmentRepository) GetAllDutyAssignmentsByShiftId(ctx context.Context, shiftId uuid.UUID) ([]models.DutyAssignment, error) {
-	tableClient := r.serviceClient.NewClient(r.tableName)
-
 	filter := fmt.Sprintf("PartitionKey eq '%s'", shiftId.String()) // filter to match the ShiftId
 
-	listOptions := &aztables.ListEntitiesOptions{
-		Filter: &filter,
-	}
-
-	pager := tableClient.NewListEntitiesPager(listOptions)
+	return r.listDutyAssignments(ctx, filter, false)
+}
 
+// GET ALL DUTY ASSIGNMENTS OF SEVERAL SHIFTS (e.g. all shifts of an event)
+func (r *DutyAssignmentRepository) GetDutyAssignmentsByShiftIds(ctx context.Context, shiftIds []uuid.UUID) ([]models.DutyAssignment, error) {
 	var dutyAssignments []models.DutyAssignment
 
-	// Loop through pages of duty assignments
-	for pager.More() {
-		page, err := pager.NextPage(ctx)
+	// Table Storage allows at most 15 comparisons in one filter, so the shifts are queried in batches
+	for start := 0; start < len(shiftIds); start += maxFilterComparisons {
+		end := start + maxFilterComparisons
+		if end > len(shiftIds) {
+			end = len(shiftIds)
+		}
+
+		conditions := make([]string, 0, end-start)
+		for _, shiftId := range shiftIds[start:end] {
+			conditions = append(conditions, fmt.Sprintf("PartitionKey eq '%s'", shiftId.String()))
+		}
+
+		batch, err := r.listDutyAssignments(ctx, strings.Join(conditions, " or "), false)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list duty assignments by ShiftId: %v", err)
+			return nil, err
 		}
+		dutyAssignments = append(dutyAssignments, batch...)
+	}
 
-		// unmarshal entities and add it to the list
-		for _, entity := range page.Entities {
-			var dutyAssignmentData map[string]interface{}
+	return dutyAssignments, nil
+}
 
-			if err := json.Unmarshal(entity, &dutyAssignmentData); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal duty assignment: %v", err)
-			}
+// GET ALL DUTY ASSIGNMENTS OF AN EMPLOYEE, optionally only of one shift
+func (r *DutyAssignmentRepository) GetDutyAssignmentsByAssignee(ctx context.Context, assigneeId string, shiftId *uuid.UUID) ([]models.DutyAssignment, error) {
+	filter := NewODataFilter()
+	if shiftId != nil {
+		filter.Eq("PartitionKey", *shiftId)
+	}
+	filter.Eq("AssigneeId", assigneeId)
 
-			// Parse the optional fields (nullable fields like image URL and note)
-			var dutyAssignmentImageUrl *string
-			if imageUrl, ok := dutyAssignmentData["DutyAssignmentImageUrl"].(string); ok && imageUrl != "" {
-				dutyAssignmentImageUrl = &imageUrl
-			}
+	return r.listDutyAssignments(ctx, filter.String(), false)
+}
 
-			var dutyAssignmentNote *string
-			if note, ok := dutyAssignmentData["DutyAssignmentNote"].(string); ok && note != "" {
-				dutyAssignmentNote = &note
-			}
+// GET ALL DUTY ASSIGNMENTS OF AN EVENT (of all its shifts)
+func (r *DutyAssignmentRepository) GetDutyAssignmentsByEventId(ctx context.Context, eventId uuid.UUID) ([]models.DutyAssignment, error) {
+	filter := fmt.Sprintf("EventId eq '%s'", eventId.String())
 
-			// Create the DutyAssignment
-			dutyAssignment := models.DutyAssignment{
-				PartitionKey:           uuid.MustParse(dutyAssignmentData["PartitionKey"].(string)),
-				RowKey:                 uuid.MustParse(dutyAssignmentData["RowKey"].(string)),
-				DutyAssignmentStatus:   models.DutyAssignmentStatus(dutyAssignmentData["DutyAssignmentStatus"].(string)),
-				DutyAssignmentImageUrl: dutyAssignmentImageUrl,
-				DutyAssignmentNote:     dutyAssignmentNote,
-			}
+	return r.listDutyAssignments(ctx, filter, false)
+}
 
-			dutyAssignments = append(dutyAssignments, dutyAssignment)
+// GET ALL DUTY ASSIGNMENTS CREATED between from and to (inclusive)
+func (r *DutyAssignmentRepository) GetDutyAssignmentsCreatedBetween(ctx context.Context, from, to time.Time) ([]models.DutyAssignment, error) {
+	// CreatedAt is stored as an RFC3339 UTC string, so string comparison orders it chronologically
+	filter := fmt.Sprintf("CreatedAt ge '%s' and CreatedAt le '%s'", from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339))
+
+	return r.listDutyAssignments(ctx, filter, false)
+}
+
+// GET ALL DUTY ASSIGNMENTS OF SEVERAL SHIFTS that were written after since, including the ones deleted since then
+// (with DeletedAt set), so a device can remove them. A zero time returns all assignments that aren't deleted.
+func (r *DutyAssignmentRepository) GetDutyAssignmentsChangedSince(ctx context.Context, shiftIds []uuid.UUID, since time.Time) ([]models.DutyAssignment, error) {
+	if since.IsZero() {
+		return r.GetDutyAssignmentsByShiftIds(ctx, shiftIds)
+	}
+
+	var dutyAssignments []models.DutyAssignment
+
+	// the Timestamp condition counts towards the 15 comparisons of a filter as well
+	batchSize := maxFilterComparisons - 1
+	for start := 0; start < len(shiftIds); start += batchSize {
+		end := start + batchSize
+		if end > len(shiftIds) {
+			end = len(shiftIds)
 		}
+
+		conditions := make([]string, 0, end-start)
+		for _, shiftId := range shiftIds[start:end] {
+			conditions = append(conditions, fmt.Sprintf("PartitionKey eq '%s'", shiftId.String()))
+		}
+		filter := fmt.Sprintf("(%s) and Timestamp gt datetime'%s'", strings.Join(conditions, " or "), models.NewSyncToken(since))
+
+		batch, err := r.listDutyAssignmentsWhere(ctx, filter, func(models.DutyAssignment) bool { return true })
+		if err != nil {
+			return nil, err
+		}
+		dutyAssignments = append(dutyAssignments, batch...)
 	}
 
 	return dutyAssignments, nil
 }
 
-// POST - creates duty assignments for a Shift
-func (r *DutyAssignmentRepository) CreateDutyAssignments(ctx context.Context, shiftId uuid.UUID, duties []models.Duty) error {
+// GET a single duty assignment by ShiftId and DutyId (deleted assignments are not found)
+func (r *DutyAssignmentRepository) GetDutyAssignment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) (*models.DutyAssignment, error) {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	resp, err := tableClient.GetEntity(ctx, shiftId.String(), dutyId.String(), nil)
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+		return nil, ErrDutyAssignmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get duty assignment: %v", err)
+	}
+
+	var dutyAssignmentData map[string]interface{}
+	if err := json.Unmarshal(resp.Value, &dutyAssignmentData); err != nil {
+		return nil, fmt.Errorf("failed to decode duty assignment: %v", err)
+	}
+
+	dutyAssignment, err := parseDutyAssignment(dutyAssignmentData)
+	if err != nil {
+		return nil, err
+	}
+	if dutyAssignment.IsDeleted() {
+		return nil, ErrDutyAssignmentNotFound
+	}
+
+	if err := r.signImageURLs(&dutyAssignment); err != nil {
+		return nil, err
+	}
+
+	return &dutyAssignment, nil
+}
+
+// GET the deleted duty assignments of a shift (of all shifts when nil) that were deleted before the given time
+func (r *DutyAssignmentRepository) GetDeletedDutyAssignments(ctx context.Context, shiftId *uuid.UUID, deletedBefore time.Time) ([]models.DutyAssignment, error) {
+	filter := NewODataFilter()
+	if shiftId != nil {
+		filter.Eq("PartitionKey", *shiftId)
+	}
+	// restoring an assignment sets DeletedAt to an empty string
+	filter.Ne("DeletedAt", "").Le("DeletedAt", deletedBefore)
+
+	return r.listDutyAssignments(ctx, filter.String(), true)
+}
+
+// POST - creates duty assignments for a Shift. All assignments share the shift's partition, so they are written in
+// transactions: a batch is stored completely or not at all. RowKeys are derived from the shift and the template and
+// templates the shift already has are skipped, so retrying after a failure only adds what is missing. Returns the
+// assignments that were created.
+func (r *DutyAssignmentRepository) CreateDutyAssignments(ctx context.Context, shiftId uuid.UUID, roleId int, eventId *uuid.UUID, assigneeId string, duties []models.Duty) ([]models.DutyAssignment, error) {
 	tableClient := r.serviceClient.NewClient(r.tableName)
 
+	assigned, err := r.getAssignedDutyIds(ctx, shiftId)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt := time.Now().UTC()
+
+	var actions []aztables.TransactionAction
+	var created []models.DutyAssignment
 	for _, duty := range duties {
+		if _, exists := assigned[duty.RowKey]; exists {
+			continue
+		}
+		assigned[duty.RowKey] = struct{}{} // a template is only assigned once per shift
+
 		dutyAssignment := models.DutyAssignment{
 			PartitionKey:           shiftId,
-			RowKey:                 uuid.New(),              // generate a new UUID for the RowKey (it's EXTREMELY unlikely for new generated UUIDS to collide with existing ones. source: https://stackoverflow.com/questions/24876188/how-big-is-the-chance-to-get-a-java-uuid-randomuuid-collision)
-			DutyAssignmentStatus:   models.StatusIncomplete, // default: Incomplete
-			DutyAssignmentImageUrl: nil,                     // no image URL on creation
-			DutyAssignmentNote:     nil,                     // no note on creation
+			RowKey:                 models.DutyAssignmentRowKey(shiftId, duty.RowKey), // the same for every retry of this clock-in
+			DutyAssignmentStatus:   models.StatusIncomplete,                           // default: Incomplete
+			DutyAssignmentImageUrl: nil,                                               // no image on creation
+			DutyAssignmentNote:     nil,                                               // no note on creation
+			DutyPartitionKey:       duty.PartitionKey,                                 // keep a reference to the template (needed to validate its form)
+			DutyRowKey:             duty.RowKey,
+			DutyVersion:            duty.Version, // the instructions as they were at clock-in
+			CreatedAt:              createdAt,
+			RoleId:                 &roleId,
+			EventId:                eventId,
+			Mandatory:              duty.Mandatory,
+			AssigneeId:             assigneeId,
+		}
+
+		// the deadline is relative to clock-in
+		if duty.DueMinutes > 0 {
+			dueAt := createdAt.Add(time.Duration(duty.DueMinutes) * time.Minute)
+			dutyAssignment.DueAt = &dueAt
 		}
 
 		// marshal to a json
 		entity := map[string]interface{}{
-			"PartitionKey":           dutyAssignment.PartitionKey.String(),
-			"RowKey":                 dutyAssignment.RowKey.String(),
-			"DutyAssignmentStatus":   string(dutyAssignment.DutyAssignmentStatus),
-			"DutyAssignmentImageUrl": dutyAssignment.DutyAssignmentImageUrl,
-			"DutyAssignmentNote":     dutyAssignment.DutyAssignmentNote,
+			"PartitionKey":         dutyAssignment.PartitionKey.String(),
+			"RowKey":               dutyAssignment.RowKey.String(),
+			"DutyAssignmentStatus": string(dutyAssignment.DutyAssignmentStatus),
+			"DutyAssignmentNote":   dutyAssignment.DutyAssignmentNote,
+			"DutyPartitionKey":     dutyAssignment.DutyPartitionKey,
+			"DutyRowKey":           dutyAssignment.DutyRowKey.String(),
+			"DutyVersion":          dutyAssignment.DutyVersion,
+			"CreatedAt":            dutyAssignment.CreatedAt.Format(time.RFC3339),
+			"DueAt":                formatOptionalTime(dutyAssignment.DueAt),
+			"RoleId":               roleId,
+			"Mandatory":            duty.Mandatory,
+		}
+		if eventId != nil {
+			entity["EventId"] = eventId.String()
+		}
+		if assigneeId != "" {
+			entity["AssigneeId"] = assigneeId
 		}
 
 		entityBytes, err := json.Marshal(entity)
 		if err != nil {
-			return fmt.Errorf("failed to marshal duty assignment: %v", err)
+			return nil, fmt.Errorf("failed to marshal duty assignment: %v", err)
+		}
+
+		actions = append(actions, aztables.TransactionAction{ActionType: aztables.TransactionTypeAdd, Entity: entityBytes})
+		created = append(created, dutyAssignment)
+	}
+
+	// a transaction holds at most 100 operations
+	for start := 0; start < len(actions); start += maxTransactionActions {
+		end := start + maxTransactionActions
+		if end > len(actions) {
+			end = len(actions)
+		}
+
+		if _, err := tableClient.SubmitTransaction(ctx, actions[start:end], nil); err != nil {
+			return nil, fmt.Errorf("failed to create duty assignments for ShiftId %s: %v", shiftId, err)
+		}
+	}
+
+	return created, nil
+}
+
+// FREEZE the duty assignments of a shift at clock-out (stores FrozenAt and IncompleteAtClockOut, in transactions of 100)
+func (r *DutyAssignmentRepository) FreezeDutyAssignments(ctx context.Context, shiftId uuid.UUID, dutyAssignments []models.DutyAssignment) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	actions := make([]aztables.TransactionAction, 0, len(dutyAssignments))
+	for _, dutyAssignment := range dutyAssignments {
+		entity := map[string]interface{}{
+			"PartitionKey":         shiftId.String(),
+			"RowKey":               dutyAssignment.RowKey.String(),
+			"FrozenAt":             formatOptionalTime(dutyAssignment.FrozenAt),
+			"IncompleteAtClockOut": dutyAssignment.IncompleteAtClockOut,
 		}
 
-		_, err = tableClient.AddEntity(ctx, entityBytes, nil) // Insert the entity into Azure Table Storage
+		entityBytes, err := json.Marshal(entity)
 		if err != nil {
-			return fmt.Errorf("failed to create duty assignment for DutyId %s: %v", duty.RowKey, err)
+			return fmt.Errorf("failed to marshal duty assignment: %v", err)
+		}
+
+		actions = append(actions, aztables.TransactionAction{ActionType: aztables.TransactionTypeUpdateMerge, Entity: entityBytes})
+	}
+
+	for start := 0; start < len(actions); start += maxTransactionActions {
+		end := start + maxTransactionActions
+		if end > len(actions) {
+			end = len(actions)
+		}
+
+		if _, err := tableClient.SubmitTransaction(ctx, actions[start:end], nil); err != nil {
+			return fmt.Errorf("failed to freeze duty assignments of ShiftId %s: %v", shiftId, err)
 		}
 	}
 
 	return nil
 }
 
-// UPDATE a duty assignment
-func (r *DutyAssignmentRepository) UpdateDutyAssignment(ctx context.Context, dutyAssignment models.DutyAssignment, image io.Reader) error {
+// getAssignedDutyIds returns the RowKeys of the duty templates a shift already has assignments of
+func (r *DutyAssignmentRepository) getAssignedDutyIds(ctx context.Context, shiftId uuid.UUID) (map[uuid.UUID]struct{}, error) {
 	tableClient := r.serviceClient.NewClient(r.tableName)
 
-	// If there's an image to upload, handle the upload and get the URL
-	if image != nil {
-		//blobName := fmt.Sprintf("%s/%s.png", dutyAssignment.PartitionKey.String(), dutyAssignment.RowKey.String()) //folder structure
-		blobName := fmt.Sprintf("%s_%s.png", dutyAssignment.PartitionKey.String(), dutyAssignment.RowKey.String()) // no folder structure, just a unique name. [shiftid]_[dutyid].png
-		imageURL, err := db.UploadImage(ctx, "dutyassignmentimages", blobName, image)
+	// deleted assignments count as well: they can be restored, so a retried clock-in doesn't assign the duty again
+	filter := fmt.Sprintf("PartitionKey eq '%s'", shiftId.String())
+	selectProperties := "DutyRowKey"
+	pager := tableClient.NewListEntitiesPager(&aztables.ListEntitiesOptions{Filter: &filter, Select: &selectProperties})
+
+	assigned := make(map[uuid.UUID]struct{})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to upload image: %v", err)
+			return nil, fmt.Errorf("failed to list duty assignments: %v", err)
+		}
+
+		for _, entity := range page.Entities {
+			var data map[string]interface{}
+			if err := json.Unmarshal(entity, &data); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal duty assignment: %v", err)
+			}
+
+			// assignments without a template reference can't be matched and are ignored
+			if dutyRowKey, err := uuid.Parse(fmt.Sprint(data["DutyRowKey"])); err == nil {
+				assigned[dutyRowKey] = struct{}{}
+			}
 		}
-		dutyAssignment.DutyAssignmentImageUrl = &imageURL
 	}
 
+	return assigned, nil
+}
+
+// UPDATE a duty assignment
+func (r *DutyAssignmentRepository) UpdateDutyAssignment(ctx context.Context, dutyAssignment models.DutyAssignment) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
 	// preparing the updated entity (only include fields that are non-nil or non-empty)
 	entity := map[string]interface{}{
 		"PartitionKey": dutyAssignment.PartitionKey.String(),
@@ -145,14 +345,39 @@ func (r *DutyAssignmentRepository) UpdateDutyAssignment(ctx context.Context, dut
 		entity["DutyAssignmentStatus"] = string(dutyAssignment.DutyAssignmentStatus)
 	}
 
-	if dutyAssignment.DutyAssignmentImageUrl != nil && *dutyAssignment.DutyAssignmentImageUrl != "" {
-		entity["DutyAssignmentImageUrl"] = dutyAssignment.DutyAssignmentImageUrl
+	// only blob names are stored, the URLs are signed when the assignment is read
+	if dutyAssignment.ImageBlobName != "" {
+		entity["DutyAssignmentImageBlobName"] = dutyAssignment.ImageBlobName
+		entity["DutyAssignmentThumbnailBlobName"] = dutyAssignment.ThumbnailBlobName
+	}
+
+	// nil keeps the stored flags (an update without a photo doesn't change them)
+	if dutyAssignment.PhotoFlags != nil {
+		if err := addAssignmentPhotoFlags(entity, dutyAssignment.PhotoFlags); err != nil {
+			return err
+		}
 	}
 
 	if dutyAssignment.DutyAssignmentNote != nil && *dutyAssignment.DutyAssignmentNote != "" {
 		entity["DutyAssignmentNote"] = dutyAssignment.DutyAssignmentNote
 	}
 
+	if dutyAssignment.UpdatedAt != nil {
+		entity["UpdatedAt"] = formatOptionalTime(dutyAssignment.UpdatedAt)
+	}
+
+	// started/completed times are always written so reopening a duty clears CompletedAt
+	entity["StartedAt"] = formatOptionalTime(dutyAssignment.StartedAt)
+	entity["CompletedAt"] = formatOptionalTime(dutyAssignment.CompletedAt)
+
+	// form answers are stored as separate properties (Form_<key>) so they can be queried
+	for key, value := range dutyAssignment.FormValues {
+		entity[formValuePrefix+key] = value
+		if _, isNumber := value.(float64); isNumber {
+			entity[formValuePrefix+key+"@odata.type"] = "Edm.Double" // keep whole numbers from being stored as Int32
+		}
+	}
+
 	entityBytes, err := json.Marshal(entity) // marshal to JSON
 	if err != nil {
 		return fmt.Errorf("failed to marshal updated entity: %v", err)
@@ -167,18 +392,402 @@ func (r *DutyAssignmentRepository) UpdateDutyAssignment(ctx context.Context, dut
 	return nil
 }
 
-// DELETE a duty assignment by ShiftId and DutyId
-func (r *DutyAssignmentRepository) DeleteDutyAssignment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) error {
+// hands a duty assignment over to another employee
+func (r *DutyAssignmentRepository) UpdateAssignee(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, assigneeId string) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	entity := map[string]interface{}{
+		"PartitionKey": shiftId.String(),
+		"RowKey":       dutyId.String(),
+		"AssigneeId":   assigneeId,
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal duty assignment: %v", err)
+	}
+
+	_, err = tableClient.UpdateEntity(ctx, entityBytes, &aztables.UpdateEntityOptions{UpdateMode: aztables.UpdateModeMerge})
+	if err != nil {
+		return fmt.Errorf("failed to update assignee of duty assignment: %v", err)
+	}
+
+	return nil
+}
+
+// sets the main photo shown on a duty assignment (empty blob names remove it)
+func (r *DutyAssignmentRepository) SetMainPhoto(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, imageBlobName string, thumbnailBlobName string) error {
 	tableClient := r.serviceClient.NewClient(r.tableName)
 
-	// Prepare the PartitionKey and RowKey
-	partitionKey := shiftId.String() // ShiftId
-	rowKey := dutyId.String()        // DutyId
+	entity := map[string]interface{}{
+		"PartitionKey":                    shiftId.String(),
+		"RowKey":                          dutyId.String(),
+		"DutyAssignmentImageBlobName":     imageBlobName,
+		"DutyAssignmentThumbnailBlobName": thumbnailBlobName,
+		"DutyAssignmentImageUrl":          "", // clear the URLs stored before images were private
+		"DutyAssignmentThumbnailUrl":      "",
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal updated entity: %v", err)
+	}
+
+	_, err = tableClient.UpdateEntity(ctx, entityBytes, &aztables.UpdateEntityOptions{UpdateMode: aztables.UpdateModeMerge})
+	if err != nil {
+		return fmt.Errorf("failed to update main photo of duty assignment: %v", err)
+	}
+
+	return nil
+}
+
+// sets the flags of the photos of a duty assignment that wait for review (empty clears them)
+func (r *DutyAssignmentRepository) SetPhotoFlags(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, flags []models.PhotoFlag) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	entity := map[string]interface{}{
+		"PartitionKey": shiftId.String(),
+		"RowKey":       dutyId.String(),
+	}
+	if err := addAssignmentPhotoFlags(entity, flags); err != nil {
+		return err
+	}
 
-	_, err := tableClient.DeleteEntity(ctx, partitionKey, rowKey, nil) // Delete the entity in Azure Table Storage
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal updated entity: %v", err)
+	}
+
+	_, err = tableClient.UpdateEntity(ctx, entityBytes, &aztables.UpdateEntityOptions{UpdateMode: aztables.UpdateModeMerge})
+	if err != nil {
+		return fmt.Errorf("failed to update photo flags of duty assignment: %v", err)
+	}
+
+	return nil
+}
+
+// marks a duty assignment as deleted, or restores it when deletedAt is nil
+func (r *DutyAssignmentRepository) SetDutyAssignmentDeleted(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, deletedAt *time.Time, deletedBy string) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	entity := map[string]interface{}{
+		"PartitionKey": shiftId.String(),
+		"RowKey":       dutyId.String(),
+		"DeletedAt":    formatOptionalTime(deletedAt),
+		"DeletedBy":    deletedBy,
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal duty assignment: %v", err)
+	}
+
+	_, err = tableClient.UpdateEntity(ctx, entityBytes, &aztables.UpdateEntityOptions{UpdateMode: aztables.UpdateModeMerge})
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+		return ErrDutyAssignmentNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update deletion of duty assignment: %v", err)
+	}
+
+	return nil
+}
+
+// DELETE a duty assignment for good, together with its main photo
+func (r *DutyAssignmentRepository) DeleteDutyAssignment(ctx context.Context, dutyAssignment models.DutyAssignment) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	// images uploaded before the photo history only exist as the main photo, so they're deleted here
+	for _, name := range []string{dutyAssignment.ImageBlobName, dutyAssignment.ThumbnailBlobName} {
+		if name == "" {
+			continue
+		}
+		if err := r.imageStore.Delete(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	_, err := tableClient.DeleteEntity(ctx, dutyAssignment.PartitionKey.String(), dutyAssignment.RowKey.String(), nil) // Delete the entity in Azure Table Storage
 	if err != nil {
 		return fmt.Errorf("failed to delete duty assignment: %v", err)
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// property name prefix of the form answers on a duty assignment entity
+const formValuePrefix = "Form_"
+
+// the maximum number of comparisons Azure Table Storage allows in a filter
+const maxFilterComparisons = 15
+
+// the maximum number of operations in an Azure Table Storage transaction
+const maxTransactionActions = 100
+
+// listDutyAssignments lists the duty assignments matching the filter: the deleted ones, or the ones that aren't.
+// Assignments from before soft deletes have no DeletedAt property, which a filter can't match, so it's checked here.
+func (r *DutyAssignmentRepository) listDutyAssignments(ctx context.Context, filter string, deleted bool) ([]models.DutyAssignment, error) {
+	return r.listDutyAssignmentsWhere(ctx, filter, func(dutyAssignment models.DutyAssignment) bool {
+		return dutyAssignment.IsDeleted() == deleted
+	})
+}
+
+// listDutyAssignmentsWhere lists the duty assignments matching the filter for which include returns true
+func (r *DutyAssignmentRepository) listDutyAssignmentsWhere(ctx context.Context, filter string, include func(models.DutyAssignment) bool) ([]models.DutyAssignment, error) {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	listOptions := &aztables.ListEntitiesOptions{
+		Filter: &filter,
+	}
+
+	pager := tableClient.NewListEntitiesPager(listOptions)
+
+	var dutyAssignments []models.DutyAssignment
+
+	// Loop through pages of duty assignments
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list duty assignments: %v", err)
+		}
+
+		// unmarshal entities and add it to the list
+		for _, entity := range page.Entities {
+			var dutyAssignmentData map[string]interface{}
+
+			if err := json.Unmarshal(entity, &dutyAssignmentData); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal duty assignment: %v", err)
+			}
+
+			dutyAssignment, err := parseDutyAssignment(dutyAssignmentData)
+			if err != nil {
+				return nil, err
+			}
+			if !include(dutyAssignment) {
+				continue
+			}
+
+			if err := r.signImageURLs(&dutyAssignment); err != nil {
+				return nil, err
+			}
+
+			dutyAssignments = append(dutyAssignments, dutyAssignment)
+		}
+	}
+
+	return dutyAssignments, nil
+}
+
+// signImageURLs fills in short-lived signed URLs for the main photo of a duty assignment
+func (r *DutyAssignmentRepository) signImageURLs(dutyAssignment *models.DutyAssignment) error {
+	if dutyAssignment.ImageBlobName == "" {
+		return nil
+	}
+
+	imageURL, err := r.imageStore.URL(dutyAssignment.ImageBlobName, r.imageURLExpiry)
+	if err != nil {
+		return err
+	}
+	dutyAssignment.DutyAssignmentImageUrl = &imageURL
+
+	if dutyAssignment.ThumbnailBlobName != "" {
+		thumbnailURL, err := r.imageStore.URL(dutyAssignment.ThumbnailBlobName, r.imageURLExpiry)
+		if err != nil {
+			return err
+		}
+		dutyAssignment.DutyAssignmentThumbnailUrl = &thumbnailURL
+	}
+
+	return nil
+}
+
+// blobNameFromURL returns the blob name of an image URL stored before images were private ("" if there is none)
+func blobNameFromURL(imageURL string) string {
+	parsed, err := url.Parse(imageURL)
+	if imageURL == "" || err != nil {
+		return ""
+	}
+	return path.Base(parsed.Path)
+}
+
+// parseDutyAssignment is a helper function to parse duty assignment data into a models.DutyAssignment object.
+func parseDutyAssignment(dutyAssignmentData map[string]interface{}) (models.DutyAssignment, error) {
+	shiftId, err := uuid.Parse(fmt.Sprint(dutyAssignmentData["PartitionKey"]))
+	if err != nil {
+		return models.DutyAssignment{}, fmt.Errorf("failed to parse PartitionKey as UUID: %v", err)
+	}
+
+	dutyId, err := uuid.Parse(fmt.Sprint(dutyAssignmentData["RowKey"]))
+	if err != nil {
+		return models.DutyAssignment{}, fmt.Errorf("failed to parse RowKey as UUID: %v", err)
+	}
+
+	// Parse the optional fields (nullable fields like the image and note)
+	imageBlobName, _ := dutyAssignmentData["DutyAssignmentImageBlobName"].(string)
+	thumbnailBlobName, _ := dutyAssignmentData["DutyAssignmentThumbnailBlobName"].(string)
+	if imageBlobName == "" { // assignments updated before images were private only have the blob URLs
+		legacyImageUrl, _ := dutyAssignmentData["DutyAssignmentImageUrl"].(string)
+		legacyThumbnailUrl, _ := dutyAssignmentData["DutyAssignmentThumbnailUrl"].(string)
+		imageBlobName, thumbnailBlobName = blobNameFromURL(legacyImageUrl), blobNameFromURL(legacyThumbnailUrl)
+	}
+
+	var dutyAssignmentNote *string
+	if note, ok := dutyAssignmentData["DutyAssignmentNote"].(string); ok && note != "" {
+		dutyAssignmentNote = &note
+	}
+
+	// assignments created before templates were referenced don't have these
+	dutyPartitionKey, _ := dutyAssignmentData["DutyPartitionKey"].(string)
+	var dutyRowKey uuid.UUID
+	if rowKey, ok := dutyAssignmentData["DutyRowKey"].(string); ok && rowKey != "" {
+		dutyRowKey, err = uuid.Parse(rowKey)
+		if err != nil {
+			return models.DutyAssignment{}, fmt.Errorf("failed to parse DutyRowKey as UUID: %v", err)
+		}
+	}
+	dutyVersion, _ := dutyAssignmentData["DutyVersion"].(float64) // 0 for assignments from before versioning
+
+	var formValues map[string]interface{}
+	for property, value := range dutyAssignmentData {
+		if !strings.HasPrefix(property, formValuePrefix) || strings.Contains(property, "@") { // skip odata type annotations
+			continue
+		}
+		if formValues == nil {
+			formValues = make(map[string]interface{})
+		}
+		formValues[strings.TrimPrefix(property, formValuePrefix)] = value
+	}
+
+	// assignments created before these were stored have no creation time or deadline
+	var createdAt time.Time
+	if value, ok := dutyAssignmentData["CreatedAt"].(string); ok && value != "" {
+		createdAt, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return models.DutyAssignment{}, fmt.Errorf("failed to parse CreatedAt: %v", err)
+		}
+	}
+
+	dueAt, err := parseOptionalTime(dutyAssignmentData, "DueAt")
+	if err != nil {
+		return models.DutyAssignment{}, err
+	}
+
+	startedAt, err := parseOptionalTime(dutyAssignmentData, "StartedAt")
+	if err != nil {
+		return models.DutyAssignment{}, err
+	}
+
+	completedAt, err := parseOptionalTime(dutyAssignmentData, "CompletedAt")
+	if err != nil {
+		return models.DutyAssignment{}, err
+	}
+
+	var roleId *int
+	if value, ok := dutyAssignmentData["RoleId"].(float64); ok {
+		role := int(value)
+		roleId = &role
+	}
+
+	var eventId *uuid.UUID
+	if value, ok := dutyAssignmentData["EventId"].(string); ok && value != "" {
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			return models.DutyAssignment{}, fmt.Errorf("failed to parse EventId as UUID: %v", err)
+		}
+		eventId = &parsed
+	}
+
+	frozenAt, err := parseOptionalTime(dutyAssignmentData, "FrozenAt")
+	if err != nil {
+		return models.DutyAssignment{}, err
+	}
+	updatedAt, err := parseOptionalTime(dutyAssignmentData, "UpdatedAt")
+	if err != nil {
+		return models.DutyAssignment{}, err
+	}
+	deletedAt, err := parseOptionalTime(dutyAssignmentData, "DeletedAt")
+	if err != nil {
+		return models.DutyAssignment{}, err
+	}
+	modifiedAt, _ := time.Parse(time.RFC3339Nano, fmt.Sprint(dutyAssignmentData["Timestamp"])) // set by Table Storage on every write
+	mandatory, _ := dutyAssignmentData["Mandatory"].(bool)
+	assigneeId, _ := dutyAssignmentData["AssigneeId"].(string) // assignments from before assignees were stored have none
+	incompleteAtClockOut, _ := dutyAssignmentData["IncompleteAtClockOut"].(bool)
+	deletedBy, _ := dutyAssignmentData["DeletedBy"].(string)
+
+	photoFlags := []models.PhotoFlag{}
+	if flagsJSON, ok := dutyAssignmentData["PhotoFlags"].(string); ok && flagsJSON != "" {
+		if err := json.Unmarshal([]byte(flagsJSON), &photoFlags); err != nil {
+			return models.DutyAssignment{}, fmt.Errorf("failed to parse PhotoFlags: %v", err)
+		}
+	}
+
+	status, _ := dutyAssignmentData["DutyAssignmentStatus"].(string)
+
+	return models.DutyAssignment{
+		PartitionKey:         shiftId,
+		RowKey:               dutyId,
+		DutyAssignmentStatus: models.DutyAssignmentStatus(status),
+		ImageBlobName:        imageBlobName,
+		ThumbnailBlobName:    thumbnailBlobName,
+		PhotoFlags:           photoFlags,
+		DutyAssignmentNote:   dutyAssignmentNote,
+		DutyPartitionKey:     dutyPartitionKey,
+		DutyRowKey:           dutyRowKey,
+		DutyVersion:          int(dutyVersion),
+		FormValues:           formValues,
+		CreatedAt:            createdAt,
+		DueAt:                dueAt,
+		StartedAt:            startedAt,
+		CompletedAt:          completedAt,
+		RoleId:               roleId,
+		EventId:              eventId,
+		Mandatory:            mandatory,
+		FrozenAt:             frozenAt,
+		IncompleteAtClockOut: incompleteAtClockOut,
+		AssigneeId:           assigneeId,
+		UpdatedAt:            updatedAt,
+		ModifiedAt:           modifiedAt,
+		DeletedAt:            deletedAt,
+		DeletedBy:            deletedBy,
+	}, nil
+}
+
+// addAssignmentPhotoFlags stores the photo flags of an assignment on the entity as a JSON string (empty clears them)
+func addAssignmentPhotoFlags(entity map[string]interface{}, flags []models.PhotoFlag) error {
+	if len(flags) == 0 {
+		entity["PhotoFlags"] = ""
+		return nil
+	}
+
+	flagsBytes, err := json.Marshal(flags)
+	if err != nil {
+		return fmt.Errorf("failed to marshal PhotoFlags: %v", err)
+	}
+	entity["PhotoFlags"] = string(flagsBytes)
+
+	return nil
+}
+
+// parseOptionalTime parses a nullable RFC3339 time property (missing or empty means nil)
+func parseOptionalTime(data map[string]interface{}, property string) (*time.Time, error) {
+	value, ok := data[property].(string)
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", property, err)
+	}
+	return &parsed, nil
+}
+
+// formatOptionalTime formats a nullable time as an RFC3339 UTC string (empty when nil)
+func formatOptionalTime(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.UTC().Format(time.RFC3339)
+}
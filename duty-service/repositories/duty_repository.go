@@ -1,24 +1,55 @@
 package repositories
 
 import (
+	"bytes"
 	"context"
+	"duty-service/images"
 	"duty-service/models"
+	"duty-service/storage"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
 	"github.com/google/uuid"
 )
 
+// ErrDutyNotFound is returned when there is no duty with the given PartitionKey and RowKey
+var ErrDutyNotFound = errors.New("duty not found")
+
+// ErrDutyChanged is returned when a duty was changed by someone else since it was read
+var ErrDutyChanged = errors.New("the duty was changed by someone else: reload it and try again")
+
+// storedDutyAttachment is how an attachment is kept in the Attachments property (the blob names aren't part of the API)
+type storedDutyAttachment struct {
+	Id                uuid.UUID `json:"Id"`
+	Kind              string    `json:"Kind"`
+	Title             string    `json:"Title"`
+	FileName          string    `json:"FileName"`
+	ContentType       string    `json:"ContentType"`
+	Size              int       `json:"Size"`
+	BlobName          string    `json:"BlobName"`
+	ThumbnailBlobName string    `json:"ThumbnailBlobName"`
+	UploadedAt        time.Time `json:"UploadedAt"`
+	UploadedBy        string    `json:"UploadedBy"`
+}
+
 type DutyRepository struct {
-	serviceClient *aztables.ServiceClient
-	tableName     string
+	imageStore     storage.ImageStore
+	serviceClient  *aztables.ServiceClient
+	tableName      string
+	imageURLExpiry time.Duration // lifetime of the signed attachment URLs
 }
 
-func NewDutyRepository(serviceClient *aztables.ServiceClient) *DutyRepository {
+func NewDutyRepository(serviceClient *aztables.ServiceClient, imageStore storage.ImageStore, imageURLExpiry time.Duration) *DutyRepository {
 	return &DutyRepository{
-		serviceClient: serviceClient,
-		tableName:     "duties",
+		imageStore:     imageStore,
+		serviceClient:  serviceClient,
+		tableName:      "duties",
+		imageURLExpiry: imageURLExpiry,
 	}
 }
 
@@ -52,6 +83,10 @@ func (r *DutyRepository) GetAllDuties(ctx context.Context, filter string) ([]mod
 				return nil, err
 			}
 
+			if err := r.signAttachmentURLs(&duty); err != nil {
+				return nil, err
+			}
+
 			duties = append(duties, duty)
 		}
 	}
@@ -63,6 +98,10 @@ func (r *DutyRepository) GetDutyById(ctx context.Context, partitionKey, rowKey s
 	tableClient := r.serviceClient.NewClient(r.tableName)
 
 	resp, err := tableClient.GetEntity(ctx, partitionKey, rowKey, nil)
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+		return nil, ErrDutyNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get duty: %v", err)
 	}
@@ -76,6 +115,11 @@ func (r *DutyRepository) GetDutyById(ctx context.Context, partitionKey, rowKey s
 	if err != nil {
 		return nil, err
 	}
+	duty.ETag = string(resp.ETag)
+
+	if err := r.signAttachmentURLs(&duty); err != nil {
+		return nil, err
+	}
 
 	return &duty, nil
 }
@@ -84,7 +128,7 @@ func (r *DutyRepository) GetDutyById(ctx context.Context, partitionKey, rowKey s
 func (r *DutyRepository) GetDutiesByRole(ctx context.Context, roleId int) ([]models.Duty, error) {
 	tableClient := r.serviceClient.NewClient(r.tableName)
 
-	filter := fmt.Sprintf("RoleId eq %d", roleId) // roleId is now an int
+	filter := NewODataFilter().Eq("RoleId", roleId).String() // roleId is now an int
 
 	listOptions := &aztables.ListEntitiesOptions{
 		Filter: &filter,
@@ -112,6 +156,10 @@ func (r *DutyRepository) GetDutiesByRole(ctx context.Context, roleId int) ([]mod
 				return nil, err
 			}
 
+			if err := r.signAttachmentURLs(&duty); err != nil {
+				return nil, err
+			}
+
 			duties = append(duties, duty)
 		}
 	}
@@ -130,6 +178,20 @@ func (r *DutyRepository) CreateDuty(ctx context.Context, duty models.Duty) error
 		"RoleId":          duty.RoleId,
 		"DutyName":        duty.DutyName,
 		"DutyDescription": duty.DutyDescription,
+		"DueMinutes":      duty.DueMinutes,
+		"Mandatory":       duty.Mandatory,
+		"DutyKey":         duty.DutyKey,
+		"Version":         duty.Version,
+	}
+
+	if err := addFormSchema(entity, duty.FormSchema); err != nil {
+		return err
+	}
+	if err := addAppliesTo(entity, duty.AppliesTo); err != nil {
+		return err
+	}
+	if err := addTags(entity, duty.Tags); err != nil {
+		return err
 	}
 
 	// Marshal the entity to JSON
@@ -158,6 +220,20 @@ func (r *DutyRepository) UpdateDuty(ctx context.Context, partitionKey, rowKey st
 		"RoleId":          duty.RoleId,
 		"DutyName":        duty.DutyName,
 		"DutyDescription": duty.DutyDescription,
+		"DueMinutes":      duty.DueMinutes,
+		"Mandatory":       duty.Mandatory,
+		"DutyKey":         duty.DutyKey,
+		"Version":         duty.Version,
+	}
+
+	if err := addFormSchema(entity, duty.FormSchema); err != nil {
+		return err
+	}
+	if err := addAppliesTo(entity, duty.AppliesTo); err != nil {
+		return err
+	}
+	if err := addTags(entity, duty.Tags); err != nil {
+		return err
 	}
 
 	entityBytes, err := json.Marshal(entity)
@@ -165,8 +241,17 @@ func (r *DutyRepository) UpdateDuty(ctx context.Context, partitionKey, rowKey st
 		return fmt.Errorf("failed to marshal updated entity: %v", err)
 	}
 
-	// Update the entity
-	_, err = tableClient.UpdateEntity(ctx, entityBytes, nil)
+	// Update the entity, only if it is still the version that was read (duties without an ETag are overwritten)
+	options := &aztables.UpdateEntityOptions{UpdateMode: aztables.UpdateModeMerge}
+	if duty.ETag != "" {
+		etag := azcore.ETag(duty.ETag)
+		options.IfMatch = &etag
+	}
+	_, err = tableClient.UpdateEntity(ctx, entityBytes, options)
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusPreconditionFailed {
+		return ErrDutyChanged
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update duty: %v", err)
 	}
@@ -174,12 +259,167 @@ func (r *DutyRepository) UpdateDuty(ctx context.Context, partitionKey, rowKey st
 	return nil
 }
 
-// DELETE A DUTY (removes a duty by PartitionKey and RowKey)
-func (r *DutyRepository) DeleteDuty(ctx context.Context, partitionKey, rowKey string) error {
+// claims the number of a new version of a duty by making it the duty's Version, only if the duty is still the version
+// that was read with etag (an empty etag claims it unconditionally). Of concurrent updates of a duty only one can
+// claim the next number; the others get ErrDutyChanged. Returns the ETag of the duty after the claim.
+func (r *DutyRepository) ClaimDutyVersion(ctx context.Context, partitionKey, rowKey string, version int, etag string) (string, error) {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	entity := map[string]interface{}{
+		"PartitionKey": partitionKey,
+		"RowKey":       rowKey,
+		"Version":      version,
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal entity: %v", err)
+	}
+
+	options := &aztables.UpdateEntityOptions{UpdateMode: aztables.UpdateModeMerge}
+	if etag != "" {
+		ifMatch := azcore.ETag(etag)
+		options.IfMatch = &ifMatch
+	}
+	resp, err := tableClient.UpdateEntity(ctx, entityBytes, options)
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+		return "", ErrDutyNotFound
+	}
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusPreconditionFailed {
+		return "", ErrDutyChanged
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to claim version of duty: %v", err)
+	}
+
+	return string(resp.ETag), nil
+}
+
+// marks a duty as deleted, or restores it when deletedAt is nil
+func (r *DutyRepository) SetDutyDeleted(ctx context.Context, partitionKey, rowKey string, deletedAt *time.Time, deletedBy string) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	entity := map[string]interface{}{
+		"PartitionKey": partitionKey,
+		"RowKey":       rowKey,
+		"DeletedAt":    formatOptionalTime(deletedAt),
+		"DeletedBy":    deletedBy,
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal entity: %v", err)
+	}
+
+	_, err = tableClient.UpdateEntity(ctx, entityBytes, &aztables.UpdateEntityOptions{UpdateMode: aztables.UpdateModeMerge})
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+		return ErrDutyNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update deletion of duty: %v", err)
+	}
+
+	return nil
+}
+
+// replaces the translations of a duty (an empty map removes them), only if it is still the version that was read
+// with etag (an empty etag overwrites them)
+func (r *DutyRepository) SetDutyTranslations(ctx context.Context, partitionKey, rowKey string, etag string, translations models.DutyTranslations) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	entity := map[string]interface{}{
+		"PartitionKey": partitionKey,
+		"RowKey":       rowKey,
+	}
+	if err := addTranslations(entity, translations); err != nil {
+		return err
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal entity: %v", err)
+	}
+
+	options := &aztables.UpdateEntityOptions{UpdateMode: aztables.UpdateModeMerge}
+	if etag != "" {
+		ifMatch := azcore.ETag(etag)
+		options.IfMatch = &ifMatch
+	}
+	_, err = tableClient.UpdateEntity(ctx, entityBytes, options)
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+		return ErrDutyNotFound
+	}
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusPreconditionFailed {
+		return ErrDutyChanged
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update translations of duty: %v", err)
+	}
+
+	return nil
+}
+
+// POST - uploads an attachment (and the thumbnail of an image) as new blobs and adds it to the duty's attachments
+func (r *DutyRepository) AddDutyAttachment(ctx context.Context, duty models.Duty, attachment models.DutyAttachment, file *images.ProcessedAttachment) (models.DutyAttachment, error) {
+	// every upload gets its own blob, so attachments are never overwritten. duty_[dutyid]_[attachmentid].[ext]
+	baseName := fmt.Sprintf("duty_%s_%s", duty.RowKey.String(), attachment.Id.String())
+	attachment.Kind = file.Kind
+	attachment.BlobName = baseName + file.File.Extension
+	attachment.ContentType = file.File.ContentType
+	attachment.Size = len(file.File.Data)
+
+	if err := r.imageStore.Upload(ctx, attachment.BlobName, file.File.ContentType, bytes.NewReader(file.File.Data)); err != nil {
+		return models.DutyAttachment{}, fmt.Errorf("failed to upload attachment: %v", err)
+	}
+
+	if file.Thumbnail != nil {
+		attachment.ThumbnailBlobName = images.ThumbnailName(baseName, *file.Thumbnail)
+		if err := r.imageStore.Upload(ctx, attachment.ThumbnailBlobName, file.Thumbnail.ContentType, bytes.NewReader(file.Thumbnail.Data)); err != nil {
+			return models.DutyAttachment{}, fmt.Errorf("failed to upload thumbnail: %v", err)
+		}
+	}
+
+	if err := r.setDutyAttachments(ctx, duty, append(append([]models.DutyAttachment{}, duty.Attachments...), attachment)); err != nil {
+		return models.DutyAttachment{}, err
+	}
+
+	if err := r.signAttachmentURL(&attachment); err != nil {
+		return models.DutyAttachment{}, err
+	}
+
+	return attachment, nil
+}
+
+// DELETE an attachment of a duty and its blobs
+func (r *DutyRepository) DeleteDutyAttachment(ctx context.Context, duty models.Duty, attachment models.DutyAttachment) error {
+	if err := r.deleteAttachmentBlobs(ctx, attachment); err != nil {
+		return err
+	}
+
+	remaining := []models.DutyAttachment{}
+	for _, existing := range duty.Attachments {
+		if existing.Id != attachment.Id {
+			remaining = append(remaining, existing)
+		}
+	}
+	return r.setDutyAttachments(ctx, duty, remaining)
+}
+
+// DELETE A DUTY for good, with the blobs of its attachments
+func (r *DutyRepository) DeleteDuty(ctx context.Context, duty models.Duty) error {
 	tableClient := r.serviceClient.NewClient(r.tableName)
 
+	for _, attachment := range duty.Attachments {
+		if err := r.deleteAttachmentBlobs(ctx, attachment); err != nil {
+			return err
+		}
+	}
+
 	// Delete the entity
-	_, err := tableClient.DeleteEntity(ctx, partitionKey, rowKey, nil)
+	_, err := tableClient.DeleteEntity(ctx, duty.PartitionKey, duty.RowKey.String(), nil)
 	if err != nil {
 		return fmt.Errorf("failed to delete duty: %v", err)
 	}
@@ -187,6 +427,98 @@ func (r *DutyRepository) DeleteDuty(ctx context.Context, partitionKey, rowKey st
 	return nil
 }
 
+// setDutyAttachments replaces the attachments of a duty (merged, so the rest of the duty is kept)
+func (r *DutyRepository) setDutyAttachments(ctx context.Context, duty models.Duty, attachments []models.DutyAttachment) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	stored := make([]storedDutyAttachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		stored = append(stored, storedDutyAttachment{
+			Id:                attachment.Id,
+			Kind:              attachment.Kind,
+			Title:             attachment.Title,
+			FileName:          attachment.FileName,
+			ContentType:       attachment.ContentType,
+			Size:              attachment.Size,
+			BlobName:          attachment.BlobName,
+			ThumbnailBlobName: attachment.ThumbnailBlobName,
+			UploadedAt:        attachment.UploadedAt,
+			UploadedBy:        attachment.UploadedBy,
+		})
+	}
+
+	entity := map[string]interface{}{
+		"PartitionKey": duty.PartitionKey,
+		"RowKey":       duty.RowKey.String(),
+		"Attachments":  "",
+	}
+	if len(stored) > 0 {
+		attachmentsBytes, err := json.Marshal(stored)
+		if err != nil {
+			return fmt.Errorf("failed to marshal Attachments: %v", err)
+		}
+		entity["Attachments"] = string(attachmentsBytes)
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal entity: %v", err)
+	}
+
+	_, err = tableClient.UpdateEntity(ctx, entityBytes, &aztables.UpdateEntityOptions{UpdateMode: aztables.UpdateModeMerge})
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+		return ErrDutyNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update attachments of duty: %v", err)
+	}
+
+	return nil
+}
+
+// deleteAttachmentBlobs deletes the file and thumbnail of an attachment
+func (r *DutyRepository) deleteAttachmentBlobs(ctx context.Context, attachment models.DutyAttachment) error {
+	if err := r.imageStore.Delete(ctx, attachment.BlobName); err != nil {
+		return err
+	}
+	if attachment.ThumbnailBlobName != "" {
+		if err := r.imageStore.Delete(ctx, attachment.ThumbnailBlobName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// signAttachmentURLs fills in short-lived signed URLs for the attachments of a duty
+func (r *DutyRepository) signAttachmentURLs(duty *models.Duty) error {
+	for i := range duty.Attachments {
+		if err := r.signAttachmentURL(&duty.Attachments[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// signAttachmentURL fills in short-lived signed URLs for an attachment and its thumbnail
+func (r *DutyRepository) signAttachmentURL(attachment *models.DutyAttachment) error {
+	url, err := r.imageStore.URL(attachment.BlobName, r.imageURLExpiry)
+	if err != nil {
+		return err
+	}
+	attachment.Url = url
+
+	if attachment.ThumbnailBlobName != "" {
+		thumbnailURL, err := r.imageStore.URL(attachment.ThumbnailBlobName, r.imageURLExpiry)
+		if err != nil {
+			return err
+		}
+		attachment.ThumbnailUrl = &thumbnailURL
+	}
+
+	return nil
+}
+
 // parseDuty is a helper function to parse duty data into a models.Duty object.
 func parseDuty(dutyData map[string]interface{}) (models.Duty, error) {
 	// Parse RowKey as UUID
@@ -202,11 +534,207 @@ func parseDuty(dutyData map[string]interface{}) (models.Duty, error) {
 	}
 	roleId := int(roleIdFloat)
 
+	// FormSchema is stored as a JSON string because table properties can't hold lists
+	var formSchema []models.FormField
+	if schemaJSON, ok := dutyData["FormSchema"].(string); ok && schemaJSON != "" {
+		if err := json.Unmarshal([]byte(schemaJSON), &formSchema); err != nil {
+			return models.Duty{}, fmt.Errorf("failed to parse FormSchema: %v", err)
+		}
+	}
+
+	appliesTo, err := parseAppliesTo(dutyData)
+	if err != nil {
+		return models.Duty{}, err
+	}
+
+	// duties from before tags have none
+	tags, err := parseTags(dutyData)
+	if err != nil {
+		return models.Duty{}, err
+	}
+
+	// duties from before translations have none
+	translations, err := parseTranslations(dutyData)
+	if err != nil {
+		return models.Duty{}, err
+	}
+
+	// duties from before attachments have none
+	attachments, err := parseAttachments(dutyData)
+	if err != nil {
+		return models.Duty{}, err
+	}
+
+	// duties created before deadlines existed have no DueMinutes
+	dueMinutes, _ := dutyData["DueMinutes"].(float64)
+
+	// duties from before clock-out checks aren't mandatory
+	mandatory, _ := dutyData["Mandatory"].(bool)
+
+	// duties created before import/export have no DutyKey (Duty.Key derives one)
+	dutyKey, _ := dutyData["DutyKey"].(string)
+
+	// duties from before versioning have no Version
+	version, _ := dutyData["Version"].(float64)
+
+	deletedAt, err := parseOptionalTime(dutyData, "DeletedAt")
+	if err != nil {
+		return models.Duty{}, err
+	}
+	deletedBy, _ := dutyData["DeletedBy"].(string)
+
 	return models.Duty{
 		PartitionKey:    dutyData["PartitionKey"].(string),
 		RowKey:          rowKeyUUID,
 		RoleId:          roleId,
 		DutyName:        dutyData["DutyName"].(string),
 		DutyDescription: dutyData["DutyDescription"].(string),
+		FormSchema:      formSchema,
+		DueMinutes:      int(dueMinutes),
+		Mandatory:       mandatory,
+		DutyKey:         dutyKey,
+		Version:         int(version),
+		AppliesTo:       appliesTo,
+		Tags:            tags,
+		Translations:    translations,
+		Attachments:     attachments,
+		DeletedAt:       deletedAt,
+		DeletedBy:       deletedBy,
 	}, nil
 }
+
+// addFormSchema stores the form schema on the entity as a JSON string (an empty string clears it on update)
+func addFormSchema(entity map[string]interface{}, formSchema []models.FormField) error {
+	if len(formSchema) == 0 {
+		entity["FormSchema"] = ""
+		return nil
+	}
+
+	schemaBytes, err := json.Marshal(formSchema)
+	if err != nil {
+		return fmt.Errorf("failed to marshal FormSchema: %v", err)
+	}
+	entity["FormSchema"] = string(schemaBytes)
+
+	return nil
+}
+
+// addAppliesTo stores the applicability rules on the entity as a JSON string (an empty string clears them on update)
+func addAppliesTo(entity map[string]interface{}, appliesTo *models.DutyApplicability) error {
+	if appliesTo.IsEmpty() {
+		entity["AppliesTo"] = ""
+		return nil
+	}
+
+	rulesBytes, err := json.Marshal(appliesTo)
+	if err != nil {
+		return fmt.Errorf("failed to marshal AppliesTo: %v", err)
+	}
+	entity["AppliesTo"] = string(rulesBytes)
+
+	return nil
+}
+
+// parseAppliesTo reads the applicability rules of a duty or duty version (nil when there are none)
+func parseAppliesTo(data map[string]interface{}) (*models.DutyApplicability, error) {
+	rulesJSON, ok := data["AppliesTo"].(string)
+	if !ok || rulesJSON == "" {
+		return nil, nil
+	}
+
+	var appliesTo models.DutyApplicability
+	if err := json.Unmarshal([]byte(rulesJSON), &appliesTo); err != nil {
+		return nil, fmt.Errorf("failed to parse AppliesTo: %v", err)
+	}
+	return &appliesTo, nil
+}
+
+// addTags stores the tags on the entity as a JSON string (an empty string clears them on update)
+func addTags(entity map[string]interface{}, tags []string) error {
+	if len(tags) == 0 {
+		entity["Tags"] = ""
+		return nil
+	}
+
+	tagsBytes, err := json.Marshal(tags)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Tags: %v", err)
+	}
+	entity["Tags"] = string(tagsBytes)
+
+	return nil
+}
+
+// parseTags reads the tags of a duty or duty version (nil when there are none)
+func parseTags(data map[string]interface{}) ([]string, error) {
+	tagsJSON, ok := data["Tags"].(string)
+	if !ok || tagsJSON == "" {
+		return nil, nil
+	}
+
+	var tags []string
+	if err := json.Unmarshal([]byte(tagsJSON), &tags); err != nil {
+		return nil, fmt.Errorf("failed to parse Tags: %v", err)
+	}
+	return tags, nil
+}
+
+// addTranslations stores the translations on the entity as a JSON string (an empty string clears them)
+func addTranslations(entity map[string]interface{}, translations models.DutyTranslations) error {
+	if len(translations) == 0 {
+		entity["Translations"] = ""
+		return nil
+	}
+
+	translationsBytes, err := json.Marshal(translations)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Translations: %v", err)
+	}
+	entity["Translations"] = string(translationsBytes)
+
+	return nil
+}
+
+// parseTranslations reads the translations of a duty (nil when there are none)
+func parseTranslations(data map[string]interface{}) (models.DutyTranslations, error) {
+	translationsJSON, ok := data["Translations"].(string)
+	if !ok || translationsJSON == "" {
+		return nil, nil
+	}
+
+	var translations models.DutyTranslations
+	if err := json.Unmarshal([]byte(translationsJSON), &translations); err != nil {
+		return nil, fmt.Errorf("failed to parse Translations: %v", err)
+	}
+	return translations, nil
+}
+
+// parseAttachments reads the attachments of a duty, without their URLs (nil when there are none)
+func parseAttachments(data map[string]interface{}) ([]models.DutyAttachment, error) {
+	attachmentsJSON, ok := data["Attachments"].(string)
+	if !ok || attachmentsJSON == "" {
+		return nil, nil
+	}
+
+	var stored []storedDutyAttachment
+	if err := json.Unmarshal([]byte(attachmentsJSON), &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse Attachments: %v", err)
+	}
+
+	attachments := make([]models.DutyAttachment, 0, len(stored))
+	for _, attachment := range stored {
+		attachments = append(attachments, models.DutyAttachment{
+			Id:                attachment.Id,
+			Kind:              attachment.Kind,
+			Title:             attachment.Title,
+			FileName:          attachment.FileName,
+			ContentType:       attachment.ContentType,
+			Size:              attachment.Size,
+			BlobName:          attachment.BlobName,
+			ThumbnailBlobName: attachment.ThumbnailBlobName,
+			UploadedAt:        attachment.UploadedAt,
+			UploadedBy:        attachment.UploadedBy,
+		})
+	}
+	return attachments, nil
+}
@@ -0,0 +1,178 @@
+package repositories
+
+import (
+	"context"
+	"duty-service/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+	"github.com/google/uuid"
+)
+
+// Stores the results of synced offline changes, so a change the crew app sends again isn't applied twice
+type DutySyncRepository struct {
+	serviceClient *aztables.ServiceClient
+	tableName     string
+}
+
+func NewDutySyncRepository(serviceClient *aztables.ServiceClient) *DutySyncRepository {
+	return &DutySyncRepository{
+		serviceClient: serviceClient,
+		tableName:     "dutySyncChanges",
+	}
+}
+
+// ErrSyncChangeInProgress is returned when a change is being applied by another sync
+var ErrSyncChangeInProgress = errors.New("the change is being applied by another sync: send it again later")
+
+// POST reserves a change before it is applied, so two syncs of the same change can't both apply it. Returns the stored
+// result when the change was synced before (nil after reserving it), or ErrSyncChangeInProgress while another sync
+// holds the reservation. A reservation older than expiry was left by a sync that failed halfway and is taken over.
+func (r *DutySyncRepository) ReserveSyncChange(ctx context.Context, shiftId, clientChangeId, dutyId uuid.UUID, reservedAt time.Time, expiry time.Duration) (*models.DutySyncChangeResult, error) {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	entityBytes, err := syncResultEntity(models.DutySyncChangeResult{
+		ClientChangeId: clientChangeId,
+		ShiftId:        shiftId,
+		DutyId:         dutyId,
+		Status:         models.SyncPending,
+		SyncedAt:       reservedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tableClient.AddEntity(ctx, entityBytes, nil)
+	var responseErr *azcore.ResponseError
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.As(err, &responseErr) || responseErr.StatusCode != http.StatusConflict {
+		return nil, fmt.Errorf("failed to reserve sync change: %v", err)
+	}
+
+	// synced before, or being synced
+	stored, etag, err := r.getSyncResult(ctx, shiftId, clientChangeId)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		// the reservation was released in the meantime: the change failed and can be sent again
+		return nil, ErrSyncChangeInProgress
+	}
+	if stored.Status != models.SyncPending {
+		return stored, nil
+	}
+	if reservedAt.Sub(stored.SyncedAt) < expiry {
+		return nil, ErrSyncChangeInProgress
+	}
+
+	// take over the expired reservation, unless another sync just did
+	_, err = tableClient.UpdateEntity(ctx, entityBytes, &aztables.UpdateEntityOptions{UpdateMode: aztables.UpdateModeReplace, IfMatch: &etag})
+	if errors.As(err, &responseErr) && (responseErr.StatusCode == http.StatusPreconditionFailed || responseErr.StatusCode == http.StatusNotFound) {
+		return nil, ErrSyncChangeInProgress
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve sync change: %v", err)
+	}
+
+	return nil, nil
+}
+
+// DELETE releases the reservation of a change that failed, so it can be sent again
+func (r *DutySyncRepository) ReleaseSyncChange(ctx context.Context, shiftId, clientChangeId uuid.UUID) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	_, err := tableClient.DeleteEntity(ctx, shiftId.String(), clientChangeId.String(), nil)
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to release sync change: %v", err)
+	}
+
+	return nil
+}
+
+// getSyncResult returns the stored result of a change and its ETag (nil when there is none)
+func (r *DutySyncRepository) getSyncResult(ctx context.Context, shiftId uuid.UUID, clientChangeId uuid.UUID) (*models.DutySyncChangeResult, azcore.ETag, error) {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	resp, err := tableClient.GetEntity(ctx, shiftId.String(), clientChangeId.String(), nil)
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get sync result: %v", err)
+	}
+
+	var resultData map[string]interface{}
+	if err := json.Unmarshal(resp.Value, &resultData); err != nil {
+		return nil, "", fmt.Errorf("failed to decode sync result: %v", err)
+	}
+
+	dutyId, err := uuid.Parse(fmt.Sprint(resultData["DutyId"]))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse DutyId as UUID: %v", err)
+	}
+
+	syncedAt, err := time.Parse(time.RFC3339Nano, fmt.Sprint(resultData["SyncedAt"]))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse SyncedAt: %v", err)
+	}
+
+	status, _ := resultData["Status"].(string)
+	errorMessage, _ := resultData["Error"].(string)
+
+	return &models.DutySyncChangeResult{
+		ClientChangeId: clientChangeId,
+		ShiftId:        shiftId,
+		DutyId:         dutyId,
+		Status:         models.SyncChangeStatus(status),
+		Error:          errorMessage,
+		SyncedAt:       syncedAt,
+	}, resp.ETag, nil
+}
+
+// PUT stores the result of a change (replacing its reservation)
+func (r *DutySyncRepository) SaveSyncResult(ctx context.Context, result models.DutySyncChangeResult) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	entityBytes, err := syncResultEntity(result)
+	if err != nil {
+		return err
+	}
+
+	_, err = tableClient.UpsertEntity(ctx, entityBytes, &aztables.UpsertEntityOptions{UpdateMode: aztables.UpdateModeReplace})
+	if err != nil {
+		return fmt.Errorf("failed to store sync result: %v", err)
+	}
+
+	return nil
+}
+
+// syncResultEntity is a helper function to marshal the result of a change into a table entity.
+func syncResultEntity(result models.DutySyncChangeResult) ([]byte, error) {
+	entity := map[string]interface{}{
+		"PartitionKey": result.ShiftId.String(),
+		"RowKey":       result.ClientChangeId.String(),
+		"DutyId":       result.DutyId.String(),
+		"Status":       string(result.Status),
+		"Error":        result.Error,
+		"SyncedAt":     result.SyncedAt.UTC().Format(time.RFC3339Nano),
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal sync result: %v", err)
+	}
+
+	return entityBytes, nil
+}
@@ -0,0 +1,264 @@
+package repositories
+
+import (
+	"context"
+	"duty-service/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+	"github.com/google/uuid"
+)
+
+// ErrDutyVersionNotFound is returned when a duty has no version with the given number
+var ErrDutyVersionNotFound = errors.New("duty version not found")
+
+// ErrDutyVersionExists is returned when a version is added that is already stored
+var ErrDutyVersionExists = errors.New("duty version already exists")
+
+type DutyVersionRepository struct {
+	serviceClient *aztables.ServiceClient
+	tableName     string
+}
+
+func NewDutyVersionRepository(serviceClient *aztables.ServiceClient) *DutyVersionRepository {
+	return &DutyVersionRepository{
+		serviceClient: serviceClient,
+		tableName:     "dutyVersions",
+	}
+}
+
+// GET ALL VERSIONS OF A DUTY (oldest first, the RowKeys are zero-padded)
+func (r *DutyVersionRepository) GetVersions(ctx context.Context, dutyId uuid.UUID) ([]models.DutyVersion, error) {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	filter := fmt.Sprintf("PartitionKey eq '%s'", dutyId.String())
+
+	listOptions := &aztables.ListEntitiesOptions{
+		Filter: &filter,
+	}
+
+	pager := tableClient.NewListEntitiesPager(listOptions)
+
+	var versions []models.DutyVersion
+
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list duty versions: %v", err)
+		}
+
+		for _, entity := range page.Entities {
+			var versionData map[string]interface{}
+
+			if err := json.Unmarshal(entity, &versionData); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal duty version: %v", err)
+			}
+
+			version, err := parseDutyVersion(versionData)
+			if err != nil {
+				return nil, err
+			}
+
+			versions = append(versions, version)
+		}
+	}
+
+	return versions, nil
+}
+
+// GET a single version of a duty
+func (r *DutyVersionRepository) GetVersion(ctx context.Context, dutyId uuid.UUID, version int) (*models.DutyVersion, error) {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	resp, err := tableClient.GetEntity(ctx, dutyId.String(), models.DutyVersionRowKey(version), nil)
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+		return nil, ErrDutyVersionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get duty version: %v", err)
+	}
+
+	var versionData map[string]interface{}
+	if err := json.Unmarshal(resp.Value, &versionData); err != nil {
+		return nil, fmt.Errorf("failed to decode duty version: %v", err)
+	}
+
+	dutyVersion, err := parseDutyVersion(versionData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dutyVersion, nil
+}
+
+// POST - stores a new version; fails with ErrDutyVersionExists when a version with the same number is stored
+func (r *DutyVersionRepository) AddVersion(ctx context.Context, version models.DutyVersion) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	entityBytes, err := dutyVersionEntity(version)
+	if err != nil {
+		return err
+	}
+
+	_, err = tableClient.AddEntity(ctx, entityBytes, nil)
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusConflict {
+		return ErrDutyVersionExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert duty version: %v", err)
+	}
+
+	return nil
+}
+
+// replaces the translations of a version (an empty map removes them). Only the current version of a duty is
+// translated: its text doesn't change, the translations of that text can.
+func (r *DutyVersionRepository) SetVersionTranslations(ctx context.Context, dutyId uuid.UUID, version int, translations models.DutyTranslations) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	entity := map[string]interface{}{
+		"PartitionKey": dutyId.String(),
+		"RowKey":       models.DutyVersionRowKey(version),
+	}
+	if err := addTranslations(entity, translations); err != nil {
+		return err
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal entity: %v", err)
+	}
+
+	_, err = tableClient.UpdateEntity(ctx, entityBytes, &aztables.UpdateEntityOptions{UpdateMode: aztables.UpdateModeMerge})
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+		return ErrDutyVersionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update translations of duty version: %v", err)
+	}
+
+	return nil
+}
+
+// dutyVersionEntity is a helper function to marshal a version into a table entity.
+func dutyVersionEntity(version models.DutyVersion) ([]byte, error) {
+
+	entity := map[string]interface{}{
+		"PartitionKey":    version.DutyId.String(),
+		"RowKey":          models.DutyVersionRowKey(version.Version),
+		"Version":         version.Version,
+		"RoleId":          version.RoleId,
+		"DutyName":        version.DutyName,
+		"DutyDescription": version.DutyDescription,
+		"DueMinutes":      version.DueMinutes,
+		"Mandatory":       version.Mandatory,
+		"DutyKey":         version.DutyKey,
+		"CreatedAt":       version.CreatedAt.UTC().Format(time.RFC3339),
+		"CreatedBy":       version.CreatedBy,
+	}
+
+	if version.RolledBackFrom != nil {
+		entity["RolledBackFrom"] = *version.RolledBackFrom
+	}
+
+	if err := addFormSchema(entity, version.FormSchema); err != nil {
+		return nil, err
+	}
+	if err := addAppliesTo(entity, version.AppliesTo); err != nil {
+		return nil, err
+	}
+	if err := addTags(entity, version.Tags); err != nil {
+		return nil, err
+	}
+	if err := addTranslations(entity, version.Translations); err != nil {
+		return nil, err
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal duty version: %v", err)
+	}
+
+	return entityBytes, nil
+}
+
+// parseDutyVersion is a helper function to parse version data into a models.DutyVersion object.
+func parseDutyVersion(versionData map[string]interface{}) (models.DutyVersion, error) {
+	dutyId, err := uuid.Parse(fmt.Sprint(versionData["PartitionKey"]))
+	if err != nil {
+		return models.DutyVersion{}, fmt.Errorf("failed to parse PartitionKey as UUID: %v", err)
+	}
+
+	version, err := strconv.Atoi(fmt.Sprint(versionData["RowKey"]))
+	if err != nil {
+		return models.DutyVersion{}, fmt.Errorf("failed to parse RowKey as version: %v", err)
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, fmt.Sprint(versionData["CreatedAt"]))
+	if err != nil {
+		return models.DutyVersion{}, fmt.Errorf("failed to parse CreatedAt: %v", err)
+	}
+
+	var formSchema []models.FormField
+	if schemaJSON, ok := versionData["FormSchema"].(string); ok && schemaJSON != "" {
+		if err := json.Unmarshal([]byte(schemaJSON), &formSchema); err != nil {
+			return models.DutyVersion{}, fmt.Errorf("failed to parse FormSchema: %v", err)
+		}
+	}
+
+	appliesTo, err := parseAppliesTo(versionData)
+	if err != nil {
+		return models.DutyVersion{}, err
+	}
+
+	tags, err := parseTags(versionData)
+	if err != nil {
+		return models.DutyVersion{}, err
+	}
+
+	translations, err := parseTranslations(versionData)
+	if err != nil {
+		return models.DutyVersion{}, err
+	}
+
+	var rolledBackFrom *int
+	if value, ok := versionData["RolledBackFrom"].(float64); ok {
+		from := int(value)
+		rolledBackFrom = &from
+	}
+
+	roleId, _ := versionData["RoleId"].(float64)
+	dueMinutes, _ := versionData["DueMinutes"].(float64)
+	mandatory, _ := versionData["Mandatory"].(bool)
+	dutyName, _ := versionData["DutyName"].(string)
+	dutyDescription, _ := versionData["DutyDescription"].(string)
+	dutyKey, _ := versionData["DutyKey"].(string)
+	createdBy, _ := versionData["CreatedBy"].(string)
+
+	return models.DutyVersion{
+		DutyId:          dutyId,
+		Version:         version,
+		RoleId:          int(roleId),
+		DutyName:        dutyName,
+		DutyDescription: dutyDescription,
+		FormSchema:      formSchema,
+		DueMinutes:      int(dueMinutes),
+		Mandatory:       mandatory,
+		DutyKey:         dutyKey,
+		AppliesTo:       appliesTo,
+		Tags:            tags,
+		Translations:    translations,
+		CreatedAt:       createdAt,
+		CreatedBy:       createdBy,
+		RolledBackFrom:  rolledBackFrom,
+	}, nil
+}
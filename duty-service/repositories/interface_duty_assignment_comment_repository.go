@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"context"
+	"duty-service/images"
+	"duty-service/models"
+
+	"github.com/google/uuid"
+)
+
+type InterfaceDutyAssignmentCommentRepository interface {
+	GetComments(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentComment, error)
+	GetComment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, commentId uuid.UUID) (*models.DutyAssignmentComment, error)
+	AddComment(ctx context.Context, comment models.DutyAssignmentComment, photos []*images.ProcessedImage) (models.DutyAssignmentComment, error)
+	UpdateComment(ctx context.Context, comment models.DutyAssignmentComment) error
+	DeleteComment(ctx context.Context, comment models.DutyAssignmentComment) error
+}
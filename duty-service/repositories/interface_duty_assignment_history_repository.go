@@ -0,0 +1,13 @@
+package repositories
+
+import (
+	"context"
+	"duty-service/models"
+
+	"github.com/google/uuid"
+)
+
+type InterfaceDutyAssignmentHistoryRepository interface {
+	GetHistory(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentHistoryEntry, error)
+	AddEntry(ctx context.Context, entry models.DutyAssignmentHistoryEntry) error
+}
@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"context"
+	"duty-service/images"
+	"duty-service/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InterfaceDutyAssignmentPhotoRepository interface {
+	GetPhotos(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentPhoto, error)
+	GetPhotosByDuty(ctx context.Context, dutyRowKey uuid.UUID, since time.Time) ([]models.DutyAssignmentPhoto, error)
+	GetPhotosForReview(ctx context.Context) ([]models.DutyAssignmentPhoto, error)
+	GetPhoto(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, photoId uuid.UUID) (*models.DutyAssignmentPhoto, error)
+	AddPhoto(ctx context.Context, photo models.DutyAssignmentPhoto, image *images.ProcessedImage) (models.DutyAssignmentPhoto, error)
+	UpdatePhotoReview(ctx context.Context, photo models.DutyAssignmentPhoto) error
+	DeletePhoto(ctx context.Context, photo models.DutyAssignmentPhoto) error
+}
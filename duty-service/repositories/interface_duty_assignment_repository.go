@@ -3,14 +3,26 @@ package repositories
 import (
 	"context"
 	"duty-service/models"
-	"io"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type InterfaceDutyAssignmentRepository interface {
 	GetAllDutyAssignmentsByShiftId(ctx context.Context, shiftId uuid.UUID) ([]models.DutyAssignment, error)
-	CreateDutyAssignments(ctx context.Context, shiftId uuid.UUID, duties []models.Duty) error
-	UpdateDutyAssignment(ctx context.Context, dutyAssignment models.DutyAssignment, image io.Reader) error
-	DeleteDutyAssignment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) error
+	GetDutyAssignmentsByShiftIds(ctx context.Context, shiftIds []uuid.UUID) ([]models.DutyAssignment, error)
+	GetDutyAssignmentsByAssignee(ctx context.Context, assigneeId string, shiftId *uuid.UUID) ([]models.DutyAssignment, error)
+	GetDutyAssignmentsByEventId(ctx context.Context, eventId uuid.UUID) ([]models.DutyAssignment, error)
+	GetDutyAssignmentsCreatedBetween(ctx context.Context, from, to time.Time) ([]models.DutyAssignment, error)
+	GetDutyAssignmentsChangedSince(ctx context.Context, shiftIds []uuid.UUID, since time.Time) ([]models.DutyAssignment, error)
+	GetDutyAssignment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) (*models.DutyAssignment, error)
+	GetDeletedDutyAssignments(ctx context.Context, shiftId *uuid.UUID, deletedBefore time.Time) ([]models.DutyAssignment, error)
+	CreateDutyAssignments(ctx context.Context, shiftId uuid.UUID, roleId int, eventId *uuid.UUID, assigneeId string, duties []models.Duty) ([]models.DutyAssignment, error)
+	UpdateDutyAssignment(ctx context.Context, dutyAssignment models.DutyAssignment) error
+	UpdateAssignee(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, assigneeId string) error
+	FreezeDutyAssignments(ctx context.Context, shiftId uuid.UUID, dutyAssignments []models.DutyAssignment) error
+	SetMainPhoto(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, imageBlobName string, thumbnailBlobName string) error
+	SetPhotoFlags(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, flags []models.PhotoFlag) error
+	SetDutyAssignmentDeleted(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, deletedAt *time.Time, deletedBy string) error
+	DeleteDutyAssignment(ctx context.Context, dutyAssignment models.DutyAssignment) error
 }
\ No newline at end of file
@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"context"
+	"duty-service/images"
 	"duty-service/models"
+	"time"
 )
 
 type InterfaceDutyRepository interface {
@@ -11,5 +13,10 @@ type InterfaceDutyRepository interface {
 	GetDutiesByRole(ctx context.Context, roleId int) ([]models.Duty, error)
 	CreateDuty(ctx context.Context, duty models.Duty) error
 	UpdateDuty(ctx context.Context, partitionKey, rowKey string, duty models.Duty) error
-	DeleteDuty(ctx context.Context, partitionKey, rowKey string) error
+	ClaimDutyVersion(ctx context.Context, partitionKey, rowKey string, version int, etag string) (string, error)
+	SetDutyDeleted(ctx context.Context, partitionKey, rowKey string, deletedAt *time.Time, deletedBy string) error
+	SetDutyTranslations(ctx context.Context, partitionKey, rowKey string, etag string, translations models.DutyTranslations) error
+	AddDutyAttachment(ctx context.Context, duty models.Duty, attachment models.DutyAttachment, file *images.ProcessedAttachment) (models.DutyAttachment, error)
+	DeleteDutyAttachment(ctx context.Context, duty models.Duty, attachment models.DutyAttachment) error
+	DeleteDuty(ctx context.Context, duty models.Duty) error
 }
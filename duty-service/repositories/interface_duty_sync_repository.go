@@ -0,0 +1,15 @@
+package repositories
+
+import (
+	"context"
+	"duty-service/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InterfaceDutySyncRepository interface {
+	ReserveSyncChange(ctx context.Context, shiftId, clientChangeId, dutyId uuid.UUID, reservedAt time.Time, expiry time.Duration) (*models.DutySyncChangeResult, error)
+	SaveSyncResult(ctx context.Context, result models.DutySyncChangeResult) error
+	ReleaseSyncChange(ctx context.Context, shiftId, clientChangeId uuid.UUID) error
+}
@@ -0,0 +1,15 @@
+package repositories
+
+import (
+	"context"
+	"duty-service/models"
+
+	"github.com/google/uuid"
+)
+
+type InterfaceDutyVersionRepository interface {
+	GetVersions(ctx context.Context, dutyId uuid.UUID) ([]models.DutyVersion, error)
+	GetVersion(ctx context.Context, dutyId uuid.UUID, version int) (*models.DutyVersion, error)
+	AddVersion(ctx context.Context, version models.DutyVersion) error
+	SetVersionTranslations(ctx context.Context, dutyId uuid.UUID, version int, translations models.DutyTranslations) error
+}
@@ -0,0 +1,15 @@
+package repositories
+
+import (
+	"context"
+	"duty-service/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InterfaceTemperatureLogRepository interface {
+	GetReadingsByShiftId(ctx context.Context, shiftId uuid.UUID) ([]models.TemperatureReading, error)
+	GetReadingsByEventId(ctx context.Context, eventId uuid.UUID, from, to time.Time) ([]models.TemperatureReading, error)
+	CreateReading(ctx context.Context, reading models.TemperatureReading) error
+}
@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// property names are identifiers: anything else would let a caller change the expression
+var odataPropertyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ODataFilter builds the filter of a Table Storage query. Values are always written as literals of their Go type
+// (strings quoted, with quotes doubled), so values from a request can't change the expression. Property names come
+// from the code and must be identifiers.
+type ODataFilter struct {
+	conditions []string
+}
+
+func NewODataFilter() *ODataFilter {
+	return &ODataFilter{}
+}
+
+// adds "property eq value"
+func (f *ODataFilter) Eq(property string, value interface{}) *ODataFilter {
+	return f.compare(property, "eq", value)
+}
+
+// adds "property ne value"
+func (f *ODataFilter) Ne(property string, value interface{}) *ODataFilter {
+	return f.compare(property, "ne", value)
+}
+
+// adds "property ge value"
+func (f *ODataFilter) Ge(property string, value interface{}) *ODataFilter {
+	return f.compare(property, "ge", value)
+}
+
+// adds "property lt value"
+func (f *ODataFilter) Lt(property string, value interface{}) *ODataFilter {
+	return f.compare(property, "lt", value)
+}
+
+// adds "property le value"
+func (f *ODataFilter) Le(property string, value interface{}) *ODataFilter {
+	return f.compare(property, "le", value)
+}
+
+// adds a condition that matches when the property equals one of the values (nothing is added without values)
+func (f *ODataFilter) In(property string, values ...interface{}) *ODataFilter {
+	if len(values) == 0 {
+		return f
+	}
+
+	alternatives := make([]string, 0, len(values))
+	for _, value := range values {
+		alternatives = append(alternatives, comparison(property, "eq", value))
+	}
+	f.conditions = append(f.conditions, "("+strings.Join(alternatives, " or ")+")")
+	return f
+}
+
+// returns the conditions joined with "and" (empty without conditions, which matches every entity)
+func (f *ODataFilter) String() string {
+	return strings.Join(f.conditions, " and ")
+}
+
+func (f *ODataFilter) compare(property string, operator string, value interface{}) *ODataFilter {
+	f.conditions = append(f.conditions, comparison(property, operator, value))
+	return f
+}
+
+// comparison returns "property operator literal". An invalid property or an unsupported type is a bug in the
+// caller, so it panics instead of sending a broken filter.
+func comparison(property string, operator string, value interface{}) string {
+	if !odataPropertyPattern.MatchString(property) {
+		panic(fmt.Sprintf("invalid OData property name '%s'", property))
+	}
+	return property + " " + operator + " " + odataLiteral(value)
+}
+
+// odataLiteral returns the literal of a value. UUIDs and times are written as strings, because that's how they're stored.
+func odataLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return quoteODataString(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10) + "L"
+	case bool:
+		return strconv.FormatBool(v)
+	case uuid.UUID:
+		return quoteODataString(v.String())
+	case time.Time:
+		return quoteODataString(v.UTC().Format(time.RFC3339))
+	default:
+		panic(fmt.Sprintf("unsupported OData value type %T", value))
+	}
+}
+
+// quoteODataString returns a string literal for a filter (quotes in the value are doubled)
+func quoteODataString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"context"
+	"duty-service/models"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+	"github.com/google/uuid"
+)
+
+type TemperatureLogRepository struct {
+	serviceClient *aztables.ServiceClient
+	tableName     string
+}
+
+func NewTemperatureLogRepository(serviceClient *aztables.ServiceClient) *TemperatureLogRepository {
+	return &TemperatureLogRepository{
+		serviceClient: serviceClient,
+		tableName:     "temperatureLogs",
+	}
+}
+
+// GET ALL READINGS OF A SHIFT
+func (r *TemperatureLogRepository) GetReadingsByShiftId(ctx context.Context, shiftId uuid.UUID) ([]models.TemperatureReading, error) {
+	filter := fmt.Sprintf("PartitionKey eq '%s'", shiftId.String())
+
+	return r.listReadings(ctx, filter)
+}
+
+// GET ALL READINGS OF AN EVENT recorded between from and to (inclusive)
+func (r *TemperatureLogRepository) GetReadingsByEventId(ctx context.Context, eventId uuid.UUID, from, to time.Time) ([]models.TemperatureReading, error) {
+	// RecordedAt is stored as an RFC3339 UTC string, so string comparison orders it chronologically
+	filter := fmt.Sprintf("EventId eq '%s' and RecordedAt ge '%s' and RecordedAt le '%s'",
+		eventId.String(), from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339))
+
+	return r.listReadings(ctx, filter)
+}
+
+// POST - stores a new reading
+func (r *TemperatureLogRepository) CreateReading(ctx context.Context, reading models.TemperatureReading) error {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	entity := map[string]interface{}{
+		"PartitionKey":           reading.PartitionKey.String(),
+		"RowKey":                 reading.RowKey.String(),
+		"EventId":                reading.EventId.String(),
+		"UnitType":               string(reading.UnitType),
+		"UnitName":               reading.UnitName,
+		"Temperature":            reading.Temperature,
+		"Temperature@odata.type": "Edm.Double", // keep whole degrees from being stored as Int32
+		"RecordedAt":             reading.RecordedAt.UTC().Format(time.RFC3339),
+		"EmployeeId":             reading.EmployeeId,
+		"WithinRange":            reading.WithinRange,
+		"CorrectiveAction":       reading.CorrectiveAction,
+	}
+
+	if reading.SafeRange != nil {
+		safeRangeBytes, err := json.Marshal(reading.SafeRange)
+		if err != nil {
+			return fmt.Errorf("failed to marshal SafeRange: %v", err)
+		}
+		entity["SafeRange"] = string(safeRangeBytes)
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal temperature reading: %v", err)
+	}
+
+	_, err = tableClient.AddEntity(ctx, entityBytes, nil)
+	if err != nil {
+		return fmt.Errorf("failed to insert temperature reading: %v", err)
+	}
+
+	return nil
+}
+
+// listReadings lists all readings matching the filter
+func (r *TemperatureLogRepository) listReadings(ctx context.Context, filter string) ([]models.TemperatureReading, error) {
+	tableClient := r.serviceClient.NewClient(r.tableName)
+
+	listOptions := &aztables.ListEntitiesOptions{
+		Filter: &filter,
+	}
+
+	pager := tableClient.NewListEntitiesPager(listOptions)
+
+	var readings []models.TemperatureReading
+
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list temperature readings: %v", err)
+		}
+
+		for _, entity := range page.Entities {
+			var readingData map[string]interface{}
+
+			if err := json.Unmarshal(entity, &readingData); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal temperature reading: %v", err)
+			}
+
+			reading, err := parseTemperatureReading(readingData)
+			if err != nil {
+				return nil, err
+			}
+
+			readings = append(readings, reading)
+		}
+	}
+
+	return readings, nil
+}
+
+// parseTemperatureReading is a helper function to parse reading data into a models.TemperatureReading object.
+func parseTemperatureReading(readingData map[string]interface{}) (models.TemperatureReading, error) {
+	shiftId, err := uuid.Parse(fmt.Sprint(readingData["PartitionKey"]))
+	if err != nil {
+		return models.TemperatureReading{}, fmt.Errorf("failed to parse PartitionKey as UUID: %v", err)
+	}
+
+	readingId, err := uuid.Parse(fmt.Sprint(readingData["RowKey"]))
+	if err != nil {
+		return models.TemperatureReading{}, fmt.Errorf("failed to parse RowKey as UUID: %v", err)
+	}
+
+	eventId, err := uuid.Parse(fmt.Sprint(readingData["EventId"]))
+	if err != nil {
+		return models.TemperatureReading{}, fmt.Errorf("failed to parse EventId as UUID: %v", err)
+	}
+
+	recordedAt, err := time.Parse(time.RFC3339, fmt.Sprint(readingData["RecordedAt"]))
+	if err != nil {
+		return models.TemperatureReading{}, fmt.Errorf("failed to parse RecordedAt: %v", err)
+	}
+
+	temperature, ok := readingData["Temperature"].(float64)
+	if !ok {
+		return models.TemperatureReading{}, fmt.Errorf("failed to parse Temperature as number")
+	}
+
+	var correctiveAction *string
+	if action, ok := readingData["CorrectiveAction"].(string); ok && action != "" {
+		correctiveAction = &action
+	}
+
+	// readings from before the range was stored have none
+	var safeRange *models.TemperatureRange
+	if safeRangeJSON, ok := readingData["SafeRange"].(string); ok && safeRangeJSON != "" {
+		if err := json.Unmarshal([]byte(safeRangeJSON), &safeRange); err != nil {
+			return models.TemperatureReading{}, fmt.Errorf("failed to parse SafeRange: %v", err)
+		}
+	}
+
+	unitType, _ := readingData["UnitType"].(string)
+	unitName, _ := readingData["UnitName"].(string)
+	employeeId, _ := readingData["EmployeeId"].(string)
+	withinRange, _ := readingData["WithinRange"].(bool)
+
+	return models.TemperatureReading{
+		PartitionKey:     shiftId,
+		RowKey:           readingId,
+		EventId:          eventId,
+		UnitType:         models.TemperatureUnitType(unitType),
+		UnitName:         unitName,
+		Temperature:      temperature,
+		RecordedAt:       recordedAt,
+		EmployeeId:       employeeId,
+		WithinRange:      withinRange,
+		SafeRange:        safeRange,
+		CorrectiveAction: correctiveAction,
+	}, nil
+}
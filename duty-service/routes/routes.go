@@ -1,26 +1,67 @@
 package routes
 
 import (
+	"duty-service/auth"
+	"duty-service/config"
 	"duty-service/handlers"
 	"duty-service/middlewares"
 	"duty-service/repositories"
 	"duty-service/services"
+	"duty-service/storage"
 	"net/http"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
-	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/gorilla/mux"
 )
 
-func RegisterRoutes(serviceClient *aztables.ServiceClient, blobServiceClient *azblob.Client, publicKeyPEM string) *mux.Router {
-	dutyRepository := repositories.NewDutyRepository(serviceClient)
-	dutyService := services.NewDutyService(dutyRepository)
+func RegisterRoutes(serviceClient *aztables.ServiceClient, imageStore storage.ImageStore, rabbitMQService *services.RabbitMQService, cfg *config.Config, publicKeyPEM string) *mux.Router {
+	dutyRepository := repositories.NewDutyRepository(serviceClient, imageStore, cfg.ImageURLExpiry)
+	dutyVersionRepository := repositories.NewDutyVersionRepository(serviceClient)
+	dutyService := services.NewDutyService(dutyRepository, dutyVersionRepository)
+	dutyTranslationService := services.NewDutyTranslationService(dutyRepository, dutyVersionRepository, cfg.DefaultLanguage, cfg.Languages)
 
-	dutyAssignmentRepository := repositories.NewDutyAssignmentRepository(serviceClient, blobServiceClient)
-	dutyAssignmentService := services.NewDutyAssignmentService(dutyAssignmentRepository, dutyRepository)
+	dutyAssignmentRepository := repositories.NewDutyAssignmentRepository(serviceClient, imageStore, cfg.ImageURLExpiry)
+	dutyAssignmentPhotoRepository := repositories.NewDutyAssignmentPhotoRepository(serviceClient, imageStore, cfg.ImageURLExpiry)
+	dutyStream := services.NewDutyStream(services.DefaultDutyStreamBufferSize)
+	dutyAssignmentHistoryRepository := repositories.NewDutyAssignmentHistoryRepository(serviceClient)
+	dutyAssignmentService := services.NewDutyAssignmentService(dutyAssignmentRepository, dutyRepository, dutyVersionRepository, dutyAssignmentPhotoRepository, dutyAssignmentHistoryRepository, services.NewEventClient(cfg.EventServiceURL), rabbitMQService, dutyStream, cfg.Timezone)
+
+	dutyAssignmentCommentRepository := repositories.NewDutyAssignmentCommentRepository(serviceClient, imageStore, cfg.ImageURLExpiry)
+	dutyAssignmentCommentService := services.NewDutyAssignmentCommentService(dutyAssignmentRepository, dutyAssignmentCommentRepository, rabbitMQService)
+
+	retentionService := services.NewRetentionService(dutyRepository, dutyAssignmentRepository, dutyAssignmentPhotoRepository, dutyAssignmentCommentRepository)
+
+	dutySyncRepository := repositories.NewDutySyncRepository(serviceClient)
+	dutySyncService := services.NewDutySyncService(dutyAssignmentRepository, dutySyncRepository, dutyAssignmentService)
+
+	dutyReportService := services.NewDutyReportService(dutyAssignmentRepository, dutyRepository)
+	dutyAnalyticsService := services.NewDutyAnalyticsService(dutyAssignmentRepository, dutyRepository)
+	dutyChecklistService := services.NewDutyChecklistService(dutyAssignmentRepository, dutyRepository, dutyVersionRepository, cfg.CompletionFormURL)
+
+	temperatureLogRepository := repositories.NewTemperatureLogRepository(serviceClient)
+	temperatureLogService := services.NewTemperatureLogService(temperatureLogRepository, rabbitMQService, cfg.TemperatureRanges)
+
+	// clock-in messages create assignments through the same service as the HTTP routes
+	if rabbitMQService != nil {
+		rabbitMQService.StartConsuming(dutyAssignmentService)
+	}
+
+	// keeps the duty analytics gauges of /duties/metrics up to date
+	dutyAnalyticsService.StartMetricsRefresh(cfg.AnalyticsInterval, cfg.AnalyticsWindow)
+
+	// purges soft-deleted duties and assignments after the retention period
+	retentionService.StartPurging(cfg.RetentionInterval, cfg.Retention)
 
 	dutyHandler := handlers.NewDutyHandler(dutyService)
+	dutyTranslationHandler := handlers.NewDutyTranslationHandler(dutyTranslationService)
 	dutyAssignmentHandler := handlers.NewDutyAssignmentHandler(dutyAssignmentService)
+	temperatureLogHandler := handlers.NewTemperatureLogHandler(temperatureLogService)
+	dutyReportHandler := handlers.NewDutyReportHandler(dutyReportService)
+	dutyAnalyticsHandler := handlers.NewDutyAnalyticsHandler(dutyAnalyticsService)
+	dutyChecklistHandler := handlers.NewDutyChecklistHandler(dutyChecklistService)
+	dutyAssignmentCommentHandler := handlers.NewDutyAssignmentCommentHandler(dutyAssignmentCommentService)
+	dutyStreamHandler := handlers.NewDutyStreamHandler(dutyStream)
+	dutySyncHandler := handlers.NewDutySyncHandler(dutySyncService)
 	metricsHandler := handlers.NewMetricsHandler()
 
 	r := mux.NewRouter()
@@ -34,6 +75,77 @@ func RegisterRoutes(serviceClient *aztables.ServiceClient, blobServiceClient *az
 	// Register the /duties/metrics route for Prometheus at the /duties path level
 	//dutiesRouter.Handle("/metrics", promhttp.Handler())
 
+	// temperature log routes (registered before the duty routes so /temperature-logs/report isn't matched as /{PartitionKey}/{RowKey})
+	dutiesRouter.HandleFunc("/temperature-logs", temperatureLogHandler.GetReadingsByShiftId).Methods(http.MethodGet)
+	dutiesRouter.HandleFunc("/temperature-logs", temperatureLogHandler.RecordReading).Methods(http.MethodPost)
+	dutiesRouter.HandleFunc("/temperature-logs/report", temperatureLogHandler.ExportReport).Methods(http.MethodGet)
+
+	// duty completion reports (?format=json|csv|html)
+	dutiesRouter.HandleFunc("/reports", dutyReportHandler.GetReport).Methods(http.MethodGet)
+	dutiesRouter.HandleFunc("/reports/shifts/{ShiftId}", dutyReportHandler.GetShiftReport).Methods(http.MethodGet)
+
+	// duty analytics (?from=&to=, the last 8 weeks by default)
+	dutiesRouter.HandleFunc("/analytics", dutyAnalyticsHandler.GetAnalytics).Methods(http.MethodGet)
+
+	// live duty assignment changes as Server-Sent Events (?shiftId= or ?eventId=)
+	dutiesRouter.HandleFunc("/stream", dutyStreamHandler.StreamDutyAssignments).Methods(http.MethodGet)
+
+	// images of the local image store (before the duty routes so /images/{Name} isn't matched as /{PartitionKey}/{RowKey})
+	if localImageStore, ok := imageStore.(*storage.LocalImageStore); ok {
+		imageHandler := handlers.NewImageHandler(localImageStore)
+		dutiesRouter.HandleFunc("/images/{Name}", imageHandler.ServeImage).Methods(http.MethodGet)
+	}
+
+	// duty template import/export (before the duty routes; the import replaces templates, so it requires the Admin role)
+	dutiesRouter.HandleFunc("/export", dutyHandler.ExportDuties).Methods(http.MethodGet)
+	dutiesRouter.Handle("/import", middlewares.JWTMiddleware(publicKeyPEM, http.HandlerFunc(dutyHandler.ImportDuties))).Methods(http.MethodPost)
+
+	// duty assignment routes (under /duties, before the duty routes so /duty-assignments/photo-reviews and the like
+	// aren't matched as /{PartitionKey}/{RowKey}). A bearer token is optional here; when sent, it identifies who uploaded a photo
+	dutyAssignmentsRouter := dutiesRouter.PathPrefix("/duty-assignments").Subrouter()
+	dutyAssignmentsRouter.Use(middlewares.IdentityMiddleware(publicKeyPEM))
+	dutyAssignmentsRouter.Use(middlewares.LanguageMiddleware(cfg.DefaultLanguage)) // after IdentityMiddleware: it falls back to the language of the caller's account
+	dutyAssignmentsRouter.HandleFunc("", dutyAssignmentHandler.GetAllDutyAssignmentsByShiftId).Methods(http.MethodGet)
+	dutyAssignmentsRouter.HandleFunc("", dutyAssignmentHandler.CreateDutyAssignments).Methods(http.MethodPost)
+	dutyAssignmentsRouter.HandleFunc("/sync", dutySyncHandler.SyncDutyAssignments).Methods(http.MethodPost) // offline changes of the crew app
+	// printable checklist of a shift with a QR code per assignment (?shiftId=&format=html|pdf)
+	dutyAssignmentsRouter.HandleFunc("/print", dutyChecklistHandler.PrintDutyChecklist).Methods(http.MethodGet)
+	// flagged photos waiting for review (Lead or Admin role required)
+	dutyAssignmentsRouter.Handle("/photo-reviews", middlewares.RequireIdentity(auth.RoleLead, auth.RoleAdmin)(http.HandlerFunc(dutyAssignmentHandler.GetPhotoReviewQueue))).Methods(http.MethodGet)
+	dutyAssignmentsRouter.HandleFunc("/{ShiftId}/can-clock-out", dutyAssignmentHandler.CanClockOut).Methods(http.MethodGet)
+	dutyAssignmentsRouter.HandleFunc("/{ShiftId}/{DutyId}", dutyAssignmentHandler.UpdateDutyAssignment).Methods(http.MethodPut)
+	dutyAssignmentsRouter.HandleFunc("/{ShiftId}/{DutyId}", dutyAssignmentHandler.DeleteDutyAssignment).Methods(http.MethodDelete)
+	dutyAssignmentsRouter.Handle("/{ShiftId}/{DutyId}/restore", middlewares.RequireIdentity(auth.RoleAdmin)(http.HandlerFunc(dutyAssignmentHandler.RestoreDutyAssignment))).Methods(http.MethodPost)
+	dutyAssignmentsRouter.Handle("/{ShiftId}/{DutyId}/assignee", middlewares.RequireIdentity(auth.RoleLead, auth.RoleAdmin)(http.HandlerFunc(dutyAssignmentHandler.ReassignDutyAssignment))).Methods(http.MethodPut)
+	dutyAssignmentsRouter.HandleFunc("/{ShiftId}/{DutyId}/history", dutyAssignmentHandler.GetDutyAssignmentHistory).Methods(http.MethodGet)
+	dutyAssignmentsRouter.HandleFunc("/{ShiftId}/{DutyId}/image", dutyAssignmentHandler.RedirectToDutyAssignmentImage).Methods(http.MethodGet)
+	dutyAssignmentsRouter.HandleFunc("/{ShiftId}/{DutyId}/photos", dutyAssignmentHandler.GetDutyAssignmentPhotos).Methods(http.MethodGet)
+	dutyAssignmentsRouter.HandleFunc("/{ShiftId}/{DutyId}/photos/{PhotoId}", dutyAssignmentHandler.DeleteDutyAssignmentPhoto).Methods(http.MethodDelete)
+	dutyAssignmentsRouter.Handle("/{ShiftId}/{DutyId}/photos/{PhotoId}/review", middlewares.RequireIdentity(auth.RoleLead, auth.RoleAdmin)(http.HandlerFunc(dutyAssignmentHandler.ReviewDutyAssignmentPhoto))).Methods(http.MethodPut)
+
+	// comment threads (the author is taken from the token, so every comment route requires one)
+	requireIdentity := middlewares.RequireIdentity()
+	dutyAssignmentsRouter.Handle("/{ShiftId}/{DutyId}/comments", requireIdentity(http.HandlerFunc(dutyAssignmentCommentHandler.GetComments))).Methods(http.MethodGet)
+	dutyAssignmentsRouter.Handle("/{ShiftId}/{DutyId}/comments", requireIdentity(http.HandlerFunc(dutyAssignmentCommentHandler.AddComment))).Methods(http.MethodPost)
+	dutyAssignmentsRouter.Handle("/{ShiftId}/{DutyId}/comments/{CommentId}", requireIdentity(http.HandlerFunc(dutyAssignmentCommentHandler.GetComment))).Methods(http.MethodGet)
+	dutyAssignmentsRouter.Handle("/{ShiftId}/{DutyId}/comments/{CommentId}", requireIdentity(http.HandlerFunc(dutyAssignmentCommentHandler.UpdateComment))).Methods(http.MethodPut)
+	dutyAssignmentsRouter.Handle("/{ShiftId}/{DutyId}/comments/{CommentId}", requireIdentity(http.HandlerFunc(dutyAssignmentCommentHandler.DeleteComment))).Methods(http.MethodDelete)
+
+	// duty translations (Admin role required; the missing translations report is registered before the duty routes)
+	dutiesRouter.Handle("/translations/missing", middlewares.JWTMiddleware(publicKeyPEM, http.HandlerFunc(dutyTranslationHandler.GetMissingTranslations))).Methods(http.MethodGet)
+	dutiesRouter.Handle("/{PartitionKey}/{RowKey}/translations/{Language}", middlewares.JWTMiddleware(publicKeyPEM, http.HandlerFunc(dutyTranslationHandler.SetDutyTranslation))).Methods(http.MethodPut)
+	dutiesRouter.Handle("/{PartitionKey}/{RowKey}/translations/{Language}", middlewares.JWTMiddleware(publicKeyPEM, http.HandlerFunc(dutyTranslationHandler.DeleteDutyTranslation))).Methods(http.MethodDelete)
+
+	// instructional images, videos and PDFs of a duty (Admin role required)
+	dutiesRouter.Handle("/{PartitionKey}/{RowKey}/attachments", middlewares.JWTMiddleware(publicKeyPEM, http.HandlerFunc(dutyHandler.AddDutyAttachment))).Methods(http.MethodPost)
+	dutiesRouter.Handle("/{PartitionKey}/{RowKey}/attachments/{AttachmentId}", middlewares.JWTMiddleware(publicKeyPEM, http.HandlerFunc(dutyHandler.DeleteDutyAttachment))).Methods(http.MethodDelete)
+
+	// duty template versions (rolling back requires the Admin role)
+	dutiesRouter.HandleFunc("/{PartitionKey}/{RowKey}/versions", dutyHandler.GetDutyVersions).Methods(http.MethodGet)
+	dutiesRouter.HandleFunc("/{PartitionKey}/{RowKey}/versions/diff", dutyHandler.DiffDutyVersions).Methods(http.MethodGet)
+	dutiesRouter.HandleFunc("/{PartitionKey}/{RowKey}/versions/{Version:[0-9]+}", dutyHandler.GetDutyVersion).Methods(http.MethodGet)
+	dutiesRouter.Handle("/{PartitionKey}/{RowKey}/versions/{Version:[0-9]+}/rollback", middlewares.JWTMiddleware(publicKeyPEM, http.HandlerFunc(dutyHandler.RollbackDuty))).Methods(http.MethodPost)
+
 	// duty routes
 	dutiesRouter.HandleFunc("", dutyHandler.GetAllDuties).Methods(http.MethodGet)
 	dutiesRouter.HandleFunc("/{PartitionKey}/{RowKey}", dutyHandler.GetDutyById).Methods(http.MethodGet)
@@ -45,11 +157,8 @@ func RegisterRoutes(serviceClient *aztables.ServiceClient, blobServiceClient *az
 	//dutiesRouter.HandleFunc("/{PartitionKey}/{RowKey}", dutyHandler.DeleteDuty).Methods(http.MethodDelete)
 	dutiesRouter.Handle("/{PartitionKey}/{RowKey}", middlewares.JWTMiddleware(publicKeyPEM, http.HandlerFunc(dutyHandler.DeleteDuty))).Methods(http.MethodDelete) //require Admin role to delete duty
 
-	// duty assignment routes (under /duties)
-	dutiesRouter.HandleFunc("/duty-assignments", dutyAssignmentHandler.GetAllDutyAssignmentsByShiftId).Methods(http.MethodGet)
-	dutiesRouter.HandleFunc("/duty-assignments", dutyAssignmentHandler.CreateDutyAssignments).Methods(http.MethodPost)
-	dutiesRouter.HandleFunc("/duty-assignments/{ShiftId}/{DutyId}", dutyAssignmentHandler.UpdateDutyAssignment).Methods(http.MethodPut)
-	dutiesRouter.HandleFunc("/duty-assignments/{ShiftId}/{DutyId}", dutyAssignmentHandler.DeleteDutyAssignment).Methods(http.MethodDelete)
+	// deleted duties can be restored until the retention job purges them (Admin role required)
+	dutiesRouter.Handle("/{PartitionKey}/{RowKey}/restore", middlewares.JWTMiddleware(publicKeyPEM, http.HandlerFunc(dutyHandler.RestoreDuty))).Methods(http.MethodPost)
 
 	//metrics routes:
 	dutiesRouter.HandleFunc("/metrics", metricsHandler.HandleMetrics).Methods(http.MethodGet)
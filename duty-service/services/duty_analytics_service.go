@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"duty-service/metrics"
+	"duty-service/models"
+	"duty-service/repositories"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// how many templates MostLeftIncomplete lists
+const mostLeftIncompleteLimit = 10
+
+type DutyAnalyticsService struct {
+	repo     repositories.InterfaceDutyAssignmentRepository
+	dutyRepo repositories.InterfaceDutyRepository
+}
+
+func NewDutyAnalyticsService(repo repositories.InterfaceDutyAssignmentRepository, dutyRepo repositories.InterfaceDutyRepository) *DutyAnalyticsService {
+	return &DutyAnalyticsService{
+		repo:     repo,
+		dutyRepo: dutyRepo,
+	}
+}
+
+// GET the analytics of the duty assignments created between from and to
+func (s *DutyAnalyticsService) GetAnalytics(ctx context.Context, from, to time.Time) (*models.DutyAnalytics, error) {
+	dutyAssignments, err := s.repo.GetDutyAssignmentsCreatedBetween(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	duties, err := s.dutyRepo.GetAllDuties(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch duty templates: %v", err)
+	}
+	dutyNames := make(map[uuid.UUID]string, len(duties))
+	for _, duty := range duties {
+		dutyNames[duty.RowKey] = duty.DutyName
+	}
+
+	now := time.Now().UTC()
+	analytics := &models.DutyAnalytics{
+		GeneratedAt:        now,
+		From:               from,
+		To:                 to,
+		Duties:             []models.DutyTemplateAnalytics{},
+		Roles:              []models.RoleDutyAnalytics{},
+		MostLeftIncomplete: []models.DutyTemplateAnalytics{},
+		Weeks:              []models.WeeklyDutyAnalytics{},
+	}
+
+	dutyIndex := make(map[uuid.UUID]int)
+	roleIndex := make(map[int]int)
+	weekIndex := make(map[string]int)
+	toComplete := make(map[uuid.UUID][]float64) // minutes from clock-in until completed, per template
+	working := make(map[uuid.UUID][]float64)    // minutes from the first update until completed, per template
+	weekToComplete := make(map[string][]float64)
+
+	for _, dutyAssignment := range dutyAssignments {
+		status := dutyAssignment.DutyAssignmentStatus
+		// recorded when the shift was clocked out (assignments of shifts that are still running weren't left incomplete)
+		leftIncomplete := dutyAssignment.FrozenAt != nil && dutyAssignment.IncompleteAtClockOut
+
+		analytics.Totals.Add(status, leftIncomplete)
+
+		// per template
+		index, ok := dutyIndex[dutyAssignment.DutyRowKey]
+		if !ok {
+			name, hasTemplate := dutyNames[dutyAssignment.DutyRowKey]
+			if !hasTemplate {
+				name = "(unknown duty)"
+			}
+			index = len(analytics.Duties)
+			dutyIndex[dutyAssignment.DutyRowKey] = index
+			analytics.Duties = append(analytics.Duties, models.DutyTemplateAnalytics{DutyId: dutyAssignment.DutyRowKey, DutyName: name})
+		}
+		analytics.Duties[index].Add(status, leftIncomplete)
+
+		// per role (assignments from before roles were stored are left out)
+		if dutyAssignment.RoleId != nil {
+			index, ok := roleIndex[*dutyAssignment.RoleId]
+			if !ok {
+				index = len(analytics.Roles)
+				roleIndex[*dutyAssignment.RoleId] = index
+				analytics.Roles = append(analytics.Roles, models.RoleDutyAnalytics{RoleId: *dutyAssignment.RoleId})
+			}
+			analytics.Roles[index].Add(status, leftIncomplete)
+		}
+
+		// per ISO week of the clock-in
+		year, week := dutyAssignment.CreatedAt.UTC().ISOWeek()
+		weekKey := fmt.Sprintf("%04d-W%02d", year, week)
+		index, ok = weekIndex[weekKey]
+		if !ok {
+			index = len(analytics.Weeks)
+			weekIndex[weekKey] = index
+			analytics.Weeks = append(analytics.Weeks, models.WeeklyDutyAnalytics{Week: weekKey, WeekStart: weekStart(dutyAssignment.CreatedAt)})
+		}
+		analytics.Weeks[index].Add(status, leftIncomplete)
+
+		// durations only count when the completion time is known
+		if status == models.StatusCompleted && dutyAssignment.CompletedAt != nil {
+			minutes := dutyAssignment.CompletedAt.Sub(dutyAssignment.CreatedAt).Minutes()
+			toComplete[dutyAssignment.DutyRowKey] = append(toComplete[dutyAssignment.DutyRowKey], minutes)
+			weekToComplete[weekKey] = append(weekToComplete[weekKey], minutes)
+
+			if dutyAssignment.StartedAt != nil {
+				working[dutyAssignment.DutyRowKey] = append(working[dutyAssignment.DutyRowKey], dutyAssignment.CompletedAt.Sub(*dutyAssignment.StartedAt).Minutes())
+			}
+		}
+	}
+
+	for i := range analytics.Duties {
+		analytics.Duties[i].MedianMinutesToComplete = median(toComplete[analytics.Duties[i].DutyId])
+		analytics.Duties[i].MedianMinutesWorking = median(working[analytics.Duties[i].DutyId])
+	}
+	for i := range analytics.Weeks {
+		analytics.Weeks[i].MedianMinutesToComplete = median(weekToComplete[analytics.Weeks[i].Week])
+	}
+
+	// stable output: duties by name, roles by ID, weeks in order
+	sort.SliceStable(analytics.Duties, func(i, j int) bool {
+		return analytics.Duties[i].DutyName < analytics.Duties[j].DutyName
+	})
+	sort.Slice(analytics.Roles, func(i, j int) bool {
+		return analytics.Roles[i].RoleId < analytics.Roles[j].RoleId
+	})
+	sort.Slice(analytics.Weeks, func(i, j int) bool {
+		return analytics.Weeks[i].WeekStart.Before(analytics.Weeks[j].WeekStart)
+	})
+
+	for _, duty := range analytics.Duties {
+		if duty.LeftIncomplete > 0 {
+			analytics.MostLeftIncomplete = append(analytics.MostLeftIncomplete, duty)
+		}
+	}
+	sort.SliceStable(analytics.MostLeftIncomplete, func(i, j int) bool {
+		return analytics.MostLeftIncomplete[i].LeftIncomplete > analytics.MostLeftIncomplete[j].LeftIncomplete
+	})
+	if len(analytics.MostLeftIncomplete) > mostLeftIncompleteLimit {
+		analytics.MostLeftIncomplete = analytics.MostLeftIncomplete[:mostLeftIncompleteLimit]
+	}
+
+	return analytics, nil
+}
+
+// RefreshMetrics recalculates the analytics of the last window and exports them as Prometheus gauges
+func (s *DutyAnalyticsService) RefreshMetrics(ctx context.Context, window time.Duration) error {
+	to := time.Now().UTC()
+	analytics, err := s.GetAnalytics(ctx, to.Add(-window), to)
+	if err != nil {
+		return err
+	}
+
+	metrics.SetDutyAnalytics(analytics)
+	return nil
+}
+
+// StartMetricsRefresh refreshes the analytics gauges right away and then every interval, in the background
+func (s *DutyAnalyticsService) StartMetricsRefresh(interval time.Duration, window time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.RefreshMetrics(context.Background(), window); err != nil {
+				log.Printf("Failed to refresh duty analytics metrics: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// weekStart returns Monday 00:00 UTC of the week of t
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, time.UTC)
+}
+
+// median returns the median of the values (nil when there are none)
+func median(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	middle := len(sorted) / 2
+	result := sorted[middle]
+	if len(sorted)%2 == 0 {
+		result = (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return &result
+}
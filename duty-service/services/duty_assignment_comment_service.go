@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"duty-service/auth"
+	"duty-service/images"
+	"duty-service/models"
+	"duty-service/repositories"
+	"errors"
+	"mime/multipart"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotCommentAuthor is returned when someone edits a comment of someone else, or deletes it without being an admin
+var ErrNotCommentAuthor = errors.New("only the author can change this comment")
+
+// DutyAssignmentCommentService manages the comment threads of duty assignments
+type DutyAssignmentCommentService struct {
+	repo        repositories.InterfaceDutyAssignmentRepository
+	commentRepo repositories.InterfaceDutyAssignmentCommentRepository
+	publisher   InterfaceMessagePublisher // publishes the mentions for the notification service (optional)
+}
+
+func NewDutyAssignmentCommentService(repo repositories.InterfaceDutyAssignmentRepository, commentRepo repositories.InterfaceDutyAssignmentCommentRepository, publisher InterfaceMessagePublisher) *DutyAssignmentCommentService {
+	return &DutyAssignmentCommentService{
+		repo:        repo,
+		commentRepo: commentRepo,
+		publisher:   publisher,
+	}
+}
+
+// GET the comments of a duty assignment (oldest first)
+func (s *DutyAssignmentCommentService) GetComments(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentComment, error) {
+	if _, err := s.repo.GetDutyAssignment(ctx, shiftId, dutyId); err != nil {
+		return nil, err
+	}
+	return s.commentRepo.GetComments(ctx, shiftId, dutyId)
+}
+
+// GET a single comment of a duty assignment
+func (s *DutyAssignmentCommentService) GetComment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, commentId uuid.UUID) (*models.DutyAssignmentComment, error) {
+	return s.commentRepo.GetComment(ctx, shiftId, dutyId, commentId)
+}
+
+// POST adds a comment by the caller to the thread of a duty assignment and notifies the mentioned employees
+func (s *DutyAssignmentCommentService) AddComment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, text string, files []multipart.File) (*models.DutyAssignmentComment, error) {
+	if err := models.ValidateComment(text, len(files)); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetDutyAssignment(ctx, shiftId, dutyId); err != nil {
+		return nil, err
+	}
+
+	// photos are stored like the assignment's own photos: re-encoded without metadata and with a thumbnail
+	photos := make([]*images.ProcessedImage, 0, len(files))
+	for _, file := range files {
+		image, err := images.Process(file)
+		if err != nil {
+			return nil, err
+		}
+		photos = append(photos, image)
+	}
+
+	comment, err := s.commentRepo.AddComment(ctx, models.DutyAssignmentComment{
+		RowKey:    uuid.New(),
+		ShiftId:   shiftId,
+		DutyId:    dutyId,
+		AuthorId:  auth.SubjectFromContext(ctx),
+		Text:      text,
+		Mentions:  models.ParseMentions(text),
+		CreatedAt: time.Now().UTC(),
+	}, photos)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishMentions(comment, comment.Mentions, comment.CreatedAt)
+	return &comment, nil
+}
+
+// PUT changes the text of the caller's comment; only employees who weren't mentioned before are notified
+func (s *DutyAssignmentCommentService) UpdateComment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, commentId uuid.UUID, text string) (*models.DutyAssignmentComment, error) {
+	comment, err := s.commentRepo.GetComment(ctx, shiftId, dutyId, commentId)
+	if err != nil {
+		return nil, err
+	}
+	if comment.AuthorId != auth.SubjectFromContext(ctx) {
+		return nil, ErrNotCommentAuthor
+	}
+	if err := models.ValidateComment(text, len(comment.Photos)); err != nil {
+		return nil, err
+	}
+
+	mentionedBefore := make(map[string]struct{}, len(comment.Mentions))
+	for _, mention := range comment.Mentions {
+		mentionedBefore[mention] = struct{}{}
+	}
+
+	now := time.Now().UTC()
+	comment.Text = text
+	comment.Mentions = models.ParseMentions(text)
+	comment.UpdatedAt = &now
+	if err := s.commentRepo.UpdateComment(ctx, *comment); err != nil {
+		return nil, err
+	}
+
+	newMentions := []string{}
+	for _, mention := range comment.Mentions {
+		if _, exists := mentionedBefore[mention]; !exists {
+			newMentions = append(newMentions, mention)
+		}
+	}
+	s.publishMentions(*comment, newMentions, now)
+
+	return comment, nil
+}
+
+// DELETE a comment with its photos (the author or an admin)
+func (s *DutyAssignmentCommentService) DeleteComment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, commentId uuid.UUID) error {
+	comment, err := s.commentRepo.GetComment(ctx, shiftId, dutyId, commentId)
+	if err != nil {
+		return err
+	}
+
+	identity, _ := auth.FromContext(ctx)
+	if comment.AuthorId != identity.Subject && !identity.IsAdmin() {
+		return ErrNotCommentAuthor
+	}
+
+	return s.commentRepo.DeleteComment(ctx, *comment)
+}
+
+// publishMentions publishes a duty.comment.mentioned event for every mentioned employee except the author
+func (s *DutyAssignmentCommentService) publishMentions(comment models.DutyAssignmentComment, mentions []string, occurredAt time.Time) {
+	for _, mention := range mentions {
+		if mention == comment.AuthorId {
+			continue
+		}
+		publishDutyEvent(s.publisher, DutyCommentMentionedKey, models.DutyCommentMentionMessage{
+			CommentID:   comment.RowKey,
+			ShiftID:     comment.ShiftId,
+			DutyID:      comment.DutyId,
+			AuthorID:    comment.AuthorId,
+			MentionedID: mention,
+			Text:        comment.Text,
+			OccurredAt:  occurredAt,
+		})
+	}
+}
@@ -2,23 +2,56 @@ package services
 
 import (
 	"context"
+	"duty-service/auth"
+	"duty-service/images"
+	"duty-service/locale"
 	"duty-service/models"
 	"duty-service/repositories"
+	"errors"
 	"fmt"
+	"log"
 	"mime/multipart"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// ErrNoImage is returned when a duty assignment has no photo
+var ErrNoImage = errors.New("duty assignment has no image")
+
+// ErrAssigneeNotOnEvent is returned when a duty is handed over to someone who isn't working the same event
+var ErrAssigneeNotOnEvent = errors.New("the new assignee isn't clocked in to the same event")
+
+// ErrPhotoNotFlagged is returned when a photo that passed the authenticity checks is reviewed
+var ErrPhotoNotFlagged = errors.New("the photo wasn't flagged: there is nothing to review")
+
+// ErrDutyAssignmentFrozen is returned when someone other than an admin changes a duty of a clocked-out shift
+var ErrDutyAssignmentFrozen = errors.New("the shift was clocked out: only admins can change its duties")
+
 type DutyAssignmentService struct {
-	repo     repositories.InterfaceDutyAssignmentRepository
-	dutyRepo repositories.InterfaceDutyRepository
+	repo        repositories.InterfaceDutyAssignmentRepository
+	dutyRepo    repositories.InterfaceDutyRepository
+	versionRepo repositories.InterfaceDutyVersionRepository // the template versions assignments were created from
+	photoRepo   repositories.InterfaceDutyAssignmentPhotoRepository
+	historyRepo repositories.InterfaceDutyAssignmentHistoryRepository // records handovers (optional)
+	eventClient InterfaceEventClient                                  // looks up the shift's event to select the duties that apply (optional)
+	publisher   InterfaceMessagePublisher                             // publishes the duty lifecycle events (optional)
+	stream      InterfaceDutyStream                                   // pushes assignment changes to the clients of /duties/stream (optional)
+	location    *time.Location                                        // timezone the weekday rules of the templates are read in (UTC when nil)
 }
 
-func NewDutyAssignmentService(repo repositories.InterfaceDutyAssignmentRepository, dutyRepo repositories.InterfaceDutyRepository) *DutyAssignmentService {
+func NewDutyAssignmentService(repo repositories.InterfaceDutyAssignmentRepository, dutyRepo repositories.InterfaceDutyRepository, versionRepo repositories.InterfaceDutyVersionRepository, photoRepo repositories.InterfaceDutyAssignmentPhotoRepository, historyRepo repositories.InterfaceDutyAssignmentHistoryRepository, eventClient InterfaceEventClient, publisher InterfaceMessagePublisher, stream InterfaceDutyStream, location *time.Location) *DutyAssignmentService {
 	return &DutyAssignmentService{
-		repo:     repo,
-		dutyRepo: dutyRepo,
+		repo:        repo,
+		dutyRepo:    dutyRepo,
+		versionRepo: versionRepo,
+		photoRepo:   photoRepo,
+		historyRepo: historyRepo,
+		eventClient: eventClient,
+		publisher:   publisher,
+		stream:      stream,
+		location:    location,
 	}
 }
 
@@ -27,22 +60,827 @@ func (s *DutyAssignmentService) GetAllDutyAssignmentsByShiftId(ctx context.Conte
 	return s.repo.GetAllDutyAssignmentsByShiftId(ctx, shiftId)
 }
 
-// POST create duty assignments for a given ShiftId and RoleId
-func (s *DutyAssignmentService) CreateDutyAssignments(ctx context.Context, shiftId uuid.UUID, roleId int) error {
+// GET the duty assignments of an employee: of one shift, or of all shifts that aren't clocked out yet
+func (s *DutyAssignmentService) GetDutyAssignmentsByAssignee(ctx context.Context, employeeId string, shiftId *uuid.UUID) ([]models.DutyAssignment, error) {
+	dutyAssignments, err := s.repo.GetDutyAssignmentsByAssignee(ctx, employeeId, shiftId)
+	if err != nil || shiftId != nil {
+		return dutyAssignments, err
+	}
+
+	current := []models.DutyAssignment{}
+	for _, dutyAssignment := range dutyAssignments {
+		if !dutyAssignment.IsFrozen() {
+			current = append(current, dutyAssignment)
+		}
+	}
+	return current, nil
+}
+
+// LocalizeDutyAssignments fills in the name and description of the template versions the assignments were created
+// from, in the first language of the request's preference a version has text in (the version's own text otherwise),
+// along with the template's attachments. Assignments from before versioning get the text of the current template.
+func (s *DutyAssignmentService) LocalizeDutyAssignments(ctx context.Context, dutyAssignments []models.DutyAssignment) ([]models.DutyAssignment, error) {
+	if len(dutyAssignments) == 0 {
+		return dutyAssignments, nil
+	}
+
+	duties, err := getAssignmentDuties(ctx, s.dutyRepo, dutyAssignments)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := getAssignmentVersions(ctx, s.versionRepo, dutyAssignments)
+	if err != nil {
+		return nil, err
+	}
+
+	preference := locale.FromContext(ctx)
+	localized := make([]models.DutyAssignment, 0, len(dutyAssignments))
+	for _, dutyAssignment := range dutyAssignments {
+		if version, ok := assignmentVersion(dutyAssignment, duties, versions); ok {
+			text, language := version.Localize(preference.Languages, preference.Default)
+			dutyAssignment.DutyName = text.DutyName
+			dutyAssignment.DutyDescription = text.DutyDescription
+			dutyAssignment.Language = language
+		}
+		// templates purged by the retention job have no attachments left
+		if duty, ok := duties[dutyAssignment.DutyRowKey]; ok {
+			dutyAssignment.Attachments = duty.Attachments
+		}
+		localized = append(localized, dutyAssignment)
+	}
+	return localized, nil
+}
+
+// a version of a duty template
+type dutyVersionKey struct {
+	DutyId  uuid.UUID
+	Version int
+}
+
+// getAssignmentDuties returns the templates the assignments were created from by RowKey, reading each of them once.
+// Templates purged by the retention job are left out.
+func getAssignmentDuties(ctx context.Context, dutyRepo repositories.InterfaceDutyRepository, dutyAssignments []models.DutyAssignment) (map[uuid.UUID]models.Duty, error) {
+	duties := map[uuid.UUID]models.Duty{}
+	read := map[uuid.UUID]bool{}
+	for _, dutyAssignment := range dutyAssignments {
+		if read[dutyAssignment.DutyRowKey] {
+			continue
+		}
+		read[dutyAssignment.DutyRowKey] = true
+
+		duty, err := dutyRepo.GetDutyById(ctx, dutyAssignment.DutyPartitionKey, dutyAssignment.DutyRowKey.String())
+		if errors.Is(err, repositories.ErrDutyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch duty template %s: %v", dutyAssignment.DutyRowKey, err)
+		}
+		duties[duty.RowKey] = *duty
+	}
+	return duties, nil
+}
+
+// getAssignmentVersions returns the template versions the assignments were created from, reading each of them once.
+// Assignments from before versioning have none.
+func getAssignmentVersions(ctx context.Context, versionRepo repositories.InterfaceDutyVersionRepository, dutyAssignments []models.DutyAssignment) (map[dutyVersionKey]models.DutyVersion, error) {
+	versions := map[dutyVersionKey]models.DutyVersion{}
+	read := map[dutyVersionKey]bool{}
+	for _, dutyAssignment := range dutyAssignments {
+		key := dutyVersionKey{DutyId: dutyAssignment.DutyRowKey, Version: dutyAssignment.DutyVersion}
+		if dutyAssignment.DutyVersion == 0 || read[key] {
+			continue
+		}
+		read[key] = true
+
+		version, err := versionRepo.GetVersion(ctx, key.DutyId, key.Version)
+		if errors.Is(err, repositories.ErrDutyVersionNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch version %d of duty template %s: %v", key.Version, key.DutyId, err)
+		}
+		versions[key] = *version
+	}
+	return versions, nil
+}
+
+// assignmentVersion returns the template version an assignment was created from; for assignments from before
+// versioning, the content of the current template. ok is false when the content is gone.
+func assignmentVersion(dutyAssignment models.DutyAssignment, duties map[uuid.UUID]models.Duty, versions map[dutyVersionKey]models.DutyVersion) (models.DutyVersion, bool) {
+	if dutyAssignment.DutyVersion == 0 {
+		duty, ok := duties[dutyAssignment.DutyRowKey]
+		if !ok {
+			return models.DutyVersion{}, false
+		}
+		return duty.NewVersion(0, time.Time{}, ""), true
+	}
+
+	version, ok := versions[dutyVersionKey{DutyId: dutyAssignment.DutyRowKey, Version: dutyAssignment.DutyVersion}]
+	return version, ok
+}
+
+// POST create duty assignments for a given ShiftId and RoleId, from the role's templates that apply to the shift's event and clock-in day
+func (s *DutyAssignmentService) CreateDutyAssignments(ctx context.Context, shiftId uuid.UUID, roleId int, clockInTime time.Time) error {
 	duties, err := s.dutyRepo.GetDutiesByRole(ctx, roleId)
 	if err != nil {
 		return fmt.Errorf("failed to fetch duties for RoleId %d: %v", roleId, err)
 	}
 
-	return s.repo.CreateDutyAssignments(ctx, shiftId, duties)
+	if clockInTime.IsZero() {
+		clockInTime = time.Now().UTC()
+	}
+
+	// the duties belong to the employee who clocked in
+	assigneeId := auth.SubjectFromContext(ctx)
+
+	// without the event the shift still gets the templates that don't depend on it. When the shift has no event that
+	// is all; when the event service failed, the error is returned after assigning them, so the clock-in message is
+	// retried and adds the event's duties (assigning is idempotent)
+	var event *models.Event
+	var eventId *uuid.UUID
+	var eventErr error
+	if s.eventClient != nil {
+		event, err = s.eventClient.GetEventByShiftId(ctx, shiftId)
+		switch {
+		case errors.Is(err, ErrEventNotFound):
+			log.Printf("No event found for shift %s, only assigning duties that don't depend on the event", shiftId)
+		case err != nil:
+			eventErr = fmt.Errorf("failed to fetch the event of shift %s: %w", shiftId, err)
+		default:
+			eventId = &event.RowKey
+		}
+	}
+
+	// the weekday of a clock-in is the local one: 00:30 on Sunday in the restaurant is still Saturday in UTC
+	localClockIn := clockInTime
+	if s.location != nil {
+		localClockIn = clockInTime.In(s.location)
+	}
+
+	applicable := models.ApplicableDuties(models.ActiveDuties(duties), event, localClockIn)
+	created, err := s.repo.CreateDutyAssignments(ctx, shiftId, roleId, eventId, assigneeId, applicable)
+	if err != nil {
+		return err
+	}
+
+	dutyNames := make(map[uuid.UUID]string, len(applicable))
+	for _, duty := range applicable {
+		dutyNames[duty.RowKey] = duty.DutyName
+	}
+	now := time.Now().UTC()
+	for _, dutyAssignment := range created {
+		publishDutyEvent(s.publisher, DutyAssignmentCreatedKey, dutyAssignment.EventMessage(dutyNames[dutyAssignment.DutyRowKey], assigneeId, now))
+		s.publishStreamEvent(dutyAssignment.StreamEvent(models.DutyStreamCreated, dutyNames[dutyAssignment.DutyRowKey], now))
+	}
+
+	return eventErr
 }
 
-// PUT update a duty
+// PUT update a duty assignment (form values are validated against the duty template's form schema)
 func (s *DutyAssignmentService) UpdateDutyAssignment(ctx context.Context, dutyAssignment models.DutyAssignment, file multipart.File) error {
-	return s.repo.UpdateDutyAssignment(ctx, dutyAssignment, file)
+	existing, err := s.repo.GetDutyAssignment(ctx, dutyAssignment.PartitionKey, dutyAssignment.RowKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.applyUpdate(ctx, *existing, dutyAssignment, file, time.Now().UTC())
+	return err
+}
+
+// applyUpdate updates a duty assignment as of changedAt (the time on the device for synced offline changes) and
+// returns its new state
+func (s *DutyAssignmentService) applyUpdate(ctx context.Context, existing models.DutyAssignment, dutyAssignment models.DutyAssignment, file multipart.File, changedAt time.Time) (models.DutyAssignment, error) {
+	if err := checkNotFrozen(ctx, existing); err != nil {
+		return models.DutyAssignment{}, err
+	}
+
+	// the form is validated against the template version that was active at clock-in, so editing the template
+	// mid-shift doesn't change the form of the assignments already handed out (older assignments have no version)
+	var formSchema []models.FormField
+	var dutyName string
+	switch {
+	case existing.DutyRowKey == uuid.Nil:
+	case existing.DutyVersion > 0:
+		version, err := s.versionRepo.GetVersion(ctx, existing.DutyRowKey, existing.DutyVersion)
+		if err != nil {
+			return models.DutyAssignment{}, fmt.Errorf("failed to fetch duty template version of the assignment: %v", err)
+		}
+		formSchema = version.FormSchema
+		dutyName = version.DutyName
+	default:
+		duty, err := s.dutyRepo.GetDutyById(ctx, existing.DutyPartitionKey, existing.DutyRowKey.String())
+		if err != nil {
+			return models.DutyAssignment{}, fmt.Errorf("failed to fetch duty template of the assignment: %v", err)
+		}
+		formSchema = duty.FormSchema
+		dutyName = duty.DutyName
+	}
+
+	formValues, err := models.ValidateFormValues(formSchema, dutyAssignment.FormValues)
+	if err != nil {
+		return models.DutyAssignment{}, err
+	}
+	dutyAssignment.FormValues = formValues
+
+	// required fields only have to be filled in once the duty is completed
+	if dutyAssignment.DutyAssignmentStatus == models.StatusCompleted {
+		mergedValues := make(map[string]interface{}, len(existing.FormValues)+len(formValues))
+		for key, value := range existing.FormValues {
+			mergedValues[key] = value
+		}
+		for key, value := range formValues {
+			mergedValues[key] = value
+		}
+
+		hasPhoto := file != nil || existing.ImageBlobName != ""
+		if err := models.CheckRequiredFormFields(formSchema, mergedValues, hasPhoto); err != nil {
+			return models.DutyAssignment{}, err
+		}
+	}
+
+	// the first update starts the duty; completing it records when (reopening clears it again)
+	now := changedAt
+	dutyAssignment.UpdatedAt = &now
+	dutyAssignment.StartedAt = existing.StartedAt
+	if dutyAssignment.StartedAt == nil {
+		dutyAssignment.StartedAt = &now
+	}
+	switch {
+	case dutyAssignment.DutyAssignmentStatus != models.StatusCompleted:
+		dutyAssignment.CompletedAt = nil
+	case existing.DutyAssignmentStatus == models.StatusCompleted && existing.CompletedAt != nil:
+		dutyAssignment.CompletedAt = existing.CompletedAt
+	default:
+		dutyAssignment.CompletedAt = &now
+	}
+
+	// only real JPEG/PNG/WebP images are stored, re-encoded without metadata and with a thumbnail
+	if file != nil {
+		image, err := images.Process(file)
+		if err != nil {
+			return models.DutyAssignment{}, err
+		}
+
+		photo := models.DutyAssignmentPhoto{
+			RowKey:          uuid.New(),
+			ShiftId:         dutyAssignment.PartitionKey,
+			DutyId:          dutyAssignment.RowKey,
+			UploadedAt:      now,
+			UploadedBy:      auth.SubjectFromContext(ctx),
+			DutyRowKey:      existing.DutyRowKey,
+			Hash:            image.Hash,
+			CapturedAt:      image.CapturedAt,
+			CapturedAtLocal: image.CapturedAtLocal,
+		}
+		s.checkPhoto(ctx, existing, &photo, now)
+
+		// every upload is added to the assignment's photos and becomes its main photo
+		photo, err = s.photoRepo.AddPhoto(ctx, photo, image)
+		if err != nil {
+			return models.DutyAssignment{}, err
+		}
+		dutyAssignment.ImageBlobName = photo.BlobName
+		dutyAssignment.ThumbnailBlobName = photo.ThumbnailBlobName
+
+		// a flagged photo flags the assignment until it is reviewed
+		if len(photo.Flags) > 0 {
+			dutyAssignment.PhotoFlags = models.MergePhotoFlags(existing.PhotoFlags, photo.Flags)
+		}
+	}
+
+	if err := s.repo.UpdateDutyAssignment(ctx, dutyAssignment); err != nil {
+		return models.DutyAssignment{}, err
+	}
+
+	updated := mergeDutyAssignmentUpdate(existing, dutyAssignment)
+	s.publishStreamEvent(updated.StreamEvent(models.DutyStreamUpdated, dutyName, now))
+	s.publishStatusChange(ctx, existing, updated, dutyName, now)
+	return updated, nil
+}
+
+// mergeDutyAssignmentUpdate returns the state of a duty assignment after an update, which only carries the changed
+// fields (the same merge the repository does)
+func mergeDutyAssignmentUpdate(existing models.DutyAssignment, update models.DutyAssignment) models.DutyAssignment {
+	merged := existing
+	if update.DutyAssignmentStatus != "" {
+		merged.DutyAssignmentStatus = update.DutyAssignmentStatus
+	}
+	if update.ImageBlobName != "" {
+		merged.ImageBlobName = update.ImageBlobName
+		merged.ThumbnailBlobName = update.ThumbnailBlobName
+	}
+	if update.PhotoFlags != nil {
+		merged.PhotoFlags = update.PhotoFlags
+	}
+	if update.DutyAssignmentNote != nil && *update.DutyAssignmentNote != "" {
+		merged.DutyAssignmentNote = update.DutyAssignmentNote
+	}
+	merged.StartedAt = update.StartedAt
+	merged.CompletedAt = update.CompletedAt
+	merged.UpdatedAt = update.UpdatedAt
+
+	merged.FormValues = make(map[string]interface{}, len(existing.FormValues)+len(update.FormValues))
+	for key, value := range existing.FormValues {
+		merged.FormValues[key] = value
+	}
+	for key, value := range update.FormValues {
+		merged.FormValues[key] = value
+	}
+
+	return merged
+}
+
+// publishStreamEvent pushes a duty assignment change to the clients of /duties/stream
+func (s *DutyAssignmentService) publishStreamEvent(event models.DutyStreamEvent) {
+	if s.stream == nil {
+		return
+	}
+	s.stream.Publish(event)
 }
 
-// DELETE a duty assignment by ShiftId and DutyId
+// publishStatusChange publishes the completed/skipped event when an update finishes a duty, and the all_completed
+// event when it was the last open duty of the shift
+func (s *DutyAssignmentService) publishStatusChange(ctx context.Context, existing models.DutyAssignment, updated models.DutyAssignment, dutyName string, now time.Time) {
+	if s.publisher == nil || updated.DutyAssignmentStatus == existing.DutyAssignmentStatus {
+		return
+	}
+
+	var routingKey string
+	switch updated.DutyAssignmentStatus {
+	case models.StatusCompleted:
+		routingKey = DutyAssignmentCompletedKey
+	case models.StatusSkipped:
+		routingKey = DutyAssignmentSkippedKey
+	default:
+		return // reopened
+	}
+
+	employeeId := auth.SubjectFromContext(ctx)
+	publishDutyEvent(s.publisher, routingKey, updated.EventMessage(dutyName, employeeId, now))
+
+	// a duty that was already finished (e.g. skipped, now completed) doesn't finish the shift again
+	if existing.DutyAssignmentStatus == models.StatusCompleted || existing.DutyAssignmentStatus == models.StatusSkipped {
+		return
+	}
+
+	dutyAssignments, err := s.repo.GetAllDutyAssignmentsByShiftId(ctx, existing.PartitionKey)
+	if err != nil {
+		log.Printf("Failed to check if all duties of shift %s are completed: %v", existing.PartitionKey, err)
+		return
+	}
+
+	summary := models.ShiftDutiesCompletedMessage{ShiftID: existing.PartitionKey, EmployeeID: employeeId, CompletedAt: now}
+	for _, dutyAssignment := range dutyAssignments {
+		status := dutyAssignment.DutyAssignmentStatus
+		if dutyAssignment.RowKey == updated.RowKey {
+			status = updated.DutyAssignmentStatus // the list may not show the update yet
+		}
+
+		switch status {
+		case models.StatusCompleted:
+			summary.Completed++
+		case models.StatusSkipped:
+			summary.Skipped++
+		default:
+			return // still open duties
+		}
+		summary.Total++
+	}
+
+	if summary.Total > 0 {
+		publishDutyEvent(s.publisher, ShiftAllCompletedKey, summary)
+	}
+}
+
+// PUT hands a duty assignment over to another crew member of the same event and records the handover
+func (s *DutyAssignmentService) ReassignDutyAssignment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, assigneeId string, note *string) (*models.DutyAssignment, error) {
+	dutyAssignment, err := s.repo.GetDutyAssignment(ctx, shiftId, dutyId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNotFrozen(ctx, *dutyAssignment); err != nil {
+		return nil, err
+	}
+	if dutyAssignment.AssigneeId == assigneeId {
+		return dutyAssignment, nil // already theirs
+	}
+
+	// the crew of an event are the employees with duties of it (of the shift when the event is unknown)
+	var crewAssignments []models.DutyAssignment
+	if dutyAssignment.EventId != nil {
+		crewAssignments, err = s.repo.GetDutyAssignmentsByEventId(ctx, *dutyAssignment.EventId)
+	} else {
+		crewAssignments, err = s.repo.GetAllDutyAssignmentsByShiftId(ctx, shiftId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	onEvent := false
+	for _, crewAssignment := range crewAssignments {
+		if crewAssignment.AssigneeId == assigneeId {
+			onEvent = true
+			break
+		}
+	}
+	if !onEvent {
+		return nil, ErrAssigneeNotOnEvent
+	}
+
+	if err := s.repo.UpdateAssignee(ctx, shiftId, dutyId, assigneeId); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	changedBy := auth.SubjectFromContext(ctx)
+	previousAssigneeId := dutyAssignment.AssigneeId
+	dutyAssignment.AssigneeId = assigneeId
+
+	// the handover already happened, so a failure to record it is logged instead of failing the request
+	if s.historyRepo != nil {
+		entry := models.DutyAssignmentHistoryEntry{
+			RowKey:         uuid.New(),
+			ShiftId:        shiftId,
+			DutyId:         dutyId,
+			Action:         models.HistoryReassigned,
+			ChangedBy:      changedBy,
+			ChangedAt:      now,
+			FromAssigneeId: previousAssigneeId,
+			ToAssigneeId:   assigneeId,
+			Note:           note,
+		}
+		if err := s.historyRepo.AddEntry(ctx, entry); err != nil {
+			log.Printf("Failed to record the handover of duty assignment %s: %v", dutyId, err)
+		}
+	}
+
+	dutyName := s.getDutyName(ctx, *dutyAssignment)
+	message := dutyAssignment.EventMessage(dutyName, changedBy, now)
+	message.PreviousAssigneeID = previousAssigneeId
+	publishDutyEvent(s.publisher, DutyAssignmentReassignedKey, message)
+	s.publishStreamEvent(dutyAssignment.StreamEvent(models.DutyStreamUpdated, dutyName, now))
+
+	return dutyAssignment, nil
+}
+
+// GET the history of a duty assignment (oldest first)
+func (s *DutyAssignmentService) GetDutyAssignmentHistory(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentHistoryEntry, error) {
+	if _, err := s.repo.GetDutyAssignment(ctx, shiftId, dutyId); err != nil {
+		return nil, err
+	}
+	return s.historyRepo.GetHistory(ctx, shiftId, dutyId)
+}
+
+// GET all photos of a duty assignment (newest first)
+func (s *DutyAssignmentService) GetDutyAssignmentPhotos(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentPhoto, error) {
+	return s.photoRepo.GetPhotos(ctx, shiftId, dutyId)
+}
+
+// DELETE a photo of a duty assignment; when it was the main photo, the newest remaining photo takes its place
+func (s *DutyAssignmentService) DeleteDutyAssignmentPhoto(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, photoId uuid.UUID) error {
+	dutyAssignment, err := s.repo.GetDutyAssignment(ctx, shiftId, dutyId)
+	if err != nil {
+		return err
+	}
+	if err := checkNotFrozen(ctx, *dutyAssignment); err != nil {
+		return err
+	}
+
+	photo, err := s.photoRepo.GetPhoto(ctx, shiftId, dutyId, photoId)
+	if err != nil {
+		return err
+	}
+
+	if err := s.photoRepo.DeletePhoto(ctx, *photo); err != nil {
+		return err
+	}
+
+	isMainPhoto := dutyAssignment.ImageBlobName == photo.BlobName
+	if !isMainPhoto && photo.ReviewStatus != models.PhotoReviewPending {
+		return nil
+	}
+
+	remaining, err := s.photoRepo.GetPhotos(ctx, shiftId, dutyId)
+	if err != nil {
+		return err
+	}
+
+	// a deleted photo no longer waits for review
+	if photo.ReviewStatus == models.PhotoReviewPending {
+		if err := s.repo.SetPhotoFlags(ctx, shiftId, dutyId, models.PendingPhotoFlags(remaining)); err != nil {
+			return err
+		}
+	}
+
+	if !isMainPhoto {
+		return nil
+	}
+	if len(remaining) == 0 {
+		return s.repo.SetMainPhoto(ctx, shiftId, dutyId, "", "")
+	}
+	return s.repo.SetMainPhoto(ctx, shiftId, dutyId, remaining[0].BlobName, remaining[0].ThumbnailBlobName)
+}
+
+// GET the flagged photos that wait for review, oldest first, with the earlier photos they duplicate
+func (s *DutyAssignmentService) GetPhotoReviewQueue(ctx context.Context) ([]models.PhotoReviewItem, error) {
+	photos, err := s.photoRepo.GetPhotosForReview(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	queue := make([]models.PhotoReviewItem, 0, len(photos))
+	for _, photo := range photos {
+		item := models.PhotoReviewItem{Photo: photo}
+
+		// the earlier photo may have been deleted since
+		if ref := photo.DuplicateOf; ref != nil {
+			duplicateOf, err := s.photoRepo.GetPhoto(ctx, ref.ShiftId, ref.DutyId, ref.PhotoId)
+			if err != nil && !errors.Is(err, repositories.ErrPhotoNotFound) {
+				return nil, err
+			}
+			item.DuplicateOf = duplicateOf
+		}
+
+		queue = append(queue, item)
+	}
+
+	return queue, nil
+}
+
+// PUT the review of a flagged photo: approving or rejecting it takes it out of the review queue. The assignment keeps
+// the flags of its photos that still wait for review. A photo can be reviewed again to change the decision.
+func (s *DutyAssignmentService) ReviewDutyAssignmentPhoto(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, photoId uuid.UUID, review models.PhotoReview) (*models.DutyAssignmentPhoto, error) {
+	if err := review.Validate(); err != nil {
+		return nil, err
+	}
+
+	photo, err := s.photoRepo.GetPhoto(ctx, shiftId, dutyId, photoId)
+	if err != nil {
+		return nil, err
+	}
+	if photo.ReviewStatus == "" {
+		return nil, ErrPhotoNotFlagged
+	}
+
+	reviewedAt := time.Now().UTC()
+	photo.ReviewStatus = review.Status
+	photo.ReviewedBy = auth.SubjectFromContext(ctx)
+	photo.ReviewedAt = &reviewedAt
+	photo.ReviewNote = strings.TrimSpace(review.Note)
+
+	if err := s.photoRepo.UpdatePhotoReview(ctx, *photo); err != nil {
+		return nil, err
+	}
+
+	photos, err := s.photoRepo.GetPhotos(ctx, shiftId, dutyId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetPhotoFlags(ctx, shiftId, dutyId, models.PendingPhotoFlags(photos)); err != nil {
+		return nil, err
+	}
+
+	return photo, nil
+}
+
+// checkPhoto runs the authenticity checks of a new photo of an assignment and flags it for review when it is a
+// near-duplicate of an earlier photo of the same duty, or was taken outside the shift's clock-in window (clock-in
+// until now, or until clock-out when the shift was clocked out)
+func (s *DutyAssignmentService) checkPhoto(ctx context.Context, dutyAssignment models.DutyAssignment, photo *models.DutyAssignmentPhoto, now time.Time) {
+	// assignments created before the clock-in time was stored have no window
+	if photo.CapturedAt != nil && !dutyAssignment.CreatedAt.IsZero() {
+		to := now
+		if dutyAssignment.FrozenAt != nil && dutyAssignment.FrozenAt.Before(now) {
+			to = *dutyAssignment.FrozenAt
+		}
+		if !models.CheckPhotoCapture(*photo.CapturedAt, photo.CapturedAtLocal, dutyAssignment.CreatedAt, to) {
+			photo.Flags = append(photo.Flags, models.PhotoFlagCapturedOutsideShift)
+		}
+	}
+
+	// earlier photos of the same assignment are retries, not reused evidence
+	if dutyAssignment.DutyRowKey != uuid.Nil {
+		// the check is best effort: when the earlier photos can't be fetched, the upload isn't rejected for it
+		earlier, err := s.photoRepo.GetPhotosByDuty(ctx, dutyAssignment.DutyRowKey, now.Add(-models.PhotoDuplicateLookback))
+		if err != nil {
+			log.Printf("Failed to fetch earlier photos of duty %s, skipping the duplicate check: %v", dutyAssignment.DutyRowKey, err)
+		}
+
+		closest := models.PhotoDuplicateMaxDistance + 1
+		for _, candidate := range earlier {
+			if candidate.ShiftId == photo.ShiftId && candidate.DutyId == photo.DutyId {
+				continue
+			}
+			if distance := images.HashDistance(photo.Hash, candidate.Hash); distance < closest {
+				closest = distance
+				photo.DuplicateOf = &models.PhotoReference{ShiftId: candidate.ShiftId, DutyId: candidate.DutyId, PhotoId: candidate.RowKey}
+			}
+		}
+		if photo.DuplicateOf != nil {
+			photo.Flags = append([]models.PhotoFlag{models.PhotoFlagDuplicate}, photo.Flags...)
+		}
+	}
+
+	if len(photo.Flags) > 0 {
+		photo.ReviewStatus = models.PhotoReviewPending
+	}
+}
+
+// GET a freshly signed URL of the main photo (or its thumbnail) of a duty assignment
+func (s *DutyAssignmentService) GetDutyAssignmentImageUrl(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, thumbnail bool) (string, error) {
+	dutyAssignment, err := s.repo.GetDutyAssignment(ctx, shiftId, dutyId)
+	if err != nil {
+		return "", err
+	}
+
+	imageUrl := dutyAssignment.DutyAssignmentImageUrl
+	if thumbnail {
+		imageUrl = dutyAssignment.DutyAssignmentThumbnailUrl
+	}
+	if imageUrl == nil {
+		return "", ErrNoImage
+	}
+
+	return *imageUrl, nil
+}
+
+// DELETE soft delete a duty assignment by ShiftId and DutyId. Its photos and comments are kept until the retention
+// job purges it, so it can be restored until then
 func (s *DutyAssignmentService) DeleteDutyAssignment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) error {
-	return s.repo.DeleteDutyAssignment(ctx, shiftId, dutyId)
+	dutyAssignment, err := s.repo.GetDutyAssignment(ctx, shiftId, dutyId)
+	if err != nil {
+		return err
+	}
+	if err := checkNotFrozen(ctx, *dutyAssignment); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if err := s.repo.SetDutyAssignmentDeleted(ctx, shiftId, dutyId, &now, auth.SubjectFromContext(ctx)); err != nil {
+		return err
+	}
+
+	s.publishStreamEvent(dutyAssignment.StreamEvent(models.DutyStreamDeleted, "", now))
+	return nil
+}
+
+// GET the deleted duty assignments of a shift
+func (s *DutyAssignmentService) GetDeletedDutyAssignments(ctx context.Context, shiftId uuid.UUID) ([]models.DutyAssignment, error) {
+	return s.repo.GetDeletedDutyAssignments(ctx, &shiftId, time.Now().UTC())
+}
+
+// POST restore a soft-deleted duty assignment
+func (s *DutyAssignmentService) RestoreDutyAssignment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) (*models.DutyAssignment, error) {
+	if err := s.repo.SetDutyAssignmentDeleted(ctx, shiftId, dutyId, nil, ""); err != nil {
+		return nil, err
+	}
+
+	dutyAssignment, err := s.repo.GetDutyAssignment(ctx, shiftId, dutyId)
+	if err != nil {
+		return nil, err
+	}
+
+	// for the clients of the stream, the restored assignment is back with its current state
+	s.publishStreamEvent(dutyAssignment.StreamEvent(models.DutyStreamUpdated, s.getDutyName(ctx, *dutyAssignment), time.Now().UTC()))
+	return dutyAssignment, nil
+}
+
+// GET whether a shift can clock out, with the mandatory duties that are still incomplete
+func (s *DutyAssignmentService) CanClockOut(ctx context.Context, shiftId uuid.UUID) (*models.CanClockOutResult, error) {
+	dutyAssignments, err := s.repo.GetAllDutyAssignmentsByShiftId(ctx, shiftId)
+	if err != nil {
+		return nil, err
+	}
+
+	var open []models.DutyAssignment
+	for _, dutyAssignment := range dutyAssignments {
+		if dutyAssignment.Mandatory && isOpen(dutyAssignment) {
+			open = append(open, dutyAssignment)
+		}
+	}
+
+	result := &models.CanClockOutResult{ShiftId: shiftId, CanClockOut: len(open) == 0, OpenMandatoryDuties: []models.OpenDuty{}}
+	if len(open) == 0 {
+		return result, nil
+	}
+
+	dutyNames, err := s.getDutyNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, dutyAssignment := range open {
+		result.OpenMandatoryDuties = append(result.OpenMandatoryDuties, dutyAssignment.OpenDuty(dutyNames[dutyAssignment.DutyRowKey]))
+	}
+
+	return result, nil
+}
+
+// ClockOut freezes the duty assignments of a shift, records which were still incomplete and publishes the shift's
+// duty summary. Assignments that are already frozen keep their state, so a repeated clock-out changes nothing.
+func (s *DutyAssignmentService) ClockOut(ctx context.Context, shiftId uuid.UUID, clockOutTime time.Time) (*models.ShiftDutySummaryMessage, error) {
+	if clockOutTime.IsZero() {
+		clockOutTime = time.Now().UTC()
+	}
+
+	dutyAssignments, err := s.repo.GetAllDutyAssignmentsByShiftId(ctx, shiftId)
+	if err != nil {
+		return nil, err
+	}
+
+	var toFreeze []models.DutyAssignment
+	for i := range dutyAssignments {
+		if dutyAssignments[i].IsFrozen() {
+			continue
+		}
+		dutyAssignments[i].FrozenAt = &clockOutTime
+		dutyAssignments[i].IncompleteAtClockOut = isOpen(dutyAssignments[i])
+		toFreeze = append(toFreeze, dutyAssignments[i])
+	}
+
+	if len(toFreeze) > 0 {
+		if err := s.repo.FreezeDutyAssignments(ctx, shiftId, toFreeze); err != nil {
+			return nil, err
+		}
+	}
+
+	dutyNames, err := s.getDutyNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.ShiftDutySummaryMessage{
+		ShiftID:          shiftId,
+		EmployeeID:       auth.SubjectFromContext(ctx),
+		ClockOutTime:     clockOutTime,
+		IncompleteDuties: []models.OpenDuty{},
+	}
+	for _, dutyAssignment := range dutyAssignments {
+		summary.Total++
+		switch {
+		case dutyAssignment.DutyAssignmentStatus == models.StatusCompleted:
+			summary.Completed++
+		case dutyAssignment.DutyAssignmentStatus == models.StatusSkipped:
+			summary.Skipped++
+		default:
+			summary.Incomplete++
+			if dutyAssignment.Mandatory {
+				summary.IncompleteMandatory++
+			}
+			summary.IncompleteDuties = append(summary.IncompleteDuties, dutyAssignment.OpenDuty(dutyNames[dutyAssignment.DutyRowKey]))
+		}
+	}
+
+	for _, dutyAssignment := range toFreeze {
+		s.publishStreamEvent(dutyAssignment.StreamEvent(models.DutyStreamFrozen, dutyNames[dutyAssignment.DutyRowKey], clockOutTime))
+	}
+
+	// a redelivered clock-out of an already frozen shift isn't reported twice
+	if len(toFreeze) > 0 || len(dutyAssignments) == 0 {
+		publishDutyEvent(s.publisher, ShiftSummaryKey, summary)
+	}
+
+	return summary, nil
+}
+
+// checkNotFrozen only lets admins change a duty assignment after its shift was clocked out
+func checkNotFrozen(ctx context.Context, dutyAssignment models.DutyAssignment) error {
+	if !dutyAssignment.IsFrozen() {
+		return nil
+	}
+	if identity, ok := auth.FromContext(ctx); ok && identity.IsAdmin() {
+		return nil
+	}
+	return ErrDutyAssignmentFrozen
+}
+
+// isOpen checks if a duty assignment is neither completed nor skipped
+func isOpen(dutyAssignment models.DutyAssignment) bool {
+	return dutyAssignment.DutyAssignmentStatus != models.StatusCompleted && dutyAssignment.DutyAssignmentStatus != models.StatusSkipped
+}
+
+// getDutyName returns the name of a duty assignment's template (empty when it can't be fetched)
+func (s *DutyAssignmentService) getDutyName(ctx context.Context, dutyAssignment models.DutyAssignment) string {
+	if dutyAssignment.DutyRowKey == uuid.Nil {
+		return ""
+	}
+
+	duty, err := s.dutyRepo.GetDutyById(ctx, dutyAssignment.DutyPartitionKey, dutyAssignment.DutyRowKey.String())
+	if err != nil {
+		log.Printf("Failed to fetch the duty template of duty assignment %s: %v", dutyAssignment.RowKey, err)
+		return ""
+	}
+	return duty.DutyName
+}
+
+// getDutyNames returns the names of all duty templates by RowKey
+func (s *DutyAssignmentService) getDutyNames(ctx context.Context) (map[uuid.UUID]string, error) {
+	duties, err := s.dutyRepo.GetAllDuties(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch duty templates: %v", err)
+	}
+
+	dutyNames := make(map[uuid.UUID]string, len(duties))
+	for _, duty := range duties {
+		dutyNames[duty.RowKey] = duty.DutyName
+	}
+	return dutyNames, nil
 }
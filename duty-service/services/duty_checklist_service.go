@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"duty-service/locale"
+	"duty-service/models"
+	"duty-service/repositories"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DutyChecklistService struct {
+	repo              repositories.InterfaceDutyAssignmentRepository
+	dutyRepo          repositories.InterfaceDutyRepository
+	versionRepo       repositories.InterfaceDutyVersionRepository // the template versions the assignments were created from
+	completionFormURL string                                      // URL template of the completion form, with {ShiftId} and {DutyId}
+}
+
+func NewDutyChecklistService(repo repositories.InterfaceDutyAssignmentRepository, dutyRepo repositories.InterfaceDutyRepository, versionRepo repositories.InterfaceDutyVersionRepository, completionFormURL string) *DutyChecklistService {
+	return &DutyChecklistService{
+		repo:              repo,
+		dutyRepo:          dutyRepo,
+		versionRepo:       versionRepo,
+		completionFormURL: completionFormURL,
+	}
+}
+
+// GET the checklist of a shift: its duty assignments in the language of the request, with the questions of their
+// forms and the link to their completion form. The text and forms are those of the template versions the assignments
+// were created from, so the checklist matches what the employees see and fill in.
+func (s *DutyChecklistService) GetDutyChecklist(ctx context.Context, shiftId uuid.UUID) (*models.DutyChecklist, error) {
+	dutyAssignments, err := s.repo.GetAllDutyAssignmentsByShiftId(ctx, shiftId)
+	if err != nil {
+		return nil, err
+	}
+
+	checklist := &models.DutyChecklist{
+		ShiftId:     shiftId,
+		GeneratedAt: time.Now().UTC(),
+		Items:       []models.DutyChecklistItem{},
+	}
+	if len(dutyAssignments) == 0 {
+		return checklist, nil
+	}
+
+	// only assignments from before versioning need the current template
+	unversioned := []models.DutyAssignment{}
+	for _, dutyAssignment := range dutyAssignments {
+		if dutyAssignment.DutyVersion == 0 {
+			unversioned = append(unversioned, dutyAssignment)
+		}
+	}
+	duties, err := getAssignmentDuties(ctx, s.dutyRepo, unversioned)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := getAssignmentVersions(ctx, s.versionRepo, dutyAssignments)
+	if err != nil {
+		return nil, err
+	}
+
+	preference := locale.FromContext(ctx)
+	for _, dutyAssignment := range dutyAssignments {
+		item := models.DutyChecklistItem{
+			DutyId:    dutyAssignment.RowKey,
+			Status:    dutyAssignment.DutyAssignmentStatus,
+			Mandatory: dutyAssignment.Mandatory,
+			DueAt:     dutyAssignment.DueAt,
+			FormUrl:   models.CompletionFormURL(s.completionFormURL, shiftId, dutyAssignment.RowKey),
+		}
+
+		if version, ok := assignmentVersion(dutyAssignment, duties, versions); ok {
+			text, _ := version.Localize(preference.Languages, preference.Default)
+			item.DutyName = text.DutyName
+			item.DutyDescription = text.DutyDescription
+			item.FormFields = version.FormSchema
+		}
+
+		checklist.Items = append(checklist.Items, item)
+	}
+
+	// the order the duties have to be done in: by deadline (duties without one last), then by name
+	sort.SliceStable(checklist.Items, func(i, j int) bool {
+		a, b := checklist.Items[i], checklist.Items[j]
+		switch {
+		case a.DueAt != nil && b.DueAt != nil && !a.DueAt.Equal(*b.DueAt):
+			return a.DueAt.Before(*b.DueAt)
+		case (a.DueAt == nil) != (b.DueAt == nil):
+			return a.DueAt != nil
+		}
+		return a.DutyName < b.DutyName
+	})
+
+	return checklist, nil
+}
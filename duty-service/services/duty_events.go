@@ -0,0 +1,31 @@
+package services
+
+import (
+	"log"
+)
+
+// topic exchange the duty lifecycle events are published to
+const DutyEventsExchange = "duty.events"
+
+// routing keys of the duty lifecycle events
+const (
+	DutyAssignmentCreatedKey    = "duty.assignment.created"
+	DutyAssignmentCompletedKey  = "duty.assignment.completed"
+	DutyAssignmentSkippedKey    = "duty.assignment.skipped"
+	DutyAssignmentReassignedKey = "duty.assignment.reassigned"
+	ShiftAllCompletedKey        = "duty.shift.all_completed"
+	ShiftSummaryKey             = "duty.shift.summary"
+	DutyCommentMentionedKey     = "duty.comment.mentioned"
+)
+
+// publishDutyEvent publishes a duty lifecycle event. The change is already stored when it is published, so a
+// failure is logged instead of failing the request.
+func publishDutyEvent(publisher InterfaceMessagePublisher, routingKey string, message interface{}) {
+	if publisher == nil {
+		return
+	}
+
+	if err := publisher.PublishToExchange(DutyEventsExchange, routingKey, message); err != nil {
+		log.Printf("Failed to publish %s event: %v", routingKey, err)
+	}
+}
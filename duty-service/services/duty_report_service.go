@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"duty-service/models"
+	"duty-service/repositories"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DutyReportService struct {
+	repo     repositories.InterfaceDutyAssignmentRepository
+	dutyRepo repositories.InterfaceDutyRepository
+}
+
+func NewDutyReportService(repo repositories.InterfaceDutyAssignmentRepository, dutyRepo repositories.InterfaceDutyRepository) *DutyReportService {
+	return &DutyReportService{
+		repo:     repo,
+		dutyRepo: dutyRepo,
+	}
+}
+
+// GET the report of a single shift
+func (s *DutyReportService) GetShiftReport(ctx context.Context, shiftId uuid.UUID) (*models.DutyReport, error) {
+	dutyAssignments, err := s.repo.GetAllDutyAssignmentsByShiftId(ctx, shiftId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.buildReport(ctx, dutyAssignments)
+}
+
+// GET the report of several shifts (e.g. all shifts of an event)
+func (s *DutyReportService) GetShiftsReport(ctx context.Context, shiftIds []uuid.UUID) (*models.DutyReport, error) {
+	dutyAssignments, err := s.repo.GetDutyAssignmentsByShiftIds(ctx, shiftIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.buildReport(ctx, dutyAssignments)
+}
+
+// GET the report of all duty assignments created between from and to
+func (s *DutyReportService) GetDateRangeReport(ctx context.Context, from, to time.Time) (*models.DutyReport, error) {
+	dutyAssignments, err := s.repo.GetDutyAssignmentsCreatedBetween(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.buildReport(ctx, dutyAssignments)
+	if err != nil {
+		return nil, err
+	}
+	report.From, report.To = &from, &to
+
+	return report, nil
+}
+
+// buildReport groups the duty assignments by shift and counts them
+func (s *DutyReportService) buildReport(ctx context.Context, dutyAssignments []models.DutyAssignment) (*models.DutyReport, error) {
+	// the templates give the duty names and tell which duties ask for a photo
+	duties, err := s.dutyRepo.GetAllDuties(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch duty templates: %v", err)
+	}
+	dutiesById := make(map[uuid.UUID]models.Duty, len(duties))
+	for _, duty := range duties {
+		dutiesById[duty.RowKey] = duty
+	}
+
+	now := time.Now()
+	report := &models.DutyReport{GeneratedAt: now.UTC(), Shifts: []models.ShiftDutyReport{}}
+	shiftIndex := make(map[uuid.UUID]int)
+
+	for _, dutyAssignment := range dutyAssignments {
+		duty, hasTemplate := dutiesById[dutyAssignment.DutyRowKey]
+		expectsPhoto := hasTemplate && duty.ExpectsPhoto()
+
+		line := models.DutyReportLine{
+			DutyId:       dutyAssignment.RowKey,
+			DutyName:     duty.DutyName,
+			Status:       dutyAssignment.DutyAssignmentStatus,
+			Note:         dutyAssignment.DutyAssignmentNote,
+			CreatedAt:    dutyAssignment.CreatedAt,
+			DueAt:        dutyAssignment.DueAt,
+			Overdue:      dutyAssignment.IsOverdue(now),
+			ExpectsPhoto: expectsPhoto,
+			MissingPhoto: expectsPhoto && dutyAssignment.DutyAssignmentStatus != models.StatusSkipped && dutyAssignment.ImageBlobName == "",
+			ThumbnailUrl: dutyAssignment.DutyAssignmentThumbnailUrl,
+		}
+		if !hasTemplate { // the template was deleted, or the assignment is older than template references
+			line.DutyName = "(unknown duty)"
+		}
+
+		index, ok := shiftIndex[dutyAssignment.PartitionKey]
+		if !ok {
+			index = len(report.Shifts)
+			shiftIndex[dutyAssignment.PartitionKey] = index
+			report.Shifts = append(report.Shifts, models.ShiftDutyReport{ShiftId: dutyAssignment.PartitionKey})
+		}
+
+		report.Shifts[index].Assignments = append(report.Shifts[index].Assignments, line)
+		report.Shifts[index].Summary.Add(line)
+		report.Summary.Add(line)
+	}
+
+	// stable output: shifts by ID, duties by name
+	sort.Slice(report.Shifts, func(i, j int) bool {
+		return report.Shifts[i].ShiftId.String() < report.Shifts[j].ShiftId.String()
+	})
+	for _, shift := range report.Shifts {
+		sort.SliceStable(shift.Assignments, func(i, j int) bool {
+			return shift.Assignments[i].DutyName < shift.Assignments[j].DutyName
+		})
+	}
+
+	return report, nil
+}
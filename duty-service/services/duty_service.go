@@ -1,28 +1,68 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"duty-service/auth"
+	"duty-service/images"
 	"duty-service/models"
 	"duty-service/repositories"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
 )
 
+// ErrDutyDeleted is returned when a deleted duty is changed
+var ErrDutyDeleted = errors.New("the duty was deleted: restore it first")
+
+// ErrDutyAttachmentNotFound is returned when a duty has no attachment with the given ID
+var ErrDutyAttachmentNotFound = errors.New("attachment not found")
+
+// ErrTooManyDutyAttachments is returned when an attachment is added to a duty that has the maximum number of them
+var ErrTooManyDutyAttachments = fmt.Errorf("a duty can't have more than %d attachments", models.MaxDutyAttachments)
+
+// ErrDutyKeyInUse is returned when a deleted duty is restored while another duty has taken its DutyKey
+var ErrDutyKeyInUse = errors.New("another duty already uses the DutyKey of this duty")
+
 type DutyService struct {
-	repo repositories.InterfaceDutyRepository
+	repo        repositories.InterfaceDutyRepository
+	versionRepo repositories.InterfaceDutyVersionRepository
 }
 
-func NewDutyService(repo repositories.InterfaceDutyRepository) *DutyService {
-	return &DutyService{repo: repo}
+func NewDutyService(repo repositories.InterfaceDutyRepository, versionRepo repositories.InterfaceDutyVersionRepository) *DutyService {
+	return &DutyService{
+		repo:        repo,
+		versionRepo: versionRepo,
+	}
 }
 
-// GET all duties
-func (s *DutyService) GetAllDuties(ctx context.Context, name string) ([]models.Duty, error) {
-	var filter string
+// GET the duties matching a search, sorted and paged (deleted ones only with IncludeDeleted)
+func (s *DutyService) GetAllDuties(ctx context.Context, search models.DutySearch) (*models.DutySearchResult, error) {
+	if err := search.Validate(); err != nil {
+		return nil, err
+	}
+
+	// exact matches are left to Table Storage; it can't search in text, so the rest is checked here
+	filter := repositories.NewODataFilter()
+	if search.Name != "" {
+		filter.Eq("DutyName", search.Name)
+	}
+	if search.RoleId != nil {
+		filter.Eq("RoleId", *search.RoleId)
+	}
 
-	if name != "" { // Check if the name is not empty
-		filter = "Name eq '" + name + "'" // Construct the filter for the name
+	duties, err := s.repo.GetAllDuties(ctx, filter.String())
+	if err != nil {
+		return nil, err
 	}
 
-	return s.repo.GetAllDuties(ctx, filter)
+	result := search.Apply(duties)
+	return &result, nil
 }
 
 // GET duty by id
@@ -32,20 +72,498 @@ func (s *DutyService) GetDutyById(ctx context.Context, partitionKey, rowKey stri
 
 // GET duty by role id
 func (s *DutyService) GetDutiesByRole(ctx context.Context, roleId int) ([]models.Duty, error) {
-	return s.repo.GetDutiesByRole(ctx, roleId)
+	duties, err := s.repo.GetDutiesByRole(ctx, roleId)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.ActiveDuties(duties), nil
 }
 
-// POST create duty
+// POST create duty (as version 1)
 func (s *DutyService) CreateDuty(ctx context.Context, duty models.Duty) error {
-	return s.repo.CreateDuty(ctx, duty)
+	if err := models.ValidateFormSchema(duty.FormSchema); err != nil {
+		return err
+	}
+
+	return s.createDuty(ctx, duty)
 }
 
-// PUT update a duty
+// PUT update a duty (stored as a new version, the old one is kept)
 func (s *DutyService) UpdateDuty(ctx context.Context, partitionKey, rowKey string, duty models.Duty) error {
-	return s.repo.UpdateDuty(ctx, partitionKey, rowKey, duty)
+	if err := models.ValidateFormSchema(duty.FormSchema); err != nil {
+		return err
+	}
+
+	existing, err := s.repo.GetDutyById(ctx, partitionKey, rowKey)
+	if err != nil {
+		return err
+	}
+
+	// a PUT body without a key keeps the template's key
+	if duty.DutyKey == "" {
+		duty.DutyKey = existing.DutyKey
+	}
+
+	_, err = s.saveNewVersion(ctx, *existing, duty, nil)
+	return err
 }
 
-// DELETE delete a duty
+// DELETE soft delete a duty: it is kept (with its versions) until the retention job purges it, and can be restored until then
 func (s *DutyService) DeleteDuty(ctx context.Context, partitionKey, rowKey string) error {
-	return s.repo.DeleteDuty(ctx, partitionKey, rowKey)
+	duty, err := s.repo.GetDutyById(ctx, partitionKey, rowKey)
+	if err != nil {
+		return err
+	}
+	if duty.IsDeleted() { // deleting twice keeps the first deletion, so the retention period doesn't start over
+		return nil
+	}
+
+	now := time.Now().UTC()
+	return s.repo.SetDutyDeleted(ctx, partitionKey, rowKey, &now, auth.SubjectFromContext(ctx))
+}
+
+// POST attach an image, video or PDF to a duty (images are re-encoded like photos; the uploader comes from the context)
+func (s *DutyService) AddDutyAttachment(ctx context.Context, partitionKey, rowKey string, title string, fileName string, file multipart.File) (*models.DutyAttachment, error) {
+	duty, err := s.repo.GetDutyById(ctx, partitionKey, rowKey)
+	if err != nil {
+		return nil, err
+	}
+	if duty.IsDeleted() {
+		return nil, ErrDutyDeleted
+	}
+	if len(duty.Attachments) >= models.MaxDutyAttachments {
+		return nil, ErrTooManyDutyAttachments
+	}
+
+	processed, err := images.ProcessAttachment(file)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := models.DutyAttachment{
+		Id:         uuid.New(),
+		Title:      title,
+		FileName:   fileName,
+		UploadedAt: time.Now().UTC(),
+		UploadedBy: auth.SubjectFromContext(ctx),
+	}
+	attachment, err = s.repo.AddDutyAttachment(ctx, *duty, attachment, processed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+// DELETE an attachment of a duty (with its blobs)
+func (s *DutyService) DeleteDutyAttachment(ctx context.Context, partitionKey, rowKey string, attachmentId uuid.UUID) error {
+	duty, err := s.repo.GetDutyById(ctx, partitionKey, rowKey)
+	if err != nil {
+		return err
+	}
+
+	attachment, ok := duty.Attachment(attachmentId)
+	if !ok {
+		return ErrDutyAttachmentNotFound
+	}
+
+	return s.repo.DeleteDutyAttachment(ctx, *duty, attachment)
+}
+
+// POST restore a soft-deleted duty
+func (s *DutyService) RestoreDuty(ctx context.Context, partitionKey, rowKey string) (*models.Duty, error) {
+	duty, err := s.repo.GetDutyById(ctx, partitionKey, rowKey)
+	if err != nil {
+		return nil, err
+	}
+	if !duty.IsDeleted() {
+		return duty, nil
+	}
+
+	// import/export match templates by DutyKey, so it has to stay unique among the duties that aren't deleted
+	duties, err := s.repo.GetAllDuties(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range models.ActiveDuties(duties) {
+		if other.Key() == duty.Key() && other.RowKey != duty.RowKey {
+			return nil, ErrDutyKeyInUse
+		}
+	}
+
+	if err := s.repo.SetDutyDeleted(ctx, partitionKey, rowKey, nil, ""); err != nil {
+		return nil, err
+	}
+
+	duty.DeletedAt, duty.DeletedBy = nil, ""
+	return duty, nil
+}
+
+// GET all duty templates for export, by role and name
+func (s *DutyService) ExportDuties(ctx context.Context) ([]models.DutyTemplate, error) {
+	duties, err := s.repo.GetAllDuties(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	duties = models.ActiveDuties(duties)
+
+	sortDuties(duties)
+
+	templates := make([]models.DutyTemplate, 0, len(duties))
+	for _, duty := range duties {
+		templates = append(templates, duty.Template())
+	}
+
+	return templates, nil
+}
+
+// POST import duty templates: creates new ones and updates existing ones with the same DutyKey.
+// With Prune, templates of the imported roles that aren't in the import are (soft) deleted. Nothing is written on a dry run.
+func (s *DutyService) ImportDuties(ctx context.Context, templates []models.DutyTemplate, options models.DutyImportOptions) (*models.DutyImportResult, error) {
+	var problems []string
+	seen := make(map[string]struct{}, len(templates))
+	importedRoles := make(map[int]struct{})
+
+	for i := range templates {
+		problems = append(problems, templates[i].Validate()...)
+
+		if templates[i].DutyKey == "" {
+			templates[i].DutyKey = models.DeriveDutyKey(templates[i].RoleId, templates[i].DutyName)
+		}
+		if _, duplicate := seen[templates[i].DutyKey]; duplicate {
+			problems = append(problems, fmt.Sprintf("'%s': DutyKey is used more than once", templates[i].DutyKey))
+		}
+		seen[templates[i].DutyKey] = struct{}{}
+		importedRoles[templates[i].RoleId] = struct{}{}
+	}
+
+	existing, err := s.repo.GetAllDuties(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	existing = models.ActiveDuties(existing) // a deleted template with the same key is left alone, the import creates a new one
+	sortDuties(existing)
+
+	existingByKey := make(map[string]models.Duty, len(existing))
+	for _, duty := range existing {
+		if _, duplicate := existingByKey[duty.Key()]; duplicate {
+			problems = append(problems, fmt.Sprintf("'%s': more than one existing duty has this DutyKey, give them unique keys first", duty.Key()))
+		}
+		existingByKey[duty.Key()] = duty
+	}
+
+	if len(problems) > 0 {
+		return nil, &models.DutyImportError{Problems: problems}
+	}
+
+	result := &models.DutyImportResult{
+		DryRun:  options.DryRun,
+		Created: []models.DutyImportChange{},
+		Updated: []models.DutyImportChange{},
+		Deleted: []models.DutyImportChange{},
+	}
+	var creates, updates, deletes []models.Duty
+
+	for _, template := range templates {
+		current, exists := existingByKey[template.DutyKey]
+		if !exists {
+			duty := dutyFromTemplate(template, "Duty", uuid.New())
+			creates = append(creates, duty)
+
+			change := models.DutyImportChange{DutyKey: template.DutyKey, DutyName: template.DutyName}
+			if !options.DryRun {
+				change.RowKey = &duty.RowKey
+			}
+			result.Created = append(result.Created, change)
+			continue
+		}
+
+		fields := changedTemplateFields(current, template)
+		if len(fields) == 0 {
+			result.Unchanged++
+			continue
+		}
+
+		updates = append(updates, dutyFromTemplate(template, current.PartitionKey, current.RowKey))
+		rowKey := current.RowKey
+		result.Updated = append(result.Updated, models.DutyImportChange{DutyKey: template.DutyKey, DutyName: template.DutyName, RowKey: &rowKey, Fields: fields})
+	}
+
+	if options.Prune {
+		for _, duty := range existing {
+			if _, imported := seen[duty.Key()]; imported {
+				continue
+			}
+			if _, roleImported := importedRoles[duty.RoleId]; !roleImported {
+				continue
+			}
+
+			deletes = append(deletes, duty)
+			rowKey := duty.RowKey
+			result.Deleted = append(result.Deleted, models.DutyImportChange{DutyKey: duty.Key(), DutyName: duty.DutyName, RowKey: &rowKey})
+		}
+	}
+
+	if options.DryRun {
+		return result, nil
+	}
+
+	// Table Storage has no transactions across partitions, so a failure can leave the import half applied.
+	// Importing the same file again finishes it, because templates are matched by DutyKey.
+	for _, duty := range creates {
+		if err := s.createDuty(ctx, duty); err != nil {
+			return nil, fmt.Errorf("failed to create duty '%s': %v", duty.DutyKey, err)
+		}
+	}
+	for _, duty := range updates {
+		if _, err := s.saveNewVersion(ctx, existingByKey[duty.DutyKey], duty, nil); err != nil {
+			return nil, fmt.Errorf("failed to update duty '%s': %v", duty.DutyKey, err)
+		}
+	}
+	now := time.Now().UTC()
+	for _, duty := range deletes {
+		if err := s.repo.SetDutyDeleted(ctx, duty.PartitionKey, duty.RowKey.String(), &now, auth.SubjectFromContext(ctx)); err != nil {
+			return nil, fmt.Errorf("failed to delete duty '%s': %v", duty.Key(), err)
+		}
+	}
+
+	return result, nil
+}
+
+// GET all versions of a duty, oldest first
+func (s *DutyService) GetDutyVersions(ctx context.Context, dutyId uuid.UUID) ([]models.DutyVersion, error) {
+	versions, err := s.versionRepo.GetVersions(ctx, dutyId)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+
+	return versions, nil
+}
+
+// GET a single version of a duty
+func (s *DutyService) GetDutyVersion(ctx context.Context, dutyId uuid.UUID, version int) (*models.DutyVersion, error) {
+	return s.versionRepo.GetVersion(ctx, dutyId, version)
+}
+
+// GET the differences between two versions of a duty
+func (s *DutyService) DiffDutyVersions(ctx context.Context, dutyId uuid.UUID, from, to int) (*models.DutyVersionDiff, error) {
+	fromVersion, err := s.versionRepo.GetVersion(ctx, dutyId, from)
+	if err != nil {
+		return nil, err
+	}
+
+	toVersion, err := s.versionRepo.GetVersion(ctx, dutyId, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := models.DiffDutyVersions(*fromVersion, *toVersion)
+	return &diff, nil
+}
+
+// POST roll a duty back to an older version. The old content becomes a new version, so the history stays intact.
+func (s *DutyService) RollbackDuty(ctx context.Context, partitionKey, rowKey string, version int) (*models.Duty, error) {
+	existing, err := s.repo.GetDutyById(ctx, partitionKey, rowKey)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := s.versionRepo.GetVersion(ctx, existing.RowKey, version)
+	if err != nil {
+		return nil, err
+	}
+
+	duty := models.Duty{
+		RoleId:          target.RoleId,
+		DutyName:        target.DutyName,
+		DutyDescription: target.DutyDescription,
+		FormSchema:      target.FormSchema,
+		DueMinutes:      target.DueMinutes,
+		Mandatory:       target.Mandatory,
+		DutyKey:         target.DutyKey,
+		AppliesTo:       target.AppliesTo,
+		Tags:            target.Tags,
+	}
+	saved, err := s.saveNewVersion(ctx, *existing, duty, &version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &saved, nil
+}
+
+// createDuty stores a new duty as version 1
+func (s *DutyService) createDuty(ctx context.Context, duty models.Duty) error {
+	duty.Version = 1
+
+	// the version is written first, so the version a duty points to always exists
+	if err := s.versionRepo.AddVersion(ctx, duty.NewVersion(1, time.Now().UTC(), auth.SubjectFromContext(ctx))); err != nil {
+		return err
+	}
+
+	return s.repo.CreateDuty(ctx, duty)
+}
+
+// saveNewVersion stores the new content of an existing duty as its next version, makes it current and returns the saved duty.
+// Duties from before versioning first get their current content recorded as version 1.
+func (s *DutyService) saveNewVersion(ctx context.Context, existing models.Duty, duty models.Duty, rolledBackFrom *int) (models.Duty, error) {
+	if existing.IsDeleted() {
+		return models.Duty{}, ErrDutyDeleted
+	}
+
+	duty.PartitionKey, duty.RowKey = existing.PartitionKey, existing.RowKey
+	duty.Translations = existing.Translations // managed with the translation endpoints, kept by updates and rollbacks
+	now := time.Now().UTC()
+
+	// every write is conditional on the duty being unchanged since it was read
+	etag := existing.ETag
+
+	if existing.Version == 0 {
+		var err error
+		if etag, err = s.addVersion(ctx, existing, existing.NewVersion(1, now, ""), etag); err != nil {
+			return models.Duty{}, err
+		}
+		existing.Version = 1
+	}
+
+	duty.Version = existing.Version
+	duty.ETag = etag
+
+	// a new version is only added when something changed (a rollback always adds one, to record it)
+	if rolledBackFrom == nil && len(models.DiffDutyVersions(existing.NewVersion(0, now, ""), duty.NewVersion(0, now, "")).Changes) == 0 {
+		if err := s.repo.UpdateDuty(ctx, duty.PartitionKey, duty.RowKey.String(), duty); err != nil {
+			return models.Duty{}, err
+		}
+		return duty, nil
+	}
+
+	duty.Version++
+	version := duty.NewVersion(duty.Version, now, auth.SubjectFromContext(ctx))
+	version.RolledBackFrom = rolledBackFrom
+
+	var err error
+	if duty.ETag, err = s.addVersion(ctx, existing, version, etag); err != nil {
+		return models.Duty{}, err
+	}
+
+	if err := s.updateClaimedDuty(ctx, &duty); err != nil {
+		return models.Duty{}, err
+	}
+
+	return duty, nil
+}
+
+// addVersion claims the number of a new version on the duty row, so of concurrent updates only one gets it (the
+// others fail with ErrDutyChanged), and then stores the version. Returns the ETag of the duty after the claim.
+// An update that fails after the claim uses the number up: the next update of the duty gets the one after it.
+func (s *DutyService) addVersion(ctx context.Context, duty models.Duty, version models.DutyVersion, etag string) (string, error) {
+	etag, err := s.repo.ClaimDutyVersion(ctx, duty.PartitionKey, duty.RowKey.String(), version.Version, etag)
+	if err != nil {
+		return "", err
+	}
+
+	// a stored version with a claimed number was left by an update from before numbers were claimed
+	err = s.versionRepo.AddVersion(ctx, version)
+	if errors.Is(err, repositories.ErrDutyVersionExists) {
+		return "", repositories.ErrDutyChanged
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return etag, nil
+}
+
+// the number of times the content of a duty is written after claiming its version, when other writes get in between
+const maxClaimedDutyUpdates = 3
+
+// updateClaimedDuty writes the content of a duty whose new version was claimed. Writes that don't claim a version
+// (translations, attachments) can change the duty between the claim and this write; they don't take the version away,
+// so the content is written again on top of them. Only a newer claim makes it fail with ErrDutyChanged.
+func (s *DutyService) updateClaimedDuty(ctx context.Context, duty *models.Duty) error {
+	for attempt := 1; ; attempt++ {
+		err := s.repo.UpdateDuty(ctx, duty.PartitionKey, duty.RowKey.String(), *duty)
+		if !errors.Is(err, repositories.ErrDutyChanged) || attempt == maxClaimedDutyUpdates {
+			return err
+		}
+
+		current, err := s.repo.GetDutyById(ctx, duty.PartitionKey, duty.RowKey.String())
+		if err != nil {
+			return err
+		}
+		if current.Version != duty.Version {
+			return repositories.ErrDutyChanged
+		}
+		duty.ETag = current.ETag
+	}
+}
+
+// dutyFromTemplate creates the duty of an imported template
+func dutyFromTemplate(template models.DutyTemplate, partitionKey string, rowKey uuid.UUID) models.Duty {
+	return models.Duty{
+		PartitionKey:    partitionKey,
+		RowKey:          rowKey,
+		RoleId:          template.RoleId,
+		DutyName:        template.DutyName,
+		DutyDescription: template.DutyDescription,
+		FormSchema:      template.FormSchema,
+		DueMinutes:      template.DueMinutes,
+		Mandatory:       template.Mandatory,
+		DutyKey:         template.DutyKey,
+		AppliesTo:       template.AppliesTo,
+		Tags:            template.Tags,
+	}
+}
+
+// changedTemplateFields lists the fields an imported template changes on an existing duty
+func changedTemplateFields(duty models.Duty, template models.DutyTemplate) []string {
+	var fields []string
+
+	if duty.DutyKey != template.DutyKey { // a duty from before keys existed gets its key stored
+		fields = append(fields, "DutyKey")
+	}
+	if duty.RoleId != template.RoleId {
+		fields = append(fields, "RoleId")
+	}
+	if duty.DutyName != template.DutyName {
+		fields = append(fields, "DutyName")
+	}
+	if duty.DutyDescription != template.DutyDescription {
+		fields = append(fields, "DutyDescription")
+	}
+	if duty.DueMinutes != template.DueMinutes {
+		fields = append(fields, "DueMinutes")
+	}
+	if duty.Mandatory != template.Mandatory {
+		fields = append(fields, "Mandatory")
+	}
+	if !duty.AppliesTo.Equal(template.AppliesTo) {
+		fields = append(fields, "AppliesTo")
+	}
+	if !models.EqualTags(duty.Tags, template.Tags) {
+		fields = append(fields, "Tags")
+	}
+	// compared as stored, so a missing and an empty list of options are the same
+	currentSchema, _ := json.Marshal(duty.FormSchema)
+	importedSchema, _ := json.Marshal(template.FormSchema)
+	if (len(duty.FormSchema) > 0 || len(template.FormSchema) > 0) && !bytes.Equal(currentSchema, importedSchema) {
+		fields = append(fields, "FormSchema")
+	}
+
+	return fields
+}
+
+// sortDuties orders duties by role and name
+func sortDuties(duties []models.Duty) {
+	sort.SliceStable(duties, func(i, j int) bool {
+		if duties[i].RoleId != duties[j].RoleId {
+			return duties[i].RoleId < duties[j].RoleId
+		}
+		return duties[i].DutyName < duties[j].DutyName
+	})
 }
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"duty-service/models"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// number of recent events a DutyStream keeps for clients that reconnect
+const DefaultDutyStreamBufferSize = 1000
+
+// events a subscriber can lag behind before it is dropped (it then reconnects and catches up from the buffer)
+const dutyStreamSubscriberBuffer = 64
+
+// DutyStream is the in-process pub/sub of duty assignment changes behind GET /duties/stream. It keeps the most
+// recent events in a ring buffer, so a client that reconnects with its Last-Event-ID gets what it missed.
+type DutyStream struct {
+	mu          sync.Mutex
+	epoch       string            // start of this process, part of every ID so IDs of an earlier run aren't replayed
+	seq         uint64            // sequence number of the last published event
+	buffer      []dutyStreamEntry // ring buffer of the most recent events
+	next        int               // position the next event is written to
+	count       int               // number of events in the buffer
+	subscribers map[*DutyStreamSubscription]struct{}
+}
+
+type dutyStreamEntry struct {
+	seq   uint64
+	event models.DutyStreamEvent
+}
+
+// DutyStreamSubscription receives the events of a DutyStream that match its filter
+type DutyStreamSubscription struct {
+	Replay []models.DutyStreamEvent      // buffered events after the Last-Event-ID, to send before Events
+	Missed bool                          // the Last-Event-ID is no longer buffered (or from an earlier run): the client has to reload
+	LastId string                        // ID of the last published event, to resume from after a reload
+	Events <-chan models.DutyStreamEvent // new events; closed when the subscriber falls too far behind
+	events chan models.DutyStreamEvent
+	filter models.DutyStreamFilter
+	stream *DutyStream
+}
+
+func NewDutyStream(bufferSize int) *DutyStream {
+	if bufferSize <= 0 {
+		bufferSize = DefaultDutyStreamBufferSize
+	}
+
+	return &DutyStream{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		buffer:      make([]dutyStreamEntry, bufferSize),
+		subscribers: make(map[*DutyStreamSubscription]struct{}),
+	}
+}
+
+// Publish assigns the event its ID, buffers it and sends it to the matching subscribers
+func (s *DutyStream) Publish(event models.DutyStreamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	event.Id = s.eventId(s.seq)
+	s.buffer[s.next] = dutyStreamEntry{seq: s.seq, event: event}
+	s.next = (s.next + 1) % len(s.buffer)
+	if s.count < len(s.buffer) {
+		s.count++
+	}
+
+	for subscription := range s.subscribers {
+		if !subscription.filter.Matches(event) {
+			continue
+		}
+
+		// a slow client must not hold up the duty updates: it is dropped and resumes from the buffer
+		select {
+		case subscription.events <- event:
+		default:
+			s.remove(subscription)
+		}
+	}
+}
+
+// Subscribe starts receiving the events matching the filter. With a Last-Event-ID, the buffered events after it
+// are returned in Replay.
+func (s *DutyStream) Subscribe(filter models.DutyStreamFilter, lastEventId string) *DutyStreamSubscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make(chan models.DutyStreamEvent, dutyStreamSubscriberBuffer)
+	subscription := &DutyStreamSubscription{
+		LastId: s.eventId(s.seq),
+		Events: events,
+		events: events,
+		filter: filter,
+		stream: s,
+	}
+	s.subscribers[subscription] = struct{}{}
+
+	if lastEventId == "" {
+		return subscription
+	}
+
+	lastSeq, ok := s.parseEventId(lastEventId)
+	oldestSeq := s.seq - uint64(s.count) + 1
+	if !ok || lastSeq > s.seq || lastSeq+1 < oldestSeq {
+		subscription.Missed = true
+		return subscription
+	}
+
+	for i := 0; i < s.count; i++ {
+		entry := s.buffer[(s.next-s.count+i+len(s.buffer))%len(s.buffer)]
+		if entry.seq > lastSeq && filter.Matches(entry.event) {
+			subscription.Replay = append(subscription.Replay, entry.event)
+		}
+	}
+
+	return subscription
+}
+
+// Close stops the subscription
+func (sub *DutyStreamSubscription) Close() {
+	sub.stream.mu.Lock()
+	defer sub.stream.mu.Unlock()
+
+	sub.stream.remove(sub)
+}
+
+// remove unregisters a subscription and closes its channel (the lock must be held)
+func (s *DutyStream) remove(subscription *DutyStreamSubscription) {
+	if _, ok := s.subscribers[subscription]; !ok {
+		return
+	}
+	delete(s.subscribers, subscription)
+	close(subscription.events)
+}
+
+func (s *DutyStream) eventId(seq uint64) string {
+	return fmt.Sprintf("%s-%d", s.epoch, seq)
+}
+
+// parseEventId returns the sequence number of an event ID of this run
+func (s *DutyStream) parseEventId(id string) (uint64, bool) {
+	epoch, seq, found := strings.Cut(id, "-")
+	if !found || epoch != s.epoch {
+		return 0, false
+	}
+
+	parsed, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return parsed, true
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"duty-service/images"
+	"duty-service/models"
+	"duty-service/repositories"
+	"errors"
+	"log"
+	"mime/multipart"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// the maximum number of changes in one sync
+const MaxSyncChanges = 500
+
+// how long a change stays reserved by the sync applying it; a reservation left by a sync that failed halfway is taken
+// over after this
+const syncReservationExpiry = 5 * time.Minute
+
+// DutySyncService applies the duty assignment changes the crew app queued while offline
+type DutySyncService struct {
+	repo        repositories.InterfaceDutyAssignmentRepository
+	syncRepo    repositories.InterfaceDutySyncRepository
+	assignments *DutyAssignmentService // applies the changes like online updates (validation, photos, events)
+}
+
+func NewDutySyncService(repo repositories.InterfaceDutyAssignmentRepository, syncRepo repositories.InterfaceDutySyncRepository, assignments *DutyAssignmentService) *DutySyncService {
+	return &DutySyncService{
+		repo:        repo,
+		syncRepo:    syncRepo,
+		assignments: assignments,
+	}
+}
+
+// dutyAssignmentKey identifies a duty assignment across shifts
+type dutyAssignmentKey struct {
+	shiftId uuid.UUID
+	dutyId  uuid.UUID
+}
+
+// Sync applies the changes in order, each at most once, and returns their results together with the assignments of
+// the shifts that changed since the request's sync token. Photos are the multipart file parts the changes name.
+func (s *DutySyncService) Sync(ctx context.Context, request models.DutySyncRequest, photos map[string]multipart.File) (*models.DutySyncResponse, error) {
+	since, err := models.ParseSyncToken(request.SyncToken)
+	if err != nil {
+		return nil, err
+	}
+
+	conflictMode := request.ConflictMode
+	if conflictMode == "" {
+		conflictMode = models.SyncLastWriterWins
+	}
+
+	// UpdatedAt of the assignments before this sync, so a batch with several changes of an assignment doesn't
+	// conflict with itself
+	before := make(map[dutyAssignmentKey]*time.Time)
+
+	response := &models.DutySyncResponse{
+		Results: make([]models.DutySyncChangeResult, 0, len(request.Changes)),
+		Changed: []models.DutyAssignment{},
+	}
+	for _, change := range request.Changes {
+		response.Results = append(response.Results, s.syncChange(ctx, change, photos, conflictMode, before))
+	}
+
+	shiftIds := make([]uuid.UUID, 0, len(request.ShiftIds))
+	seen := make(map[uuid.UUID]struct{})
+	for _, shiftId := range request.ShiftIds {
+		if _, exists := seen[shiftId]; !exists {
+			seen[shiftId] = struct{}{}
+			shiftIds = append(shiftIds, shiftId)
+		}
+	}
+	for _, change := range request.Changes {
+		if _, exists := seen[change.ShiftId]; !exists && change.ShiftId != uuid.Nil {
+			seen[change.ShiftId] = struct{}{}
+			shiftIds = append(shiftIds, change.ShiftId)
+		}
+	}
+
+	changed, err := s.repo.GetDutyAssignmentsChangedSince(ctx, shiftIds, since)
+	if err != nil {
+		return nil, err
+	}
+
+	// the next sync continues after the latest write returned now
+	latest := since
+	for _, dutyAssignment := range changed {
+		if dutyAssignment.ModifiedAt.After(latest) {
+			latest = dutyAssignment.ModifiedAt
+		}
+	}
+	if changed != nil {
+		response.Changed = changed
+	}
+	response.SyncToken = models.NewSyncToken(latest)
+
+	return response, nil
+}
+
+// syncChange applies a change unless it was synced before, and stores its result once it is final. The change is
+// reserved first, so a change sent by two syncs at the same time is applied by one of them.
+func (s *DutySyncService) syncChange(ctx context.Context, change models.DutySyncChange, photos map[string]multipart.File, conflictMode models.SyncConflictMode, before map[dutyAssignmentKey]*time.Time) models.DutySyncChangeResult {
+	result := models.DutySyncChangeResult{
+		ClientChangeId: change.ClientChangeId,
+		ShiftId:        change.ShiftId,
+		DutyId:         change.DutyId,
+		SyncedAt:       time.Now().UTC(),
+	}
+
+	// without these the change can't be recorded, so it's rejected without storing the result
+	if change.ClientChangeId == uuid.Nil || change.ShiftId == uuid.Nil || change.DutyId == uuid.Nil {
+		result.Status = models.SyncRejected
+		result.Error = "ClientChangeId, ShiftId and DutyId are required"
+		return result
+	}
+
+	stored, err := s.syncRepo.ReserveSyncChange(ctx, change.ShiftId, change.ClientChangeId, change.DutyId, result.SyncedAt, syncReservationExpiry)
+	if err != nil {
+		result.Status = models.SyncFailed
+		result.Error = err.Error()
+		return result
+	}
+	if stored != nil {
+		stored.Duplicate = true
+		return *stored
+	}
+
+	s.applyChange(ctx, change, photos, conflictMode, before, &result)
+
+	// a failed change is released, so it can be sent again
+	if !result.IsFinal() {
+		if err := s.syncRepo.ReleaseSyncChange(ctx, change.ShiftId, change.ClientChangeId); err != nil {
+			log.Printf("Failed to release synced change %s: %v", change.ClientChangeId, err)
+		}
+		return result
+	}
+
+	// the change is already applied, so a failure to record it is logged instead of asking for it again
+	if err := s.syncRepo.SaveSyncResult(ctx, result); err != nil {
+		log.Printf("Failed to store the result of synced change %s: %v", change.ClientChangeId, err)
+	}
+
+	return result
+}
+
+// applyChange applies a change to its duty assignment unless the server has a later or (when reporting) a
+// concurrent change of it
+func (s *DutySyncService) applyChange(ctx context.Context, change models.DutySyncChange, photos map[string]multipart.File, conflictMode models.SyncConflictMode, before map[dutyAssignmentKey]*time.Time, result *models.DutySyncChangeResult) {
+	reject := func(message string) {
+		result.Status = models.SyncRejected
+		result.Error = message
+	}
+
+	if change.ClientTimestamp.IsZero() {
+		reject("ClientTimestamp is required")
+		return
+	}
+	if change.DutyAssignmentStatus != "" && !models.ValidateDutyAssignmentStatus(change.DutyAssignmentStatus) {
+		reject("Invalid DutyAssignmentStatus. Valid values are 'Completed', 'Incomplete' or 'Skipped'.")
+		return
+	}
+
+	var file multipart.File
+	if change.Photo != "" {
+		var ok bool
+		if file, ok = photos[change.Photo]; !ok {
+			reject("missing photo part '" + change.Photo + "'")
+			return
+		}
+	}
+
+	existing, err := s.repo.GetDutyAssignment(ctx, change.ShiftId, change.DutyId)
+	if errors.Is(err, repositories.ErrDutyAssignmentNotFound) {
+		reject(err.Error())
+		return
+	}
+	if err != nil {
+		result.Status = models.SyncFailed
+		result.Error = err.Error()
+		return
+	}
+
+	key := dutyAssignmentKey{shiftId: change.ShiftId, dutyId: change.DutyId}
+	baseline, touched := before[key]
+	if !touched {
+		baseline = existing.UpdatedAt
+		before[key] = baseline
+	}
+
+	// UpdatedAt is stored with second precision
+	changedAt := change.ClientTimestamp.UTC().Truncate(time.Second)
+	switch {
+	case existing.UpdatedAt != nil && changedAt.Before(*existing.UpdatedAt):
+		// the assignment has a later change
+		result.Status = models.SyncStale
+		if conflictMode == models.SyncReportConflicts {
+			result.Status = models.SyncConflict
+		}
+		result.DutyAssignment = existing
+		return
+
+	case conflictMode == models.SyncReportConflicts && baseline != nil && (change.BaseUpdatedAt == nil || baseline.After(*change.BaseUpdatedAt)):
+		// changed on the server since the device last saw the assignment
+		result.Status = models.SyncConflict
+		result.DutyAssignment = existing
+		return
+	}
+
+	// a device clock that runs ahead must not make its changes win over all later ones
+	if now := time.Now().UTC(); changedAt.After(now) {
+		changedAt = now
+	}
+
+	update := models.DutyAssignment{
+		PartitionKey:         change.ShiftId,
+		RowKey:               change.DutyId,
+		DutyAssignmentStatus: change.DutyAssignmentStatus,
+		DutyAssignmentNote:   change.DutyAssignmentNote,
+		FormValues:           change.FormValues,
+	}
+	if update.DutyAssignmentStatus == "" {
+		update.DutyAssignmentStatus = existing.DutyAssignmentStatus
+	}
+
+	if _, err := s.assignments.applyUpdate(ctx, *existing, update, file, changedAt); err != nil {
+		var formErr *models.FormValidationError
+		if errors.As(err, &formErr) || errors.Is(err, images.ErrUnsupportedImage) || errors.Is(err, images.ErrInvalidImage) || errors.Is(err, ErrDutyAssignmentFrozen) {
+			reject(err.Error())
+			return
+		}
+		result.Status = models.SyncFailed
+		result.Error = err.Error()
+		return
+	}
+
+	result.Status = models.SyncApplied
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"duty-service/models"
+	"duty-service/repositories"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrDutyTranslationNotFound is returned when a duty has no translation in a language
+var ErrDutyTranslationNotFound = errors.New("the duty has no translation in this language")
+
+type DutyTranslationService struct {
+	repo            repositories.InterfaceDutyRepository
+	versionRepo     repositories.InterfaceDutyVersionRepository // the current version of a duty is translated along with it
+	defaultLanguage string                                      // language the duties themselves are written in
+	languages       []string                                    // languages every duty should be translated into
+}
+
+func NewDutyTranslationService(repo repositories.InterfaceDutyRepository, versionRepo repositories.InterfaceDutyVersionRepository, defaultLanguage string, languages []string) *DutyTranslationService {
+	return &DutyTranslationService{
+		repo:            repo,
+		versionRepo:     versionRepo,
+		defaultLanguage: defaultLanguage,
+		languages:       languages,
+	}
+}
+
+// PUT adds or replaces the translation of a duty in a language and returns the duty
+func (s *DutyTranslationService) SetDutyTranslation(ctx context.Context, partitionKey, rowKey string, language string, translation models.DutyTranslation) (*models.Duty, error) {
+	language, err := s.translatableLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+	if err := translation.Validate(); err != nil {
+		return nil, err
+	}
+
+	duty, err := s.repo.GetDutyById(ctx, partitionKey, rowKey)
+	if err != nil {
+		return nil, err
+	}
+	if duty.IsDeleted() {
+		return nil, ErrDutyDeleted
+	}
+
+	translations := make(models.DutyTranslations, len(duty.Translations)+1)
+	for lang, existing := range duty.Translations {
+		translations[lang] = existing
+	}
+	translations[language] = translation
+
+	if err := s.setTranslations(ctx, *duty, translations); err != nil {
+		return nil, err
+	}
+
+	duty.Translations = translations
+	return duty, nil
+}
+
+// DELETE removes the translation of a duty in a language
+func (s *DutyTranslationService) DeleteDutyTranslation(ctx context.Context, partitionKey, rowKey string, language string) error {
+	language, err := s.translatableLanguage(language)
+	if err != nil {
+		return err
+	}
+
+	duty, err := s.repo.GetDutyById(ctx, partitionKey, rowKey)
+	if err != nil {
+		return err
+	}
+	if _, ok := duty.Translations[language]; !ok {
+		return ErrDutyTranslationNotFound
+	}
+
+	translations := make(models.DutyTranslations, len(duty.Translations))
+	for lang, existing := range duty.Translations {
+		if lang != language {
+			translations[lang] = existing
+		}
+	}
+
+	return s.setTranslations(ctx, *duty, translations)
+}
+
+// setTranslations replaces the translations of a duty and of its current version, which assignments created from now
+// on are shown in. It fails with ErrDutyChanged when the duty was changed since it was read, so a concurrent update
+// can't add a version without them.
+func (s *DutyTranslationService) setTranslations(ctx context.Context, duty models.Duty, translations models.DutyTranslations) error {
+	if err := s.repo.SetDutyTranslations(ctx, duty.PartitionKey, duty.RowKey.String(), duty.ETag, translations); err != nil {
+		return err
+	}
+
+	// duties from before versioning have no version to translate
+	if duty.Version == 0 {
+		return nil
+	}
+	// an update claimed the version but didn't store it yet: saving the translation again translates it
+	err := s.versionRepo.SetVersionTranslations(ctx, duty.RowKey, duty.Version, translations)
+	if errors.Is(err, repositories.ErrDutyVersionNotFound) {
+		return repositories.ErrDutyChanged
+	}
+	return err
+}
+
+// GET the duties that aren't (completely) translated into the languages, by role and name. Without languages the
+// configured ones are checked, or when none are configured every language at least one duty was translated into.
+func (s *DutyTranslationService) GetMissingTranslations(ctx context.Context, languages []string) ([]models.MissingDutyTranslation, error) {
+	if len(languages) == 0 {
+		languages = s.languages
+	}
+	normalized := make([]string, 0, len(languages))
+	for _, language := range languages {
+		tag, err := models.NormalizeLanguageTag(language)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, tag)
+	}
+	languages = normalized
+
+	duties, err := s.repo.GetAllDuties(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	duties = models.ActiveDuties(duties)
+
+	if len(languages) == 0 {
+		languages = translatedLanguages(duties)
+	}
+
+	missing := []models.MissingDutyTranslation{}
+	for _, duty := range duties {
+		missingLanguages := duty.MissingLanguages(languages, s.defaultLanguage)
+		if len(missingLanguages) == 0 {
+			continue
+		}
+		missing = append(missing, models.MissingDutyTranslation{
+			DutyPartitionKey: duty.PartitionKey,
+			DutyRowKey:       duty.RowKey,
+			DutyKey:          duty.Key(),
+			DutyName:         duty.DutyName,
+			RoleId:           duty.RoleId,
+			Languages:        missingLanguages,
+		})
+	}
+
+	sort.SliceStable(missing, func(i, j int) bool {
+		if missing[i].RoleId != missing[j].RoleId {
+			return missing[i].RoleId < missing[j].RoleId
+		}
+		return missing[i].DutyName < missing[j].DutyName
+	})
+	return missing, nil
+}
+
+// translatableLanguage normalizes a language tag and rejects the default language, whose text is the duty's own
+func (s *DutyTranslationService) translatableLanguage(language string) (string, error) {
+	language, err := models.NormalizeLanguageTag(language)
+	if err != nil {
+		return "", err
+	}
+	if language == s.defaultLanguage {
+		return "", fmt.Errorf("%w: '%s' is the language of the duty itself: update the duty instead", models.ErrInvalidDutyTranslation, language)
+	}
+	return language, nil
+}
+
+// translatedLanguages returns every language at least one of the duties has a translation in, sorted
+func translatedLanguages(duties []models.Duty) []string {
+	seen := map[string]struct{}{}
+	languages := []string{}
+	for _, duty := range duties {
+		for language := range duty.Translations {
+			if _, ok := seen[language]; !ok {
+				seen[language] = struct{}{}
+				languages = append(languages, language)
+			}
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
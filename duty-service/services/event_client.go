@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"duty-service/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrEventNotFound is returned when the event service knows no event for a shift
+var ErrEventNotFound = errors.New("no event found for shift")
+
+// how long a request to the event service may take (clock-in shouldn't wait on a slow event service)
+const eventClientTimeout = 5 * time.Second
+
+// EventClient looks up events in the event service
+type EventClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewEventClient(baseURL string) *EventClient {
+	return &EventClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: eventClientTimeout},
+	}
+}
+
+// GET the event a shift belongs to
+func (c *EventClient) GetEventByShiftId(ctx context.Context, shiftId uuid.UUID) (*models.Event, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/events/"+shiftId.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event request: %v", err)
+	}
+	req.Header.Set("X-From-Gateway", "true") // the event service only accepts requests that come through the gateway
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch event: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrEventNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch event: event service returned %s", resp.Status)
+	}
+
+	var event models.Event
+	if err := json.NewDecoder(resp.Body).Decode(&event); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %v", err)
+	}
+
+	return &event, nil
+}
@@ -0,0 +1,11 @@
+package services
+
+import (
+	"context"
+	"duty-service/models"
+	"time"
+)
+
+type InterfaceDutyAnalyticsService interface {
+	GetAnalytics(ctx context.Context, from, to time.Time) (*models.DutyAnalytics, error)
+}
@@ -0,0 +1,17 @@
+package services
+
+import (
+	"context"
+	"duty-service/models"
+	"mime/multipart"
+
+	"github.com/google/uuid"
+)
+
+type InterfaceDutyAssignmentCommentService interface {
+	GetComments(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentComment, error)
+	GetComment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, commentId uuid.UUID) (*models.DutyAssignmentComment, error)
+	AddComment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, text string, files []multipart.File) (*models.DutyAssignmentComment, error)
+	UpdateComment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, commentId uuid.UUID, text string) (*models.DutyAssignmentComment, error)
+	DeleteComment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, commentId uuid.UUID) error
+}
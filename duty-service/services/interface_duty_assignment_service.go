@@ -4,13 +4,27 @@ import (
 	"context"
 	"duty-service/models"
 	"mime/multipart"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type InterfaceDutyAssignmentService interface {
 	GetAllDutyAssignmentsByShiftId(ctx context.Context, shiftId uuid.UUID) ([]models.DutyAssignment, error)
-	CreateDutyAssignments(ctx context.Context, shiftId uuid.UUID, roleId int) error
+	GetDutyAssignmentsByAssignee(ctx context.Context, employeeId string, shiftId *uuid.UUID) ([]models.DutyAssignment, error)
+	LocalizeDutyAssignments(ctx context.Context, dutyAssignments []models.DutyAssignment) ([]models.DutyAssignment, error)
+	CreateDutyAssignments(ctx context.Context, shiftId uuid.UUID, roleId int, clockInTime time.Time) error
 	UpdateDutyAssignment(ctx context.Context, dutyAssignment models.DutyAssignment, file multipart.File) error
+	ReassignDutyAssignment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, assigneeId string, note *string) (*models.DutyAssignment, error)
+	GetDutyAssignmentHistory(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentHistoryEntry, error)
+	GetDutyAssignmentPhotos(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentPhoto, error)
+	DeleteDutyAssignmentPhoto(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, photoId uuid.UUID) error
+	GetPhotoReviewQueue(ctx context.Context) ([]models.PhotoReviewItem, error)
+	ReviewDutyAssignmentPhoto(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, photoId uuid.UUID, review models.PhotoReview) (*models.DutyAssignmentPhoto, error)
+	GetDutyAssignmentImageUrl(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, thumbnail bool) (string, error)
 	DeleteDutyAssignment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) error
+	GetDeletedDutyAssignments(ctx context.Context, shiftId uuid.UUID) ([]models.DutyAssignment, error)
+	RestoreDutyAssignment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) (*models.DutyAssignment, error)
+	CanClockOut(ctx context.Context, shiftId uuid.UUID) (*models.CanClockOutResult, error)
+	ClockOut(ctx context.Context, shiftId uuid.UUID, clockOutTime time.Time) (*models.ShiftDutySummaryMessage, error)
 }
@@ -0,0 +1,12 @@
+package services
+
+import (
+	"context"
+	"duty-service/models"
+
+	"github.com/google/uuid"
+)
+
+type InterfaceDutyChecklistService interface {
+	GetDutyChecklist(ctx context.Context, shiftId uuid.UUID) (*models.DutyChecklist, error)
+}
@@ -0,0 +1,15 @@
+package services
+
+import (
+	"context"
+	"duty-service/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InterfaceDutyReportService interface {
+	GetShiftReport(ctx context.Context, shiftId uuid.UUID) (*models.DutyReport, error)
+	GetShiftsReport(ctx context.Context, shiftIds []uuid.UUID) (*models.DutyReport, error)
+	GetDateRangeReport(ctx context.Context, from, to time.Time) (*models.DutyReport, error)
+}
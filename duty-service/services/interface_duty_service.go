@@ -3,13 +3,25 @@ package services
 import (
 	"context"
 	"duty-service/models"
+	"mime/multipart"
+
+	"github.com/google/uuid"
 )
 
 type InterfaceDutyService interface {
-	GetAllDuties(ctx context.Context, name string) ([]models.Duty, error)
+	GetAllDuties(ctx context.Context, search models.DutySearch) (*models.DutySearchResult, error)
 	GetDutyById(ctx context.Context, partitionKey, rowKey string) (*models.Duty, error)
 	GetDutiesByRole(ctx context.Context, roleId int) ([]models.Duty, error)
 	CreateDuty(ctx context.Context, duty models.Duty) error
 	UpdateDuty(ctx context.Context, partitionKey, rowKey string, duty models.Duty) error
 	DeleteDuty(ctx context.Context, partitionKey, rowKey string) error
+	RestoreDuty(ctx context.Context, partitionKey, rowKey string) (*models.Duty, error)
+	AddDutyAttachment(ctx context.Context, partitionKey, rowKey string, title string, fileName string, file multipart.File) (*models.DutyAttachment, error)
+	DeleteDutyAttachment(ctx context.Context, partitionKey, rowKey string, attachmentId uuid.UUID) error
+	ExportDuties(ctx context.Context) ([]models.DutyTemplate, error)
+	ImportDuties(ctx context.Context, templates []models.DutyTemplate, options models.DutyImportOptions) (*models.DutyImportResult, error)
+	GetDutyVersions(ctx context.Context, dutyId uuid.UUID) ([]models.DutyVersion, error)
+	GetDutyVersion(ctx context.Context, dutyId uuid.UUID, version int) (*models.DutyVersion, error)
+	DiffDutyVersions(ctx context.Context, dutyId uuid.UUID, from, to int) (*models.DutyVersionDiff, error)
+	RollbackDuty(ctx context.Context, partitionKey, rowKey string, version int) (*models.Duty, error)
 }
@@ -0,0 +1,10 @@
+package services
+
+import (
+	"duty-service/models"
+)
+
+type InterfaceDutyStream interface {
+	Publish(event models.DutyStreamEvent)
+	Subscribe(filter models.DutyStreamFilter, lastEventId string) *DutyStreamSubscription
+}
@@ -0,0 +1,11 @@
+package services
+
+import (
+	"context"
+	"duty-service/models"
+	"mime/multipart"
+)
+
+type InterfaceDutySyncService interface {
+	Sync(ctx context.Context, request models.DutySyncRequest, photos map[string]multipart.File) (*models.DutySyncResponse, error)
+}
@@ -0,0 +1,12 @@
+package services
+
+import (
+	"context"
+	"duty-service/models"
+)
+
+type InterfaceDutyTranslationService interface {
+	SetDutyTranslation(ctx context.Context, partitionKey, rowKey string, language string, translation models.DutyTranslation) (*models.Duty, error)
+	DeleteDutyTranslation(ctx context.Context, partitionKey, rowKey string, language string) error
+	GetMissingTranslations(ctx context.Context, languages []string) ([]models.MissingDutyTranslation, error)
+}
@@ -0,0 +1,12 @@
+package services
+
+import (
+	"context"
+	"duty-service/models"
+
+	"github.com/google/uuid"
+)
+
+type InterfaceEventClient interface {
+	GetEventByShiftId(ctx context.Context, shiftId uuid.UUID) (*models.Event, error)
+}
@@ -0,0 +1,6 @@
+package services
+
+type InterfaceMessagePublisher interface {
+	PublishMessage(queueName string, message interface{}) error
+	PublishToExchange(exchange string, routingKey string, message interface{}) error
+}
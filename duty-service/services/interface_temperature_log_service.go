@@ -0,0 +1,15 @@
+package services
+
+import (
+	"context"
+	"duty-service/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InterfaceTemperatureLogService interface {
+	GetReadingsByShiftId(ctx context.Context, shiftId uuid.UUID) ([]models.TemperatureReading, error)
+	RecordReading(ctx context.Context, reading models.TemperatureReading) (models.TemperatureReading, error)
+	GetReport(ctx context.Context, eventId uuid.UUID, from, to time.Time) ([]models.TemperatureReading, error)
+}
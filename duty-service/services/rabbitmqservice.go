@@ -2,97 +2,266 @@ package services
 
 import (
 	"context"
+	"duty-service/auth"
 	"duty-service/models"
 	"encoding/json"
+	"fmt"
 	"log"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// queues the duty service consumes (clockIn is the QueueName of the service)
+const (
+	ClockInQueue     = "clockIn"
+	ClockOutQueue    = "clockOut"
+	CanClockOutQueue = "canClockOut" // RPC: the answer is sent to the request's reply_to queue
+)
+
 type RabbitMQService struct {
-	Connection  *amqp.Connection
-	Channel     *amqp.Channel
-	QueueName   string
-	DutyService *DutyAssignmentService
+	Connection     *amqp.Connection
+	Channel        *amqp.Channel
+	PublishChannel *amqp.Channel // separate channel so publishing doesn't interfere with consumer acks
+	QueueName      string
+	DutyService    *DutyAssignmentService
 }
 
-func NewRabbitMQService(
-	dutyService *DutyAssignmentService,
-	connection *amqp.Connection,
-) *RabbitMQService {
+func NewRabbitMQService(connection *amqp.Connection) *RabbitMQService {
 	// Create channel
 	ch, err := connection.Channel()
 	if err != nil {
 		log.Fatalf("Failed to open a channel: %v", err)
 	}
 
-	// Declare queue
-	q, err := ch.QueueDeclare(
-		"clockIn", // queue name
-		true,      // durable
-		false,     // delete when unused
-		false,     // exclusive
-		false,     // no-wait
-		nil,       // arguments
+	publishCh, err := connection.Channel()
+	if err != nil {
+		log.Fatalf("Failed to open a publish channel: %v", err)
+	}
+
+	// Declare queues
+	for _, queueName := range []string{ClockInQueue, ClockOutQueue, CanClockOutQueue} {
+		_, err = ch.QueueDeclare(
+			queueName, // queue name
+			true,      // durable
+			false,     // delete when unused
+			false,     // exclusive
+			false,     // no-wait
+			nil,       // arguments
+		)
+		if err != nil {
+			log.Fatalf("Failed to declare queue %s: %v", queueName, err)
+		}
+	}
+
+	// topic exchange of the duty lifecycle events, consumers bind their own queues to it
+	err = publishCh.ExchangeDeclare(
+		DutyEventsExchange, // exchange name
+		"topic",            // type
+		true,               // durable
+		false,              // auto-deleted
+		false,              // internal
+		false,              // no-wait
+		nil,                // arguments
 	)
 	if err != nil {
-		log.Fatalf("Failed to declare queue: %v", err)
+		log.Fatalf("Failed to declare exchange: %v", err)
 	}
 
-	rmqService := &RabbitMQService{
-		Connection:  connection,
-		Channel:     ch,
-		QueueName:   q.Name,
-		DutyService: dutyService,
+	return &RabbitMQService{
+		Connection:     connection,
+		Channel:        ch,
+		PublishChannel: publishCh,
+		QueueName:      ClockInQueue,
 	}
+}
 
-	// Start consuming messages
-	rmqService.StartConsuming()
+// StartConsuming handles the clock-in, clock-out and can-clock-out messages using the given service
+func (s *RabbitMQService) StartConsuming(dutyService *DutyAssignmentService) {
+	s.DutyService = dutyService
 
-	return rmqService
+	s.consume(s.QueueName, s.HandleClockInMessage)
+	s.consume(ClockOutQueue, s.HandleClockOutMessage)
+	s.consume(CanClockOutQueue, s.HandleCanClockOutRequest)
 }
 
-func (s *RabbitMQService) StartConsuming() {
-	// Consume messages
+// consume handles the messages of a queue in the background
+func (s *RabbitMQService) consume(queueName string, handle func(msg amqp.Delivery)) {
 	msgs, err := s.Channel.Consume(
-		s.QueueName, // queue
-		"",          // consumer tag
-		false,       // auto-ack
-		false,       // exclusive
-		false,       // no-local
-		false,       // no-wait
-		nil,         // arguments
+		queueName, // queue
+		"",        // consumer tag
+		false,     // auto-ack
+		false,     // exclusive
+		false,     // no-local
+		false,     // no-wait
+		nil,       // arguments
 	)
 	if err != nil {
-		log.Fatalf("Failed to register a consumer: %v", err)
+		log.Fatalf("Failed to register a consumer for %s: %v", queueName, err)
 	}
 
 	go func() {
 		for msg := range msgs {
-			var clockInMessage models.ClockInMessage
-			err := json.Unmarshal(msg.Body, &clockInMessage)
-			if err != nil {
-				log.Printf("Error unmarshaling message: %v", err)
-				msg.Nack(false, false)
-				continue
-			}
-
-			// Process the message
-			err = s.DutyService.CreateDutyAssignments(context.Background(), clockInMessage.ShiftID, clockInMessage.RoleId)
-			if err != nil {
-				log.Printf("Error creating duty list: %v", err)
-				msg.Nack(false, false)
-				continue
-			}
-
-			// Acknowledge the message
-			msg.Ack(false)
+			handle(msg)
 		}
 	}()
 }
 
+// HandleClockInMessage creates the duty assignments of a clock-in message and acks it. A failed message is requeued
+// once: creating the assignments is idempotent, so the retry only adds what is missing. When it fails again it is dropped.
+func (s *RabbitMQService) HandleClockInMessage(msg amqp.Delivery) {
+	var clockInMessage models.ClockInMessage
+	err := json.Unmarshal(msg.Body, &clockInMessage)
+	if err != nil {
+		log.Printf("Error unmarshaling message: %v", err)
+		msg.Nack(false, false)
+		return
+	}
+
+	// the employee who clocked in is reported as the one the duties were created for
+	ctx := context.Background()
+	if clockInMessage.EmployeeId != "" {
+		ctx = auth.WithIdentity(ctx, auth.Identity{Subject: clockInMessage.EmployeeId})
+	}
+
+	// Process the message
+	err = s.DutyService.CreateDutyAssignments(ctx, clockInMessage.ShiftID, clockInMessage.RoleId, clockInMessage.ClockInTime)
+	if err != nil {
+		requeue := !msg.Redelivered
+		log.Printf("Error creating duty list for shift %s (requeued: %t): %v", clockInMessage.ShiftID, requeue, err)
+		msg.Nack(false, requeue)
+		return
+	}
+
+	// Acknowledge the message
+	msg.Ack(false)
+}
+
+// HandleClockOutMessage freezes the duty assignments of a clocked-out shift and publishes its duty summary.
+// Like a clock-in, a failed message is requeued once (freezing again skips what is already frozen).
+func (s *RabbitMQService) HandleClockOutMessage(msg amqp.Delivery) {
+	var clockOutMessage models.ClockOutMessage
+	if err := json.Unmarshal(msg.Body, &clockOutMessage); err != nil {
+		log.Printf("Error unmarshaling clock-out message: %v", err)
+		msg.Nack(false, false)
+		return
+	}
+
+	ctx := context.Background()
+	if clockOutMessage.EmployeeId != "" {
+		ctx = auth.WithIdentity(ctx, auth.Identity{Subject: clockOutMessage.EmployeeId})
+	}
+
+	if _, err := s.DutyService.ClockOut(ctx, clockOutMessage.ShiftID, clockOutMessage.ClockOutTime); err != nil {
+		requeue := !msg.Redelivered
+		log.Printf("Error freezing the duties of shift %s (requeued: %t): %v", clockOutMessage.ShiftID, requeue, err)
+		msg.Nack(false, requeue)
+		return
+	}
+
+	msg.Ack(false)
+}
+
+// HandleCanClockOutRequest answers a can-clock-out request on its reply_to queue, with the same body as
+// GET /duties/duty-assignments/{ShiftId}/can-clock-out (or {"error": ...} when the check failed)
+func (s *RabbitMQService) HandleCanClockOutRequest(msg amqp.Delivery) {
+	var request models.CanClockOutRequest
+	var reply interface{}
+
+	if err := json.Unmarshal(msg.Body, &request); err != nil {
+		reply = map[string]string{"error": "invalid request: " + err.Error()}
+	} else if result, err := s.DutyService.CanClockOut(context.Background(), request.ShiftID); err != nil {
+		log.Printf("Error checking clock-out of shift %s: %v", request.ShiftID, err)
+		reply = map[string]string{"error": err.Error()}
+	} else {
+		reply = result
+	}
+
+	// a request nobody waits for is only acknowledged
+	if msg.ReplyTo != "" {
+		body, err := json.Marshal(reply)
+		if err == nil {
+			err = s.PublishChannel.Publish(
+				"",          // default exchange
+				msg.ReplyTo, // routing key (reply queue)
+				false,       // mandatory
+				false,       // immediate
+				amqp.Publishing{
+					ContentType:   "application/json",
+					CorrelationId: msg.CorrelationId,
+					Body:          body,
+					Timestamp:     time.Now(),
+				},
+			)
+		}
+		if err != nil {
+			log.Printf("Failed to reply to can-clock-out request of shift %s: %v", request.ShiftID, err)
+		}
+	}
+
+	msg.Ack(false)
+}
+
+// PublishMessage publishes a message as JSON to the given (durable) queue
+func (s *RabbitMQService) PublishMessage(queueName string, message interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+
+	// make sure the queue exists so the message isn't dropped when nobody consumes it yet
+	_, err = s.PublishChannel.QueueDeclare(
+		queueName, // queue name
+		true,      // durable
+		false,     // delete when unused
+		false,     // exclusive
+		false,     // no-wait
+		nil,       // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare queue %s: %v", queueName, err)
+	}
+
+	return s.PublishChannel.Publish(
+		"",        // exchange
+		queueName, // routing key (queue name)
+		false,     // mandatory
+		false,     // immediate
+		amqp.Publishing{
+			ContentType:  "application/json",
+			Body:         body,
+			DeliveryMode: amqp.Persistent,
+			Timestamp:    time.Now(),
+		},
+	)
+}
+
+// PublishToExchange publishes a message as JSON to an exchange with the given routing key
+func (s *RabbitMQService) PublishToExchange(exchange string, routingKey string, message interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+
+	return s.PublishChannel.Publish(
+		exchange,   // exchange
+		routingKey, // routing key
+		false,      // mandatory
+		false,      // immediate
+		amqp.Publishing{
+			ContentType:  "application/json",
+			Body:         body,
+			DeliveryMode: amqp.Persistent,
+			Timestamp:    time.Now(),
+		},
+	)
+}
+
 // Close closes the RabbitMQ connection and channel
 func (s *RabbitMQService) Close() {
+	if s.PublishChannel != nil {
+		s.PublishChannel.Close()
+	}
 	if s.Channel != nil {
 		s.Channel.Close()
 	}
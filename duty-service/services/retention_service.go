@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"duty-service/models"
+	"duty-service/repositories"
+	"log"
+	"time"
+)
+
+// RetentionService purges duties and duty assignments that were soft deleted longer than the retention period ago
+type RetentionService struct {
+	dutyRepo    repositories.InterfaceDutyRepository
+	repo        repositories.InterfaceDutyAssignmentRepository
+	photoRepo   repositories.InterfaceDutyAssignmentPhotoRepository
+	commentRepo repositories.InterfaceDutyAssignmentCommentRepository
+}
+
+func NewRetentionService(dutyRepo repositories.InterfaceDutyRepository, repo repositories.InterfaceDutyAssignmentRepository, photoRepo repositories.InterfaceDutyAssignmentPhotoRepository, commentRepo repositories.InterfaceDutyAssignmentCommentRepository) *RetentionService {
+	return &RetentionService{
+		dutyRepo:    dutyRepo,
+		repo:        repo,
+		photoRepo:   photoRepo,
+		commentRepo: commentRepo,
+	}
+}
+
+// Purge deletes the duties and duty assignments that were soft deleted more than retention ago for good, together with
+// the attachments of the duties and the photos and comments of the assignments (and their blobs). Duty versions and handover history are kept.
+// A row that fails is logged and retried on the next run, so one bad row doesn't stop the others from being purged.
+func (s *RetentionService) Purge(ctx context.Context, retention time.Duration) (*models.PurgeResult, error) {
+	cutoff := time.Now().UTC().Add(-retention)
+	result := &models.PurgeResult{}
+
+	duties, err := s.dutyRepo.GetAllDuties(ctx, repositories.NewODataFilter().Ne("DeletedAt", "").Le("DeletedAt", cutoff).String())
+	if err != nil {
+		return nil, err
+	}
+	for _, duty := range duties {
+		if !duty.IsDeleted() || duty.DeletedAt.After(cutoff) {
+			continue
+		}
+		if err := s.dutyRepo.DeleteDuty(ctx, duty); err != nil {
+			log.Printf("Failed to purge duty %s: %v", duty.RowKey, err)
+			continue
+		}
+		result.Duties++
+		result.DutyAttachments += len(duty.Attachments)
+	}
+
+	dutyAssignments, err := s.repo.GetDeletedDutyAssignments(ctx, nil, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	for _, dutyAssignment := range dutyAssignments {
+		photos, comments, err := s.purgeAttachments(ctx, dutyAssignment)
+		result.Photos += photos
+		result.Comments += comments
+		if err != nil {
+			log.Printf("Failed to purge duty assignment %s of shift %s: %v", dutyAssignment.RowKey, dutyAssignment.PartitionKey, err)
+			continue
+		}
+
+		// the assignment is deleted last, so a failed run still finds it (and what's left of its photos and comments) next time
+		if err := s.repo.DeleteDutyAssignment(ctx, dutyAssignment); err != nil {
+			log.Printf("Failed to purge duty assignment %s of shift %s: %v", dutyAssignment.RowKey, dutyAssignment.PartitionKey, err)
+			continue
+		}
+		result.DutyAssignments++
+	}
+
+	return result, nil
+}
+
+// StartPurging purges right away and then every interval, in the background
+func (s *RetentionService) StartPurging(interval time.Duration, retention time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			result, err := s.Purge(context.Background(), retention)
+			if err != nil {
+				log.Printf("Failed to purge deleted duties: %v", err)
+			} else if result.Duties > 0 || result.DutyAssignments > 0 {
+				log.Printf("Purged %d deleted duties (%d attachments) and %d deleted duty assignments (%d photos, %d comments)", result.Duties, result.DutyAttachments, result.DutyAssignments, result.Photos, result.Comments)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// purgeAttachments deletes the photos and comments of a duty assignment and returns how many were deleted
+func (s *RetentionService) purgeAttachments(ctx context.Context, dutyAssignment models.DutyAssignment) (int, int, error) {
+	var photoCount, commentCount int
+
+	photos, err := s.photoRepo.GetPhotos(ctx, dutyAssignment.PartitionKey, dutyAssignment.RowKey)
+	if err != nil {
+		return photoCount, commentCount, err
+	}
+	for _, photo := range photos {
+		if err := s.photoRepo.DeletePhoto(ctx, photo); err != nil {
+			return photoCount, commentCount, err
+		}
+		photoCount++
+	}
+
+	comments, err := s.commentRepo.GetComments(ctx, dutyAssignment.PartitionKey, dutyAssignment.RowKey)
+	if err != nil {
+		return photoCount, commentCount, err
+	}
+	for _, comment := range comments {
+		if err := s.commentRepo.DeleteComment(ctx, comment); err != nil {
+			return photoCount, commentCount, err
+		}
+		commentCount++
+	}
+
+	return photoCount, commentCount, nil
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"duty-service/models"
+	"duty-service/repositories"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// queue the out-of-range alerts are published to
+const TemperatureAlertQueue = "temperatureAlert"
+
+// ErrCorrectiveActionRequired is returned when an out-of-range reading has no corrective-action note
+var ErrCorrectiveActionRequired = errors.New("temperature is out of the safe range: a corrective action is required")
+
+type TemperatureLogService struct {
+	repo      repositories.InterfaceTemperatureLogRepository
+	publisher InterfaceMessagePublisher
+	ranges    map[models.TemperatureUnitType]models.TemperatureRange
+}
+
+func NewTemperatureLogService(repo repositories.InterfaceTemperatureLogRepository, publisher InterfaceMessagePublisher, ranges map[models.TemperatureUnitType]models.TemperatureRange) *TemperatureLogService {
+	return &TemperatureLogService{
+		repo:      repo,
+		publisher: publisher,
+		ranges:    ranges,
+	}
+}
+
+// GET all readings of a shift
+func (s *TemperatureLogService) GetReadingsByShiftId(ctx context.Context, shiftId uuid.UUID) ([]models.TemperatureReading, error) {
+	return s.repo.GetReadingsByShiftId(ctx, shiftId)
+}
+
+// POST record a reading: checks it against the safe range of its unit type and publishes an alert when it's out of range
+func (s *TemperatureLogService) RecordReading(ctx context.Context, reading models.TemperatureReading) (models.TemperatureReading, error) {
+	// the range is stored with the reading, so reports show what it was checked against even after a config change
+	safeRange := s.SafeRange(reading.UnitType)
+	reading.SafeRange = &safeRange
+	reading.WithinRange = safeRange.Contains(reading.Temperature)
+
+	if !reading.WithinRange && (reading.CorrectiveAction == nil || strings.TrimSpace(*reading.CorrectiveAction) == "") {
+		return models.TemperatureReading{}, ErrCorrectiveActionRequired
+	}
+
+	reading.RowKey = uuid.New()
+	if reading.RecordedAt.IsZero() {
+		reading.RecordedAt = time.Now().UTC()
+	}
+
+	if err := s.repo.CreateReading(ctx, reading); err != nil {
+		return models.TemperatureReading{}, err
+	}
+
+	if !reading.WithinRange && s.publisher != nil {
+		alert := models.TemperatureAlertMessage{
+			ShiftID:          reading.PartitionKey,
+			EventID:          reading.EventId,
+			ReadingID:        reading.RowKey,
+			UnitType:         reading.UnitType,
+			UnitName:         reading.UnitName,
+			Temperature:      reading.Temperature,
+			SafeRange:        safeRange,
+			RecordedAt:       reading.RecordedAt,
+			EmployeeID:       reading.EmployeeId,
+			CorrectiveAction: *reading.CorrectiveAction,
+		}
+
+		// the reading is already stored, so a failed alert is logged instead of failing the request
+		if err := s.publisher.PublishMessage(TemperatureAlertQueue, alert); err != nil {
+			log.Printf("Failed to publish temperature alert for reading %s: %v", reading.RowKey, err)
+		}
+	}
+
+	return reading, nil
+}
+
+// GET the readings of an event within a date range, ordered by time (for inspection reports)
+func (s *TemperatureLogService) GetReport(ctx context.Context, eventId uuid.UUID, from, to time.Time) ([]models.TemperatureReading, error) {
+	readings, err := s.repo.GetReadingsByEventId(ctx, eventId, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(readings, func(i, j int) bool {
+		return readings[i].RecordedAt.Before(readings[j].RecordedAt)
+	})
+
+	return readings, nil
+}
+
+// returns the configured safe range of a unit type
+func (s *TemperatureLogService) SafeRange(unitType models.TemperatureUnitType) models.TemperatureRange {
+	return s.ranges[unitType]
+}
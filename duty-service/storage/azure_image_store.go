@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+)
+
+// AzureImageStore stores images in a private Azure Blob Storage container and returns SAS URLs
+type AzureImageStore struct {
+	client        *azblob.Client
+	containerName string
+}
+
+func NewAzureImageStore(client *azblob.Client, containerName string) *AzureImageStore {
+	return &AzureImageStore{
+		client:        client,
+		containerName: containerName,
+	}
+}
+
+// Upload uploads an image with its content type to the container
+func (s *AzureImageStore) Upload(ctx context.Context, name string, contentType string, data io.Reader) error {
+	// Ensure the container exists (create if not). Without an access level it is private, so images are only readable through SAS URLs
+	_, err := s.client.CreateContainer(ctx, s.containerName, nil)
+	if err != nil && !isContainerExistsError(err) {
+		return fmt.Errorf("failed to create blob container: %v", err)
+	}
+
+	_, err = s.client.UploadStream(ctx, s.containerName, name, data, &azblob.UploadStreamOptions{
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType}, // so browsers display the image instead of downloading it
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload image: %v", err)
+	}
+
+	return nil
+}
+
+// Delete deletes an image from the container
+func (s *AzureImageStore) Delete(ctx context.Context, name string) error {
+	_, err := s.client.DeleteBlob(ctx, s.containerName, name, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+		return fmt.Errorf("failed to delete image: %v", err)
+	}
+
+	return nil
+}
+
+// URL returns a read-only SAS URL of an image
+func (s *AzureImageStore) URL(name string, expiry time.Duration) (string, error) {
+	blobClient := s.client.ServiceClient().NewContainerClient(s.containerName).NewBlobClient(name)
+
+	// the connection string must contain the account key to sign the URL
+	imageURL, err := blobClient.GetSASURL(sas.BlobPermissions{Read: true}, time.Now().Add(expiry), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create image URL: %v", err)
+	}
+
+	return imageURL, nil
+}
+
+// isContainerExistsError checks if an error is due to the container already existing
+func isContainerExistsError(err error) bool {
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) {
+		return responseErr.StatusCode == http.StatusConflict // 409 Conflict indicates container exists
+	}
+	return false
+}
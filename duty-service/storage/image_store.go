@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrImageNotFound is returned when an image doesn't exist in the store
+var ErrImageNotFound = errors.New("image not found")
+
+// ImageStore stores the duty assignment images. Images are private, they are read through short-lived URLs
+type ImageStore interface {
+	// Upload stores an image under the given name (an existing image with that name is replaced)
+	Upload(ctx context.Context, name string, contentType string, data io.Reader) error
+	// Delete deletes an image (deleting an image that doesn't exist is not an error)
+	Delete(ctx context.Context, name string) error
+	// URL returns a read-only URL of an image that expires after the given duration
+	URL(name string, expiry time.Duration) (string, error)
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidImageURL is returned when a local image URL has a wrong signature or name
+	ErrInvalidImageURL = errors.New("invalid image URL")
+	// ErrImageURLExpired is returned when a local image URL is used after it expired
+	ErrImageURLExpired = errors.New("image URL expired")
+)
+
+// LocalImageStore stores images in a directory on the local filesystem (for running the service without Azurite).
+// Its URLs point to the duty service itself and are signed with HMAC, so they expire like SAS URLs.
+type LocalImageStore struct {
+	directory  string
+	baseURL    string // URL the images are served under, e.g. http://localhost:3004/duties/images
+	signingKey []byte
+}
+
+func NewLocalImageStore(directory string, baseURL string, signingKey []byte) (*LocalImageStore, error) {
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create image directory: %v", err)
+	}
+
+	return &LocalImageStore{
+		directory:  directory,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		signingKey: signingKey,
+	}, nil
+}
+
+// Upload writes an image to the directory (the content type is derived from the extension when it is served)
+func (s *LocalImageStore) Upload(ctx context.Context, name string, contentType string, data io.Reader) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so a failed upload never leaves half an image behind
+	file, err := os.CreateTemp(s.directory, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to upload image: %v", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to upload image: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to upload image: %v", err)
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("failed to upload image: %v", err)
+	}
+
+	return nil
+}
+
+// Delete removes an image from the directory
+func (s *LocalImageStore) Delete(ctx context.Context, name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete image: %v", err)
+	}
+
+	return nil
+}
+
+// URL returns a signed URL of an image served by the duty service
+func (s *LocalImageStore) URL(name string, expiry time.Duration) (string, error) {
+	if _, err := s.path(name); err != nil {
+		return "", err
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+
+	return fmt.Sprintf("%s/%s?expires=%s&signature=%s", s.baseURL, url.PathEscape(name), expires, s.sign(name, expires)), nil
+}
+
+// Open checks the signature and expiry of an image URL and opens the image with its content type
+func (s *LocalImageStore) Open(name string, expires string, signature string) (*os.File, string, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.sign(name, expires))) {
+		return nil, "", ErrInvalidImageURL
+	}
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return nil, "", ErrInvalidImageURL
+	}
+	if time.Now().Unix() > expiresAt {
+		return nil, "", ErrImageURLExpired
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", ErrImageNotFound
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open image: %v", err)
+	}
+
+	return file, mime.TypeByExtension(filepath.Ext(name)), nil
+}
+
+// sign returns the HMAC of an image name and expiry time
+func (s *LocalImageStore) sign(name string, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(name + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// path returns the file path of an image, rejecting names that would leave the directory
+func (s *LocalImageStore) path(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("%w: bad image name '%s'", ErrInvalidImageURL, name)
+	}
+	return filepath.Join(s.directory, name), nil
+}
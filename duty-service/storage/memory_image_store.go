@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// an image kept in memory
+type memoryImage struct {
+	data        []byte
+	contentType string
+}
+
+// MemoryImageStore keeps images in memory (for tests and throwaway local runs, nothing is persisted)
+type MemoryImageStore struct {
+	mu     sync.RWMutex
+	images map[string]memoryImage
+}
+
+func NewMemoryImageStore() *MemoryImageStore {
+	return &MemoryImageStore{images: make(map[string]memoryImage)}
+}
+
+// Upload keeps a copy of the image
+func (s *MemoryImageStore) Upload(ctx context.Context, name string, contentType string, data io.Reader) error {
+	bytes, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("failed to upload image: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.images[name] = memoryImage{data: bytes, contentType: contentType}
+
+	return nil
+}
+
+// Delete forgets an image
+func (s *MemoryImageStore) Delete(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.images, name)
+
+	return nil
+}
+
+// URL returns a memory:// URL of an image (it can't be opened over HTTP)
+func (s *MemoryImageStore) URL(name string, expiry time.Duration) (string, error) {
+	return fmt.Sprintf("memory://%s?expires=%d", url.PathEscape(name), time.Now().Add(expiry).Unix()), nil
+}
+
+// Get returns a stored image and its content type
+func (s *MemoryImageStore) Get(name string) ([]byte, string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	image, ok := s.images[name]
+	return image.data, image.contentType, ok
+}
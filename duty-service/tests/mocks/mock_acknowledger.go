@@ -0,0 +1,23 @@
+package mocks
+
+import "github.com/stretchr/testify/mock"
+
+// MockAcknowledger records how a RabbitMQ delivery was acknowledged
+type MockAcknowledger struct {
+	mock.Mock
+}
+
+func (m *MockAcknowledger) Ack(tag uint64, multiple bool) error {
+	args := m.Called(tag, multiple)
+	return args.Error(0)
+}
+
+func (m *MockAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	args := m.Called(tag, multiple, requeue)
+	return args.Error(0)
+}
+
+func (m *MockAcknowledger) Reject(tag uint64, requeue bool) error {
+	args := m.Called(tag, requeue)
+	return args.Error(0)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"duty-service/models"
 	"mime/multipart"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
@@ -18,8 +19,13 @@ func (m *MockDutyAssignmentService) GetAllDutyAssignmentsByShiftId(ctx context.C
 	return args.Get(0).([]models.DutyAssignment), args.Error(1)
 }
 
-func (m *MockDutyAssignmentService) CreateDutyAssignments(ctx context.Context, shiftId uuid.UUID, roleId int) error {
-	args := m.Called(ctx, shiftId, roleId)
+func (m *MockDutyAssignmentService) GetDutyAssignmentsByAssignee(ctx context.Context, employeeId string, shiftId *uuid.UUID) ([]models.DutyAssignment, error) {
+	args := m.Called(ctx, employeeId, shiftId)
+	return args.Get(0).([]models.DutyAssignment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentService) CreateDutyAssignments(ctx context.Context, shiftId uuid.UUID, roleId int, clockInTime time.Time) error {
+	args := m.Called(ctx, shiftId, roleId, clockInTime)
 	return args.Error(0)
 }
 
@@ -28,7 +34,91 @@ func (m *MockDutyAssignmentService) UpdateDutyAssignment(ctx context.Context, du
 	return args.Error(0)
 }
 
+func (m *MockDutyAssignmentService) ReassignDutyAssignment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, assigneeId string, note *string) (*models.DutyAssignment, error) {
+	args := m.Called(ctx, shiftId, dutyId, assigneeId, note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyAssignment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentService) GetDutyAssignmentHistory(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentHistoryEntry, error) {
+	args := m.Called(ctx, shiftId, dutyId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DutyAssignmentHistoryEntry), args.Error(1)
+}
+
+func (m *MockDutyAssignmentService) GetDutyAssignmentPhotos(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentPhoto, error) {
+	args := m.Called(ctx, shiftId, dutyId)
+	return args.Get(0).([]models.DutyAssignmentPhoto), args.Error(1)
+}
+
+func (m *MockDutyAssignmentService) DeleteDutyAssignmentPhoto(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, photoId uuid.UUID) error {
+	args := m.Called(ctx, shiftId, dutyId, photoId)
+	return args.Error(0)
+}
+
+func (m *MockDutyAssignmentService) GetPhotoReviewQueue(ctx context.Context) ([]models.PhotoReviewItem, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PhotoReviewItem), args.Error(1)
+}
+
+func (m *MockDutyAssignmentService) ReviewDutyAssignmentPhoto(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, photoId uuid.UUID, review models.PhotoReview) (*models.DutyAssignmentPhoto, error) {
+	args := m.Called(ctx, shiftId, dutyId, photoId, review)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyAssignmentPhoto), args.Error(1)
+}
+
+func (m *MockDutyAssignmentService) GetDutyAssignmentImageUrl(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, thumbnail bool) (string, error) {
+	args := m.Called(ctx, shiftId, dutyId, thumbnail)
+	return args.String(0), args.Error(1)
+}
+
 func (m *MockDutyAssignmentService) DeleteDutyAssignment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) error {
 	args := m.Called(ctx, shiftId, dutyId)
 	return args.Error(0)
 }
+
+func (m *MockDutyAssignmentService) LocalizeDutyAssignments(ctx context.Context, dutyAssignments []models.DutyAssignment) ([]models.DutyAssignment, error) {
+	args := m.Called(ctx, dutyAssignments)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DutyAssignment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentService) GetDeletedDutyAssignments(ctx context.Context, shiftId uuid.UUID) ([]models.DutyAssignment, error) {
+	args := m.Called(ctx, shiftId)
+	return args.Get(0).([]models.DutyAssignment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentService) RestoreDutyAssignment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) (*models.DutyAssignment, error) {
+	args := m.Called(ctx, shiftId, dutyId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyAssignment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentService) CanClockOut(ctx context.Context, shiftId uuid.UUID) (*models.CanClockOutResult, error) {
+	args := m.Called(ctx, shiftId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CanClockOutResult), args.Error(1)
+}
+
+func (m *MockDutyAssignmentService) ClockOut(ctx context.Context, shiftId uuid.UUID, clockOutTime time.Time) (*models.ShiftDutySummaryMessage, error) {
+	args := m.Called(ctx, shiftId, clockOutTime)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ShiftDutySummaryMessage), args.Error(1)
+}
@@ -0,0 +1,21 @@
+package mocks
+
+import (
+	"context"
+	"duty-service/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDutyAnalyticsService struct {
+	mock.Mock
+}
+
+func (m *MockDutyAnalyticsService) GetAnalytics(ctx context.Context, from, to time.Time) (*models.DutyAnalytics, error) {
+	args := m.Called(ctx, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyAnalytics), args.Error(1)
+}
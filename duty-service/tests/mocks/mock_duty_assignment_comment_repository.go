@@ -0,0 +1,46 @@
+package mocks
+
+import (
+	"context"
+	"duty-service/images"
+	"duty-service/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// full mock implementation of InterfaceDutyAssignmentCommentRepository
+type MockDutyAssignmentCommentRepository struct {
+	mock.Mock
+}
+
+func (m *MockDutyAssignmentCommentRepository) GetComments(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentComment, error) {
+	args := m.Called(ctx, shiftId, dutyId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DutyAssignmentComment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentCommentRepository) GetComment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, commentId uuid.UUID) (*models.DutyAssignmentComment, error) {
+	args := m.Called(ctx, shiftId, dutyId, commentId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyAssignmentComment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentCommentRepository) AddComment(ctx context.Context, comment models.DutyAssignmentComment, photos []*images.ProcessedImage) (models.DutyAssignmentComment, error) {
+	args := m.Called(ctx, comment, photos)
+	return args.Get(0).(models.DutyAssignmentComment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentCommentRepository) UpdateComment(ctx context.Context, comment models.DutyAssignmentComment) error {
+	args := m.Called(ctx, comment)
+	return args.Error(0)
+}
+
+func (m *MockDutyAssignmentCommentRepository) DeleteComment(ctx context.Context, comment models.DutyAssignmentComment) error {
+	args := m.Called(ctx, comment)
+	return args.Error(0)
+}
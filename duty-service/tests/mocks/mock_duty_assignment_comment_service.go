@@ -0,0 +1,52 @@
+package mocks
+
+import (
+	"context"
+	"duty-service/models"
+	"mime/multipart"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// full mock implementation of InterfaceDutyAssignmentCommentService
+type MockDutyAssignmentCommentService struct {
+	mock.Mock
+}
+
+func (m *MockDutyAssignmentCommentService) GetComments(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentComment, error) {
+	args := m.Called(ctx, shiftId, dutyId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DutyAssignmentComment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentCommentService) GetComment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, commentId uuid.UUID) (*models.DutyAssignmentComment, error) {
+	args := m.Called(ctx, shiftId, dutyId, commentId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyAssignmentComment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentCommentService) AddComment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, text string, files []multipart.File) (*models.DutyAssignmentComment, error) {
+	args := m.Called(ctx, shiftId, dutyId, text, files)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyAssignmentComment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentCommentService) UpdateComment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, commentId uuid.UUID, text string) (*models.DutyAssignmentComment, error) {
+	args := m.Called(ctx, shiftId, dutyId, commentId, text)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyAssignmentComment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentCommentService) DeleteComment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, commentId uuid.UUID) error {
+	args := m.Called(ctx, shiftId, dutyId, commentId)
+	return args.Error(0)
+}
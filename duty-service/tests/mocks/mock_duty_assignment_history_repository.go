@@ -0,0 +1,27 @@
+package mocks
+
+import (
+	"context"
+	"duty-service/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// full mock implementation of InterfaceDutyAssignmentHistoryRepository
+type MockDutyAssignmentHistoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockDutyAssignmentHistoryRepository) GetHistory(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentHistoryEntry, error) {
+	args := m.Called(ctx, shiftId, dutyId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DutyAssignmentHistoryEntry), args.Error(1)
+}
+
+func (m *MockDutyAssignmentHistoryRepository) AddEntry(ctx context.Context, entry models.DutyAssignmentHistoryEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
@@ -0,0 +1,57 @@
+package mocks
+
+import (
+	"context"
+	"duty-service/images"
+	"duty-service/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// full mock implementation of InterfaceDutyAssignmentPhotoRepository
+type MockDutyAssignmentPhotoRepository struct {
+	mock.Mock
+}
+
+func (m *MockDutyAssignmentPhotoRepository) GetPhotos(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) ([]models.DutyAssignmentPhoto, error) {
+	args := m.Called(ctx, shiftId, dutyId)
+	return args.Get(0).([]models.DutyAssignmentPhoto), args.Error(1)
+}
+
+func (m *MockDutyAssignmentPhotoRepository) GetPhotosByDuty(ctx context.Context, dutyRowKey uuid.UUID, since time.Time) ([]models.DutyAssignmentPhoto, error) {
+	args := m.Called(ctx, dutyRowKey, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DutyAssignmentPhoto), args.Error(1)
+}
+
+func (m *MockDutyAssignmentPhotoRepository) GetPhotosForReview(ctx context.Context) ([]models.DutyAssignmentPhoto, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.DutyAssignmentPhoto), args.Error(1)
+}
+
+func (m *MockDutyAssignmentPhotoRepository) GetPhoto(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, photoId uuid.UUID) (*models.DutyAssignmentPhoto, error) {
+	args := m.Called(ctx, shiftId, dutyId, photoId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyAssignmentPhoto), args.Error(1)
+}
+
+func (m *MockDutyAssignmentPhotoRepository) AddPhoto(ctx context.Context, photo models.DutyAssignmentPhoto, image *images.ProcessedImage) (models.DutyAssignmentPhoto, error) {
+	args := m.Called(ctx, photo, image)
+	return args.Get(0).(models.DutyAssignmentPhoto), args.Error(1)
+}
+
+func (m *MockDutyAssignmentPhotoRepository) UpdatePhotoReview(ctx context.Context, photo models.DutyAssignmentPhoto) error {
+	args := m.Called(ctx, photo)
+	return args.Error(0)
+}
+
+func (m *MockDutyAssignmentPhotoRepository) DeletePhoto(ctx context.Context, photo models.DutyAssignmentPhoto) error {
+	args := m.Called(ctx, photo)
+	return args.Error(0)
+}
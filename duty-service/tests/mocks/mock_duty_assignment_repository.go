@@ -0,0 +1,101 @@
+package mocks
+
+import (
+	"context"
+	"duty-service/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// full mock implementation of InterfaceDutyAssignmentRepository
+type MockDutyAssignmentRepository struct {
+	mock.Mock
+}
+
+func (m *MockDutyAssignmentRepository) GetAllDutyAssignmentsByShiftId(ctx context.Context, shiftId uuid.UUID) ([]models.DutyAssignment, error) {
+	args := m.Called(ctx, shiftId)
+	return args.Get(0).([]models.DutyAssignment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentRepository) GetDutyAssignmentsByShiftIds(ctx context.Context, shiftIds []uuid.UUID) ([]models.DutyAssignment, error) {
+	args := m.Called(ctx, shiftIds)
+	return args.Get(0).([]models.DutyAssignment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentRepository) GetDutyAssignmentsByAssignee(ctx context.Context, assigneeId string, shiftId *uuid.UUID) ([]models.DutyAssignment, error) {
+	args := m.Called(ctx, assigneeId, shiftId)
+	return args.Get(0).([]models.DutyAssignment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentRepository) GetDutyAssignmentsByEventId(ctx context.Context, eventId uuid.UUID) ([]models.DutyAssignment, error) {
+	args := m.Called(ctx, eventId)
+	return args.Get(0).([]models.DutyAssignment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentRepository) GetDutyAssignmentsCreatedBetween(ctx context.Context, from, to time.Time) ([]models.DutyAssignment, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).([]models.DutyAssignment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentRepository) GetDutyAssignmentsChangedSince(ctx context.Context, shiftIds []uuid.UUID, since time.Time) ([]models.DutyAssignment, error) {
+	args := m.Called(ctx, shiftIds, since)
+	return args.Get(0).([]models.DutyAssignment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentRepository) GetDutyAssignment(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID) (*models.DutyAssignment, error) {
+	args := m.Called(ctx, shiftId, dutyId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyAssignment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentRepository) GetDeletedDutyAssignments(ctx context.Context, shiftId *uuid.UUID, deletedBefore time.Time) ([]models.DutyAssignment, error) {
+	args := m.Called(ctx, shiftId, deletedBefore)
+	return args.Get(0).([]models.DutyAssignment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentRepository) CreateDutyAssignments(ctx context.Context, shiftId uuid.UUID, roleId int, eventId *uuid.UUID, assigneeId string, duties []models.Duty) ([]models.DutyAssignment, error) {
+	args := m.Called(ctx, shiftId, roleId, eventId, assigneeId, duties)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DutyAssignment), args.Error(1)
+}
+
+func (m *MockDutyAssignmentRepository) UpdateDutyAssignment(ctx context.Context, dutyAssignment models.DutyAssignment) error {
+	args := m.Called(ctx, dutyAssignment)
+	return args.Error(0)
+}
+
+func (m *MockDutyAssignmentRepository) UpdateAssignee(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, assigneeId string) error {
+	args := m.Called(ctx, shiftId, dutyId, assigneeId)
+	return args.Error(0)
+}
+
+func (m *MockDutyAssignmentRepository) FreezeDutyAssignments(ctx context.Context, shiftId uuid.UUID, dutyAssignments []models.DutyAssignment) error {
+	args := m.Called(ctx, shiftId, dutyAssignments)
+	return args.Error(0)
+}
+
+func (m *MockDutyAssignmentRepository) SetMainPhoto(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, imageUrl string, thumbnailUrl string) error {
+	args := m.Called(ctx, shiftId, dutyId, imageUrl, thumbnailUrl)
+	return args.Error(0)
+}
+
+func (m *MockDutyAssignmentRepository) SetPhotoFlags(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, flags []models.PhotoFlag) error {
+	args := m.Called(ctx, shiftId, dutyId, flags)
+	return args.Error(0)
+}
+
+func (m *MockDutyAssignmentRepository) SetDutyAssignmentDeleted(ctx context.Context, shiftId uuid.UUID, dutyId uuid.UUID, deletedAt *time.Time, deletedBy string) error {
+	args := m.Called(ctx, shiftId, dutyId, deletedAt, deletedBy)
+	return args.Error(0)
+}
+
+func (m *MockDutyAssignmentRepository) DeleteDutyAssignment(ctx context.Context, dutyAssignment models.DutyAssignment) error {
+	args := m.Called(ctx, dutyAssignment)
+	return args.Error(0)
+}
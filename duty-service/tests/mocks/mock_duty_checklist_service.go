@@ -0,0 +1,22 @@
+package mocks
+
+import (
+	"context"
+	"duty-service/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// mock implementation of InterfaceDutyChecklistService
+type MockDutyChecklistService struct {
+	mock.Mock
+}
+
+func (m *MockDutyChecklistService) GetDutyChecklist(ctx context.Context, shiftId uuid.UUID) (*models.DutyChecklist, error) {
+	args := m.Called(ctx, shiftId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyChecklist), args.Error(1)
+}
@@ -0,0 +1,38 @@
+package mocks
+
+import (
+	"context"
+	"duty-service/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDutyReportService struct {
+	mock.Mock
+}
+
+func (m *MockDutyReportService) GetShiftReport(ctx context.Context, shiftId uuid.UUID) (*models.DutyReport, error) {
+	args := m.Called(ctx, shiftId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyReport), args.Error(1)
+}
+
+func (m *MockDutyReportService) GetShiftsReport(ctx context.Context, shiftIds []uuid.UUID) (*models.DutyReport, error) {
+	args := m.Called(ctx, shiftIds)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyReport), args.Error(1)
+}
+
+func (m *MockDutyReportService) GetDateRangeReport(ctx context.Context, from, to time.Time) (*models.DutyReport, error) {
+	args := m.Called(ctx, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyReport), args.Error(1)
+}
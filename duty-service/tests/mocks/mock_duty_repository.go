@@ -0,0 +1,73 @@
+package mocks
+
+import (
+	"context"
+	"duty-service/images"
+	"duty-service/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// full mock implementation of InterfaceDutyRepository
+type MockDutyRepository struct {
+	mock.Mock
+}
+
+func (m *MockDutyRepository) GetAllDuties(ctx context.Context, filter string) ([]models.Duty, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.Duty), args.Error(1)
+}
+
+func (m *MockDutyRepository) GetDutyById(ctx context.Context, partitionKey, rowKey string) (*models.Duty, error) {
+	args := m.Called(ctx, partitionKey, rowKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Duty), args.Error(1)
+}
+
+func (m *MockDutyRepository) GetDutiesByRole(ctx context.Context, roleId int) ([]models.Duty, error) {
+	args := m.Called(ctx, roleId)
+	return args.Get(0).([]models.Duty), args.Error(1)
+}
+
+func (m *MockDutyRepository) CreateDuty(ctx context.Context, duty models.Duty) error {
+	args := m.Called(ctx, duty)
+	return args.Error(0)
+}
+
+func (m *MockDutyRepository) UpdateDuty(ctx context.Context, partitionKey, rowKey string, duty models.Duty) error {
+	args := m.Called(ctx, partitionKey, rowKey, duty)
+	return args.Error(0)
+}
+
+func (m *MockDutyRepository) ClaimDutyVersion(ctx context.Context, partitionKey, rowKey string, version int, etag string) (string, error) {
+	args := m.Called(ctx, partitionKey, rowKey, version, etag)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockDutyRepository) SetDutyDeleted(ctx context.Context, partitionKey, rowKey string, deletedAt *time.Time, deletedBy string) error {
+	args := m.Called(ctx, partitionKey, rowKey, deletedAt, deletedBy)
+	return args.Error(0)
+}
+
+func (m *MockDutyRepository) SetDutyTranslations(ctx context.Context, partitionKey, rowKey string, etag string, translations models.DutyTranslations) error {
+	args := m.Called(ctx, partitionKey, rowKey, etag, translations)
+	return args.Error(0)
+}
+
+func (m *MockDutyRepository) AddDutyAttachment(ctx context.Context, duty models.Duty, attachment models.DutyAttachment, file *images.ProcessedAttachment) (models.DutyAttachment, error) {
+	args := m.Called(ctx, duty, attachment, file)
+	return args.Get(0).(models.DutyAttachment), args.Error(1)
+}
+
+func (m *MockDutyRepository) DeleteDutyAttachment(ctx context.Context, duty models.Duty, attachment models.DutyAttachment) error {
+	args := m.Called(ctx, duty, attachment)
+	return args.Error(0)
+}
+
+func (m *MockDutyRepository) DeleteDuty(ctx context.Context, duty models.Duty) error {
+	args := m.Called(ctx, duty)
+	return args.Error(0)
+}
@@ -3,7 +3,9 @@ package mocks
 import (
 	"context"
 	"duty-service/models"
+	"mime/multipart"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -12,9 +14,12 @@ type MockDutyService struct {
 	mock.Mock
 }
 
-func (m *MockDutyService) GetAllDuties(ctx context.Context, name string) ([]models.Duty, error) {
-	args := m.Called(ctx, name)
-	return args.Get(0).([]models.Duty), args.Error(1)
+func (m *MockDutyService) GetAllDuties(ctx context.Context, search models.DutySearch) (*models.DutySearchResult, error) {
+	args := m.Called(ctx, search)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutySearchResult), args.Error(1)
 }
 
 func (m *MockDutyService) GetDutyById(ctx context.Context, partitionKey, rowKey string) (*models.Duty, error) {
@@ -41,3 +46,66 @@ func (m *MockDutyService) DeleteDuty(ctx context.Context, partitionKey, rowKey s
 	args := m.Called(ctx, partitionKey, rowKey)
 	return args.Error(0)
 }
+
+func (m *MockDutyService) RestoreDuty(ctx context.Context, partitionKey, rowKey string) (*models.Duty, error) {
+	args := m.Called(ctx, partitionKey, rowKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Duty), args.Error(1)
+}
+
+func (m *MockDutyService) AddDutyAttachment(ctx context.Context, partitionKey, rowKey string, title string, fileName string, file multipart.File) (*models.DutyAttachment, error) {
+	args := m.Called(ctx, partitionKey, rowKey, title, fileName, file)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyAttachment), args.Error(1)
+}
+
+func (m *MockDutyService) DeleteDutyAttachment(ctx context.Context, partitionKey, rowKey string, attachmentId uuid.UUID) error {
+	args := m.Called(ctx, partitionKey, rowKey, attachmentId)
+	return args.Error(0)
+}
+
+func (m *MockDutyService) ExportDuties(ctx context.Context) ([]models.DutyTemplate, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.DutyTemplate), args.Error(1)
+}
+
+func (m *MockDutyService) ImportDuties(ctx context.Context, templates []models.DutyTemplate, options models.DutyImportOptions) (*models.DutyImportResult, error) {
+	args := m.Called(ctx, templates, options)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyImportResult), args.Error(1)
+}
+
+func (m *MockDutyService) GetDutyVersions(ctx context.Context, dutyId uuid.UUID) ([]models.DutyVersion, error) {
+	args := m.Called(ctx, dutyId)
+	return args.Get(0).([]models.DutyVersion), args.Error(1)
+}
+
+func (m *MockDutyService) GetDutyVersion(ctx context.Context, dutyId uuid.UUID, version int) (*models.DutyVersion, error) {
+	args := m.Called(ctx, dutyId, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyVersion), args.Error(1)
+}
+
+func (m *MockDutyService) DiffDutyVersions(ctx context.Context, dutyId uuid.UUID, from, to int) (*models.DutyVersionDiff, error) {
+	args := m.Called(ctx, dutyId, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyVersionDiff), args.Error(1)
+}
+
+func (m *MockDutyService) RollbackDuty(ctx context.Context, partitionKey, rowKey string, version int) (*models.Duty, error) {
+	args := m.Called(ctx, partitionKey, rowKey, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Duty), args.Error(1)
+}
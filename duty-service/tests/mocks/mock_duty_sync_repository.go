@@ -0,0 +1,33 @@
+package mocks
+
+import (
+	"context"
+	"duty-service/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// full mock implementation of InterfaceDutySyncRepository
+type MockDutySyncRepository struct {
+	mock.Mock
+}
+
+func (m *MockDutySyncRepository) ReserveSyncChange(ctx context.Context, shiftId, clientChangeId, dutyId uuid.UUID, reservedAt time.Time, expiry time.Duration) (*models.DutySyncChangeResult, error) {
+	args := m.Called(ctx, shiftId, clientChangeId, dutyId, reservedAt, expiry)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutySyncChangeResult), args.Error(1)
+}
+
+func (m *MockDutySyncRepository) SaveSyncResult(ctx context.Context, result models.DutySyncChangeResult) error {
+	args := m.Called(ctx, result)
+	return args.Error(0)
+}
+
+func (m *MockDutySyncRepository) ReleaseSyncChange(ctx context.Context, shiftId, clientChangeId uuid.UUID) error {
+	args := m.Called(ctx, shiftId, clientChangeId)
+	return args.Error(0)
+}
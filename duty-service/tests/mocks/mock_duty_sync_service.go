@@ -0,0 +1,21 @@
+package mocks
+
+import (
+	"context"
+	"duty-service/models"
+	"mime/multipart"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDutySyncService struct {
+	mock.Mock
+}
+
+func (m *MockDutySyncService) Sync(ctx context.Context, request models.DutySyncRequest, photos map[string]multipart.File) (*models.DutySyncResponse, error) {
+	args := m.Called(ctx, request, photos)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutySyncResponse), args.Error(1)
+}
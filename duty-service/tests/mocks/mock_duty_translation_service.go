@@ -0,0 +1,34 @@
+package mocks
+
+import (
+	"context"
+	"duty-service/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// mock implementation of InterfaceDutyTranslationService
+type MockDutyTranslationService struct {
+	mock.Mock
+}
+
+func (m *MockDutyTranslationService) SetDutyTranslation(ctx context.Context, partitionKey, rowKey string, language string, translation models.DutyTranslation) (*models.Duty, error) {
+	args := m.Called(ctx, partitionKey, rowKey, language, translation)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Duty), args.Error(1)
+}
+
+func (m *MockDutyTranslationService) DeleteDutyTranslation(ctx context.Context, partitionKey, rowKey string, language string) error {
+	args := m.Called(ctx, partitionKey, rowKey, language)
+	return args.Error(0)
+}
+
+func (m *MockDutyTranslationService) GetMissingTranslations(ctx context.Context, languages []string) ([]models.MissingDutyTranslation, error) {
+	args := m.Called(ctx, languages)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.MissingDutyTranslation), args.Error(1)
+}
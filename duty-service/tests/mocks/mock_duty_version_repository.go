@@ -0,0 +1,36 @@
+package mocks
+
+import (
+	"context"
+	"duty-service/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDutyVersionRepository struct {
+	mock.Mock
+}
+
+func (m *MockDutyVersionRepository) GetVersions(ctx context.Context, dutyId uuid.UUID) ([]models.DutyVersion, error) {
+	args := m.Called(ctx, dutyId)
+	return args.Get(0).([]models.DutyVersion), args.Error(1)
+}
+
+func (m *MockDutyVersionRepository) GetVersion(ctx context.Context, dutyId uuid.UUID, version int) (*models.DutyVersion, error) {
+	args := m.Called(ctx, dutyId, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DutyVersion), args.Error(1)
+}
+
+func (m *MockDutyVersionRepository) AddVersion(ctx context.Context, version models.DutyVersion) error {
+	args := m.Called(ctx, version)
+	return args.Error(0)
+}
+
+func (m *MockDutyVersionRepository) SetVersionTranslations(ctx context.Context, dutyId uuid.UUID, version int, translations models.DutyTranslations) error {
+	args := m.Called(ctx, dutyId, version, translations)
+	return args.Error(0)
+}
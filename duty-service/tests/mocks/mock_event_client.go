@@ -0,0 +1,21 @@
+package mocks
+
+import (
+	"context"
+	"duty-service/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockEventClient struct {
+	mock.Mock
+}
+
+func (m *MockEventClient) GetEventByShiftId(ctx context.Context, shiftId uuid.UUID) (*models.Event, error) {
+	args := m.Called(ctx, shiftId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Event), args.Error(1)
+}
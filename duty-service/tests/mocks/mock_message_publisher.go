@@ -0,0 +1,20 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+// full mock implementation of InterfaceMessagePublisher
+type MockMessagePublisher struct {
+	mock.Mock
+}
+
+func (m *MockMessagePublisher) PublishMessage(queueName string, message interface{}) error {
+	args := m.Called(queueName, message)
+	return args.Error(0)
+}
+
+func (m *MockMessagePublisher) PublishToExchange(exchange string, routingKey string, message interface{}) error {
+	args := m.Called(exchange, routingKey, message)
+	return args.Error(0)
+}
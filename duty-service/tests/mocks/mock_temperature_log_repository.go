@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"context"
+	"duty-service/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// full mock implementation of InterfaceTemperatureLogRepository
+type MockTemperatureLogRepository struct {
+	mock.Mock
+}
+
+func (m *MockTemperatureLogRepository) GetReadingsByShiftId(ctx context.Context, shiftId uuid.UUID) ([]models.TemperatureReading, error) {
+	args := m.Called(ctx, shiftId)
+	return args.Get(0).([]models.TemperatureReading), args.Error(1)
+}
+
+func (m *MockTemperatureLogRepository) GetReadingsByEventId(ctx context.Context, eventId uuid.UUID, from, to time.Time) ([]models.TemperatureReading, error) {
+	args := m.Called(ctx, eventId, from, to)
+	return args.Get(0).([]models.TemperatureReading), args.Error(1)
+}
+
+func (m *MockTemperatureLogRepository) CreateReading(ctx context.Context, reading models.TemperatureReading) error {
+	args := m.Called(ctx, reading)
+	return args.Error(0)
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"context"
+	"duty-service/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTemperatureLogService struct {
+	mock.Mock
+}
+
+func (m *MockTemperatureLogService) GetReadingsByShiftId(ctx context.Context, shiftId uuid.UUID) ([]models.TemperatureReading, error) {
+	args := m.Called(ctx, shiftId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.TemperatureReading), args.Error(1)
+}
+
+func (m *MockTemperatureLogService) RecordReading(ctx context.Context, reading models.TemperatureReading) (models.TemperatureReading, error) {
+	args := m.Called(ctx, reading)
+	return args.Get(0).(models.TemperatureReading), args.Error(1)
+}
+
+func (m *MockTemperatureLogService) GetReport(ctx context.Context, eventId uuid.UUID, from, to time.Time) ([]models.TemperatureReading, error) {
+	args := m.Called(ctx, eventId, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.TemperatureReading), args.Error(1)
+}
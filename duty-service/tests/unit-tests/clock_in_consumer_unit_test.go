@@ -0,0 +1,84 @@
+package unit_tests
+
+import (
+	"duty-service/models"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func clockInDelivery(t *testing.T, acknowledger *mocks.MockAcknowledger, shiftId uuid.UUID, redelivered bool) amqp.Delivery {
+	body, err := json.Marshal(models.ClockInMessage{ShiftID: shiftId, ClockInTime: time.Now().UTC(), RoleId: 2})
+	require.NoError(t, err)
+
+	return amqp.Delivery{Acknowledger: acknowledger, Body: body, Redelivered: redelivered}
+}
+
+func clockInConsumer(mockRepo *mocks.MockDutyAssignmentRepository, mockDutyRepo *mocks.MockDutyRepository) *services.RabbitMQService {
+	return &services.RabbitMQService{DutyService: services.NewDutyAssignmentService(mockRepo, mockDutyRepo, nil, nil, nil, nil, nil, nil, nil)}
+}
+
+// SUCCESS CASES:
+func TestDutyAssignmentRowKey_Deterministic(t *testing.T) {
+	shiftId, dutyId := uuid.New(), uuid.New()
+
+	require.Equal(t, models.DutyAssignmentRowKey(shiftId, dutyId), models.DutyAssignmentRowKey(shiftId, dutyId))
+	require.NotEqual(t, models.DutyAssignmentRowKey(shiftId, dutyId), models.DutyAssignmentRowKey(uuid.New(), dutyId))
+	require.NotEqual(t, models.DutyAssignmentRowKey(shiftId, dutyId), models.DutyAssignmentRowKey(shiftId, uuid.New()))
+}
+
+func TestHandleClockInMessage_Ack(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	acknowledger := new(mocks.MockAcknowledger)
+	shiftId := uuid.New()
+
+	mockDutyRepo.On("GetDutiesByRole", mock.Anything, 2).Return([]models.Duty{{RowKey: uuid.New()}}, nil)
+	mockRepo.On("CreateDutyAssignments", mock.Anything, shiftId, 2, (*uuid.UUID)(nil), "", mock.Anything).Return([]models.DutyAssignment{}, nil)
+	acknowledger.On("Ack", uint64(0), false).Return(nil)
+
+	clockInConsumer(mockRepo, mockDutyRepo).HandleClockInMessage(clockInDelivery(t, acknowledger, shiftId, false))
+
+	acknowledger.AssertExpectations(t)
+}
+
+// FAILURE CASES:
+func TestHandleClockInMessage_RequeuedOnce(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	shiftId := uuid.New()
+
+	mockDutyRepo.On("GetDutiesByRole", mock.Anything, 2).Return([]models.Duty{{RowKey: uuid.New()}}, nil)
+	mockRepo.On("CreateDutyAssignments", mock.Anything, shiftId, 2, (*uuid.UUID)(nil), "", mock.Anything).Return(nil, errors.New("transaction failed"))
+	consumer := clockInConsumer(mockRepo, mockDutyRepo)
+
+	// the first failure is requeued
+	firstAttempt := new(mocks.MockAcknowledger)
+	firstAttempt.On("Nack", uint64(0), false, true).Return(nil)
+	consumer.HandleClockInMessage(clockInDelivery(t, firstAttempt, shiftId, false))
+	firstAttempt.AssertExpectations(t)
+
+	// a redelivered message that fails again is dropped
+	secondAttempt := new(mocks.MockAcknowledger)
+	secondAttempt.On("Nack", uint64(0), false, false).Return(nil)
+	consumer.HandleClockInMessage(clockInDelivery(t, secondAttempt, shiftId, true))
+	secondAttempt.AssertExpectations(t)
+}
+
+func TestHandleClockInMessage_InvalidBody(t *testing.T) {
+	acknowledger := new(mocks.MockAcknowledger)
+	acknowledger.On("Nack", uint64(0), false, false).Return(nil)
+
+	consumer := clockInConsumer(new(mocks.MockDutyAssignmentRepository), new(mocks.MockDutyRepository))
+	consumer.HandleClockInMessage(amqp.Delivery{Acknowledger: acknowledger, Body: []byte("not json")})
+
+	acknowledger.AssertExpectations(t)
+}
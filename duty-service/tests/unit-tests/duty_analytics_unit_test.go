@@ -0,0 +1,177 @@
+package unit_tests
+
+import (
+	"context"
+	"duty-service/handlers"
+	"duty-service/models"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(value int) *int {
+	return &value
+}
+
+func timePtr(value time.Time) *time.Time {
+	return &value
+}
+
+// SUCCESS CASES:
+func TestGetAnalytics_TemplatesRolesAndWeeks(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyAnalyticsService(mockRepo, mockDutyRepo)
+
+	grill := models.Duty{RowKey: uuid.New(), DutyName: "Clean grill"}
+	stock := models.Duty{RowKey: uuid.New(), DutyName: "Count stock"}
+	lastWeek := time.Now().UTC().Add(-7 * 24 * time.Hour)
+	from, to := lastWeek.Add(-time.Hour), time.Now().UTC()
+
+	mockDutyRepo.On("GetAllDuties", mock.Anything, "").Return([]models.Duty{grill, stock}, nil)
+	mockRepo.On("GetDutyAssignmentsCreatedBetween", mock.Anything, from, to).Return([]models.DutyAssignment{
+		{DutyRowKey: grill.RowKey, RoleId: intPtr(2), CreatedAt: lastWeek, DutyAssignmentStatus: models.StatusCompleted,
+			StartedAt: timePtr(lastWeek.Add(10 * time.Minute)), CompletedAt: timePtr(lastWeek.Add(30 * time.Minute))},
+		{DutyRowKey: grill.RowKey, RoleId: intPtr(2), CreatedAt: lastWeek, DutyAssignmentStatus: models.StatusCompleted,
+			StartedAt: timePtr(lastWeek.Add(50 * time.Minute)), CompletedAt: timePtr(lastWeek.Add(60 * time.Minute))},
+		{DutyRowKey: grill.RowKey, RoleId: intPtr(3), CreatedAt: lastWeek, DutyAssignmentStatus: models.StatusIncomplete,
+			FrozenAt: timePtr(lastWeek.Add(8 * time.Hour)), IncompleteAtClockOut: true}, // left incomplete
+		{DutyRowKey: stock.RowKey, RoleId: intPtr(3), CreatedAt: lastWeek, DutyAssignmentStatus: models.StatusSkipped},
+		{DutyRowKey: stock.RowKey, CreatedAt: lastWeek, DutyAssignmentStatus: models.StatusIncomplete,
+			FrozenAt: timePtr(lastWeek.Add(8 * time.Hour)), IncompleteAtClockOut: true}, // older assignment without a role
+	}, nil)
+
+	analytics, err := service.GetAnalytics(context.Background(), from, to)
+
+	require.NoError(t, err)
+	require.Equal(t, models.DutyAnalyticsCounts{Assignments: 5, Completed: 2, Skipped: 1, LeftIncomplete: 2, CompletionRate: 0.5}, analytics.Totals)
+
+	require.Len(t, analytics.Duties, 2)
+	require.Equal(t, "Clean grill", analytics.Duties[0].DutyName)
+	require.Equal(t, 45.0, *analytics.Duties[0].MedianMinutesToComplete) // median of 30 and 60
+	require.Equal(t, 15.0, *analytics.Duties[0].MedianMinutesWorking)    // median of 20 and 10
+	require.Nil(t, analytics.Duties[1].MedianMinutesToComplete)
+
+	require.Equal(t, []models.RoleDutyAnalytics{
+		{RoleId: 2, DutyAnalyticsCounts: models.DutyAnalyticsCounts{Assignments: 2, Completed: 2, CompletionRate: 1}},
+		{RoleId: 3, DutyAnalyticsCounts: models.DutyAnalyticsCounts{Assignments: 2, Skipped: 1, LeftIncomplete: 1, CompletionRate: 0}},
+	}, analytics.Roles)
+
+	require.Len(t, analytics.MostLeftIncomplete, 2)
+	require.Len(t, analytics.Weeks, 1)
+	require.Equal(t, time.Monday, analytics.Weeks[0].WeekStart.Weekday())
+	require.Equal(t, 5, analytics.Weeks[0].Assignments)
+}
+
+func TestGetAnalytics_LeftIncompleteAtClockOut(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyAnalyticsService(mockRepo, mockDutyRepo)
+
+	grill := models.Duty{RowKey: uuid.New(), DutyName: "Clean grill"}
+	clockIn := time.Now().UTC().Add(-30 * time.Hour)
+	clockOut := clockIn.Add(8 * time.Hour)
+	from, to := clockIn.Add(-time.Hour), time.Now().UTC()
+
+	mockDutyRepo.On("GetAllDuties", mock.Anything, "").Return([]models.Duty{grill}, nil)
+	mockRepo.On("GetDutyAssignmentsCreatedBetween", mock.Anything, from, to).Return([]models.DutyAssignment{
+		// a shift of more than 24 hours that is still running
+		{DutyRowKey: grill.RowKey, CreatedAt: clockIn, DutyAssignmentStatus: models.StatusIncomplete},
+		// done at clock-out, reopened by an admin afterwards
+		{DutyRowKey: grill.RowKey, CreatedAt: clockIn, DutyAssignmentStatus: models.StatusIncomplete, FrozenAt: &clockOut},
+		// left incomplete, completed by an admin afterwards
+		{DutyRowKey: grill.RowKey, CreatedAt: clockIn, DutyAssignmentStatus: models.StatusCompleted, FrozenAt: &clockOut, IncompleteAtClockOut: true},
+	}, nil)
+
+	analytics, err := service.GetAnalytics(context.Background(), from, to)
+
+	require.NoError(t, err)
+	require.Equal(t, 1, analytics.Totals.LeftIncomplete)
+	require.Equal(t, 1, analytics.Totals.Completed)
+}
+
+func TestUpdateDutyAssignment_RecordsStartAndCompletion(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	startedAt := time.Now().UTC().Add(-time.Hour)
+
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&models.DutyAssignment{
+		PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusIncomplete, StartedAt: &startedAt,
+	}, nil)
+	mockRepo.On("UpdateDutyAssignment", mock.Anything, mock.MatchedBy(func(dutyAssignment models.DutyAssignment) bool {
+		return dutyAssignment.StartedAt.Equal(startedAt) && dutyAssignment.CompletedAt != nil && dutyAssignment.CompletedAt.After(startedAt)
+	})).Return(nil)
+
+	err := service.UpdateDutyAssignment(context.Background(), models.DutyAssignment{
+		PartitionKey:         shiftId,
+		RowKey:               dutyId,
+		DutyAssignmentStatus: models.StatusCompleted,
+	}, nil)
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateDutyAssignment_ReopeningClearsCompletion(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	completedAt := time.Now().UTC().Add(-time.Hour)
+
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&models.DutyAssignment{
+		PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusCompleted, CompletedAt: &completedAt,
+	}, nil)
+	mockRepo.On("UpdateDutyAssignment", mock.Anything, mock.MatchedBy(func(dutyAssignment models.DutyAssignment) bool {
+		return dutyAssignment.StartedAt != nil && dutyAssignment.CompletedAt == nil
+	})).Return(nil)
+
+	err := service.UpdateDutyAssignment(context.Background(), models.DutyAssignment{
+		PartitionKey:         shiftId,
+		RowKey:               dutyId,
+		DutyAssignmentStatus: models.StatusIncomplete,
+	}, nil)
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetAnalyticsHandler_DateRange(t *testing.T) {
+	mockService := new(mocks.MockDutyAnalyticsService)
+	handler := handlers.NewDutyAnalyticsHandler(mockService)
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC)
+	mockService.On("GetAnalytics", mock.Anything, from, to).Return(&models.DutyAnalytics{From: from, To: to}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/duties/analytics?from=2024-03-01&to=2024-03-31", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetAnalytics(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	mockService.AssertExpectations(t)
+}
+
+// FAILURE CASES:
+func TestGetAnalyticsHandler_FromAfterTo(t *testing.T) {
+	mockService := new(mocks.MockDutyAnalyticsService)
+	handler := handlers.NewDutyAnalyticsHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/duties/analytics?from=2024-04-01&to=2024-03-01", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetAnalytics(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Result().StatusCode)
+	mockService.AssertNotCalled(t, "GetAnalytics", mock.Anything, mock.Anything, mock.Anything)
+}
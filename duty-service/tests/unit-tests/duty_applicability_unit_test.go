@@ -0,0 +1,150 @@
+package unit_tests
+
+import (
+	"context"
+	"duty-service/models"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// a Saturday
+var festivalClockIn = time.Date(2024, 6, 15, 9, 0, 0, 0, time.UTC)
+
+func festivalEvent() *models.Event {
+	return &models.Event{RowKey: uuid.New(), Venue: "Central Park", Tags: []string{"Festival", "outdoor"}, TruckID: "truck-2"}
+}
+
+// SUCCESS CASES:
+func TestDutyApplicability_Matches(t *testing.T) {
+	event := festivalEvent()
+
+	require.True(t, (*models.DutyApplicability)(nil).Matches(nil, festivalClockIn))
+	require.True(t, (&models.DutyApplicability{EventTags: []string{"festival"}}).Matches(event, festivalClockIn)) // case-insensitive
+	require.True(t, (&models.DutyApplicability{Venues: []string{"Harbour", "Central Park"}, TruckIds: []string{"truck-2"}}).Matches(event, festivalClockIn))
+	require.True(t, (&models.DutyApplicability{Weekdays: []string{"Saturday", "Sunday"}}).Matches(nil, festivalClockIn))
+
+	require.False(t, (&models.DutyApplicability{EventTags: []string{"corporate"}}).Matches(event, festivalClockIn))
+	require.False(t, (&models.DutyApplicability{EventTags: []string{"festival"}, TruckIds: []string{"truck-1"}}).Matches(event, festivalClockIn)) // every rule has to match
+	require.False(t, (&models.DutyApplicability{Weekdays: []string{"Monday"}}).Matches(event, festivalClockIn))
+	require.False(t, (&models.DutyApplicability{Venues: []string{"Central Park"}}).Matches(nil, festivalClockIn)) // event unknown
+}
+
+func TestCreateDutyAssignments_SelectsDutiesForEvent(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	mockEventClient := new(mocks.MockEventClient)
+	service := services.NewDutyAssignmentService(mockRepo, mockDutyRepo, nil, nil, nil, mockEventClient, nil, nil, nil)
+
+	shiftId := uuid.New()
+	event := festivalEvent()
+	always := models.Duty{RowKey: uuid.New(), DutyName: "Clean grill"}
+	outdoor := models.Duty{RowKey: uuid.New(), DutyName: "Set up tent", AppliesTo: &models.DutyApplicability{EventTags: []string{"outdoor"}}}
+	corporate := models.Duty{RowKey: uuid.New(), DutyName: "Set up buffet", AppliesTo: &models.DutyApplicability{EventTags: []string{"corporate"}}}
+	weekday := models.Duty{RowKey: uuid.New(), DutyName: "Order stock", AppliesTo: &models.DutyApplicability{Weekdays: []string{"Monday"}}}
+
+	mockDutyRepo.On("GetDutiesByRole", mock.Anything, 2).Return([]models.Duty{always, outdoor, corporate, weekday}, nil)
+	mockEventClient.On("GetEventByShiftId", mock.Anything, shiftId).Return(event, nil)
+	mockRepo.On("CreateDutyAssignments", mock.Anything, shiftId, 2, &event.RowKey, "", []models.Duty{always, outdoor}).Return([]models.DutyAssignment{}, nil)
+
+	err := service.CreateDutyAssignments(context.Background(), shiftId, 2, festivalClockIn)
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateDutyAssignments_MatchesWeekdayInLocalTime(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err)
+
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyAssignmentService(mockRepo, mockDutyRepo, nil, nil, nil, nil, nil, nil, amsterdam)
+
+	shiftId := uuid.New()
+	saturday := models.Duty{RowKey: uuid.New(), DutyName: "Order stock", AppliesTo: &models.DutyApplicability{Weekdays: []string{"Saturday"}}}
+	sunday := models.Duty{RowKey: uuid.New(), DutyName: "Deep clean fryer", AppliesTo: &models.DutyApplicability{Weekdays: []string{"Sunday"}}}
+
+	mockDutyRepo.On("GetDutiesByRole", mock.Anything, 2).Return([]models.Duty{saturday, sunday}, nil)
+	mockRepo.On("CreateDutyAssignments", mock.Anything, shiftId, 2, (*uuid.UUID)(nil), "", []models.Duty{sunday}).Return([]models.DutyAssignment{}, nil)
+
+	// 22:30 on Saturday in UTC is 00:30 on Sunday in Amsterdam
+	err = service.CreateDutyAssignments(context.Background(), shiftId, 2, time.Date(2024, 6, 15, 22, 30, 0, 0, time.UTC))
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateDutyAssignments_WithoutEvent(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	mockEventClient := new(mocks.MockEventClient)
+	service := services.NewDutyAssignmentService(mockRepo, mockDutyRepo, nil, nil, nil, mockEventClient, nil, nil, nil)
+
+	shiftId := uuid.New()
+	always := models.Duty{RowKey: uuid.New(), DutyName: "Clean grill"}
+	saturday := models.Duty{RowKey: uuid.New(), DutyName: "Deep clean", AppliesTo: &models.DutyApplicability{Weekdays: []string{"saturday"}}}
+	outdoor := models.Duty{RowKey: uuid.New(), DutyName: "Set up tent", AppliesTo: &models.DutyApplicability{EventTags: []string{"outdoor"}}}
+
+	mockDutyRepo.On("GetDutiesByRole", mock.Anything, 3).Return([]models.Duty{always, saturday, outdoor}, nil)
+	mockEventClient.On("GetEventByShiftId", mock.Anything, shiftId).Return(nil, errors.New("connection refused"))
+	mockRepo.On("CreateDutyAssignments", mock.Anything, shiftId, 3, (*uuid.UUID)(nil), "", []models.Duty{always, saturday}).Return([]models.DutyAssignment{}, nil)
+
+	// the duties that don't need the event are assigned right away; the error makes the consumer retry the rest
+	err := service.CreateDutyAssignments(context.Background(), shiftId, 3, festivalClockIn)
+
+	require.ErrorContains(t, err, "connection refused")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateDutyAssignments_NoEvent(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	mockEventClient := new(mocks.MockEventClient)
+	service := services.NewDutyAssignmentService(mockRepo, mockDutyRepo, nil, nil, nil, mockEventClient, nil, nil, nil)
+
+	shiftId := uuid.New()
+	always := models.Duty{RowKey: uuid.New(), DutyName: "Clean grill"}
+	outdoor := models.Duty{RowKey: uuid.New(), DutyName: "Set up tent", AppliesTo: &models.DutyApplicability{EventTags: []string{"outdoor"}}}
+
+	mockDutyRepo.On("GetDutiesByRole", mock.Anything, 3).Return([]models.Duty{always, outdoor}, nil)
+	mockEventClient.On("GetEventByShiftId", mock.Anything, shiftId).Return(nil, services.ErrEventNotFound)
+	mockRepo.On("CreateDutyAssignments", mock.Anything, shiftId, 3, (*uuid.UUID)(nil), "", []models.Duty{always}).Return([]models.DutyAssignment{}, nil)
+
+	// a shift without an event only gets the duties that don't need one, and isn't retried
+	err := service.CreateDutyAssignments(context.Background(), shiftId, 3, festivalClockIn)
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+// FAILURE CASES:
+func TestDutyApplicability_Validate(t *testing.T) {
+	require.NoError(t, (*models.DutyApplicability)(nil).Validate())
+	require.NoError(t, (&models.DutyApplicability{Weekdays: []string{"friday"}, Venues: []string{"Harbour"}}).Validate())
+
+	require.Error(t, (&models.DutyApplicability{Weekdays: []string{"Fri"}}).Validate())
+	require.Error(t, (&models.DutyApplicability{EventTags: []string{" "}}).Validate())
+}
+
+func TestImportDuties_InvalidApplicability(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyService(mockRepo, new(mocks.MockDutyVersionRepository))
+
+	mockRepo.On("GetAllDuties", mock.Anything, "").Return([]models.Duty{}, nil)
+
+	_, err := service.ImportDuties(context.Background(), []models.DutyTemplate{
+		{RoleId: 2, DutyName: "Set up tent", AppliesTo: &models.DutyApplicability{Weekdays: []string{"Someday"}}},
+	}, models.DutyImportOptions{})
+
+	var importErr *models.DutyImportError
+	require.ErrorAs(t, err, &importErr)
+	require.Len(t, importErr.Problems, 1)
+	mockRepo.AssertNotCalled(t, "CreateDuty", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,122 @@
+package unit_tests
+
+import (
+	"context"
+	"duty-service/auth"
+	"duty-service/handlers"
+	"duty-service/middlewares"
+	"duty-service/models"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// SUCCESS CASES:
+func TestReassignDutyAssignment_RecordsHandover(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	mockHistoryRepo := new(mocks.MockDutyAssignmentHistoryRepository)
+	mockPublisher := new(mocks.MockMessagePublisher)
+	service := services.NewDutyAssignmentService(mockRepo, mockDutyRepo, nil, nil, mockHistoryRepo, nil, mockPublisher, nil, nil)
+
+	shiftId, dutyId, eventId := uuid.New(), uuid.New(), uuid.New()
+	grill := &models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), DutyName: "Clean grill"}
+	existing := &models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, EventId: &eventId, AssigneeId: "employee-1", DutyPartitionKey: "Duty", DutyRowKey: grill.RowKey}
+	note := "Ana is on break"
+
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(existing, nil)
+	mockRepo.On("GetDutyAssignmentsByEventId", mock.Anything, eventId).Return([]models.DutyAssignment{{AssigneeId: "employee-1"}, {AssigneeId: "employee-2"}}, nil)
+	mockRepo.On("UpdateAssignee", mock.Anything, shiftId, dutyId, "employee-2").Return(nil)
+	mockHistoryRepo.On("AddEntry", mock.Anything, mock.MatchedBy(func(entry models.DutyAssignmentHistoryEntry) bool {
+		return entry.Action == models.HistoryReassigned && entry.FromAssigneeId == "employee-1" && entry.ToAssigneeId == "employee-2" &&
+			entry.ChangedBy == "lead-1" && *entry.Note == note
+	})).Return(nil)
+	mockDutyRepo.On("GetDutyById", mock.Anything, "Duty", grill.RowKey.String()).Return(grill, nil)
+	mockPublisher.On("PublishToExchange", services.DutyEventsExchange, services.DutyAssignmentReassignedKey, mock.MatchedBy(func(message models.DutyAssignmentEventMessage) bool {
+		return message.AssigneeID == "employee-2" && message.PreviousAssigneeID == "employee-1" && message.DutyName == "Clean grill"
+	})).Return(nil)
+
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Subject: "lead-1", Roles: []string{auth.RoleLead}})
+	dutyAssignment, err := service.ReassignDutyAssignment(ctx, shiftId, dutyId, "employee-2", &note)
+
+	require.NoError(t, err)
+	require.Equal(t, "employee-2", dutyAssignment.AssigneeId)
+	mockRepo.AssertExpectations(t)
+	mockHistoryRepo.AssertExpectations(t)
+	mockPublisher.AssertExpectations(t)
+}
+
+func TestGetDutyAssignmentsByAssignee_OnlyCurrentShifts(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	frozenAt := time.Now().UTC()
+	open := models.DutyAssignment{RowKey: uuid.New(), AssigneeId: "employee-1"}
+	mockRepo.On("GetDutyAssignmentsByAssignee", mock.Anything, "employee-1", (*uuid.UUID)(nil)).Return([]models.DutyAssignment{open, {RowKey: uuid.New(), AssigneeId: "employee-1", FrozenAt: &frozenAt}}, nil)
+
+	dutyAssignments, err := service.GetDutyAssignmentsByAssignee(context.Background(), "employee-1", nil)
+
+	require.NoError(t, err)
+	require.Equal(t, []models.DutyAssignment{open}, dutyAssignments)
+}
+
+func TestGetAllDutyAssignments_ByEmployeeId(t *testing.T) {
+	mockService := new(mocks.MockDutyAssignmentService)
+	handler := handlers.NewDutyAssignmentHandler(mockService)
+
+	mockService.On("GetDutyAssignmentsByAssignee", mock.Anything, "employee-1", (*uuid.UUID)(nil)).Return([]models.DutyAssignment{}, nil)
+	mockService.On("LocalizeDutyAssignments", mock.Anything, []models.DutyAssignment{}).Return([]models.DutyAssignment{}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/duty-assignments?employeeId=employee-1", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetAllDutyAssignmentsByShiftId(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+// FAILURE CASES:
+func TestReassignDutyAssignment_AssigneeNotOnEvent(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, nil, new(mocks.MockDutyAssignmentHistoryRepository), nil, nil, nil, nil)
+
+	shiftId, dutyId, eventId := uuid.New(), uuid.New(), uuid.New()
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, EventId: &eventId, AssigneeId: "employee-1"}, nil)
+	mockRepo.On("GetDutyAssignmentsByEventId", mock.Anything, eventId).Return([]models.DutyAssignment{{AssigneeId: "employee-1"}}, nil)
+
+	_, err := service.ReassignDutyAssignment(context.Background(), shiftId, dutyId, "employee-9", nil)
+
+	require.ErrorIs(t, err, services.ErrAssigneeNotOnEvent)
+	mockRepo.AssertNotCalled(t, "UpdateAssignee", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReassignDutyAssignment_RequiresLeadOrAdmin(t *testing.T) {
+	mockService := new(mocks.MockDutyAssignmentService)
+	handler := middlewares.RequireIdentity(auth.RoleLead, auth.RoleAdmin)(http.HandlerFunc(handlers.NewDutyAssignmentHandler(mockService).ReassignDutyAssignment))
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPut, "/duty-assignments/"+uuid.NewString()+"/"+uuid.NewString()+"/assignee", strings.NewReader(`{"AssigneeId":"employee-2"}`))
+		return mux.SetURLVars(req, map[string]string{"ShiftId": uuid.NewString(), "DutyId": uuid.NewString()})
+	}
+
+	anonymous := httptest.NewRecorder()
+	handler.ServeHTTP(anonymous, newRequest())
+	require.Equal(t, http.StatusUnauthorized, anonymous.Code)
+
+	crew := httptest.NewRecorder()
+	req := newRequest()
+	handler.ServeHTTP(crew, req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{Subject: "employee-1"})))
+	require.Equal(t, http.StatusForbidden, crew.Code)
+
+	mockService.AssertNotCalled(t, "ReassignDutyAssignment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,155 @@
+package unit_tests
+
+import (
+	"context"
+	"duty-service/auth"
+	"duty-service/handlers"
+	"duty-service/images"
+	"duty-service/models"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func withCaller(subject string, roles ...string) context.Context {
+	return auth.WithIdentity(context.Background(), auth.Identity{Subject: subject, Roles: roles})
+}
+
+// SUCCESS CASES:
+func TestParseMentions(t *testing.T) {
+	mentions := models.ParseMentions("@anna can you check the fryer? cc @bob.smith, not mail@example.com. Thanks @anna.")
+
+	require.Equal(t, []string{"anna", "bob.smith"}, mentions)
+}
+
+func TestAddComment_PublishesMentions(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockCommentRepo := new(mocks.MockDutyAssignmentCommentRepository)
+	mockPublisher := new(mocks.MockMessagePublisher)
+	service := services.NewDutyAssignmentCommentService(mockRepo, mockCommentRepo, mockPublisher)
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	text := "@employee-2 the grill is still dirty, @employee-1 will check later"
+	stored := models.DutyAssignmentComment{RowKey: uuid.New(), ShiftId: shiftId, DutyId: dutyId, AuthorId: "employee-1", Text: text, Mentions: []string{"employee-2", "employee-1"}}
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId}, nil)
+	mockCommentRepo.On("AddComment", mock.Anything, mock.MatchedBy(func(comment models.DutyAssignmentComment) bool {
+		return comment.AuthorId == "employee-1" && comment.Text == text && len(comment.Mentions) == 2
+	}), []*images.ProcessedImage{}).Return(stored, nil)
+	mockPublisher.On("PublishToExchange", services.DutyEventsExchange, services.DutyCommentMentionedKey, mock.MatchedBy(func(message models.DutyCommentMentionMessage) bool {
+		return message.MentionedID == "employee-2" && message.AuthorID == "employee-1" && message.DutyID == dutyId
+	})).Return(nil).Once()
+
+	// mentioning yourself doesn't notify anyone
+	comment, err := service.AddComment(withCaller("employee-1"), shiftId, dutyId, text, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"employee-2", "employee-1"}, comment.Mentions)
+	mockCommentRepo.AssertExpectations(t)
+	mockPublisher.AssertExpectations(t)
+}
+
+func TestUpdateComment_OnlyNotifiesNewMentions(t *testing.T) {
+	mockCommentRepo := new(mocks.MockDutyAssignmentCommentRepository)
+	mockPublisher := new(mocks.MockMessagePublisher)
+	service := services.NewDutyAssignmentCommentService(nil, mockCommentRepo, mockPublisher)
+
+	shiftId, dutyId, commentId := uuid.New(), uuid.New(), uuid.New()
+	existing := &models.DutyAssignmentComment{RowKey: commentId, ShiftId: shiftId, DutyId: dutyId, AuthorId: "employee-1", Text: "@employee-2 please check", Mentions: []string{"employee-2"}, CreatedAt: time.Now().UTC()}
+	mockCommentRepo.On("GetComment", mock.Anything, shiftId, dutyId, commentId).Return(existing, nil)
+	mockCommentRepo.On("UpdateComment", mock.Anything, mock.MatchedBy(func(comment models.DutyAssignmentComment) bool {
+		return comment.UpdatedAt != nil && comment.Text == "@employee-2 @employee-3 please check"
+	})).Return(nil)
+	mockPublisher.On("PublishToExchange", services.DutyEventsExchange, services.DutyCommentMentionedKey, mock.MatchedBy(func(message models.DutyCommentMentionMessage) bool {
+		return message.MentionedID == "employee-3"
+	})).Return(nil).Once()
+
+	comment, err := service.UpdateComment(withCaller("employee-1"), shiftId, dutyId, commentId, "@employee-2 @employee-3 please check")
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"employee-2", "employee-3"}, comment.Mentions)
+	mockPublisher.AssertExpectations(t)
+}
+
+func TestDeleteComment_ByAdmin(t *testing.T) {
+	mockCommentRepo := new(mocks.MockDutyAssignmentCommentRepository)
+	service := services.NewDutyAssignmentCommentService(nil, mockCommentRepo, nil)
+
+	shiftId, dutyId, commentId := uuid.New(), uuid.New(), uuid.New()
+	existing := &models.DutyAssignmentComment{RowKey: commentId, ShiftId: shiftId, DutyId: dutyId, AuthorId: "employee-1"}
+	mockCommentRepo.On("GetComment", mock.Anything, shiftId, dutyId, commentId).Return(existing, nil)
+	mockCommentRepo.On("DeleteComment", mock.Anything, *existing).Return(nil)
+
+	err := service.DeleteComment(withCaller("admin-1", auth.RoleAdmin), shiftId, dutyId, commentId)
+
+	require.NoError(t, err)
+	mockCommentRepo.AssertExpectations(t)
+}
+
+func TestAddCommentHandler_Created(t *testing.T) {
+	mockService := new(mocks.MockDutyAssignmentCommentService)
+	handler := handlers.NewDutyAssignmentCommentHandler(mockService)
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	mockService.On("AddComment", mock.Anything, shiftId, dutyId, "Out of degreaser", []multipart.File(nil)).Return(&models.DutyAssignmentComment{ShiftId: shiftId, DutyId: dutyId, Text: "Out of degreaser"}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/duties/duty-assignments/"+shiftId.String()+"/"+dutyId.String()+"/comments", strings.NewReader(`{"Text":"Out of degreaser"}`))
+	req = mux.SetURLVars(req, map[string]string{"ShiftId": shiftId.String(), "DutyId": dutyId.String()})
+	rec := httptest.NewRecorder()
+
+	handler.AddComment(rec, req)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Contains(t, rec.Body.String(), "Out of degreaser")
+}
+
+// FAILURE CASES:
+func TestAddComment_RequiresTextOrPhoto(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockCommentRepo := new(mocks.MockDutyAssignmentCommentRepository)
+	service := services.NewDutyAssignmentCommentService(mockRepo, mockCommentRepo, nil)
+
+	_, err := service.AddComment(withCaller("employee-1"), uuid.New(), uuid.New(), "   ", nil)
+
+	require.ErrorIs(t, err, models.ErrInvalidComment)
+	mockCommentRepo.AssertNotCalled(t, "AddComment", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateComment_NotTheAuthor(t *testing.T) {
+	mockCommentRepo := new(mocks.MockDutyAssignmentCommentRepository)
+	service := services.NewDutyAssignmentCommentService(nil, mockCommentRepo, nil)
+
+	shiftId, dutyId, commentId := uuid.New(), uuid.New(), uuid.New()
+	mockCommentRepo.On("GetComment", mock.Anything, shiftId, dutyId, commentId).Return(&models.DutyAssignmentComment{RowKey: commentId, AuthorId: "employee-1"}, nil)
+
+	// not even admins can put words in someone else's mouth
+	_, err := service.UpdateComment(withCaller("admin-1", auth.RoleAdmin), shiftId, dutyId, commentId, "edited")
+
+	require.ErrorIs(t, err, services.ErrNotCommentAuthor)
+	mockCommentRepo.AssertNotCalled(t, "UpdateComment", mock.Anything, mock.Anything)
+}
+
+func TestDeleteCommentHandler_NotTheAuthor(t *testing.T) {
+	mockService := new(mocks.MockDutyAssignmentCommentService)
+	handler := handlers.NewDutyAssignmentCommentHandler(mockService)
+
+	shiftId, dutyId, commentId := uuid.New(), uuid.New(), uuid.New()
+	mockService.On("DeleteComment", mock.Anything, shiftId, dutyId, commentId).Return(services.ErrNotCommentAuthor)
+
+	req := httptest.NewRequest(http.MethodDelete, "/duties/duty-assignments/"+shiftId.String()+"/"+dutyId.String()+"/comments/"+commentId.String(), nil)
+	req = mux.SetURLVars(req, map[string]string{"ShiftId": shiftId.String(), "DutyId": dutyId.String(), "CommentId": commentId.String()})
+	rec := httptest.NewRecorder()
+
+	handler.DeleteComment(rec, req)
+
+	require.Equal(t, http.StatusForbidden, rec.Code)
+}
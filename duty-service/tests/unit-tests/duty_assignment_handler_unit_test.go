@@ -5,12 +5,14 @@ import (
 	"context"
 	"duty-service/handlers"
 	"duty-service/models"
+	"duty-service/repositories"
 	"duty-service/tests/mocks"
 	"encoding/json"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -41,6 +43,7 @@ func TestGetAllDutyAssignmentsByShiftId_Success(t *testing.T) {
 	}
 
 	mockService.On("GetAllDutyAssignmentsByShiftId", context.Background(), mock.AnythingOfType("uuid.UUID")).Return(mockDutyAssignments, nil)
+	mockService.On("LocalizeDutyAssignments", mock.Anything, mockDutyAssignments).Return(mockDutyAssignments, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/duty-assignments?shiftId=d9b2d63d-bbf7-4f2f-9d7c-0e67f060d8b0", nil)
 	rec := httptest.NewRecorder()
@@ -67,7 +70,7 @@ func TestCreateDutyAssignments_Success(t *testing.T) {
 	shiftId := uuid.MustParse("d9b2d63d-bbf7-4f2f-9d7c-0e67f060d8b0")
 	roleId := 1
 
-	mockService.On("CreateDutyAssignments", context.Background(), shiftId, roleId).Return(nil)
+	mockService.On("CreateDutyAssignments", context.Background(), shiftId, roleId, mock.AnythingOfType("time.Time")).Return(nil)
 
 	requestBody := map[string]interface{}{
 		"ShiftId": shiftId.String(),
@@ -176,3 +179,196 @@ func TestDeleteDutyAssignment_Success(t *testing.T) {
 
 	mockService.AssertExpectations(t)
 }
+
+func TestGetDutyAssignmentPhotos_Success(t *testing.T) {
+	mockService := new(mocks.MockDutyAssignmentService)
+	handler := handlers.NewDutyAssignmentHandler(mockService)
+
+	shiftId := uuid.New()
+	dutyId := uuid.New()
+
+	mockPhotos := []models.DutyAssignmentPhoto{
+		{
+			PartitionKey: models.DutyAssignmentPhotoPartitionKey(shiftId, dutyId),
+			RowKey:       uuid.New(),
+			ShiftId:      shiftId,
+			DutyId:       dutyId,
+			ImageUrl:     "https://example.com/photo.jpg",
+			ThumbnailUrl: "https://example.com/photo_thumb.jpg",
+			ContentType:  "image/jpeg",
+			UploadedAt:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			UploadedBy:   "user-1",
+		},
+	}
+
+	mockService.On("GetDutyAssignmentPhotos", mock.Anything, shiftId, dutyId).Return(mockPhotos, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/duty-assignments/"+shiftId.String()+"/"+dutyId.String()+"/photos", nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"ShiftId": shiftId.String(),
+		"DutyId":  dutyId.String(),
+	})
+
+	rec := httptest.NewRecorder()
+
+	handler.GetDutyAssignmentPhotos(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	require.Equal(t, "application/json", rec.Result().Header.Get("Content-Type"))
+
+	var response []models.DutyAssignmentPhoto
+	err := json.NewDecoder(rec.Body).Decode(&response)
+	require.NoError(t, err)
+	require.Equal(t, mockPhotos, response)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestRedirectToDutyAssignmentImage_Success(t *testing.T) {
+	mockService := new(mocks.MockDutyAssignmentService)
+	handler := handlers.NewDutyAssignmentHandler(mockService)
+
+	shiftId := uuid.New()
+	dutyId := uuid.New()
+	signedUrl := "https://example.blob.core.windows.net/dutyassignmentimages/photo.jpg?se=2024-05-01T12%3A15%3A00Z&sig=abc"
+
+	mockService.On("GetDutyAssignmentImageUrl", mock.Anything, shiftId, dutyId, true).Return(signedUrl, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/duty-assignments/"+shiftId.String()+"/"+dutyId.String()+"/image?thumbnail=true", nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"ShiftId": shiftId.String(),
+		"DutyId":  dutyId.String(),
+	})
+
+	rec := httptest.NewRecorder()
+
+	handler.RedirectToDutyAssignmentImage(rec, req)
+
+	require.Equal(t, http.StatusFound, rec.Result().StatusCode)
+	require.Equal(t, signedUrl, rec.Result().Header.Get("Location"))
+	require.Equal(t, "no-store", rec.Result().Header.Get("Cache-Control"))
+	mockService.AssertExpectations(t)
+}
+
+// FAILURE CASES:
+func TestUpdateDutyAssignment_InvalidFormValues(t *testing.T) {
+	mockService := new(mocks.MockDutyAssignmentService)
+	handler := handlers.NewDutyAssignmentHandler(mockService)
+
+	partitionKey := uuid.New()
+	rowKey := uuid.New()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	_ = writer.WriteField("PartitionKey", partitionKey.String())
+	_ = writer.WriteField("RowKey", rowKey.String())
+	_ = writer.WriteField("DutyAssignmentStatus", string(models.StatusCompleted))
+	_ = writer.WriteField("FormValues", `{"stockCount": -3}`)
+	writer.Close()
+
+	// the service rejects the value because it is below the field's minimum
+	mockService.On("UpdateDutyAssignment", mock.Anything, mock.AnythingOfType("models.DutyAssignment"), mock.Anything).
+		Return(&models.FormValidationError{Field: "stockCount", Reason: "must be at least 0pcs"})
+
+	req := httptest.NewRequest(http.MethodPut, "/duty-assignments/"+partitionKey.String()+"/"+rowKey.String(), body)
+	req = mux.SetURLVars(req, map[string]string{
+		"ShiftId": partitionKey.String(),
+		"DutyId":  rowKey.String(),
+	})
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	rec := httptest.NewRecorder()
+
+	handler.UpdateDutyAssignment(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Result().StatusCode)
+	require.Equal(t, "invalid form field 'stockCount': must be at least 0pcs\n", rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestUpdateDutyAssignment_MalformedFormValues(t *testing.T) {
+	mockService := new(mocks.MockDutyAssignmentService)
+	handler := handlers.NewDutyAssignmentHandler(mockService)
+
+	partitionKey := uuid.New()
+	rowKey := uuid.New()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	_ = writer.WriteField("PartitionKey", partitionKey.String())
+	_ = writer.WriteField("RowKey", rowKey.String())
+	_ = writer.WriteField("DutyAssignmentStatus", string(models.StatusIncomplete))
+	_ = writer.WriteField("FormValues", `not json`)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPut, "/duty-assignments/"+partitionKey.String()+"/"+rowKey.String(), body)
+	req = mux.SetURLVars(req, map[string]string{
+		"ShiftId": partitionKey.String(),
+		"DutyId":  rowKey.String(),
+	})
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	rec := httptest.NewRecorder()
+
+	handler.UpdateDutyAssignment(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Result().StatusCode)
+	require.Contains(t, rec.Body.String(), "Invalid FormValues")
+	mockService.AssertNotCalled(t, "UpdateDutyAssignment", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateDutyAssignment_NotFound(t *testing.T) {
+	mockService := new(mocks.MockDutyAssignmentService)
+	handler := handlers.NewDutyAssignmentHandler(mockService)
+
+	partitionKey := uuid.New()
+	rowKey := uuid.New()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	_ = writer.WriteField("PartitionKey", partitionKey.String())
+	_ = writer.WriteField("RowKey", rowKey.String())
+	_ = writer.WriteField("DutyAssignmentStatus", string(models.StatusCompleted))
+	writer.Close()
+
+	// missing or soft deleted
+	mockService.On("UpdateDutyAssignment", mock.Anything, mock.AnythingOfType("models.DutyAssignment"), mock.Anything).
+		Return(repositories.ErrDutyAssignmentNotFound)
+
+	req := httptest.NewRequest(http.MethodPut, "/duty-assignments/"+partitionKey.String()+"/"+rowKey.String(), body)
+	req = mux.SetURLVars(req, map[string]string{
+		"ShiftId": partitionKey.String(),
+		"DutyId":  rowKey.String(),
+	})
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	rec := httptest.NewRecorder()
+
+	handler.UpdateDutyAssignment(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Result().StatusCode)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeleteDutyAssignmentPhoto_NotFound(t *testing.T) {
+	mockService := new(mocks.MockDutyAssignmentService)
+	handler := handlers.NewDutyAssignmentHandler(mockService)
+
+	shiftId, dutyId, photoId := uuid.New(), uuid.New(), uuid.New()
+
+	mockService.On("DeleteDutyAssignmentPhoto", mock.Anything, shiftId, dutyId, photoId).Return(repositories.ErrPhotoNotFound)
+
+	req := httptest.NewRequest(http.MethodDelete, "/duty-assignments/"+shiftId.String()+"/"+dutyId.String()+"/photos/"+photoId.String(), nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"ShiftId": shiftId.String(),
+		"DutyId":  dutyId.String(),
+		"PhotoId": photoId.String(),
+	})
+
+	rec := httptest.NewRecorder()
+
+	handler.DeleteDutyAssignmentPhoto(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Result().StatusCode)
+	mockService.AssertExpectations(t)
+}
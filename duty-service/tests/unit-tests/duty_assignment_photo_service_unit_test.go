@@ -0,0 +1,149 @@
+package unit_tests
+
+import (
+	"bytes"
+	"context"
+	"duty-service/auth"
+	"duty-service/models"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// an in-memory upload that satisfies multipart.File
+type testUpload struct {
+	*bytes.Reader
+}
+
+func (testUpload) Close() error { return nil }
+
+// SUCCESS CASES:
+func TestUpdateDutyAssignment_AddsPhotoWithUploader(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockPhotoRepo := new(mocks.MockDutyAssignmentPhotoRepository)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, mockPhotoRepo, nil, nil, nil, nil, nil)
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	var upload bytes.Buffer
+	require.NoError(t, png.Encode(&upload, testImage(64, 64)))
+
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId}, nil)
+	mockPhotoRepo.On("AddPhoto", mock.Anything, mock.MatchedBy(func(photo models.DutyAssignmentPhoto) bool {
+		return photo.ShiftId == shiftId && photo.DutyId == dutyId && photo.UploadedBy == "user-1" && photo.RowKey != uuid.Nil
+	}), mock.Anything).Return(models.DutyAssignmentPhoto{BlobName: "image.png", ThumbnailBlobName: "image_thumb.jpg"}, nil)
+	mockRepo.On("UpdateDutyAssignment", mock.Anything, mock.MatchedBy(func(dutyAssignment models.DutyAssignment) bool {
+		return dutyAssignment.ImageBlobName == "image.png" && dutyAssignment.ThumbnailBlobName == "image_thumb.jpg"
+	})).Return(nil)
+
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Subject: "user-1"})
+	err := service.UpdateDutyAssignment(ctx, models.DutyAssignment{
+		PartitionKey:         shiftId,
+		RowKey:               dutyId,
+		DutyAssignmentStatus: models.StatusCompleted,
+	}, testUpload{bytes.NewReader(upload.Bytes())})
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockPhotoRepo.AssertExpectations(t)
+}
+
+func TestDeleteDutyAssignmentPhoto_MainPhotoFallsBackToNewest(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockPhotoRepo := new(mocks.MockDutyAssignmentPhotoRepository)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, mockPhotoRepo, nil, nil, nil, nil, nil)
+
+	shiftId, dutyId, photoId := uuid.New(), uuid.New(), uuid.New()
+	deleted := models.DutyAssignmentPhoto{RowKey: photoId, BlobName: "main.png"}
+	remaining := []models.DutyAssignmentPhoto{
+		{BlobName: "newer.png", ThumbnailBlobName: "newer_thumb.jpg", UploadedAt: time.Now()},
+		{BlobName: "older.png", ThumbnailBlobName: "older_thumb.jpg", UploadedAt: time.Now().Add(-time.Hour)},
+	}
+
+	mockPhotoRepo.On("GetPhoto", mock.Anything, shiftId, dutyId, photoId).Return(&deleted, nil)
+	mockPhotoRepo.On("DeletePhoto", mock.Anything, deleted).Return(nil)
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&models.DutyAssignment{ImageBlobName: "main.png"}, nil)
+	mockPhotoRepo.On("GetPhotos", mock.Anything, shiftId, dutyId).Return(remaining, nil)
+	mockRepo.On("SetMainPhoto", mock.Anything, shiftId, dutyId, "newer.png", "newer_thumb.jpg").Return(nil)
+
+	err := service.DeleteDutyAssignmentPhoto(context.Background(), shiftId, dutyId, photoId)
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockPhotoRepo.AssertExpectations(t)
+}
+
+func TestDeleteDutyAssignmentPhoto_LastPhotoClearsMainPhoto(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockPhotoRepo := new(mocks.MockDutyAssignmentPhotoRepository)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, mockPhotoRepo, nil, nil, nil, nil, nil)
+
+	shiftId, dutyId, photoId := uuid.New(), uuid.New(), uuid.New()
+	deleted := models.DutyAssignmentPhoto{RowKey: photoId, BlobName: "main.png"}
+
+	mockPhotoRepo.On("GetPhoto", mock.Anything, shiftId, dutyId, photoId).Return(&deleted, nil)
+	mockPhotoRepo.On("DeletePhoto", mock.Anything, deleted).Return(nil)
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&models.DutyAssignment{ImageBlobName: "main.png"}, nil)
+	mockPhotoRepo.On("GetPhotos", mock.Anything, shiftId, dutyId).Return([]models.DutyAssignmentPhoto{}, nil)
+	mockRepo.On("SetMainPhoto", mock.Anything, shiftId, dutyId, "", "").Return(nil)
+
+	err := service.DeleteDutyAssignmentPhoto(context.Background(), shiftId, dutyId, photoId)
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockPhotoRepo.AssertExpectations(t)
+}
+
+func TestDeleteDutyAssignmentPhoto_OlderPhotoKeepsMainPhoto(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockPhotoRepo := new(mocks.MockDutyAssignmentPhotoRepository)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, mockPhotoRepo, nil, nil, nil, nil, nil)
+
+	shiftId, dutyId, photoId := uuid.New(), uuid.New(), uuid.New()
+	deleted := models.DutyAssignmentPhoto{RowKey: photoId, BlobName: "older.png"}
+
+	mockPhotoRepo.On("GetPhoto", mock.Anything, shiftId, dutyId, photoId).Return(&deleted, nil)
+	mockPhotoRepo.On("DeletePhoto", mock.Anything, deleted).Return(nil)
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&models.DutyAssignment{ImageBlobName: "main.png"}, nil)
+
+	err := service.DeleteDutyAssignmentPhoto(context.Background(), shiftId, dutyId, photoId)
+
+	require.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "SetMainPhoto", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetDutyAssignmentImageUrl_NoImage(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId}, nil)
+
+	_, err := service.GetDutyAssignmentImageUrl(context.Background(), shiftId, dutyId, false)
+
+	require.ErrorIs(t, err, services.ErrNoImage)
+}
+
+func TestDeleteDutyAssignment_KeepsPhotos(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockPhotoRepo := new(mocks.MockDutyAssignmentPhotoRepository)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, mockPhotoRepo, nil, nil, nil, nil, nil)
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+
+	// a soft delete keeps the photos, so the assignment can be restored with them
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId}, nil)
+	mockRepo.On("SetDutyAssignmentDeleted", mock.Anything, shiftId, dutyId, mock.AnythingOfType("*time.Time"), "").Return(nil)
+
+	err := service.DeleteDutyAssignment(context.Background(), shiftId, dutyId)
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockPhotoRepo.AssertNotCalled(t, "DeletePhoto", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,169 @@
+package unit_tests
+
+import (
+	"bytes"
+	"context"
+	"duty-service/auth"
+	"duty-service/handlers"
+	"duty-service/images"
+	"duty-service/models"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"encoding/json"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// the start of a PDF file, enough for content sniffing
+var testPDF = []byte("%PDF-1.7\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+
+// attachmentRequest builds a multipart request that uploads data as the "file" of a duty's attachment
+func attachmentRequest(t *testing.T, dutyId uuid.UUID, title string, data []byte) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	_ = writer.WriteField("Title", title)
+	part, err := writer.CreateFormFile("file", "instructions.pdf")
+	require.NoError(t, err)
+	_, _ = part.Write(data)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/duties/Duty/"+dutyId.String()+"/attachments", body)
+	req = mux.SetURLVars(req, map[string]string{"PartitionKey": "Duty", "RowKey": dutyId.String()})
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// SUCCESS CASES:
+func TestProcessAttachment_Kinds(t *testing.T) {
+	document, err := images.ProcessAttachment(bytes.NewReader(testPDF))
+	require.NoError(t, err)
+	require.Equal(t, images.AttachmentDocument, document.Kind)
+	require.Equal(t, images.ContentTypePDF, document.File.ContentType)
+	require.Equal(t, testPDF, document.File.Data) // kept as uploaded
+	require.Nil(t, document.Thumbnail)
+
+	var upload bytes.Buffer
+	require.NoError(t, png.Encode(&upload, testImage(64, 64)))
+	image, err := images.ProcessAttachment(&upload)
+	require.NoError(t, err)
+	require.Equal(t, images.AttachmentImage, image.Kind)
+	require.NotNil(t, image.Thumbnail)
+}
+
+func TestAddDutyAttachment_StoresDocumentWithUploader(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyService(mockRepo, nil)
+
+	duty := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), DutyName: "Clean grill"}
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil)
+	mockRepo.On("AddDutyAttachment", mock.Anything, duty, mock.MatchedBy(func(attachment models.DutyAttachment) bool {
+		return attachment.Title == "How to" && attachment.FileName == "grill.pdf" && attachment.UploadedBy == "admin-1" && attachment.Id != uuid.Nil
+	}), mock.MatchedBy(func(file *images.ProcessedAttachment) bool {
+		return file.Kind == images.AttachmentDocument
+	})).Return(models.DutyAttachment{Kind: images.AttachmentDocument, Url: "https://blob/duty.pdf"}, nil)
+
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Subject: "admin-1"})
+	attachment, err := service.AddDutyAttachment(ctx, "Duty", duty.RowKey.String(), "How to", "grill.pdf", testUpload{bytes.NewReader(testPDF)})
+
+	require.NoError(t, err)
+	require.Equal(t, "https://blob/duty.pdf", attachment.Url)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAddDutyAttachmentHandler_Created(t *testing.T) {
+	mockService := new(mocks.MockDutyService)
+	handler := handlers.NewDutyHandler(mockService)
+
+	dutyId := uuid.New()
+	created := &models.DutyAttachment{Id: uuid.New(), Kind: images.AttachmentDocument, Title: "How to", FileName: "instructions.pdf"}
+	mockService.On("AddDutyAttachment", mock.Anything, "Duty", dutyId.String(), "How to", "instructions.pdf", mock.Anything).Return(created, nil)
+
+	rec := httptest.NewRecorder()
+	handler.AddDutyAttachment(rec, attachmentRequest(t, dutyId, " How to ", testPDF))
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var response models.DutyAttachment
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.Equal(t, created.Id, response.Id)
+}
+
+func TestLocalizeDutyAssignments_AddsTemplateAttachments(t *testing.T) {
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyAssignmentService(nil, mockDutyRepo, nil, nil, nil, nil, nil, nil, nil)
+
+	duty := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), DutyName: "Clean grill", Attachments: []models.DutyAttachment{
+		{Id: uuid.New(), Kind: images.AttachmentVideo, Url: "https://blob/duty.mp4", UploadedAt: time.Now()},
+	}}
+	mockDutyRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil)
+
+	resolved, err := service.LocalizeDutyAssignments(context.Background(), []models.DutyAssignment{
+		{PartitionKey: uuid.New(), RowKey: uuid.New(), DutyPartitionKey: "Duty", DutyRowKey: duty.RowKey},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, duty.Attachments, resolved[0].Attachments)
+}
+
+// FAILURE CASES:
+func TestProcessAttachment_Unsupported(t *testing.T) {
+	_, err := images.ProcessAttachment(bytes.NewReader([]byte("just some text, not an attachment")))
+	require.ErrorIs(t, err, images.ErrUnsupportedAttachment)
+}
+
+func TestAddDutyAttachment_DeletedOrFullDuty(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyService(mockRepo, nil)
+
+	deletedAt := time.Now()
+	deleted := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), DeletedAt: &deletedAt}
+	full := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), Attachments: make([]models.DutyAttachment, models.MaxDutyAttachments)}
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", deleted.RowKey.String()).Return(&deleted, nil)
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", full.RowKey.String()).Return(&full, nil)
+
+	_, err := service.AddDutyAttachment(context.Background(), "Duty", deleted.RowKey.String(), "", "grill.pdf", testUpload{bytes.NewReader(testPDF)})
+	require.ErrorIs(t, err, services.ErrDutyDeleted)
+
+	_, err = service.AddDutyAttachment(context.Background(), "Duty", full.RowKey.String(), "", "grill.pdf", testUpload{bytes.NewReader(testPDF)})
+	require.ErrorIs(t, err, services.ErrTooManyDutyAttachments)
+
+	mockRepo.AssertNotCalled(t, "AddDutyAttachment", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAddDutyAttachmentHandler_UnsupportedType(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	handler := handlers.NewDutyHandler(services.NewDutyService(mockRepo, nil))
+
+	duty := models.Duty{PartitionKey: "Duty", RowKey: uuid.New()}
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil)
+
+	rec := httptest.NewRecorder()
+	handler.AddDutyAttachment(rec, attachmentRequest(t, duty.RowKey, "", []byte("#!/bin/sh\necho not a pdf\n")))
+
+	require.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+}
+
+func TestDeleteDutyAttachmentHandler_NotFound(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	handler := handlers.NewDutyHandler(services.NewDutyService(mockRepo, nil))
+
+	duty := models.Duty{PartitionKey: "Duty", RowKey: uuid.New()}
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil)
+
+	attachmentId := uuid.NewString()
+	req := httptest.NewRequest(http.MethodDelete, "/duties/Duty/"+duty.RowKey.String()+"/attachments/"+attachmentId, nil)
+	req = mux.SetURLVars(req, map[string]string{"PartitionKey": "Duty", "RowKey": duty.RowKey.String(), "AttachmentId": attachmentId})
+	rec := httptest.NewRecorder()
+
+	handler.DeleteDutyAttachment(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,192 @@
+package unit_tests
+
+import (
+	"bytes"
+	"context"
+	"duty-service/handlers"
+	"duty-service/locale"
+	"duty-service/models"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testCompletionFormURL = "https://crew.example.com/duties/{ShiftId}/{DutyId}"
+
+// a checklist with a translated duty that has a form
+func testChecklist() *models.DutyChecklist {
+	dueAt := time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC)
+	return &models.DutyChecklist{
+		ShiftId:     uuid.New(),
+		GeneratedAt: time.Now().UTC(),
+		Items: []models.DutyChecklistItem{{
+			DutyId:          uuid.New(),
+			DutyName:        "Wyczyść grill",
+			DutyDescription: "Wyszoruj ruszty",
+			Status:          models.StatusIncomplete,
+			Mandatory:       true,
+			DueAt:           &dueAt,
+			FormFields: []models.FormField{
+				{Key: "temperature", Label: "Fridge temperature", Type: models.FieldNumber, Unit: "°C", Required: true},
+				{Key: "oil", Label: "Oil changed", Type: models.FieldCheckbox},
+			},
+			FormUrl: "https://crew.example.com/duties/a/b",
+		}},
+	}
+}
+
+// SUCCESS CASES:
+func TestCompletionFormURL(t *testing.T) {
+	shiftId, dutyId := uuid.New(), uuid.New()
+
+	formURL := models.CompletionFormURL(testCompletionFormURL, shiftId, dutyId)
+
+	require.Equal(t, "https://crew.example.com/duties/"+shiftId.String()+"/"+dutyId.String(), formURL)
+}
+
+func TestGetDutyChecklist_LocalizedAndOrderedByDeadline(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyChecklistService(mockRepo, mockDutyRepo, mockVersionRepo, testCompletionFormURL)
+
+	duty := translatedDuty()
+	duty.FormSchema = []models.FormField{{Key: "done", Label: "Grates scrubbed", Type: models.FieldCheckbox}}
+	other := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), DutyName: "Count cash"}
+	mockDutyRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil)
+	mockDutyRepo.On("GetDutyById", mock.Anything, "Duty", other.RowKey.String()).Return(&other, nil)
+
+	shiftId := uuid.New()
+	early, late := time.Now().Add(time.Hour), time.Now().Add(2*time.Hour)
+	withoutDeadline := models.DutyAssignment{PartitionKey: shiftId, RowKey: uuid.New(), DutyPartitionKey: "Duty", DutyRowKey: other.RowKey}
+	lateDuty := models.DutyAssignment{PartitionKey: shiftId, RowKey: uuid.New(), DutyPartitionKey: "Duty", DutyRowKey: other.RowKey, DueAt: &late}
+	earlyDuty := models.DutyAssignment{PartitionKey: shiftId, RowKey: uuid.New(), DutyPartitionKey: "Duty", DutyRowKey: duty.RowKey, DueAt: &early, Mandatory: true}
+	mockRepo.On("GetAllDutyAssignmentsByShiftId", mock.Anything, shiftId).Return([]models.DutyAssignment{withoutDeadline, lateDuty, earlyDuty}, nil)
+
+	ctx := locale.WithPreference(context.Background(), locale.Preference{Languages: []string{"pl"}, Default: "en"})
+	checklist, err := service.GetDutyChecklist(ctx, shiftId)
+
+	require.NoError(t, err)
+	require.Len(t, checklist.Items, 3)
+	require.Equal(t, []uuid.UUID{earlyDuty.RowKey, lateDuty.RowKey, withoutDeadline.RowKey},
+		[]uuid.UUID{checklist.Items[0].DutyId, checklist.Items[1].DutyId, checklist.Items[2].DutyId})
+	require.Equal(t, "Wyczyść grill", checklist.Items[0].DutyName)
+	require.Equal(t, duty.FormSchema, checklist.Items[0].FormFields)
+	require.True(t, checklist.Items[0].Mandatory)
+	require.Equal(t, models.CompletionFormURL(testCompletionFormURL, shiftId, earlyDuty.RowKey), checklist.Items[0].FormUrl)
+}
+
+func TestGetDutyChecklist_FromVersionAtClockIn(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyChecklistService(mockRepo, mockDutyRepo, mockVersionRepo, testCompletionFormURL)
+
+	// the shift was clocked in on version 1 of the template
+	duty := translatedDuty()
+	version := duty.NewVersion(1, time.Now(), "")
+	version.FormSchema = []models.FormField{{Key: "done", Label: "Grates scrubbed", Type: models.FieldCheckbox}}
+	mockVersionRepo.On("GetVersion", mock.Anything, duty.RowKey, 1).Return(&version, nil).Once()
+
+	shiftId := uuid.New()
+	mockRepo.On("GetAllDutyAssignmentsByShiftId", mock.Anything, shiftId).Return([]models.DutyAssignment{
+		{PartitionKey: shiftId, RowKey: uuid.New(), DutyPartitionKey: "Duty", DutyRowKey: duty.RowKey, DutyVersion: 1},
+		{PartitionKey: shiftId, RowKey: uuid.New(), DutyPartitionKey: "Duty", DutyRowKey: duty.RowKey, DutyVersion: 1},
+	}, nil)
+
+	ctx := locale.WithPreference(context.Background(), locale.Preference{Languages: []string{"pl"}, Default: "en"})
+	checklist, err := service.GetDutyChecklist(ctx, shiftId)
+
+	require.NoError(t, err)
+	require.Len(t, checklist.Items, 2)
+	require.Equal(t, "Wyczyść grill", checklist.Items[0].DutyName)
+	require.Equal(t, version.FormSchema, checklist.Items[0].FormFields)
+	mockVersionRepo.AssertExpectations(t)
+	mockDutyRepo.AssertNotCalled(t, "GetDutyById", mock.Anything, mock.Anything, mock.Anything) // the template isn't needed
+}
+
+func TestPrintDutyChecklistHandler_HTML(t *testing.T) {
+	mockService := new(mocks.MockDutyChecklistService)
+	handler := handlers.NewDutyChecklistHandler(mockService)
+
+	checklist := testChecklist()
+	mockService.On("GetDutyChecklist", mock.Anything, checklist.ShiftId).Return(checklist, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/duties/duty-assignments/print?shiftId="+checklist.ShiftId.String(), nil)
+	rec := httptest.NewRecorder()
+
+	handler.PrintDutyChecklist(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	require.Contains(t, body, "Wyczyść grill")
+	require.Contains(t, body, "Wyszoruj ruszty")
+	require.Contains(t, body, "Fridge temperature *: ________ °C")
+	require.Contains(t, body, "Due 2024-05-01 18:00 UTC · mandatory")
+	require.Contains(t, body, `src="data:image/png;base64,`)
+}
+
+func TestPrintDutyChecklistHandler_PDF(t *testing.T) {
+	mockService := new(mocks.MockDutyChecklistService)
+	handler := handlers.NewDutyChecklistHandler(mockService)
+
+	// enough duties to fill more than one page
+	checklist := testChecklist()
+	for i := 0; i < 20; i++ {
+		checklist.Items = append(checklist.Items, checklist.Items[0])
+	}
+	mockService.On("GetDutyChecklist", mock.Anything, checklist.ShiftId).Return(checklist, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/duties/duty-assignments/print?format=pdf&shiftId="+checklist.ShiftId.String(), nil)
+	rec := httptest.NewRecorder()
+
+	handler.PrintDutyChecklist(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/pdf", rec.Header().Get("Content-Type"))
+	require.True(t, bytes.HasPrefix(rec.Body.Bytes(), []byte("%PDF")))
+}
+
+func TestPrintDutyChecklistHandler_PDFWithEmoji(t *testing.T) {
+	mockService := new(mocks.MockDutyChecklistService)
+	handler := handlers.NewDutyChecklistHandler(mockService)
+
+	// characters outside the Basic Multilingual Plane, which the PDF font has no glyphs for
+	checklist := testChecklist()
+	checklist.Items[0].DutyName = "Grill 🔥 clean"
+	checklist.Items[0].DutyDescription = "Scrub 🧽 the grates"
+	checklist.Items[0].FormFields[0].Label = "Fridge ❄️ temperature 🌡"
+	mockService.On("GetDutyChecklist", mock.Anything, checklist.ShiftId).Return(checklist, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/duties/duty-assignments/print?format=pdf&shiftId="+checklist.ShiftId.String(), nil)
+	rec := httptest.NewRecorder()
+
+	handler.PrintDutyChecklist(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.True(t, bytes.HasPrefix(rec.Body.Bytes(), []byte("%PDF")))
+}
+
+// FAILURE CASES:
+func TestPrintDutyChecklistHandler_InvalidParameters(t *testing.T) {
+	mockService := new(mocks.MockDutyChecklistService)
+	handler := handlers.NewDutyChecklistHandler(mockService)
+
+	for _, query := range []string{"", "?shiftId=not-a-uuid", "?format=docx&shiftId=" + uuid.NewString()} {
+		req := httptest.NewRequest(http.MethodGet, "/duties/duty-assignments/print"+query, nil)
+		rec := httptest.NewRecorder()
+
+		handler.PrintDutyChecklist(rec, req)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+	mockService.AssertNotCalled(t, "GetDutyChecklist", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,207 @@
+package unit_tests
+
+import (
+	"context"
+	"duty-service/auth"
+	"duty-service/handlers"
+	"duty-service/models"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// SUCCESS CASES:
+func TestClockOut_FreezesAndPublishesSummary(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	mockPublisher := new(mocks.MockMessagePublisher)
+	service := services.NewDutyAssignmentService(mockRepo, mockDutyRepo, nil, nil, nil, nil, mockPublisher, nil, nil)
+
+	shiftId := uuid.New()
+	closing := models.Duty{RowKey: uuid.New(), DutyName: "Turn off gas", Mandatory: true}
+	clockOutTime := time.Date(2024, 6, 15, 23, 0, 0, 0, time.UTC)
+	dutyAssignments := []models.DutyAssignment{
+		{PartitionKey: shiftId, RowKey: uuid.New(), DutyAssignmentStatus: models.StatusCompleted},
+		{PartitionKey: shiftId, RowKey: uuid.New(), DutyAssignmentStatus: models.StatusSkipped},
+		{PartitionKey: shiftId, RowKey: uuid.New(), DutyAssignmentStatus: models.StatusIncomplete, DutyRowKey: closing.RowKey, Mandatory: true},
+	}
+
+	mockRepo.On("GetAllDutyAssignmentsByShiftId", mock.Anything, shiftId).Return(dutyAssignments, nil)
+	mockRepo.On("FreezeDutyAssignments", mock.Anything, shiftId, mock.MatchedBy(func(frozen []models.DutyAssignment) bool {
+		return len(frozen) == 3 && *frozen[0].FrozenAt == clockOutTime && !frozen[0].IncompleteAtClockOut && frozen[2].IncompleteAtClockOut
+	})).Return(nil)
+	mockDutyRepo.On("GetAllDuties", mock.Anything, "").Return([]models.Duty{closing}, nil)
+	mockPublisher.On("PublishToExchange", services.DutyEventsExchange, services.ShiftSummaryKey, mock.Anything).Return(nil).Once()
+
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Subject: "employee-1"})
+	summary, err := service.ClockOut(ctx, shiftId, clockOutTime)
+
+	require.NoError(t, err)
+	require.Equal(t, 3, summary.Total)
+	require.Equal(t, 1, summary.Completed)
+	require.Equal(t, 1, summary.Skipped)
+	require.Equal(t, 1, summary.IncompleteMandatory)
+	require.Equal(t, "employee-1", summary.EmployeeID)
+	require.Equal(t, "Turn off gas", summary.IncompleteDuties[0].DutyName)
+	mockRepo.AssertExpectations(t)
+	mockPublisher.AssertExpectations(t)
+}
+
+func TestClockOut_AlreadyFrozen(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	mockPublisher := new(mocks.MockMessagePublisher)
+	service := services.NewDutyAssignmentService(mockRepo, mockDutyRepo, nil, nil, nil, nil, mockPublisher, nil, nil)
+
+	shiftId := uuid.New()
+	frozenAt := time.Now().UTC().Add(-time.Hour)
+
+	mockRepo.On("GetAllDutyAssignmentsByShiftId", mock.Anything, shiftId).Return([]models.DutyAssignment{
+		{PartitionKey: shiftId, RowKey: uuid.New(), DutyAssignmentStatus: models.StatusCompleted, FrozenAt: &frozenAt},
+	}, nil)
+	mockDutyRepo.On("GetAllDuties", mock.Anything, "").Return([]models.Duty{}, nil)
+
+	// a redelivered clock-out changes nothing and isn't reported again
+	_, err := service.ClockOut(context.Background(), shiftId, time.Now().UTC())
+
+	require.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "FreezeDutyAssignments", mock.Anything, mock.Anything, mock.Anything)
+	mockPublisher.AssertNotCalled(t, "PublishToExchange", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCanClockOut_ListsOpenMandatoryDuties(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyAssignmentService(mockRepo, mockDutyRepo, nil, nil, nil, nil, nil, nil, nil)
+
+	shiftId := uuid.New()
+	closing := models.Duty{RowKey: uuid.New(), DutyName: "Turn off gas", Mandatory: true}
+
+	mockRepo.On("GetAllDutyAssignmentsByShiftId", mock.Anything, shiftId).Return([]models.DutyAssignment{
+		{RowKey: uuid.New(), DutyAssignmentStatus: models.StatusIncomplete},                                              // optional
+		{RowKey: uuid.New(), DutyAssignmentStatus: models.StatusSkipped, Mandatory: true},                                // done
+		{RowKey: uuid.New(), DutyAssignmentStatus: models.StatusIncomplete, DutyRowKey: closing.RowKey, Mandatory: true}, // open
+	}, nil)
+	mockDutyRepo.On("GetAllDuties", mock.Anything, "").Return([]models.Duty{closing}, nil)
+
+	result, err := service.CanClockOut(context.Background(), shiftId)
+
+	require.NoError(t, err)
+	require.False(t, result.CanClockOut)
+	require.Len(t, result.OpenMandatoryDuties, 1)
+	require.Equal(t, "Turn off gas", result.OpenMandatoryDuties[0].DutyName)
+}
+
+func TestUpdateDutyAssignment_FrozenByAdmin(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	frozenAt := time.Now().UTC()
+
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, FrozenAt: &frozenAt}, nil)
+	mockRepo.On("UpdateDutyAssignment", mock.Anything, mock.Anything).Return(nil)
+
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Subject: "admin-1", Roles: []string{auth.RoleAdmin}})
+	err := service.UpdateDutyAssignment(ctx, models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusIncomplete}, nil)
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCanClockOutHandler_Success(t *testing.T) {
+	mockService := new(mocks.MockDutyAssignmentService)
+	handler := handlers.NewDutyAssignmentHandler(mockService)
+
+	shiftId := uuid.New()
+	mockService.On("CanClockOut", mock.Anything, shiftId).Return(&models.CanClockOutResult{ShiftId: shiftId, CanClockOut: true, OpenMandatoryDuties: []models.OpenDuty{}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/duties/duty-assignments/"+shiftId.String()+"/can-clock-out", nil)
+	req = mux.SetURLVars(req, map[string]string{"ShiftId": shiftId.String()})
+	rec := httptest.NewRecorder()
+
+	handler.CanClockOut(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var result models.CanClockOutResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&result))
+	require.True(t, result.CanClockOut)
+}
+
+func TestHandleCanClockOutRequest_WithoutReplyTo(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	acknowledger := new(mocks.MockAcknowledger)
+	shiftId := uuid.New()
+
+	mockRepo.On("GetAllDutyAssignmentsByShiftId", mock.Anything, shiftId).Return([]models.DutyAssignment{}, nil)
+	acknowledger.On("Ack", uint64(0), false).Return(nil)
+
+	body, err := json.Marshal(models.CanClockOutRequest{ShiftID: shiftId})
+	require.NoError(t, err)
+
+	consumer := &services.RabbitMQService{DutyService: services.NewDutyAssignmentService(mockRepo, nil, nil, nil, nil, nil, nil, nil, nil)}
+	consumer.HandleCanClockOutRequest(amqp.Delivery{Acknowledger: acknowledger, Body: body})
+
+	acknowledger.AssertExpectations(t)
+}
+
+// FAILURE CASES:
+func TestUpdateDutyAssignment_FrozenForEmployee(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	frozenAt := time.Now().UTC()
+
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, FrozenAt: &frozenAt}, nil)
+
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Subject: "employee-1"})
+	err := service.UpdateDutyAssignment(ctx, models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusCompleted}, nil)
+
+	require.ErrorIs(t, err, services.ErrDutyAssignmentFrozen)
+	mockRepo.AssertNotCalled(t, "UpdateDutyAssignment", mock.Anything, mock.Anything)
+}
+
+func TestDeleteDutyAssignmentHandler_Frozen(t *testing.T) {
+	mockService := new(mocks.MockDutyAssignmentService)
+	handler := handlers.NewDutyAssignmentHandler(mockService)
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	mockService.On("DeleteDutyAssignment", mock.Anything, shiftId, dutyId).Return(services.ErrDutyAssignmentFrozen)
+
+	req := httptest.NewRequest(http.MethodDelete, "/duties/duty-assignments/"+shiftId.String()+"/"+dutyId.String(), nil)
+	req = mux.SetURLVars(req, map[string]string{"ShiftId": shiftId.String(), "DutyId": dutyId.String()})
+	rec := httptest.NewRecorder()
+
+	handler.DeleteDutyAssignment(rec, req)
+
+	require.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestHandleClockOutMessage_RequeuedOnce(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	acknowledger := new(mocks.MockAcknowledger)
+	shiftId := uuid.New()
+
+	mockRepo.On("GetAllDutyAssignmentsByShiftId", mock.Anything, shiftId).Return([]models.DutyAssignment(nil), errors.New("table unavailable"))
+	acknowledger.On("Nack", uint64(0), false, true).Return(nil)
+
+	body, err := json.Marshal(models.ClockOutMessage{ShiftID: shiftId, ClockOutTime: time.Now().UTC()})
+	require.NoError(t, err)
+
+	consumer := &services.RabbitMQService{DutyService: services.NewDutyAssignmentService(mockRepo, nil, nil, nil, nil, nil, nil, nil, nil)}
+	consumer.HandleClockOutMessage(amqp.Delivery{Acknowledger: acknowledger, Body: body})
+
+	acknowledger.AssertExpectations(t)
+}
@@ -0,0 +1,130 @@
+package unit_tests
+
+import (
+	"context"
+	"duty-service/auth"
+	"duty-service/models"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// SUCCESS CASES:
+func TestCreateDutyAssignments_PublishesCreatedEvents(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	mockPublisher := new(mocks.MockMessagePublisher)
+	service := services.NewDutyAssignmentService(mockRepo, mockDutyRepo, nil, nil, nil, nil, mockPublisher, nil, nil)
+
+	shiftId := uuid.New()
+	grill := models.Duty{RowKey: uuid.New(), DutyName: "Clean grill"}
+	created := models.DutyAssignment{PartitionKey: shiftId, RowKey: models.DutyAssignmentRowKey(shiftId, grill.RowKey), DutyRowKey: grill.RowKey,
+		DutyAssignmentStatus: models.StatusIncomplete, RoleId: intPtr(2), CreatedAt: time.Now().UTC()}
+
+	mockDutyRepo.On("GetDutiesByRole", mock.Anything, 2).Return([]models.Duty{grill}, nil)
+	mockRepo.On("CreateDutyAssignments", mock.Anything, shiftId, 2, (*uuid.UUID)(nil), "employee-1", []models.Duty{grill}).Return([]models.DutyAssignment{created}, nil)
+	mockPublisher.On("PublishToExchange", services.DutyEventsExchange, services.DutyAssignmentCreatedKey, mock.MatchedBy(func(message models.DutyAssignmentEventMessage) bool {
+		return message.ShiftID == shiftId && message.DutyID == created.RowKey && message.DutyTemplateID == grill.RowKey &&
+			message.DutyName == "Clean grill" && message.EmployeeID == "employee-1" && message.Status == models.StatusIncomplete
+	})).Return(nil).Once()
+
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Subject: "employee-1"})
+	err := service.CreateDutyAssignments(ctx, shiftId, 2, time.Now().UTC())
+
+	require.NoError(t, err)
+	mockPublisher.AssertExpectations(t)
+}
+
+func TestUpdateDutyAssignment_PublishesCompletedAndAllCompleted(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockPublisher := new(mocks.MockMessagePublisher)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, nil, nil, nil, mockPublisher, nil, nil)
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	existing := models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusIncomplete}
+
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&existing, nil)
+	mockRepo.On("UpdateDutyAssignment", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("GetAllDutyAssignmentsByShiftId", mock.Anything, shiftId).Return([]models.DutyAssignment{
+		existing, // not updated in the list yet
+		{PartitionKey: shiftId, RowKey: uuid.New(), DutyAssignmentStatus: models.StatusCompleted},
+		{PartitionKey: shiftId, RowKey: uuid.New(), DutyAssignmentStatus: models.StatusSkipped},
+	}, nil)
+	mockPublisher.On("PublishToExchange", services.DutyEventsExchange, services.DutyAssignmentCompletedKey, mock.MatchedBy(func(message models.DutyAssignmentEventMessage) bool {
+		return message.DutyID == dutyId && message.Status == models.StatusCompleted && message.CompletedAt != nil && message.EmployeeID == "employee-1"
+	})).Return(nil).Once()
+	mockPublisher.On("PublishToExchange", services.DutyEventsExchange, services.ShiftAllCompletedKey, mock.MatchedBy(func(message models.ShiftDutiesCompletedMessage) bool {
+		return message.ShiftID == shiftId && message.Total == 3 && message.Completed == 2 && message.Skipped == 1 && message.EmployeeID == "employee-1"
+	})).Return(nil).Once()
+
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Subject: "employee-1"})
+	err := service.UpdateDutyAssignment(ctx, models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusCompleted}, nil)
+
+	require.NoError(t, err)
+	mockPublisher.AssertExpectations(t)
+}
+
+func TestUpdateDutyAssignment_SkippedWithOpenDuties(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockPublisher := new(mocks.MockMessagePublisher)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, nil, nil, nil, mockPublisher, nil, nil)
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	existing := models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusIncomplete}
+
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&existing, nil)
+	mockRepo.On("UpdateDutyAssignment", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("GetAllDutyAssignmentsByShiftId", mock.Anything, shiftId).Return([]models.DutyAssignment{
+		existing,
+		{PartitionKey: shiftId, RowKey: uuid.New(), DutyAssignmentStatus: models.StatusIncomplete},
+	}, nil)
+	mockPublisher.On("PublishToExchange", services.DutyEventsExchange, services.DutyAssignmentSkippedKey, mock.Anything).Return(nil).Once()
+
+	err := service.UpdateDutyAssignment(context.Background(), models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusSkipped}, nil)
+
+	require.NoError(t, err)
+	mockPublisher.AssertExpectations(t)
+	mockPublisher.AssertNotCalled(t, "PublishToExchange", services.DutyEventsExchange, services.ShiftAllCompletedKey, mock.Anything)
+}
+
+func TestUpdateDutyAssignment_NoEventWithoutStatusChange(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockPublisher := new(mocks.MockMessagePublisher)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, nil, nil, nil, mockPublisher, nil, nil)
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusIncomplete}, nil)
+	mockRepo.On("UpdateDutyAssignment", mock.Anything, mock.Anything).Return(nil)
+
+	note := "halfway"
+	err := service.UpdateDutyAssignment(context.Background(), models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusIncomplete, DutyAssignmentNote: &note}, nil)
+
+	require.NoError(t, err)
+	mockPublisher.AssertNotCalled(t, "PublishToExchange", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// FAILURE CASES:
+func TestUpdateDutyAssignment_PublishFailureDoesNotFailUpdate(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockPublisher := new(mocks.MockMessagePublisher)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, nil, nil, nil, mockPublisher, nil, nil)
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	existing := models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusIncomplete}
+
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&existing, nil)
+	mockRepo.On("UpdateDutyAssignment", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("GetAllDutyAssignmentsByShiftId", mock.Anything, shiftId).Return([]models.DutyAssignment{existing}, nil)
+	mockPublisher.On("PublishToExchange", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("channel closed"))
+
+	err := service.UpdateDutyAssignment(context.Background(), models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusCompleted}, nil)
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
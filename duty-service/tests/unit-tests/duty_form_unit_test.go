@@ -0,0 +1,94 @@
+package unit_tests
+
+import (
+	"duty-service/models"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
+
+// dummy form used by the tests below
+var testFormSchema = []models.FormField{
+	{Key: "oilChanged", Label: "Oil changed?", Type: models.FieldCheckbox, Required: true},
+	{Key: "stockCount", Label: "Stock count", Type: models.FieldNumber, Unit: "pcs", Min: floatPtr(0), Max: floatPtr(500)},
+	{Key: "grillState", Label: "Grill state", Type: models.FieldChoice, Options: []string{"Clean", "Dirty"}},
+	{Key: "remarks", Label: "Remarks", Type: models.FieldText},
+	{Key: "grillPhoto", Label: "Photo of the grill", Type: models.FieldPhoto, Required: true},
+}
+
+// SUCCESS CASES:
+func TestValidateFormSchema_Valid(t *testing.T) {
+	require.NoError(t, models.ValidateFormSchema(testFormSchema))
+}
+
+func TestValidateFormValues_Valid(t *testing.T) {
+	values := map[string]interface{}{
+		"oilChanged": true,
+		"stockCount": float64(42),
+		"grillState": "Clean",
+		"remarks":    "all good",
+	}
+
+	normalised, err := models.ValidateFormValues(testFormSchema, values)
+
+	require.NoError(t, err)
+	require.Equal(t, values, normalised)
+}
+
+func TestCheckRequiredFormFields_Complete(t *testing.T) {
+	values := map[string]interface{}{"oilChanged": false}
+
+	require.NoError(t, models.CheckRequiredFormFields(testFormSchema, values, true))
+}
+
+// FAILURE CASES:
+func TestValidateFormSchema_Invalid(t *testing.T) {
+	cases := map[string][]models.FormField{
+		"invalid key":          {{Key: "1st value", Type: models.FieldText}},
+		"duplicate key":        {{Key: "count", Type: models.FieldNumber}, {Key: "Count", Type: models.FieldNumber}},
+		"unknown type":         {{Key: "count", Type: "Slider"}},
+		"choice without opts":  {{Key: "state", Type: models.FieldChoice}},
+		"min greater than max": {{Key: "count", Type: models.FieldNumber, Min: floatPtr(10), Max: floatPtr(1)}},
+	}
+
+	for name, schema := range cases {
+		err := models.ValidateFormSchema(schema)
+
+		var formErr *models.FormValidationError
+		require.True(t, errors.As(err, &formErr), name)
+	}
+}
+
+func TestValidateFormValues_Invalid(t *testing.T) {
+	cases := map[string]map[string]interface{}{
+		"unknown field":      {"temperature": float64(4)},
+		"checkbox not bool":  {"oilChanged": "yes"},
+		"number as text":     {"stockCount": "12"},
+		"number below min":   {"stockCount": float64(-1)},
+		"number above max":   {"stockCount": float64(501)},
+		"choice not allowed": {"grillState": "Sparkling"},
+		"photo as value":     {"grillPhoto": "photo.png"},
+	}
+
+	for name, values := range cases {
+		_, err := models.ValidateFormValues(testFormSchema, values)
+
+		var formErr *models.FormValidationError
+		require.True(t, errors.As(err, &formErr), name)
+	}
+}
+
+func TestCheckRequiredFormFields_Missing(t *testing.T) {
+	// checkbox missing
+	err := models.CheckRequiredFormFields(testFormSchema, map[string]interface{}{}, true)
+	require.EqualError(t, err, "invalid form field 'oilChanged': is required")
+
+	// photo missing
+	err = models.CheckRequiredFormFields(testFormSchema, map[string]interface{}{"oilChanged": true}, false)
+	require.EqualError(t, err, "invalid form field 'grillPhoto': a photo is required")
+}
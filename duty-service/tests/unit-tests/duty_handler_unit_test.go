@@ -41,7 +41,7 @@ func TestGetAllDuties(t *testing.T) {
 		},
 	}
 
-	mockService.On("GetAllDuties", context.Background(), "TestName").Return(mockDuties, nil)
+	mockService.On("GetAllDuties", context.Background(), models.DutySearch{Name: "TestName"}).Return(&models.DutySearchResult{Duties: mockDuties, Total: 2}, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/duties?name=TestName", nil)
 	rec := httptest.NewRecorder()
@@ -50,6 +50,7 @@ func TestGetAllDuties(t *testing.T) {
 
 	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
 	require.Equal(t, "application/json", rec.Result().Header.Get("Content-Type"))
+	require.Equal(t, "2", rec.Result().Header.Get("X-Total-Count"))
 
 	// parse the response
 	var response []models.Duty
@@ -0,0 +1,269 @@
+package unit_tests
+
+import (
+	"bytes"
+	"context"
+	"duty-service/handlers"
+	"duty-service/images"
+	"duty-service/models"
+	"duty-service/repositories"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// jpegWithCaptureTime encodes an image as a JPEG with an EXIF DateTimeOriginal ("2006:01:02 15:04:05") and, when
+// offset isn't empty, an OffsetTimeOriginal ("+02:00")
+func jpegWithCaptureTime(t *testing.T, img image.Image, dateTime string, offset string) []byte {
+	var encoded bytes.Buffer
+	require.NoError(t, jpeg.Encode(&encoded, img, nil))
+
+	// little endian TIFF: IFD0 at 8 points to the Exif IFD at 26, whose values start at 56
+	order := binary.LittleEndian
+	tiff := make([]byte, 56)
+	copy(tiff, "II*\x00")
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x8769) // Exif IFD pointer
+	order.PutUint16(tiff[12:], 4)      // LONG
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint32(tiff[18:], 26)
+
+	entries := [][2]string{{"\x03\x90", dateTime}} // DateTimeOriginal (0x9003)
+	if offset != "" {
+		entries = append(entries, [2]string{"\x11\x90", offset}) // OffsetTimeOriginal (0x9011)
+	}
+	order.PutUint16(tiff[26:], uint16(len(entries)))
+	for i, entry := range entries {
+		value := entry[1] + "\x00"
+		position := 28 + i*12
+		copy(tiff[position:], entry[0])
+		order.PutUint16(tiff[position+2:], 2) // ASCII
+		order.PutUint32(tiff[position+4:], uint32(len(value)))
+		order.PutUint32(tiff[position+8:], uint32(len(tiff)))
+		tiff = append(tiff, value...)
+	}
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+
+	data := encoded.Bytes()
+	return append(append(append([]byte{}, data[:2]...), append(app1, segment...)...), data[2:]...)
+}
+
+// inverted returns the negative of an image: a different picture with the same layout
+func inverted(img image.Image) image.Image {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			dst.Set(x, y, color.RGBA{R: 255 - uint8(r>>8), G: 255 - uint8(g>>8), B: 255 - uint8(b>>8), A: 255})
+		}
+	}
+	return dst
+}
+
+// SUCCESS CASES:
+func TestProcessImage_HashAndCaptureTime(t *testing.T) {
+	picture := testImage(200, 120)
+
+	withOffset, err := images.Process(bytes.NewReader(jpegWithCaptureTime(t, picture, "2026:10:19 14:03:00", "+02:00")))
+	require.NoError(t, err)
+	require.NotNil(t, withOffset.CapturedAt)
+	require.True(t, withOffset.CapturedAt.Equal(time.Date(2026, 10, 19, 12, 3, 0, 0, time.UTC)))
+	require.False(t, withOffset.CapturedAtLocal)
+
+	withoutOffset, err := images.Process(bytes.NewReader(jpegWithCaptureTime(t, picture, "2026:10:19 14:03:00", "")))
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2026, 10, 19, 14, 3, 0, 0, time.UTC), *withoutOffset.CapturedAt)
+	require.True(t, withoutOffset.CapturedAtLocal)
+
+	// the same picture re-encoded is a near-duplicate, its negative isn't
+	require.LessOrEqual(t, images.HashDistance(withOffset.Hash, withoutOffset.Hash), models.PhotoDuplicateMaxDistance)
+	require.Greater(t, images.HashDistance(withOffset.Hash, images.DifferenceHash(inverted(picture))), models.PhotoDuplicateMaxDistance)
+}
+
+func TestCheckPhotoCapture(t *testing.T) {
+	clockIn := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	now := clockIn.Add(4 * time.Hour)
+
+	require.True(t, models.CheckPhotoCapture(clockIn.Add(time.Hour), false, clockIn, now))
+	require.True(t, models.CheckPhotoCapture(clockIn.Add(-10*time.Minute), false, clockIn, now)) // camera clock a bit behind
+	require.False(t, models.CheckPhotoCapture(clockIn.Add(-time.Hour), false, clockIn, now))
+
+	// without an offset, any time zone could be meant, but not an earlier day
+	require.True(t, models.CheckPhotoCapture(clockIn.Add(-6*time.Hour), true, clockIn, now))
+	require.False(t, models.CheckPhotoCapture(clockIn.Add(-24*time.Hour), true, clockIn, now))
+}
+
+func TestUpdateDutyAssignment_FlagsDuplicateTakenBeforeShift(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	mockPhotoRepo := new(mocks.MockDutyAssignmentPhotoRepository)
+	service := services.NewDutyAssignmentService(mockRepo, mockDutyRepo, nil, mockPhotoRepo, nil, nil, nil, nil, nil)
+
+	shiftId, duty := uuid.New(), models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), DutyName: "Clean grill"}
+	dutyId := models.DutyAssignmentRowKey(shiftId, duty.RowKey)
+	existing := models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyPartitionKey: "Duty", DutyRowKey: duty.RowKey, CreatedAt: time.Now().Add(-time.Hour)}
+
+	// taken a month ago, and uploaded for an earlier shift before
+	upload := jpegWithCaptureTime(t, testImage(200, 120), time.Now().AddDate(0, -1, 0).UTC().Format("2006:01:02 15:04:05"), "+00:00")
+	processed, err := images.Process(bytes.NewReader(upload))
+	require.NoError(t, err)
+	earlier := models.DutyAssignmentPhoto{RowKey: uuid.New(), ShiftId: uuid.New(), DutyId: uuid.New(), Hash: processed.Hash}
+	retry := models.DutyAssignmentPhoto{RowKey: uuid.New(), ShiftId: shiftId, DutyId: dutyId, Hash: processed.Hash} // same assignment
+
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&existing, nil)
+	mockDutyRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil)
+	mockPhotoRepo.On("GetPhotosByDuty", mock.Anything, duty.RowKey, mock.Anything).Return([]models.DutyAssignmentPhoto{retry, earlier}, nil)
+	mockPhotoRepo.On("AddPhoto", mock.Anything, mock.MatchedBy(func(photo models.DutyAssignmentPhoto) bool {
+		return photo.ReviewStatus == models.PhotoReviewPending &&
+			len(photo.Flags) == 2 && photo.Flags[0] == models.PhotoFlagDuplicate && photo.Flags[1] == models.PhotoFlagCapturedOutsideShift &&
+			*photo.DuplicateOf == models.PhotoReference{ShiftId: earlier.ShiftId, DutyId: earlier.DutyId, PhotoId: earlier.RowKey}
+	}), mock.Anything).Return(models.DutyAssignmentPhoto{BlobName: "image.jpg", Flags: []models.PhotoFlag{models.PhotoFlagDuplicate, models.PhotoFlagCapturedOutsideShift}}, nil)
+	mockRepo.On("UpdateDutyAssignment", mock.Anything, mock.MatchedBy(func(dutyAssignment models.DutyAssignment) bool {
+		return len(dutyAssignment.PhotoFlags) == 2
+	})).Return(nil)
+
+	err = service.UpdateDutyAssignment(context.Background(), models.DutyAssignment{
+		PartitionKey:         shiftId,
+		RowKey:               dutyId,
+		DutyAssignmentStatus: models.StatusCompleted,
+	}, testUpload{bytes.NewReader(upload)})
+
+	require.NoError(t, err)
+	mockPhotoRepo.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateDutyAssignment_PhotoLookupFailureDoesNotRejectUpload(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	mockPhotoRepo := new(mocks.MockDutyAssignmentPhotoRepository)
+	service := services.NewDutyAssignmentService(mockRepo, mockDutyRepo, nil, mockPhotoRepo, nil, nil, nil, nil, nil)
+
+	shiftId, duty := uuid.New(), models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), DutyName: "Clean grill"}
+	dutyId := models.DutyAssignmentRowKey(shiftId, duty.RowKey)
+	existing := models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyPartitionKey: "Duty", DutyRowKey: duty.RowKey}
+
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&existing, nil)
+	mockDutyRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil)
+	mockPhotoRepo.On("GetPhotosByDuty", mock.Anything, duty.RowKey, mock.Anything).Return(nil, errors.New("table unavailable"))
+	mockPhotoRepo.On("AddPhoto", mock.Anything, mock.MatchedBy(func(photo models.DutyAssignmentPhoto) bool {
+		return len(photo.Flags) == 0 && photo.DuplicateOf == nil
+	}), mock.Anything).Return(models.DutyAssignmentPhoto{BlobName: "image.png"}, nil)
+	mockRepo.On("UpdateDutyAssignment", mock.Anything, mock.Anything).Return(nil)
+
+	var upload bytes.Buffer
+	require.NoError(t, jpeg.Encode(&upload, testImage(200, 120), nil))
+
+	err := service.UpdateDutyAssignment(context.Background(), models.DutyAssignment{
+		PartitionKey:         shiftId,
+		RowKey:               dutyId,
+		DutyAssignmentStatus: models.StatusCompleted,
+	}, testUpload{bytes.NewReader(upload.Bytes())})
+
+	require.NoError(t, err)
+	mockPhotoRepo.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestReviewDutyAssignmentPhoto_ClearsAssignmentFlags(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockPhotoRepo := new(mocks.MockDutyAssignmentPhotoRepository)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, mockPhotoRepo, nil, nil, nil, nil, nil)
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	photo := models.DutyAssignmentPhoto{RowKey: uuid.New(), ShiftId: shiftId, DutyId: dutyId, Flags: []models.PhotoFlag{models.PhotoFlagDuplicate}, ReviewStatus: models.PhotoReviewPending}
+	approved := photo
+	approved.ReviewStatus = models.PhotoReviewApproved
+
+	mockPhotoRepo.On("GetPhoto", mock.Anything, shiftId, dutyId, photo.RowKey).Return(&photo, nil)
+	mockPhotoRepo.On("UpdatePhotoReview", mock.Anything, mock.MatchedBy(func(reviewed models.DutyAssignmentPhoto) bool {
+		return reviewed.ReviewStatus == models.PhotoReviewApproved && reviewed.ReviewedBy == "lead-1" && reviewed.ReviewNote == "Same grill, new shift" && reviewed.ReviewedAt != nil
+	})).Return(nil)
+	mockPhotoRepo.On("GetPhotos", mock.Anything, shiftId, dutyId).Return([]models.DutyAssignmentPhoto{approved}, nil)
+	mockRepo.On("SetPhotoFlags", mock.Anything, shiftId, dutyId, []models.PhotoFlag{}).Return(nil)
+
+	reviewed, err := service.ReviewDutyAssignmentPhoto(withCaller("lead-1", "Lead"), shiftId, dutyId, photo.RowKey, models.PhotoReview{Status: models.PhotoReviewApproved, Note: " Same grill, new shift "})
+
+	require.NoError(t, err)
+	require.Equal(t, models.PhotoReviewApproved, reviewed.ReviewStatus)
+	mockRepo.AssertExpectations(t)
+	mockPhotoRepo.AssertExpectations(t)
+}
+
+func TestGetPhotoReviewQueue_ResolvesDuplicates(t *testing.T) {
+	mockPhotoRepo := new(mocks.MockDutyAssignmentPhotoRepository)
+	service := services.NewDutyAssignmentService(nil, nil, nil, mockPhotoRepo, nil, nil, nil, nil, nil)
+
+	original := models.DutyAssignmentPhoto{RowKey: uuid.New(), ShiftId: uuid.New(), DutyId: uuid.New()}
+	deletedRef := &models.PhotoReference{ShiftId: uuid.New(), DutyId: uuid.New(), PhotoId: uuid.New()}
+	flagged := []models.DutyAssignmentPhoto{
+		{RowKey: uuid.New(), ReviewStatus: models.PhotoReviewPending, DuplicateOf: &models.PhotoReference{ShiftId: original.ShiftId, DutyId: original.DutyId, PhotoId: original.RowKey}},
+		{RowKey: uuid.New(), ReviewStatus: models.PhotoReviewPending, DuplicateOf: deletedRef},
+		{RowKey: uuid.New(), ReviewStatus: models.PhotoReviewPending, Flags: []models.PhotoFlag{models.PhotoFlagCapturedOutsideShift}},
+	}
+	mockPhotoRepo.On("GetPhotosForReview", mock.Anything).Return(flagged, nil)
+	mockPhotoRepo.On("GetPhoto", mock.Anything, original.ShiftId, original.DutyId, original.RowKey).Return(&original, nil)
+	mockPhotoRepo.On("GetPhoto", mock.Anything, deletedRef.ShiftId, deletedRef.DutyId, deletedRef.PhotoId).Return(nil, repositories.ErrPhotoNotFound)
+
+	queue, err := service.GetPhotoReviewQueue(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, queue, 3)
+	require.Equal(t, &original, queue[0].DuplicateOf)
+	require.Nil(t, queue[1].DuplicateOf)
+	require.Nil(t, queue[2].DuplicateOf)
+}
+
+// FAILURE CASES:
+func TestReviewDutyAssignmentPhoto_NotFlagged(t *testing.T) {
+	mockPhotoRepo := new(mocks.MockDutyAssignmentPhotoRepository)
+	handler := handlers.NewDutyAssignmentHandler(services.NewDutyAssignmentService(nil, nil, nil, mockPhotoRepo, nil, nil, nil, nil, nil))
+
+	shiftId, dutyId, photoId := uuid.New(), uuid.New(), uuid.New()
+	mockPhotoRepo.On("GetPhoto", mock.Anything, shiftId, dutyId, photoId).Return(&models.DutyAssignmentPhoto{RowKey: photoId}, nil)
+
+	req := httptest.NewRequest(http.MethodPut, "/duties/duty-assignments/"+shiftId.String()+"/"+dutyId.String()+"/photos/"+photoId.String()+"/review", strings.NewReader(`{"Status": "Rejected"}`))
+	req = mux.SetURLVars(req, map[string]string{"ShiftId": shiftId.String(), "DutyId": dutyId.String(), "PhotoId": photoId.String()})
+	rec := httptest.NewRecorder()
+
+	handler.ReviewDutyAssignmentPhoto(rec, req)
+
+	require.Equal(t, http.StatusConflict, rec.Code)
+	mockPhotoRepo.AssertNotCalled(t, "UpdatePhotoReview", mock.Anything, mock.Anything)
+}
+
+func TestReviewDutyAssignmentPhotoHandler_InvalidStatus(t *testing.T) {
+	mockService := new(mocks.MockDutyAssignmentService)
+	handler := handlers.NewDutyAssignmentHandler(mockService)
+
+	shiftId, dutyId, photoId := uuid.New(), uuid.New(), uuid.New()
+	review := models.PhotoReview{Status: models.PhotoReviewPending}
+	mockService.On("ReviewDutyAssignmentPhoto", mock.Anything, shiftId, dutyId, photoId, review).Return(nil, review.Validate())
+
+	req := httptest.NewRequest(http.MethodPut, "/duties/duty-assignments/"+shiftId.String()+"/"+dutyId.String()+"/photos/"+photoId.String()+"/review", strings.NewReader(`{"Status": "Pending"}`))
+	req = mux.SetURLVars(req, map[string]string{"ShiftId": shiftId.String(), "DutyId": dutyId.String(), "PhotoId": photoId.String()})
+	rec := httptest.NewRecorder()
+
+	handler.ReviewDutyAssignmentPhoto(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
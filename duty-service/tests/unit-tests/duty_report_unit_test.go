@@ -0,0 +1,136 @@
+package unit_tests
+
+import (
+	"context"
+	"duty-service/handlers"
+	"duty-service/models"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// SUCCESS CASES:
+func TestGetShiftReport_CountsAssignments(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyReportService(mockRepo, mockDutyRepo)
+
+	shiftId := uuid.New()
+	cleaning := models.Duty{RowKey: uuid.New(), DutyName: "Clean grill", FormSchema: []models.FormField{{Key: "photo", Label: "Photo", Type: models.FieldPhoto}}}
+	stock := models.Duty{RowKey: uuid.New(), DutyName: "Count stock"}
+	past := time.Now().Add(-time.Hour)
+
+	mockDutyRepo.On("GetAllDuties", mock.Anything, "").Return([]models.Duty{cleaning, stock}, nil)
+	mockRepo.On("GetAllDutyAssignmentsByShiftId", mock.Anything, shiftId).Return([]models.DutyAssignment{
+		{PartitionKey: shiftId, RowKey: uuid.New(), DutyRowKey: cleaning.RowKey, DutyAssignmentStatus: models.StatusIncomplete, DueAt: &past}, // overdue, no photo
+		{PartitionKey: shiftId, RowKey: uuid.New(), DutyRowKey: cleaning.RowKey, DutyAssignmentStatus: models.StatusCompleted, ImageBlobName: "photo.jpg"},
+		{PartitionKey: shiftId, RowKey: uuid.New(), DutyRowKey: stock.RowKey, DutyAssignmentStatus: models.StatusCompleted},
+		{PartitionKey: shiftId, RowKey: uuid.New(), DutyRowKey: stock.RowKey, DutyAssignmentStatus: models.StatusSkipped, DueAt: &past},
+	}, nil)
+
+	report, err := service.GetShiftReport(context.Background(), shiftId)
+
+	require.NoError(t, err)
+	require.Equal(t, models.DutyReportSummary{
+		Total:                4,
+		Completed:            2,
+		Incomplete:           1,
+		Skipped:              1,
+		Overdue:              1,
+		MissingPhotos:        1,
+		CompletionPercentage: 200.0 / 3, // skipped duties don't count
+	}, report.Summary)
+	require.Len(t, report.Shifts, 1)
+	require.Equal(t, report.Summary, report.Shifts[0].Summary)
+	require.Equal(t, "Clean grill", report.Shifts[0].Assignments[0].DutyName)
+}
+
+func TestGetReport_CSV(t *testing.T) {
+	mockService := new(mocks.MockDutyReportService)
+	handler := handlers.NewDutyReportHandler(mockService)
+
+	shiftIds := []uuid.UUID{uuid.New(), uuid.New()}
+	note := "grill still greasy"
+	report := &models.DutyReport{
+		GeneratedAt: time.Now(),
+		Shifts: []models.ShiftDutyReport{{
+			ShiftId: shiftIds[0],
+			Assignments: []models.DutyReportLine{
+				{DutyId: uuid.New(), DutyName: "Clean grill", Status: models.StatusIncomplete, Note: &note, Overdue: true},
+			},
+		}},
+	}
+
+	mockService.On("GetShiftsReport", mock.Anything, shiftIds).Return(report, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/duties/reports?format=csv&shiftIds="+shiftIds[0].String()+","+shiftIds[1].String(), nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetReport(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	require.Equal(t, "text/csv; charset=utf-8", rec.Result().Header.Get("Content-Type"))
+
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, []string{shiftIds[0].String(), "Clean grill", "Incomplete", "", "", "true", "false", "false", note}, rows[1])
+	mockService.AssertExpectations(t)
+}
+
+func TestGetShiftReport_HTML(t *testing.T) {
+	mockService := new(mocks.MockDutyReportService)
+	handler := handlers.NewDutyReportHandler(mockService)
+
+	shiftId := uuid.New()
+	thumbnailUrl := "https://example.com/photo_thumb.jpg?sig=a&se=b"
+	report := &models.DutyReport{
+		GeneratedAt: time.Now(),
+		Summary:     models.DutyReportSummary{Total: 1, Completed: 1, CompletionPercentage: 100},
+		Shifts: []models.ShiftDutyReport{{
+			ShiftId: shiftId,
+			Summary: models.DutyReportSummary{Total: 1, Completed: 1, CompletionPercentage: 100},
+			Assignments: []models.DutyReportLine{
+				{DutyId: uuid.New(), DutyName: "<Clean> grill", Status: models.StatusCompleted, ThumbnailUrl: &thumbnailUrl},
+			},
+		}},
+	}
+
+	mockService.On("GetShiftReport", mock.Anything, shiftId).Return(report, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/duties/reports/shifts/"+shiftId.String()+"?format=html", nil)
+	req = mux.SetURLVars(req, map[string]string{"ShiftId": shiftId.String()})
+	rec := httptest.NewRecorder()
+
+	handler.GetShiftReport(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	require.Equal(t, "text/html; charset=utf-8", rec.Result().Header.Get("Content-Type"))
+	body := rec.Body.String()
+	require.Contains(t, body, "&lt;Clean&gt; grill") // escaped
+	require.Contains(t, body, `<img src="https://example.com/photo_thumb.jpg?sig=a&amp;se=b"`)
+	require.Contains(t, body, "100%")
+}
+
+// FAILURE CASES:
+func TestGetReport_MissingParameters(t *testing.T) {
+	mockService := new(mocks.MockDutyReportService)
+	handler := handlers.NewDutyReportHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/duties/reports?from=2024-05-01", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetReport(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Result().StatusCode)
+	mockService.AssertNotCalled(t, "GetDateRangeReport", mock.Anything, mock.Anything, mock.Anything)
+}
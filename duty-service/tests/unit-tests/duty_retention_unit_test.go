@@ -0,0 +1,242 @@
+package unit_tests
+
+import (
+	"context"
+	"duty-service/handlers"
+	"duty-service/models"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// SUCCESS CASES:
+func TestDeleteDuty_SoftDeletes(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyService(mockRepo, new(mocks.MockDutyVersionRepository))
+
+	duty := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), RoleId: 2, DutyName: "Clean grill"}
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil)
+	mockRepo.On("SetDutyDeleted", mock.Anything, "Duty", duty.RowKey.String(), mock.AnythingOfType("*time.Time"), "admin-1").Return(nil)
+
+	err := service.DeleteDuty(withCaller("admin-1", "Admin"), "Duty", duty.RowKey.String())
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "DeleteDuty", mock.Anything, mock.Anything)
+}
+
+func TestDeleteDuty_AlreadyDeletedKeepsFirstDeletion(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyService(mockRepo, new(mocks.MockDutyVersionRepository))
+
+	deletedAt := time.Now().Add(-time.Hour)
+	duty := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), DeletedAt: &deletedAt, DeletedBy: "admin-1"}
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil)
+
+	err := service.DeleteDuty(withCaller("admin-2", "Admin"), "Duty", duty.RowKey.String())
+
+	require.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "SetDutyDeleted", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetAllDuties_ExcludesDeleted(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyService(mockRepo, new(mocks.MockDutyVersionRepository))
+
+	deletedAt := time.Now()
+	active := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), DutyName: "Clean grill"}
+	deleted := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), DutyName: "Count stock", DeletedAt: &deletedAt}
+	mockRepo.On("GetAllDuties", mock.Anything, "").Return([]models.Duty{active, deleted}, nil)
+
+	result, err := service.GetAllDuties(context.Background(), models.DutySearch{})
+	require.NoError(t, err)
+	require.Equal(t, []models.Duty{active}, result.Duties)
+
+	result, err = service.GetAllDuties(context.Background(), models.DutySearch{IncludeDeleted: true})
+	require.NoError(t, err)
+	require.Len(t, result.Duties, 2)
+}
+
+func TestRestoreDuty_Success(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyService(mockRepo, new(mocks.MockDutyVersionRepository))
+
+	deletedAt := time.Now()
+	duty := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), DutyKey: "chef-clean-grill", DeletedAt: &deletedAt, DeletedBy: "admin-1"}
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil)
+	mockRepo.On("GetAllDuties", mock.Anything, "").Return([]models.Duty{duty, {PartitionKey: "Duty", RowKey: uuid.New(), DutyKey: "chef-count-stock"}}, nil)
+	mockRepo.On("SetDutyDeleted", mock.Anything, "Duty", duty.RowKey.String(), (*time.Time)(nil), "").Return(nil)
+
+	restored, err := service.RestoreDuty(context.Background(), "Duty", duty.RowKey.String())
+
+	require.NoError(t, err)
+	require.False(t, restored.IsDeleted())
+	require.Empty(t, restored.DeletedBy)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRestoreDutyAssignment_Success(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	stream := services.NewDutyStream(services.DefaultDutyStreamBufferSize)
+	service := services.NewDutyAssignmentService(mockRepo, mockDutyRepo, nil, nil, nil, nil, nil, stream, nil)
+
+	shiftId, dutyId, dutyRowKey := uuid.New(), uuid.New(), uuid.New()
+	subscription := stream.Subscribe(models.DutyStreamFilter{}, "")
+	defer subscription.Close()
+
+	mockRepo.On("SetDutyAssignmentDeleted", mock.Anything, shiftId, dutyId, (*time.Time)(nil), "").Return(nil)
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).
+		Return(&models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyPartitionKey: "Duty", DutyRowKey: dutyRowKey}, nil)
+	mockDutyRepo.On("GetDutyById", mock.Anything, "Duty", dutyRowKey.String()).Return(&models.Duty{DutyName: "Clean grill"}, nil)
+
+	restored, err := service.RestoreDutyAssignment(context.Background(), shiftId, dutyId)
+
+	require.NoError(t, err)
+	require.Equal(t, dutyId, restored.RowKey)
+	event := <-subscription.Events
+	require.Equal(t, models.DutyStreamUpdated, event.Type)
+	require.Equal(t, "Clean grill", event.DutyName)
+}
+
+func TestGetAllDutyAssignmentsHandler_IncludeDeleted(t *testing.T) {
+	mockService := new(mocks.MockDutyAssignmentService)
+	handler := handlers.NewDutyAssignmentHandler(mockService)
+
+	shiftId := uuid.New()
+	deletedAt := time.Now()
+	active := models.DutyAssignment{PartitionKey: shiftId, RowKey: uuid.New()}
+	deleted := models.DutyAssignment{PartitionKey: shiftId, RowKey: uuid.New(), DeletedAt: &deletedAt}
+	mockService.On("GetAllDutyAssignmentsByShiftId", mock.Anything, shiftId).Return([]models.DutyAssignment{active}, nil)
+	mockService.On("GetDeletedDutyAssignments", mock.Anything, shiftId).Return([]models.DutyAssignment{deleted}, nil)
+	mockService.On("LocalizeDutyAssignments", mock.Anything, []models.DutyAssignment{active, deleted}).Return([]models.DutyAssignment{active, deleted}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/duties/duty-assignments?shiftId="+shiftId.String()+"&includeDeleted=true", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetAllDutyAssignmentsByShiftId(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var response []models.DutyAssignment
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.Len(t, response, 2)
+	require.True(t, response[1].IsDeleted())
+	mockService.AssertExpectations(t)
+}
+
+func TestPurge_DeletesExpiredRowsWithPhotosAndComments(t *testing.T) {
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockPhotoRepo := new(mocks.MockDutyAssignmentPhotoRepository)
+	mockCommentRepo := new(mocks.MockDutyAssignmentCommentRepository)
+	service := services.NewRetentionService(mockDutyRepo, mockRepo, mockPhotoRepo, mockCommentRepo)
+
+	expiredAt := time.Now().Add(-48 * time.Hour)
+	recentAt := time.Now()
+	expired := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), DeletedAt: &expiredAt, Attachments: []models.DutyAttachment{{Id: uuid.New(), BlobName: "manual.pdf"}}}
+	recent := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), DeletedAt: &recentAt}
+	dutyAssignment := models.DutyAssignment{PartitionKey: uuid.New(), RowKey: uuid.New(), DeletedAt: &expiredAt}
+	photo := models.DutyAssignmentPhoto{RowKey: uuid.New(), BlobName: "photo.png"}
+	comment := models.DutyAssignmentComment{RowKey: uuid.New(), Text: "Grill is clean"}
+
+	mockDutyRepo.On("GetAllDuties", mock.Anything, mock.AnythingOfType("string")).Return([]models.Duty{expired, recent}, nil)
+	mockDutyRepo.On("DeleteDuty", mock.Anything, expired).Return(nil)
+	mockRepo.On("GetDeletedDutyAssignments", mock.Anything, (*uuid.UUID)(nil), mock.AnythingOfType("time.Time")).Return([]models.DutyAssignment{dutyAssignment}, nil)
+	mockPhotoRepo.On("GetPhotos", mock.Anything, dutyAssignment.PartitionKey, dutyAssignment.RowKey).Return([]models.DutyAssignmentPhoto{photo}, nil)
+	mockPhotoRepo.On("DeletePhoto", mock.Anything, photo).Return(nil)
+	mockCommentRepo.On("GetComments", mock.Anything, dutyAssignment.PartitionKey, dutyAssignment.RowKey).Return([]models.DutyAssignmentComment{comment}, nil)
+	mockCommentRepo.On("DeleteComment", mock.Anything, comment).Return(nil)
+	mockRepo.On("DeleteDutyAssignment", mock.Anything, dutyAssignment).Return(nil)
+
+	result, err := service.Purge(context.Background(), 24*time.Hour)
+
+	require.NoError(t, err)
+	require.Equal(t, &models.PurgeResult{Duties: 1, DutyAttachments: 1, DutyAssignments: 1, Photos: 1, Comments: 1}, result)
+	mockDutyRepo.AssertNotCalled(t, "DeleteDuty", mock.Anything, recent)
+	mockDutyRepo.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+	mockPhotoRepo.AssertExpectations(t)
+	mockCommentRepo.AssertExpectations(t)
+}
+
+// FAILURE CASES:
+func TestUpdateDuty_Deleted(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyService(mockRepo, mockVersionRepo)
+
+	deletedAt := time.Now()
+	duty := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), Version: 2, DeletedAt: &deletedAt}
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil)
+
+	err := service.UpdateDuty(context.Background(), "Duty", duty.RowKey.String(), models.Duty{DutyName: "Clean grill"})
+
+	require.ErrorIs(t, err, services.ErrDutyDeleted)
+	mockRepo.AssertNotCalled(t, "UpdateDuty", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockVersionRepo.AssertNotCalled(t, "AddVersion", mock.Anything, mock.Anything)
+}
+
+func TestRestoreDuty_KeyInUse(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyService(mockRepo, new(mocks.MockDutyVersionRepository))
+
+	deletedAt := time.Now()
+	duty := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), DutyKey: "chef-clean-grill", DeletedAt: &deletedAt}
+	replacement := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), DutyKey: "chef-clean-grill"}
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil)
+	mockRepo.On("GetAllDuties", mock.Anything, "").Return([]models.Duty{duty, replacement}, nil)
+
+	_, err := service.RestoreDuty(context.Background(), "Duty", duty.RowKey.String())
+
+	require.ErrorIs(t, err, services.ErrDutyKeyInUse)
+	mockRepo.AssertNotCalled(t, "SetDutyDeleted", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRestoreDutyHandler_KeyInUse(t *testing.T) {
+	mockService := new(mocks.MockDutyService)
+	handler := handlers.NewDutyHandler(mockService)
+
+	mockService.On("RestoreDuty", mock.Anything, "Duty", "TestRowKey").Return(nil, services.ErrDutyKeyInUse)
+
+	req := httptest.NewRequest(http.MethodPost, "/duties/Duty/TestRowKey/restore", nil)
+	req = mux.SetURLVars(req, map[string]string{"PartitionKey": "Duty", "RowKey": "TestRowKey"})
+	rec := httptest.NewRecorder()
+
+	handler.RestoreDuty(rec, req)
+
+	require.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestPurge_KeepsAssignmentWhenPhotoFails(t *testing.T) {
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockPhotoRepo := new(mocks.MockDutyAssignmentPhotoRepository)
+	mockCommentRepo := new(mocks.MockDutyAssignmentCommentRepository)
+	service := services.NewRetentionService(mockDutyRepo, mockRepo, mockPhotoRepo, mockCommentRepo)
+
+	expiredAt := time.Now().Add(-48 * time.Hour)
+	dutyAssignment := models.DutyAssignment{PartitionKey: uuid.New(), RowKey: uuid.New(), DeletedAt: &expiredAt}
+	photo := models.DutyAssignmentPhoto{RowKey: uuid.New(), BlobName: "photo.png"}
+
+	mockDutyRepo.On("GetAllDuties", mock.Anything, mock.AnythingOfType("string")).Return([]models.Duty{}, nil)
+	mockRepo.On("GetDeletedDutyAssignments", mock.Anything, (*uuid.UUID)(nil), mock.AnythingOfType("time.Time")).Return([]models.DutyAssignment{dutyAssignment}, nil)
+	mockPhotoRepo.On("GetPhotos", mock.Anything, dutyAssignment.PartitionKey, dutyAssignment.RowKey).Return([]models.DutyAssignmentPhoto{photo}, nil)
+	mockPhotoRepo.On("DeletePhoto", mock.Anything, photo).Return(errors.New("blob storage unavailable"))
+
+	result, err := service.Purge(context.Background(), 24*time.Hour)
+
+	// the next run retries it
+	require.NoError(t, err)
+	require.Equal(t, 0, result.DutyAssignments)
+	mockRepo.AssertNotCalled(t, "DeleteDutyAssignment", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,169 @@
+package unit_tests
+
+import (
+	"context"
+	"duty-service/handlers"
+	"duty-service/models"
+	"duty-service/repositories"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// templates to search: two chef duties and a staff duty
+func searchableDuties() []models.Duty {
+	return []models.Duty{
+		{PartitionKey: "Duty", RowKey: uuid.New(), RoleId: 2, DutyName: "Clean grill", DutyDescription: "Scrub the grates", Tags: []string{"cleaning", "closing"}, DueMinutes: 30},
+		{PartitionKey: "Duty", RowKey: uuid.New(), RoleId: 2, DutyName: "Count stock", DutyDescription: "Count the fridge", Tags: []string{"Closing"}, DueMinutes: 10},
+		{PartitionKey: "Duty", RowKey: uuid.New(), RoleId: 3, DutyName: "Mop floor", DutyDescription: "Clean the truck floor", Tags: []string{"cleaning"}},
+	}
+}
+
+func dutyNames(duties []models.Duty) []string {
+	names := []string{}
+	for _, duty := range duties {
+		names = append(names, duty.DutyName)
+	}
+	return names
+}
+
+// SUCCESS CASES:
+func TestODataFilter_EscapesValues(t *testing.T) {
+	shiftId := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+
+	filter := repositories.NewODataFilter().
+		Eq("DutyName", "O'Brien' or RoleId eq 1").
+		Eq("RoleId", 2).
+		Eq("PartitionKey", shiftId).
+		Le("DeletedAt", time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)).
+		In("AssigneeId", "anna", "bob")
+
+	require.Equal(t, "DutyName eq 'O''Brien'' or RoleId eq 1' and RoleId eq 2 and PartitionKey eq '123e4567-e89b-12d3-a456-426614174000'"+
+		" and DeletedAt le '2024-06-15T10:00:00Z' and (AssigneeId eq 'anna' or AssigneeId eq 'bob')", filter.String())
+	require.Empty(t, repositories.NewODataFilter().String())
+}
+
+func TestGetAllDuties_FiltersByNameAndRoleInTableStorage(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyService(mockRepo, new(mocks.MockDutyVersionRepository))
+
+	roleId := 2
+	mockRepo.On("GetAllDuties", mock.Anything, "DutyName eq 'Chef''s special' and RoleId eq 2").Return([]models.Duty{}, nil)
+
+	result, err := service.GetAllDuties(context.Background(), models.DutySearch{Name: "Chef's special", RoleId: &roleId})
+
+	require.NoError(t, err)
+	require.Equal(t, 0, result.Total)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetAllDuties_SearchesTextAndTags(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyService(mockRepo, new(mocks.MockDutyVersionRepository))
+
+	mockRepo.On("GetAllDuties", mock.Anything, "").Return(searchableDuties(), nil)
+
+	// the text is searched in the name and the description
+	result, err := service.GetAllDuties(context.Background(), models.DutySearch{Query: "CLEAN"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Clean grill", "Mop floor"}, dutyNames(result.Duties))
+
+	result, err = service.GetAllDuties(context.Background(), models.DutySearch{NamePrefix: "co"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Count stock"}, dutyNames(result.Duties))
+
+	// every tag has to match
+	result, err = service.GetAllDuties(context.Background(), models.DutySearch{Tags: []string{"closing", "cleaning"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Clean grill"}, dutyNames(result.Duties))
+}
+
+func TestGetAllDuties_SortsAndPages(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyService(mockRepo, new(mocks.MockDutyVersionRepository))
+
+	mockRepo.On("GetAllDuties", mock.Anything, "").Return(searchableDuties(), nil)
+
+	result, err := service.GetAllDuties(context.Background(), models.DutySearch{Sort: "-DueMinutes", Page: 1, PageSize: 2})
+	require.NoError(t, err)
+	require.Equal(t, 3, result.Total)
+	require.Equal(t, []string{"Clean grill", "Count stock"}, dutyNames(result.Duties))
+
+	result, err = service.GetAllDuties(context.Background(), models.DutySearch{Sort: "-DueMinutes", Page: 2, PageSize: 2})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Mop floor"}, dutyNames(result.Duties))
+
+	result, err = service.GetAllDuties(context.Background(), models.DutySearch{Page: 3, PageSize: 2})
+	require.NoError(t, err)
+	require.Empty(t, result.Duties)
+}
+
+func TestGetAllDutiesHandler_ParsesSearch(t *testing.T) {
+	mockService := new(mocks.MockDutyService)
+	handler := handlers.NewDutyHandler(mockService)
+
+	roleId := 2
+	expected := models.DutySearch{Query: "grill", NamePrefix: "Cl", RoleId: &roleId, Tags: []string{"closing", "cleaning"}, Sort: "-DutyName", Page: 2, PageSize: 10}
+	mockService.On("GetAllDuties", mock.Anything, expected).Return(&models.DutySearchResult{Duties: []models.Duty{}, Total: 12}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/duties?q=grill&prefix=Cl&roleId=2&tag=closing&tag=cleaning&sort=-DutyName&page=2&pageSize=10", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetAllDuties(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "12", rec.Header().Get("X-Total-Count"))
+	var response []models.Duty
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.Empty(t, response)
+	mockService.AssertExpectations(t)
+}
+
+func TestDiffDutyVersions_Tags(t *testing.T) {
+	from := models.DutyVersion{Version: 1, Tags: []string{"cleaning"}}
+	to := models.DutyVersion{Version: 2, Tags: []string{"cleaning", "closing"}}
+
+	diff := models.DiffDutyVersions(from, to)
+
+	require.Equal(t, []models.DutyFieldChange{{Field: "Tags", From: []string{"cleaning"}, To: []string{"cleaning", "closing"}}}, diff.Changes)
+}
+
+// FAILURE CASES:
+func TestGetAllDuties_InvalidSort(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyService(mockRepo, new(mocks.MockDutyVersionRepository))
+
+	_, err := service.GetAllDuties(context.Background(), models.DutySearch{Sort: "Name eq 'x'"})
+
+	require.ErrorIs(t, err, models.ErrInvalidDutySearch)
+	mockRepo.AssertNotCalled(t, "GetAllDuties", mock.Anything, mock.Anything)
+}
+
+func TestGetAllDutiesHandler_InvalidParameters(t *testing.T) {
+	mockService := new(mocks.MockDutyService)
+	handler := handlers.NewDutyHandler(mockService)
+
+	for _, query := range []string{"roleId=9", "roleId=chef", "page=0", "pageSize=-5"} {
+		req := httptest.NewRequest(http.MethodGet, "/duties?"+query, nil)
+		rec := httptest.NewRecorder()
+
+		handler.GetAllDuties(rec, req)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+	mockService.AssertNotCalled(t, "GetAllDuties", mock.Anything, mock.Anything)
+}
+
+func TestODataFilter_RejectsInvalidProperty(t *testing.T) {
+	require.Panics(t, func() {
+		repositories.NewODataFilter().Eq("DutyName eq 'x' or RoleId", 1)
+	})
+}
@@ -0,0 +1,159 @@
+package unit_tests
+
+import (
+	"context"
+	"duty-service/handlers"
+	"duty-service/models"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func streamEvent(shiftId uuid.UUID, eventId *uuid.UUID, eventType models.DutyStreamEventType) models.DutyStreamEvent {
+	return models.DutyAssignment{PartitionKey: shiftId, RowKey: uuid.New(), EventId: eventId}.StreamEvent(eventType, "Clean grill", time.Now().UTC())
+}
+
+// SUCCESS CASES:
+func TestDutyStream_SendsMatchingEvents(t *testing.T) {
+	stream := services.NewDutyStream(10)
+	shiftId, eventId := uuid.New(), uuid.New()
+
+	subscription := stream.Subscribe(models.DutyStreamFilter{EventId: &eventId}, "")
+	defer subscription.Close()
+
+	stream.Publish(streamEvent(uuid.New(), nil, models.DutyStreamCreated))
+	stream.Publish(streamEvent(shiftId, &eventId, models.DutyStreamUpdated))
+
+	event := <-subscription.Events
+	require.Equal(t, models.DutyStreamUpdated, event.Type)
+	require.Equal(t, shiftId, event.ShiftId)
+	require.NotEmpty(t, event.Id)
+	require.Empty(t, subscription.Events)
+}
+
+func TestDutyStream_ReplaysEventsAfterLastEventId(t *testing.T) {
+	stream := services.NewDutyStream(10)
+	shiftId := uuid.New()
+	filter := models.DutyStreamFilter{ShiftId: &shiftId}
+
+	first := stream.Subscribe(filter, "")
+	stream.Publish(streamEvent(shiftId, nil, models.DutyStreamCreated))
+	lastEventId := (<-first.Events).Id
+	first.Close()
+
+	stream.Publish(streamEvent(uuid.New(), nil, models.DutyStreamCreated))
+	stream.Publish(streamEvent(shiftId, nil, models.DutyStreamUpdated))
+
+	subscription := stream.Subscribe(filter, lastEventId)
+	defer subscription.Close()
+
+	require.False(t, subscription.Missed)
+	require.Len(t, subscription.Replay, 1)
+	require.Equal(t, models.DutyStreamUpdated, subscription.Replay[0].Type)
+}
+
+func TestDutyStreamHandler_WritesReplayedEvents(t *testing.T) {
+	stream := services.NewDutyStream(10)
+	shiftId := uuid.New()
+
+	subscription := stream.Subscribe(models.DutyStreamFilter{}, "")
+	stream.Publish(streamEvent(shiftId, nil, models.DutyStreamCreated))
+	lastEventId := (<-subscription.Events).Id
+	subscription.Close()
+	stream.Publish(streamEvent(shiftId, nil, models.DutyStreamUpdated))
+
+	// a cancelled request returns right after the replay
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/duties/stream?shiftId="+shiftId.String(), nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", lastEventId)
+	rec := httptest.NewRecorder()
+
+	handlers.NewDutyStreamHandler(stream).StreamDutyAssignments(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Body.String(), "event: updated\n")
+	require.NotContains(t, rec.Body.String(), "event: created\n")
+}
+
+func TestUpdateDutyAssignment_PublishesStreamEvent(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	stream := services.NewDutyStream(10)
+	service := services.NewDutyAssignmentService(mockRepo, nil, nil, nil, nil, nil, nil, stream, nil)
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	existing := &models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusIncomplete, FormValues: map[string]interface{}{"temperature": 4.0}}
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(existing, nil)
+	mockRepo.On("UpdateDutyAssignment", mock.Anything, mock.Anything).Return(nil)
+
+	subscription := stream.Subscribe(models.DutyStreamFilter{ShiftId: &shiftId}, "")
+	defer subscription.Close()
+
+	err := service.UpdateDutyAssignment(context.Background(), models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusSkipped}, nil)
+
+	require.NoError(t, err)
+	event := <-subscription.Events
+	require.Equal(t, models.DutyStreamUpdated, event.Type)
+	require.Equal(t, models.StatusSkipped, event.DutyAssignment.DutyAssignmentStatus)
+	require.Equal(t, 4.0, event.DutyAssignment.FormValues["temperature"]) // unchanged fields come from the stored assignment
+}
+
+// FAILURE CASES:
+func TestDutyStream_MissedWhenLastEventIdIsNoLongerBuffered(t *testing.T) {
+	stream := services.NewDutyStream(2)
+	shiftId := uuid.New()
+
+	subscription := stream.Subscribe(models.DutyStreamFilter{}, "")
+	stream.Publish(streamEvent(shiftId, nil, models.DutyStreamCreated))
+	lastEventId := (<-subscription.Events).Id
+	subscription.Close()
+
+	for i := 0; i < 3; i++ {
+		stream.Publish(streamEvent(shiftId, nil, models.DutyStreamUpdated))
+	}
+
+	missed := stream.Subscribe(models.DutyStreamFilter{}, lastEventId)
+	defer missed.Close()
+	require.True(t, missed.Missed)
+	require.Empty(t, missed.Replay)
+
+	// IDs of an earlier run of the service can't be resumed either
+	restarted := stream.Subscribe(models.DutyStreamFilter{}, "0-1")
+	defer restarted.Close()
+	require.True(t, restarted.Missed)
+}
+
+func TestDutyStream_DropsSlowSubscriber(t *testing.T) {
+	stream := services.NewDutyStream(200)
+	subscription := stream.Subscribe(models.DutyStreamFilter{}, "")
+
+	for i := 0; i < 100; i++ {
+		stream.Publish(streamEvent(uuid.New(), nil, models.DutyStreamCreated))
+	}
+
+	received := 0
+	for range subscription.Events {
+		received++
+	}
+	require.Less(t, received, 100)
+	subscription.Close() // closing a dropped subscription is fine
+}
+
+func TestDutyStreamHandler_RequiresShiftOrEvent(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/duties/stream", nil)
+	rec := httptest.NewRecorder()
+
+	handlers.NewDutyStreamHandler(services.NewDutyStream(10)).StreamDutyAssignments(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.True(t, strings.Contains(rec.Body.String(), "shiftId"))
+}
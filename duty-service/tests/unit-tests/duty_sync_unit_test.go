@@ -0,0 +1,211 @@
+package unit_tests
+
+import (
+	"bytes"
+	"context"
+	"duty-service/handlers"
+	"duty-service/models"
+	"duty-service/repositories"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newSyncService() (*services.DutySyncService, *mocks.MockDutyAssignmentRepository, *mocks.MockDutySyncRepository) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockSyncRepo := new(mocks.MockDutySyncRepository)
+	assignments := services.NewDutyAssignmentService(mockRepo, nil, nil, nil, nil, nil, nil, nil, nil)
+	return services.NewDutySyncService(mockRepo, mockSyncRepo, assignments), mockRepo, mockSyncRepo
+}
+
+// SUCCESS CASES:
+func TestSync_AppliesChangeAsOfClientTime(t *testing.T) {
+	service, mockRepo, mockSyncRepo := newSyncService()
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	clientTime := time.Date(2024, 6, 15, 10, 30, 0, 0, time.UTC)
+	modifiedAt := time.Date(2024, 6, 15, 11, 0, 0, 123456700, time.UTC)
+	change := models.DutySyncChange{ClientChangeId: uuid.New(), ShiftId: shiftId, DutyId: dutyId, ClientTimestamp: clientTime, DutyAssignmentStatus: models.StatusCompleted}
+
+	mockSyncRepo.On("ReserveSyncChange", mock.Anything, shiftId, change.ClientChangeId, change.DutyId, mock.Anything, mock.Anything).Return(nil, nil)
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusIncomplete}, nil)
+	mockRepo.On("UpdateDutyAssignment", mock.Anything, mock.MatchedBy(func(a models.DutyAssignment) bool {
+		return *a.CompletedAt == clientTime && *a.UpdatedAt == clientTime
+	})).Return(nil)
+	mockSyncRepo.On("SaveSyncResult", mock.Anything, mock.MatchedBy(func(r models.DutySyncChangeResult) bool {
+		return r.Status == models.SyncApplied
+	})).Return(nil)
+	mockRepo.On("GetDutyAssignmentsChangedSince", mock.Anything, []uuid.UUID{shiftId}, time.Time{}).Return([]models.DutyAssignment{{PartitionKey: shiftId, RowKey: dutyId, ModifiedAt: modifiedAt}}, nil)
+
+	response, err := service.Sync(context.Background(), models.DutySyncRequest{Changes: []models.DutySyncChange{change}}, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, models.SyncApplied, response.Results[0].Status)
+	require.Len(t, response.Changed, 1)
+	require.Equal(t, "2024-06-15T11:00:00.1234567Z", response.SyncToken)
+	mockRepo.AssertExpectations(t)
+	mockSyncRepo.AssertExpectations(t)
+}
+
+func TestSync_ReturnsStoredResultOfDuplicate(t *testing.T) {
+	service, mockRepo, mockSyncRepo := newSyncService()
+
+	shiftId := uuid.New()
+	since := time.Date(2024, 6, 15, 11, 0, 0, 0, time.UTC)
+	change := models.DutySyncChange{ClientChangeId: uuid.New(), ShiftId: shiftId, DutyId: uuid.New(), ClientTimestamp: since}
+
+	mockSyncRepo.On("ReserveSyncChange", mock.Anything, shiftId, change.ClientChangeId, change.DutyId, mock.Anything, mock.Anything).Return(&models.DutySyncChangeResult{ClientChangeId: change.ClientChangeId, Status: models.SyncApplied}, nil)
+	mockRepo.On("GetDutyAssignmentsChangedSince", mock.Anything, []uuid.UUID{shiftId}, since).Return([]models.DutyAssignment(nil), nil)
+
+	response, err := service.Sync(context.Background(), models.DutySyncRequest{SyncToken: models.NewSyncToken(since), Changes: []models.DutySyncChange{change}}, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, models.SyncApplied, response.Results[0].Status)
+	require.True(t, response.Results[0].Duplicate)
+	require.Empty(t, response.Changed)
+	require.Equal(t, models.NewSyncToken(since), response.SyncToken) // nothing new, the next sync starts at the same point
+	mockRepo.AssertNotCalled(t, "UpdateDutyAssignment", mock.Anything, mock.Anything)
+}
+
+func TestSync_LastWriterWinsDiscardsOlderChange(t *testing.T) {
+	service, mockRepo, mockSyncRepo := newSyncService()
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	serverUpdate := time.Date(2024, 6, 15, 10, 45, 0, 0, time.UTC)
+	change := models.DutySyncChange{ClientChangeId: uuid.New(), ShiftId: shiftId, DutyId: dutyId, ClientTimestamp: serverUpdate.Add(-10 * time.Minute), DutyAssignmentStatus: models.StatusSkipped}
+
+	mockSyncRepo.On("ReserveSyncChange", mock.Anything, shiftId, change.ClientChangeId, change.DutyId, mock.Anything, mock.Anything).Return(nil, nil)
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusCompleted, UpdatedAt: &serverUpdate}, nil)
+	mockSyncRepo.On("SaveSyncResult", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("GetDutyAssignmentsChangedSince", mock.Anything, []uuid.UUID{shiftId}, time.Time{}).Return([]models.DutyAssignment{}, nil)
+
+	response, err := service.Sync(context.Background(), models.DutySyncRequest{Changes: []models.DutySyncChange{change}}, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, models.SyncStale, response.Results[0].Status)
+	require.Equal(t, models.StatusCompleted, response.Results[0].DutyAssignment.DutyAssignmentStatus)
+	mockRepo.AssertNotCalled(t, "UpdateDutyAssignment", mock.Anything, mock.Anything)
+}
+
+func TestSync_ReportsConcurrentChange(t *testing.T) {
+	service, mockRepo, mockSyncRepo := newSyncService()
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	seenByDevice := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	serverUpdate := seenByDevice.Add(20 * time.Minute)
+	change := models.DutySyncChange{
+		ClientChangeId:       uuid.New(),
+		ShiftId:              shiftId,
+		DutyId:               dutyId,
+		ClientTimestamp:      serverUpdate.Add(5 * time.Minute), // later, but made without knowing the server's change
+		BaseUpdatedAt:        &seenByDevice,
+		DutyAssignmentStatus: models.StatusCompleted,
+	}
+
+	mockSyncRepo.On("ReserveSyncChange", mock.Anything, shiftId, change.ClientChangeId, change.DutyId, mock.Anything, mock.Anything).Return(nil, nil)
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyAssignmentStatus: models.StatusSkipped, UpdatedAt: &serverUpdate}, nil)
+	mockSyncRepo.On("SaveSyncResult", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("GetDutyAssignmentsChangedSince", mock.Anything, []uuid.UUID{shiftId}, time.Time{}).Return([]models.DutyAssignment{}, nil)
+
+	response, err := service.Sync(context.Background(), models.DutySyncRequest{ConflictMode: models.SyncReportConflicts, Changes: []models.DutySyncChange{change}}, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, models.SyncConflict, response.Results[0].Status)
+	mockRepo.AssertNotCalled(t, "UpdateDutyAssignment", mock.Anything, mock.Anything)
+}
+
+// FAILURE CASES:
+func TestSync_RejectsChangeOfUnknownAssignment(t *testing.T) {
+	service, mockRepo, mockSyncRepo := newSyncService()
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	change := models.DutySyncChange{ClientChangeId: uuid.New(), ShiftId: shiftId, DutyId: dutyId, ClientTimestamp: time.Now().UTC()}
+
+	mockSyncRepo.On("ReserveSyncChange", mock.Anything, shiftId, change.ClientChangeId, change.DutyId, mock.Anything, mock.Anything).Return(nil, nil)
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(nil, repositories.ErrDutyAssignmentNotFound)
+	mockSyncRepo.On("SaveSyncResult", mock.Anything, mock.MatchedBy(func(r models.DutySyncChangeResult) bool {
+		return r.Status == models.SyncRejected
+	})).Return(nil)
+	mockRepo.On("GetDutyAssignmentsChangedSince", mock.Anything, []uuid.UUID{shiftId}, time.Time{}).Return([]models.DutyAssignment{}, nil)
+
+	response, err := service.Sync(context.Background(), models.DutySyncRequest{Changes: []models.DutySyncChange{change}}, nil)
+
+	require.NoError(t, err) // one bad change doesn't fail the others
+	require.Equal(t, models.SyncRejected, response.Results[0].Status)
+	mockSyncRepo.AssertExpectations(t)
+}
+
+func TestSync_DoesNotStoreFailedChange(t *testing.T) {
+	service, mockRepo, mockSyncRepo := newSyncService()
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	change := models.DutySyncChange{ClientChangeId: uuid.New(), ShiftId: shiftId, DutyId: dutyId, ClientTimestamp: time.Now().UTC()}
+
+	mockSyncRepo.On("ReserveSyncChange", mock.Anything, shiftId, change.ClientChangeId, change.DutyId, mock.Anything, mock.Anything).Return(nil, nil)
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(nil, context.DeadlineExceeded)
+	mockSyncRepo.On("ReleaseSyncChange", mock.Anything, shiftId, change.ClientChangeId).Return(nil)
+	mockRepo.On("GetDutyAssignmentsChangedSince", mock.Anything, []uuid.UUID{shiftId}, time.Time{}).Return([]models.DutyAssignment{}, nil)
+
+	response, err := service.Sync(context.Background(), models.DutySyncRequest{Changes: []models.DutySyncChange{change}}, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, models.SyncFailed, response.Results[0].Status)
+	mockSyncRepo.AssertNotCalled(t, "SaveSyncResult", mock.Anything, mock.Anything) // sending it again applies it
+	mockSyncRepo.AssertExpectations(t)
+}
+
+func TestSync_ChangeAppliedByAnotherSync(t *testing.T) {
+	service, mockRepo, mockSyncRepo := newSyncService()
+
+	shiftId, dutyId := uuid.New(), uuid.New()
+	change := models.DutySyncChange{ClientChangeId: uuid.New(), ShiftId: shiftId, DutyId: dutyId, ClientTimestamp: time.Now().UTC(), DutyAssignmentStatus: models.StatusCompleted}
+
+	// the app retried the sync while the first one is still applying the change
+	mockSyncRepo.On("ReserveSyncChange", mock.Anything, shiftId, change.ClientChangeId, dutyId, mock.Anything, mock.Anything).Return(nil, repositories.ErrSyncChangeInProgress)
+	mockRepo.On("GetDutyAssignmentsChangedSince", mock.Anything, []uuid.UUID{shiftId}, time.Time{}).Return([]models.DutyAssignment{}, nil)
+
+	response, err := service.Sync(context.Background(), models.DutySyncRequest{Changes: []models.DutySyncChange{change}}, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, models.SyncFailed, response.Results[0].Status) // sent again later, it gets the stored result
+	mockRepo.AssertNotCalled(t, "GetDutyAssignment", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "UpdateDutyAssignment", mock.Anything, mock.Anything)
+	mockSyncRepo.AssertNotCalled(t, "ReleaseSyncChange", mock.Anything, mock.Anything, mock.Anything) // the reservation isn't ours
+}
+
+func TestSyncHandler_InvalidSyncToken(t *testing.T) {
+	mockService := new(mocks.MockDutySyncService)
+	handler := handlers.NewDutySyncHandler(mockService)
+
+	mockService.On("Sync", mock.Anything, mock.Anything, mock.Anything).Return(nil, models.ErrInvalidSyncToken)
+
+	body, _ := json.Marshal(models.DutySyncRequest{SyncToken: "yesterday"})
+	req := httptest.NewRequest(http.MethodPost, "/duties/duty-assignments/sync", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.SyncDutyAssignments(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestSyncHandler_InvalidConflictMode(t *testing.T) {
+	mockService := new(mocks.MockDutySyncService)
+	handler := handlers.NewDutySyncHandler(mockService)
+
+	body, _ := json.Marshal(models.DutySyncRequest{ConflictMode: "server-wins"})
+	req := httptest.NewRequest(http.MethodPost, "/duties/duty-assignments/sync", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.SyncDutyAssignments(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertNotCalled(t, "Sync", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,189 @@
+package unit_tests
+
+import (
+	"context"
+	"duty-service/handlers"
+	"duty-service/models"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// existing templates: two chef duties (one from before keys existed) and a staff duty
+func existingDuties() []models.Duty {
+	return []models.Duty{
+		{PartitionKey: "Duty", RowKey: uuid.New(), RoleId: 2, DutyName: "Clean grill", DutyKey: "chef-clean-grill", DueMinutes: 30},
+		{PartitionKey: "Duty", RowKey: uuid.New(), RoleId: 2, DutyName: "Count stock"}, // key derived: chef-count-stock
+		{PartitionKey: "Duty", RowKey: uuid.New(), RoleId: 3, DutyName: "Mop floor", DutyKey: "staff-mop-floor"},
+	}
+}
+
+// SUCCESS CASES:
+func TestImportDuties_DryRunReturnsDiff(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyService(mockRepo, mockVersionRepo)
+
+	mockRepo.On("GetAllDuties", mock.Anything, "").Return(existingDuties(), nil)
+
+	result, err := service.ImportDuties(context.Background(), []models.DutyTemplate{
+		{DutyKey: "chef-clean-grill", RoleId: 2, DutyName: "Clean grill", DueMinutes: 45}, // updated
+		{RoleId: 2, DutyName: "Prep sauces"},                                              // created
+	}, models.DutyImportOptions{DryRun: true, Prune: true})
+
+	require.NoError(t, err)
+	require.True(t, result.DryRun)
+	require.Len(t, result.Created, 1)
+	require.Equal(t, "chef-prep-sauces", result.Created[0].DutyKey)
+	require.Nil(t, result.Created[0].RowKey)
+	require.Len(t, result.Updated, 1)
+	require.Equal(t, []string{"DueMinutes"}, result.Updated[0].Fields)
+	require.Len(t, result.Deleted, 1) // only chef duties are pruned, the staff duty isn't in an imported role
+	require.Equal(t, "chef-count-stock", result.Deleted[0].DutyKey)
+	mockRepo.AssertNotCalled(t, "CreateDuty", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "UpdateDuty", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "SetDutyDeleted", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestImportDuties_AppliesChanges(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyService(mockRepo, mockVersionRepo)
+
+	duties := existingDuties()
+	mockRepo.On("GetAllDuties", mock.Anything, "").Return(duties, nil)
+	mockVersionRepo.On("AddVersion", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("ClaimDutyVersion", mock.Anything, "Duty", duties[1].RowKey.String(), mock.Anything, mock.Anything).Return("", nil)
+	mockRepo.On("CreateDuty", mock.Anything, mock.MatchedBy(func(duty models.Duty) bool {
+		return duty.DutyKey == "chef-prep-sauces" && duty.PartitionKey == "Duty" && duty.RowKey != uuid.Nil && duty.Version == 1
+	})).Return(nil)
+	// the duty without a key gets its derived key stored (as version 2, after its old content as version 1)
+	mockRepo.On("UpdateDuty", mock.Anything, "Duty", duties[1].RowKey.String(), mock.MatchedBy(func(duty models.Duty) bool {
+		return duty.DutyKey == "chef-count-stock" && duty.DutyDescription == "Count the fridge" && duty.Version == 2
+	})).Return(nil)
+
+	result, err := service.ImportDuties(context.Background(), []models.DutyTemplate{
+		{DutyKey: "chef-clean-grill", RoleId: 2, DutyName: "Clean grill", DueMinutes: 30}, // unchanged
+		{RoleId: 2, DutyName: "Count stock", DutyDescription: "Count the fridge"},
+		{RoleId: 2, DutyName: "Prep sauces"},
+	}, models.DutyImportOptions{})
+
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Unchanged)
+	require.Equal(t, []string{"DutyKey", "DutyDescription"}, result.Updated[0].Fields)
+	require.NotNil(t, result.Created[0].RowKey)
+	require.Empty(t, result.Deleted)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "SetDutyDeleted", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockVersionRepo.AssertNumberOfCalls(t, "AddVersion", 3)
+}
+
+func TestExportDuties_YAML(t *testing.T) {
+	mockService := new(mocks.MockDutyService)
+	handler := handlers.NewDutyHandler(mockService)
+
+	templates := []models.DutyTemplate{
+		{DutyKey: "chef-clean-grill", RoleId: 2, DutyName: "Clean grill", FormSchema: []models.FormField{{Key: "done", Label: "Done?", Type: models.FieldCheckbox}}},
+	}
+	mockService.On("ExportDuties", mock.Anything).Return(templates, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/duties/export?format=yaml", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ExportDuties(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	require.Equal(t, "application/yaml", rec.Header().Get("Content-Type"))
+
+	var exported []models.DutyTemplate
+	require.NoError(t, yaml.Unmarshal(rec.Body.Bytes(), &exported))
+	require.Equal(t, templates, exported)
+}
+
+func TestImportDuties_CSV(t *testing.T) {
+	mockService := new(mocks.MockDutyService)
+	handler := handlers.NewDutyHandler(mockService)
+
+	body := "DutyKey,RoleId,DutyName,DutyDescription,DueMinutes,FormSchema\n" +
+		`chef-clean-grill,2,Clean grill,Scrub it,30,"[{""Key"":""done"",""Label"":""Done?"",""Type"":""Checkbox"",""Required"":true}]"` + "\n" +
+		",3,Mop floor,,,\n"
+
+	expected := []models.DutyTemplate{
+		{DutyKey: "chef-clean-grill", RoleId: 2, DutyName: "Clean grill", DutyDescription: "Scrub it", DueMinutes: 30,
+			FormSchema: []models.FormField{{Key: "done", Label: "Done?", Type: models.FieldCheckbox, Required: true}}},
+		{RoleId: 3, DutyName: "Mop floor"},
+	}
+	mockService.On("ImportDuties", mock.Anything, expected, models.DutyImportOptions{DryRun: true}).
+		Return(&models.DutyImportResult{DryRun: true}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/duties/import?dryRun=true", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	rec := httptest.NewRecorder()
+
+	handler.ImportDuties(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	mockService.AssertExpectations(t)
+}
+
+// FAILURE CASES:
+func TestImportDuties_InvalidTemplates(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyService(mockRepo, mockVersionRepo)
+
+	mockRepo.On("GetAllDuties", mock.Anything, "").Return(existingDuties(), nil)
+
+	_, err := service.ImportDuties(context.Background(), []models.DutyTemplate{
+		{DutyKey: "clean", RoleId: 7, DutyName: "Clean grill"},
+		{DutyKey: "clean", RoleId: 2, DutyName: "Clean truck"},
+	}, models.DutyImportOptions{})
+
+	var importErr *models.DutyImportError
+	require.True(t, errors.As(err, &importErr))
+	require.Len(t, importErr.Problems, 2) // the unknown role and the duplicate key
+	mockRepo.AssertNotCalled(t, "CreateDuty", mock.Anything, mock.Anything)
+}
+
+func TestImportDutiesHandler_InvalidTemplates(t *testing.T) {
+	mockService := new(mocks.MockDutyService)
+	handler := handlers.NewDutyHandler(mockService)
+
+	templates := []models.DutyTemplate{{RoleId: 9, DutyName: "Clean grill"}}
+	mockService.On("ImportDuties", mock.Anything, templates, models.DutyImportOptions{}).
+		Return(nil, &models.DutyImportError{Problems: []string{"'Clean grill': invalid RoleId 9"}})
+
+	body, _ := json.Marshal(templates)
+	req := httptest.NewRequest(http.MethodPost, "/duties/import", strings.NewReader(string(body)))
+	rec := httptest.NewRecorder()
+
+	handler.ImportDuties(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Result().StatusCode)
+	var response map[string][]string
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.Len(t, response["errors"], 1)
+}
+
+func TestImportDutiesHandler_UnknownField(t *testing.T) {
+	mockService := new(mocks.MockDutyService)
+	handler := handlers.NewDutyHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodPost, "/duties/import?format=yaml", strings.NewReader("- DutyName: Clean grill\n  Role: 2\n"))
+	rec := httptest.NewRecorder()
+
+	handler.ImportDuties(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Result().StatusCode)
+	mockService.AssertNotCalled(t, "ImportDuties", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,261 @@
+package unit_tests
+
+import (
+	"context"
+	"duty-service/auth"
+	"duty-service/handlers"
+	"duty-service/locale"
+	"duty-service/middlewares"
+	"duty-service/models"
+	"duty-service/repositories"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// a duty written in English with a Polish and a Brazilian Portuguese translation
+func translatedDuty() models.Duty {
+	return models.Duty{
+		PartitionKey:    "Duty",
+		RowKey:          uuid.New(),
+		RoleId:          2,
+		DutyName:        "Clean grill",
+		DutyDescription: "Scrub the grates",
+		Translations: models.DutyTranslations{
+			"pl":    {DutyName: "Wyczyść grill", DutyDescription: "Wyszoruj ruszty"},
+			"pt-BR": {DutyName: "Limpar a grelha"},
+		},
+	}
+}
+
+// SUCCESS CASES:
+func TestDutyLocalize(t *testing.T) {
+	duty := translatedDuty()
+
+	text, language := duty.Localize([]string{"pl"}, "en")
+	require.Equal(t, models.DutyTranslation{DutyName: "Wyczyść grill", DutyDescription: "Wyszoruj ruszty"}, text)
+	require.Equal(t, "pl", language)
+
+	// a regional language falls back to its base language
+	_, language = duty.Localize([]string{"pl-PL"}, "en")
+	require.Equal(t, "pl", language)
+
+	// a translation without a description shows the duty's own description
+	text, language = duty.Localize([]string{"pt-BR"}, "en")
+	require.Equal(t, models.DutyTranslation{DutyName: "Limpar a grelha", DutyDescription: "Scrub the grates"}, text)
+	require.Equal(t, "pt-BR", language)
+
+	// the first language the duty has text in wins, including the language it is written in
+	_, language = duty.Localize([]string{"ro", "en-GB", "pl"}, "en")
+	require.Equal(t, "en", language)
+
+	text, language = duty.Localize([]string{"ro"}, "en")
+	require.Equal(t, "Clean grill", text.DutyName)
+	require.Equal(t, "en", language)
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	require.Equal(t, []string{"pl", "en-GB", "nl"}, locale.ParseAcceptLanguage("nl;q=0.5, pl, en-gb;q=0.8, ro;q=0"))
+	require.Nil(t, locale.ParseAcceptLanguage(""))
+	require.Nil(t, locale.ParseAcceptLanguage("not a language header;q=x"))
+}
+
+func TestLanguageMiddleware_AcceptLanguageThenAccountLanguage(t *testing.T) {
+	var preference locale.Preference
+	handler := middlewares.LanguageMiddleware("en")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		preference = locale.FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/duties/duty-assignments?shiftId="+uuid.NewString(), nil)
+	req.Header.Set("Accept-Language", "ro, nl;q=0.5")
+	req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{Subject: "employee-1", Locale: "pl"}))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Equal(t, locale.Preference{Languages: []string{"ro", "nl", "pl"}, Default: "en"}, preference)
+}
+
+func TestLocalizeDutyAssignments(t *testing.T) {
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyAssignmentService(nil, mockDutyRepo, mockVersionRepo, nil, nil, nil, nil, nil, nil)
+
+	// the template was renamed since version 1 and its Polish translation changed with it
+	duty := translatedDuty()
+	duty.Version = 2
+	version := duty.NewVersion(1, time.Now(), "")
+	version.DutyName = "Clean the grill"
+	version.Translations = models.DutyTranslations{"pl": {DutyName: "Wyczyść ruszt"}}
+	duty.Translations = models.DutyTranslations{"pl": {DutyName: "Wyczyść grill", DutyDescription: "Wyszoruj ruszty"}}
+	purgedId := uuid.New()
+
+	mockDutyRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil).Once()
+	mockDutyRepo.On("GetDutyById", mock.Anything, "Duty", purgedId.String()).Return(nil, repositories.ErrDutyNotFound).Once()
+	mockVersionRepo.On("GetVersion", mock.Anything, duty.RowKey, 1).Return(&version, nil).Once()
+
+	shiftId := uuid.New()
+	dutyAssignments := []models.DutyAssignment{
+		{PartitionKey: shiftId, RowKey: uuid.New(), DutyPartitionKey: "Duty", DutyRowKey: duty.RowKey},                 // from before versioning
+		{PartitionKey: shiftId, RowKey: uuid.New(), DutyPartitionKey: "Duty", DutyRowKey: duty.RowKey, DutyVersion: 1}, // created from version 1
+		{PartitionKey: shiftId, RowKey: uuid.New(), DutyPartitionKey: "Duty", DutyRowKey: purgedId},                    // template was purged
+	}
+
+	ctx := locale.WithPreference(context.Background(), locale.Preference{Languages: []string{"pl"}, Default: "en"})
+	localized, err := service.LocalizeDutyAssignments(ctx, dutyAssignments)
+
+	require.NoError(t, err)
+	require.Equal(t, "Wyczyść grill", localized[0].DutyName)
+	require.Equal(t, "Wyszoruj ruszty", localized[0].DutyDescription)
+	require.Equal(t, "pl", localized[0].Language)
+	require.Equal(t, "Wyczyść ruszt", localized[1].DutyName)
+	require.Equal(t, "Scrub the grates", localized[1].DutyDescription) // the version's own description
+	require.Empty(t, localized[2].DutyName)
+	require.Empty(t, dutyAssignments[0].DutyName) // the input isn't changed
+	mockDutyRepo.AssertExpectations(t)
+	mockDutyRepo.AssertNotCalled(t, "GetAllDuties", mock.Anything, mock.Anything) // only the templates of the assignments are read
+	mockVersionRepo.AssertExpectations(t)
+}
+
+func TestSetDutyTranslation_AddsToExistingTranslations(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyTranslationService(mockRepo, mockVersionRepo, "en", []string{"pl"})
+
+	duty := translatedDuty()
+	duty.Version, duty.ETag = 3, "etag-1"
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil)
+
+	expected := models.DutyTranslations{
+		"pl":    duty.Translations["pl"],
+		"pt-BR": duty.Translations["pt-BR"],
+		"ro":    {DutyName: "Curăță grătarul"},
+	}
+	mockRepo.On("SetDutyTranslations", mock.Anything, "Duty", duty.RowKey.String(), "etag-1", expected).Return(nil)
+	mockVersionRepo.On("SetVersionTranslations", mock.Anything, duty.RowKey, 3, expected).Return(nil) // assignments created from now on are translated too
+
+	updated, err := service.SetDutyTranslation(context.Background(), "Duty", duty.RowKey.String(), "RO", models.DutyTranslation{DutyName: "Curăță grătarul"})
+
+	require.NoError(t, err)
+	require.Equal(t, expected, updated.Translations)
+	mockRepo.AssertExpectations(t)
+	mockVersionRepo.AssertExpectations(t)
+}
+
+func TestGetMissingTranslations(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyTranslationService(mockRepo, nil, "en", nil)
+
+	deletedAt := time.Now()
+	translated := translatedDuty()
+	untranslated := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), RoleId: 1, DutyName: "Count cash"}
+	deleted := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), RoleId: 1, DutyName: "Old duty", DeletedAt: &deletedAt}
+	mockRepo.On("GetAllDuties", mock.Anything, "").Return([]models.Duty{translated, untranslated, deleted}, nil)
+
+	// without configured languages, every language some duty was translated into is checked
+	missing, err := service.GetMissingTranslations(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, missing, 2)
+	require.Equal(t, "Count cash", missing[0].DutyName)
+	require.Equal(t, []string{"pl", "pt-BR"}, missing[0].Languages)
+	require.Equal(t, "Clean grill", missing[1].DutyName)
+	require.Equal(t, []string{"pt-BR"}, missing[1].Languages) // translated without a description
+
+	missing, err = service.GetMissingTranslations(context.Background(), []string{"PL", "en"})
+	require.NoError(t, err)
+	require.Len(t, missing, 1)
+	require.Equal(t, []string{"pl"}, missing[0].Languages)
+}
+
+func TestGetMissingTranslationsHandler(t *testing.T) {
+	mockService := new(mocks.MockDutyTranslationService)
+	handler := handlers.NewDutyTranslationHandler(mockService)
+
+	missing := []models.MissingDutyTranslation{{DutyPartitionKey: "Duty", DutyRowKey: uuid.New(), DutyName: "Count cash", Languages: []string{"pl"}}}
+	mockService.On("GetMissingTranslations", mock.Anything, []string{"pl", "ro"}).Return(missing, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/duties/translations/missing?languages=pl,%20ro", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetMissingTranslations(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var response []models.MissingDutyTranslation
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.Equal(t, missing, response)
+}
+
+// FAILURE CASES:
+func TestSetDutyTranslation_InvalidLanguage(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyTranslationService(mockRepo, nil, "en", nil)
+
+	for _, language := range []string{"not a language", "en", "EN"} {
+		_, err := service.SetDutyTranslation(context.Background(), "Duty", uuid.NewString(), language, models.DutyTranslation{DutyName: "Name"})
+		require.ErrorIs(t, err, models.ErrInvalidDutyTranslation, language)
+	}
+
+	_, err := service.SetDutyTranslation(context.Background(), "Duty", uuid.NewString(), "pl", models.DutyTranslation{DutyName: " "})
+	require.ErrorIs(t, err, models.ErrInvalidDutyTranslation)
+	mockRepo.AssertNotCalled(t, "GetDutyById", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSetDutyTranslation_DeletedDuty(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	service := services.NewDutyTranslationService(mockRepo, nil, "en", nil)
+
+	deletedAt := time.Now()
+	duty := translatedDuty()
+	duty.DeletedAt = &deletedAt
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil)
+
+	_, err := service.SetDutyTranslation(context.Background(), "Duty", duty.RowKey.String(), "ro", models.DutyTranslation{DutyName: "Curăță grătarul"})
+
+	require.ErrorIs(t, err, services.ErrDutyDeleted)
+	mockRepo.AssertNotCalled(t, "SetDutyTranslations", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSetDutyTranslation_ChangedConcurrently(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	handler := handlers.NewDutyTranslationHandler(services.NewDutyTranslationService(mockRepo, mockVersionRepo, "en", nil))
+
+	duty := translatedDuty()
+	duty.Version, duty.ETag = 3, "etag-1"
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil)
+	mockRepo.On("SetDutyTranslations", mock.Anything, "Duty", duty.RowKey.String(), "etag-1", mock.Anything).Return(repositories.ErrDutyChanged)
+
+	req := httptest.NewRequest(http.MethodPut, "/duties/Duty/"+duty.RowKey.String()+"/translations/ro", strings.NewReader(`{"DutyName":"Curăță grătarul"}`))
+	req = mux.SetURLVars(req, map[string]string{"PartitionKey": "Duty", "RowKey": duty.RowKey.String(), "Language": "ro"})
+	rec := httptest.NewRecorder()
+
+	handler.SetDutyTranslation(rec, req)
+
+	require.Equal(t, http.StatusConflict, rec.Code)
+	mockVersionRepo.AssertNotCalled(t, "SetVersionTranslations", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDeleteDutyTranslationHandler_NotFound(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	handler := handlers.NewDutyTranslationHandler(services.NewDutyTranslationService(mockRepo, nil, "en", nil))
+
+	duty := translatedDuty()
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", duty.RowKey.String()).Return(&duty, nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/duties/Duty/"+duty.RowKey.String()+"/translations/ro", nil)
+	req = mux.SetURLVars(req, map[string]string{"PartitionKey": "Duty", "RowKey": duty.RowKey.String(), "Language": "ro"})
+	rec := httptest.NewRecorder()
+
+	handler.DeleteDutyTranslation(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.True(t, strings.Contains(rec.Body.String(), "no translation"))
+}
@@ -0,0 +1,300 @@
+package unit_tests
+
+import (
+	"context"
+	"duty-service/auth"
+	"duty-service/handlers"
+	"duty-service/models"
+	"duty-service/repositories"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// SUCCESS CASES:
+func TestUpdateDuty_AddsVersion(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyService(mockRepo, mockVersionRepo)
+
+	existing := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), RoleId: 2, DutyName: "Clean grill", DutyKey: "chef-clean-grill", Version: 3, ETag: "etag-1"}
+
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", existing.RowKey.String()).Return(&existing, nil)
+	mockRepo.On("ClaimDutyVersion", mock.Anything, "Duty", existing.RowKey.String(), 4, "etag-1").Return("etag-2", nil)
+	mockVersionRepo.On("AddVersion", mock.Anything, mock.MatchedBy(func(version models.DutyVersion) bool {
+		return version.DutyId == existing.RowKey && version.Version == 4 && version.DutyName == "Clean the grill" && version.CreatedBy == "admin-1"
+	})).Return(nil)
+	mockRepo.On("UpdateDuty", mock.Anything, "Duty", existing.RowKey.String(), mock.MatchedBy(func(duty models.Duty) bool {
+		return duty.Version == 4 && duty.DutyKey == "chef-clean-grill" && duty.ETag == "etag-2" // the key is kept when the body has none
+	})).Return(nil)
+
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Subject: "admin-1", Roles: []string{auth.RoleAdmin}})
+	err := service.UpdateDuty(ctx, "Duty", existing.RowKey.String(), models.Duty{RoleId: 2, DutyName: "Clean the grill"})
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockVersionRepo.AssertExpectations(t)
+}
+
+func TestUpdateDuty_UnversionedDutyKeepsOldContent(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyService(mockRepo, mockVersionRepo)
+
+	existing := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), RoleId: 2, DutyName: "Clean grill"}
+
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", existing.RowKey.String()).Return(&existing, nil)
+	mockRepo.On("ClaimDutyVersion", mock.Anything, "Duty", existing.RowKey.String(), 1, "").Return("etag-1", nil)
+	mockRepo.On("ClaimDutyVersion", mock.Anything, "Duty", existing.RowKey.String(), 2, "etag-1").Return("etag-2", nil)
+	mockVersionRepo.On("AddVersion", mock.Anything, mock.MatchedBy(func(version models.DutyVersion) bool {
+		return version.Version == 1 && version.DutyName == "Clean grill"
+	})).Return(nil).Once()
+	mockVersionRepo.On("AddVersion", mock.Anything, mock.MatchedBy(func(version models.DutyVersion) bool {
+		return version.Version == 2 && version.DutyName == "Clean the grill"
+	})).Return(nil).Once()
+	mockRepo.On("UpdateDuty", mock.Anything, "Duty", existing.RowKey.String(), mock.Anything).Return(nil)
+
+	err := service.UpdateDuty(context.Background(), "Duty", existing.RowKey.String(), models.Duty{RoleId: 2, DutyName: "Clean the grill"})
+
+	require.NoError(t, err)
+	mockVersionRepo.AssertExpectations(t)
+}
+
+func TestUpdateDuty_UnchangedAddsNoVersion(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyService(mockRepo, mockVersionRepo)
+
+	existing := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), RoleId: 2, DutyName: "Clean grill", DutyKey: "chef-clean-grill", Version: 2}
+
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", existing.RowKey.String()).Return(&existing, nil)
+	mockRepo.On("UpdateDuty", mock.Anything, "Duty", existing.RowKey.String(), mock.MatchedBy(func(duty models.Duty) bool {
+		return duty.Version == 2
+	})).Return(nil)
+
+	err := service.UpdateDuty(context.Background(), "Duty", existing.RowKey.String(), models.Duty{RoleId: 2, DutyName: "Clean grill"})
+
+	require.NoError(t, err)
+	mockVersionRepo.AssertNotCalled(t, "AddVersion", mock.Anything, mock.Anything)
+}
+
+func TestRollbackDuty_RestoresOldVersionAsNewVersion(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyService(mockRepo, mockVersionRepo)
+
+	existing := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), RoleId: 2, DutyName: "Clean the grill", DutyKey: "chef-clean-grill", Version: 4}
+	old := models.DutyVersion{DutyId: existing.RowKey, Version: 2, RoleId: 2, DutyName: "Clean grill", DutyKey: "chef-clean-grill", DueMinutes: 30}
+
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", existing.RowKey.String()).Return(&existing, nil)
+	mockVersionRepo.On("GetVersion", mock.Anything, existing.RowKey, 2).Return(&old, nil)
+	mockRepo.On("ClaimDutyVersion", mock.Anything, "Duty", existing.RowKey.String(), 5, "").Return("etag-2", nil)
+	mockVersionRepo.On("AddVersion", mock.Anything, mock.MatchedBy(func(version models.DutyVersion) bool {
+		return version.Version == 5 && version.DutyName == "Clean grill" && version.RolledBackFrom != nil && *version.RolledBackFrom == 2
+	})).Return(nil)
+	mockRepo.On("UpdateDuty", mock.Anything, "Duty", existing.RowKey.String(), mock.Anything).Return(nil)
+
+	duty, err := service.RollbackDuty(context.Background(), "Duty", existing.RowKey.String(), 2)
+
+	require.NoError(t, err)
+	require.Equal(t, 5, duty.Version)
+	require.Equal(t, 30, duty.DueMinutes)
+	mockVersionRepo.AssertExpectations(t)
+}
+
+func TestDiffDutyVersions_ComparesFormFieldsByKey(t *testing.T) {
+	max := 7.0
+	from := models.DutyVersion{Version: 1, DutyName: "Check fridge", FormSchema: []models.FormField{
+		{Key: "temp", Label: "Temperature", Type: models.FieldNumber},
+		{Key: "clean", Label: "Clean?", Type: models.FieldCheckbox},
+	}}
+	to := models.DutyVersion{Version: 2, DutyName: "Check fridge", DueMinutes: 60, FormSchema: []models.FormField{
+		{Key: "temp", Label: "Temperature", Type: models.FieldNumber, Max: &max},
+		{Key: "photo", Label: "Photo", Type: models.FieldPhoto},
+	}, CreatedAt: time.Now()}
+
+	diff := models.DiffDutyVersions(from, to)
+
+	var fields []string
+	for _, change := range diff.Changes {
+		fields = append(fields, change.Field)
+	}
+	require.Equal(t, []string{"DueMinutes", "FormSchema.temp", "FormSchema.clean", "FormSchema.photo"}, fields)
+	require.Nil(t, diff.Changes[2].To)   // removed
+	require.Nil(t, diff.Changes[3].From) // added
+}
+
+func TestUpdateDutyAssignment_ValidatesAgainstVersionAtClockIn(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockDutyRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyAssignmentService(mockRepo, mockDutyRepo, mockVersionRepo, nil, nil, nil, nil, nil, nil)
+
+	// the shift clocked in with version 1; the template was changed to version 2 (without the temperature) since
+	shiftId, dutyId, templateId := uuid.New(), uuid.New(), uuid.New()
+	existing := models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyPartitionKey: "Duty", DutyRowKey: templateId, DutyVersion: 1,
+		DutyAssignmentStatus: models.StatusIncomplete}
+	atClockIn := models.DutyVersion{DutyId: templateId, Version: 1, DutyName: "Check fridge",
+		FormSchema: []models.FormField{{Key: "temperature", Label: "Temperature", Type: models.FieldNumber, Required: true}}}
+
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&existing, nil)
+	mockVersionRepo.On("GetVersion", mock.Anything, templateId, 1).Return(&atClockIn, nil)
+	mockRepo.On("UpdateDutyAssignment", mock.Anything, mock.Anything).Return(nil)
+
+	err := service.UpdateDutyAssignment(context.Background(), models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId,
+		DutyAssignmentStatus: models.StatusCompleted, FormValues: map[string]interface{}{"temperature": 4.0}}, nil)
+
+	require.NoError(t, err)
+	mockDutyRepo.AssertNotCalled(t, "GetDutyById", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateDuty_RewritesContentAfterTranslationWrite(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyService(mockRepo, mockVersionRepo)
+
+	existing := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), RoleId: 2, DutyName: "Clean grill", Version: 3, ETag: "etag-1"}
+	translated := existing
+	translated.Version, translated.ETag = 4, "etag-3"
+
+	// a translation was saved between claiming version 4 and writing its content
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", existing.RowKey.String()).Return(&existing, nil).Once()
+	mockRepo.On("ClaimDutyVersion", mock.Anything, "Duty", existing.RowKey.String(), 4, "etag-1").Return("etag-2", nil)
+	mockVersionRepo.On("AddVersion", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("UpdateDuty", mock.Anything, "Duty", existing.RowKey.String(), mock.MatchedBy(func(duty models.Duty) bool {
+		return duty.ETag == "etag-2"
+	})).Return(repositories.ErrDutyChanged).Once()
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", existing.RowKey.String()).Return(&translated, nil).Once()
+	mockRepo.On("UpdateDuty", mock.Anything, "Duty", existing.RowKey.String(), mock.MatchedBy(func(duty models.Duty) bool {
+		return duty.ETag == "etag-3" && duty.Version == 4 && duty.DutyName == "Clean the grill"
+	})).Return(nil).Once()
+
+	err := service.UpdateDuty(context.Background(), "Duty", existing.RowKey.String(), models.Duty{RoleId: 2, DutyName: "Clean the grill"})
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+// FAILURE CASES:
+func TestUpdateDuty_ConcurrentUpdatesClaimTheVersionOnce(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyService(mockRepo, mockVersionRepo)
+
+	existing := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), RoleId: 2, DutyName: "Clean grill", Version: 3, ETag: "etag-1"}
+
+	// both updates read the duty at etag-1; the first claims version 4, which changes the ETag, so the claim of the
+	// second fails before it writes anything
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", existing.RowKey.String()).Return(&existing, nil).Twice()
+	mockRepo.On("ClaimDutyVersion", mock.Anything, "Duty", existing.RowKey.String(), 4, "etag-1").Return("etag-2", nil).Once()
+	mockRepo.On("ClaimDutyVersion", mock.Anything, "Duty", existing.RowKey.String(), 4, "etag-1").Return("", repositories.ErrDutyChanged).Once()
+	mockVersionRepo.On("AddVersion", mock.Anything, mock.MatchedBy(func(version models.DutyVersion) bool {
+		return version.Version == 4 && version.DutyName == "Clean the grill"
+	})).Return(nil).Once()
+	mockRepo.On("UpdateDuty", mock.Anything, "Duty", existing.RowKey.String(), mock.MatchedBy(func(duty models.Duty) bool {
+		return duty.ETag == "etag-2" && duty.DutyName == "Clean the grill"
+	})).Return(nil).Once()
+
+	first := service.UpdateDuty(context.Background(), "Duty", existing.RowKey.String(), models.Duty{RoleId: 2, DutyName: "Clean the grill"})
+	second := service.UpdateDuty(context.Background(), "Duty", existing.RowKey.String(), models.Duty{RoleId: 2, DutyName: "Scrub grill"})
+
+	require.NoError(t, first)
+	require.ErrorIs(t, second, repositories.ErrDutyChanged)
+	mockRepo.AssertExpectations(t)
+	mockVersionRepo.AssertExpectations(t) // version 4 is the first update's
+}
+
+func TestUpdateDuty_VersionLeftByEarlierUpdate(t *testing.T) {
+	mockRepo := new(mocks.MockDutyRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyService(mockRepo, mockVersionRepo)
+
+	existing := models.Duty{PartitionKey: "Duty", RowKey: uuid.New(), RoleId: 2, DutyName: "Clean grill", Version: 3, ETag: "etag-1"}
+
+	mockRepo.On("GetDutyById", mock.Anything, "Duty", existing.RowKey.String()).Return(&existing, nil)
+	mockRepo.On("ClaimDutyVersion", mock.Anything, "Duty", existing.RowKey.String(), 4, "etag-1").Return("etag-2", nil)
+	mockVersionRepo.On("AddVersion", mock.Anything, mock.Anything).Return(repositories.ErrDutyVersionExists)
+
+	err := service.UpdateDuty(context.Background(), "Duty", existing.RowKey.String(), models.Duty{RoleId: 2, DutyName: "Clean the grill"})
+
+	require.ErrorIs(t, err, repositories.ErrDutyChanged) // sent again, it claims version 5
+	mockRepo.AssertNotCalled(t, "UpdateDuty", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateDutyHandler_ChangedConcurrently(t *testing.T) {
+	mockService := new(mocks.MockDutyService)
+	handler := handlers.NewDutyHandler(mockService)
+
+	mockService.On("UpdateDuty", mock.Anything, "Duty", "abc", mock.Anything).Return(repositories.ErrDutyChanged)
+
+	req := httptest.NewRequest(http.MethodPut, "/duties/Duty/abc", strings.NewReader(`{"RoleId": 2, "DutyName": "Clean the grill"}`))
+	req = mux.SetURLVars(req, map[string]string{"PartitionKey": "Duty", "RowKey": "abc"})
+	rec := httptest.NewRecorder()
+
+	handler.UpdateDuty(rec, req)
+
+	require.Equal(t, http.StatusConflict, rec.Result().StatusCode)
+}
+
+func TestUpdateDutyAssignment_RequiredFieldOfVersionAtClockIn(t *testing.T) {
+	mockRepo := new(mocks.MockDutyAssignmentRepository)
+	mockVersionRepo := new(mocks.MockDutyVersionRepository)
+	service := services.NewDutyAssignmentService(mockRepo, nil, mockVersionRepo, nil, nil, nil, nil, nil, nil)
+
+	shiftId, dutyId, templateId := uuid.New(), uuid.New(), uuid.New()
+	existing := models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId, DutyPartitionKey: "Duty", DutyRowKey: templateId, DutyVersion: 1,
+		DutyAssignmentStatus: models.StatusIncomplete}
+	atClockIn := models.DutyVersion{DutyId: templateId, Version: 1,
+		FormSchema: []models.FormField{{Key: "temperature", Label: "Temperature", Type: models.FieldNumber, Required: true}}}
+
+	mockRepo.On("GetDutyAssignment", mock.Anything, shiftId, dutyId).Return(&existing, nil)
+	mockVersionRepo.On("GetVersion", mock.Anything, templateId, 1).Return(&atClockIn, nil)
+
+	err := service.UpdateDutyAssignment(context.Background(), models.DutyAssignment{PartitionKey: shiftId, RowKey: dutyId,
+		DutyAssignmentStatus: models.StatusCompleted}, nil)
+
+	var formErr *models.FormValidationError
+	require.ErrorAs(t, err, &formErr)
+	require.Equal(t, "temperature", formErr.Field)
+	mockRepo.AssertNotCalled(t, "UpdateDutyAssignment", mock.Anything, mock.Anything)
+}
+
+func TestRollbackDutyHandler_VersionNotFound(t *testing.T) {
+	mockService := new(mocks.MockDutyService)
+	handler := handlers.NewDutyHandler(mockService)
+
+	mockService.On("RollbackDuty", mock.Anything, "Duty", "abc", 9).Return(nil, repositories.ErrDutyVersionNotFound)
+
+	req := httptest.NewRequest(http.MethodPost, "/duties/Duty/abc/versions/9/rollback", nil)
+	req = mux.SetURLVars(req, map[string]string{"PartitionKey": "Duty", "RowKey": "abc", "Version": "9"})
+	rec := httptest.NewRecorder()
+
+	handler.RollbackDuty(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Result().StatusCode)
+}
+
+func TestDiffDutyVersionsHandler_InvalidVersion(t *testing.T) {
+	mockService := new(mocks.MockDutyService)
+	handler := handlers.NewDutyHandler(mockService)
+
+	dutyId := uuid.New()
+	req := httptest.NewRequest(http.MethodGet, "/duties/Duty/"+dutyId.String()+"/versions/diff?from=0&to=2", nil)
+	req = mux.SetURLVars(req, map[string]string{"PartitionKey": "Duty", "RowKey": dutyId.String()})
+	rec := httptest.NewRecorder()
+
+	handler.DiffDutyVersions(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Result().StatusCode)
+	mockService.AssertNotCalled(t, "DiffDutyVersions", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
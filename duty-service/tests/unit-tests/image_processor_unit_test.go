@@ -0,0 +1,78 @@
+package unit_tests
+
+import (
+	"bytes"
+	"duty-service/images"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// creates a dummy image of the given size
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	return img
+}
+
+// SUCCESS CASES:
+func TestProcessImage_PNG(t *testing.T) {
+	var upload bytes.Buffer
+	require.NoError(t, png.Encode(&upload, testImage(800, 400)))
+
+	processed, err := images.Process(&upload)
+	require.NoError(t, err)
+
+	require.Equal(t, images.ContentTypePNG, processed.Original.ContentType)
+	require.Equal(t, ".png", processed.Original.Extension)
+
+	// the thumbnail is a JPEG scaled down to the maximum size, keeping the aspect ratio
+	require.Equal(t, images.ContentTypeJPEG, processed.Thumbnail.ContentType)
+	thumbnail, err := jpeg.Decode(bytes.NewReader(processed.Thumbnail.Data))
+	require.NoError(t, err)
+	require.Equal(t, images.ThumbnailMaxSize, thumbnail.Bounds().Dx())
+	require.Equal(t, images.ThumbnailMaxSize/2, thumbnail.Bounds().Dy())
+
+	require.Equal(t, "shift_duty_thumb.jpg", images.ThumbnailName("shift_duty", processed.Thumbnail))
+}
+
+func TestProcessImage_JPEG(t *testing.T) {
+	var upload bytes.Buffer
+	require.NoError(t, jpeg.Encode(&upload, testImage(100, 50), nil))
+
+	processed, err := images.Process(&upload)
+	require.NoError(t, err)
+
+	require.Equal(t, images.ContentTypeJPEG, processed.Original.ContentType)
+	require.Equal(t, ".jpg", processed.Original.Extension)
+
+	// small images are not scaled up
+	thumbnail, err := jpeg.Decode(bytes.NewReader(processed.Thumbnail.Data))
+	require.NoError(t, err)
+	require.Equal(t, 100, thumbnail.Bounds().Dx())
+}
+
+// FAILURE CASES:
+func TestProcessImage_NotAnImage(t *testing.T) {
+	_, err := images.Process(bytes.NewReader([]byte("this is definitely not a photo of a clean grill")))
+
+	require.ErrorIs(t, err, images.ErrUnsupportedImage)
+}
+
+func TestProcessImage_Corrupt(t *testing.T) {
+	var upload bytes.Buffer
+	require.NoError(t, png.Encode(&upload, testImage(50, 50)))
+	corrupt := upload.Bytes()[:upload.Len()/2] // valid PNG header, truncated data
+
+	_, err := images.Process(bytes.NewReader(corrupt))
+
+	require.ErrorIs(t, err, images.ErrInvalidImage)
+}
@@ -0,0 +1,111 @@
+package unit_tests
+
+import (
+	"context"
+	"duty-service/handlers"
+	"duty-service/storage"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+// serves a local image URL through the image handler like the /duties/images/{Name} route
+func serveLocalImage(store *storage.LocalImageStore, imageURL string) *httptest.ResponseRecorder {
+	parsed, _ := url.Parse(imageURL)
+
+	req := httptest.NewRequest(http.MethodGet, parsed.RequestURI(), nil)
+	req = mux.SetURLVars(req, map[string]string{"Name": path.Base(parsed.Path)})
+	rec := httptest.NewRecorder()
+
+	handlers.NewImageHandler(store).ServeImage(rec, req)
+	return rec
+}
+
+// SUCCESS CASES:
+func TestLocalImageStore_ServesSignedURL(t *testing.T) {
+	store, err := storage.NewLocalImageStore(t.TempDir(), "http://localhost:3004/duties/images", []byte("secret"))
+	require.NoError(t, err)
+
+	require.NoError(t, store.Upload(context.Background(), "shift_duty_photo.jpg", "image/jpeg", strings.NewReader("jpeg data")))
+
+	imageURL, err := store.URL("shift_duty_photo.jpg", time.Minute)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(imageURL, "http://localhost:3004/duties/images/shift_duty_photo.jpg?"))
+
+	rec := serveLocalImage(store, imageURL)
+
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	require.Equal(t, "image/jpeg", rec.Result().Header.Get("Content-Type"))
+	require.Equal(t, "jpeg data", rec.Body.String())
+}
+
+func TestLocalImageStore_Delete(t *testing.T) {
+	store, err := storage.NewLocalImageStore(t.TempDir(), "http://localhost:3004/duties/images", []byte("secret"))
+	require.NoError(t, err)
+
+	require.NoError(t, store.Upload(context.Background(), "photo.png", "image/png", strings.NewReader("png data")))
+	require.NoError(t, store.Delete(context.Background(), "photo.png"))
+	require.NoError(t, store.Delete(context.Background(), "photo.png")) // deleting twice is not an error
+
+	imageURL, err := store.URL("photo.png", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, serveLocalImage(store, imageURL).Result().StatusCode)
+}
+
+func TestMemoryImageStore_UploadAndDelete(t *testing.T) {
+	store := storage.NewMemoryImageStore()
+
+	require.NoError(t, store.Upload(context.Background(), "photo.png", "image/png", strings.NewReader("png data")))
+
+	data, contentType, ok := store.Get("photo.png")
+	require.True(t, ok)
+	require.Equal(t, "png data", string(data))
+	require.Equal(t, "image/png", contentType)
+
+	require.NoError(t, store.Delete(context.Background(), "photo.png"))
+	_, _, ok = store.Get("photo.png")
+	require.False(t, ok)
+}
+
+// FAILURE CASES:
+func TestLocalImageStore_RejectsTamperedURL(t *testing.T) {
+	store, err := storage.NewLocalImageStore(t.TempDir(), "http://localhost:3004/duties/images", []byte("secret"))
+	require.NoError(t, err)
+	require.NoError(t, store.Upload(context.Background(), "a.jpg", "image/jpeg", strings.NewReader("a")))
+	require.NoError(t, store.Upload(context.Background(), "b.jpg", "image/jpeg", strings.NewReader("b")))
+
+	imageURL, err := store.URL("a.jpg", time.Minute)
+	require.NoError(t, err)
+
+	// the signature of a.jpg doesn't open b.jpg
+	rec := serveLocalImage(store, strings.Replace(imageURL, "/a.jpg?", "/b.jpg?", 1))
+	require.Equal(t, http.StatusForbidden, rec.Result().StatusCode)
+}
+
+func TestLocalImageStore_RejectsExpiredURL(t *testing.T) {
+	store, err := storage.NewLocalImageStore(t.TempDir(), "http://localhost:3004/duties/images", []byte("secret"))
+	require.NoError(t, err)
+	require.NoError(t, store.Upload(context.Background(), "a.jpg", "image/jpeg", strings.NewReader("a")))
+
+	imageURL, err := store.URL("a.jpg", -time.Minute)
+	require.NoError(t, err)
+
+	rec := serveLocalImage(store, imageURL)
+	require.Equal(t, http.StatusForbidden, rec.Result().StatusCode)
+	require.Contains(t, rec.Body.String(), "expired")
+}
+
+func TestLocalImageStore_RejectsPathTraversal(t *testing.T) {
+	store, err := storage.NewLocalImageStore(t.TempDir(), "http://localhost:3004/duties/images", []byte("secret"))
+	require.NoError(t, err)
+
+	err = store.Upload(context.Background(), "../escape.jpg", "image/jpeg", strings.NewReader("x"))
+	require.ErrorIs(t, err, storage.ErrInvalidImageURL)
+}
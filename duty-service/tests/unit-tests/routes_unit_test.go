@@ -0,0 +1,52 @@
+package unit_tests
+
+import (
+	"duty-service/config"
+	"duty-service/routes"
+	"duty-service/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+// testRouter builds the service's router on a table service that can't be reached, for requests that are answered
+// before the storage is used
+func testRouter(t *testing.T) *mux.Router {
+	serviceClient, err := aztables.NewServiceClientWithNoCredential("http://127.0.0.1:1/devstoreaccount1", nil)
+	require.NoError(t, err)
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	return routes.RegisterRoutes(serviceClient, storage.NewMemoryImageStore(), nil, cfg, "")
+}
+
+// serveRoute sends a request through the router as the gateway does
+func serveRoute(router *mux.Router, method string, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-From-Gateway", "true")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// SUCCESS CASES:
+func TestRoutes_PhotoReviewQueueIsNotADutyId(t *testing.T) {
+	rec := serveRoute(testRouter(t), http.MethodGet, "/duties/duty-assignments/photo-reviews")
+
+	// reaches RequireIdentity of the review queue instead of GetDutyById
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Contains(t, rec.Body.String(), "No token provided")
+}
+
+func TestRoutes_PrintIsNotADutyId(t *testing.T) {
+	rec := serveRoute(testRouter(t), http.MethodGet, "/duties/duty-assignments/print")
+
+	// reaches PrintDutyChecklist instead of GetDutyById
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "Missing 'shiftId' query parameter")
+}
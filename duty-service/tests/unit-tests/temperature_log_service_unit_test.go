@@ -0,0 +1,131 @@
+package unit_tests
+
+import (
+	"context"
+	"duty-service/handlers"
+	"duty-service/models"
+	"duty-service/services"
+	"duty-service/tests/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fridge at most 7°C
+func testTemperatureRanges() map[models.TemperatureUnitType]models.TemperatureRange {
+	return map[models.TemperatureUnitType]models.TemperatureRange{
+		models.UnitFridge: {Max: floatPtr(7)},
+	}
+}
+
+// SUCCESS CASES:
+func TestRecordReading_WithinRange(t *testing.T) {
+	mockRepo := new(mocks.MockTemperatureLogRepository)
+	mockPublisher := new(mocks.MockMessagePublisher)
+	service := services.NewTemperatureLogService(mockRepo, mockPublisher, testTemperatureRanges())
+
+	reading := models.TemperatureReading{
+		PartitionKey: uuid.New(),
+		EventId:      uuid.New(),
+		UnitType:     models.UnitFridge,
+		UnitName:     "Fridge 1",
+		Temperature:  4.5,
+		EmployeeId:   "employee-1",
+	}
+
+	// the range the reading is checked against is stored with it
+	mockRepo.On("CreateReading", mock.Anything, mock.MatchedBy(func(reading models.TemperatureReading) bool {
+		return reading.SafeRange != nil && reading.SafeRange.Max != nil && *reading.SafeRange.Max == 7
+	})).Return(nil)
+
+	recorded, err := service.RecordReading(context.Background(), reading)
+
+	require.NoError(t, err)
+	require.True(t, recorded.WithinRange)
+	require.NotEqual(t, uuid.Nil, recorded.RowKey)
+	require.False(t, recorded.RecordedAt.IsZero())
+
+	mockRepo.AssertExpectations(t)
+	mockPublisher.AssertNotCalled(t, "PublishMessage", mock.Anything, mock.Anything)
+}
+
+func TestRecordReading_OutOfRangePublishesAlert(t *testing.T) {
+	mockRepo := new(mocks.MockTemperatureLogRepository)
+	mockPublisher := new(mocks.MockMessagePublisher)
+	service := services.NewTemperatureLogService(mockRepo, mockPublisher, testTemperatureRanges())
+
+	correctiveAction := "Moved stock to the backup fridge"
+	reading := models.TemperatureReading{
+		PartitionKey:     uuid.New(),
+		EventId:          uuid.New(),
+		UnitType:         models.UnitFridge,
+		UnitName:         "Fridge 1",
+		Temperature:      11,
+		EmployeeId:       "employee-1",
+		CorrectiveAction: &correctiveAction,
+	}
+
+	mockRepo.On("CreateReading", mock.Anything, mock.AnythingOfType("models.TemperatureReading")).Return(nil)
+	mockPublisher.On("PublishMessage", services.TemperatureAlertQueue, mock.MatchedBy(func(alert models.TemperatureAlertMessage) bool {
+		return alert.Temperature == 11 && alert.CorrectiveAction == correctiveAction
+	})).Return(nil)
+
+	recorded, err := service.RecordReading(context.Background(), reading)
+
+	require.NoError(t, err)
+	require.False(t, recorded.WithinRange)
+
+	mockRepo.AssertExpectations(t)
+	mockPublisher.AssertExpectations(t)
+}
+
+func TestExportReportHandler_PrintsStoredSafeRange(t *testing.T) {
+	mockService := new(mocks.MockTemperatureLogService)
+	handler := handlers.NewTemperatureLogHandler(mockService)
+
+	// checked against ≤ 7 °C, whatever the range is configured as now
+	eventId := uuid.New()
+	readings := []models.TemperatureReading{
+		{PartitionKey: uuid.New(), EventId: eventId, UnitType: models.UnitFridge, UnitName: "Fridge 1", Temperature: 6,
+			RecordedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), WithinRange: true, SafeRange: &models.TemperatureRange{Max: floatPtr(7)}},
+		{PartitionKey: uuid.New(), EventId: eventId, UnitType: models.UnitFridge, UnitName: "Fridge 2", Temperature: 4,
+			RecordedAt: time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC), WithinRange: true},
+	}
+	mockService.On("GetReport", mock.Anything, eventId, mock.Anything, mock.Anything).Return(readings, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/duties/temperature-logs/report?eventId="+eventId.String()+"&from=2024-05-01&to=2024-05-01", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ExportReport(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "Fridge 1,6.0,≤ 7.0,OK")
+	require.Contains(t, rec.Body.String(), "Fridge 2,4.0,unknown,OK")
+}
+
+// FAILURE CASES:
+func TestRecordReading_OutOfRangeWithoutCorrectiveAction(t *testing.T) {
+	mockRepo := new(mocks.MockTemperatureLogRepository)
+	mockPublisher := new(mocks.MockMessagePublisher)
+	service := services.NewTemperatureLogService(mockRepo, mockPublisher, testTemperatureRanges())
+
+	reading := models.TemperatureReading{
+		PartitionKey: uuid.New(),
+		EventId:      uuid.New(),
+		UnitType:     models.UnitFridge,
+		UnitName:     "Fridge 1",
+		Temperature:  9,
+		EmployeeId:   "employee-1",
+	}
+
+	_, err := service.RecordReading(context.Background(), reading)
+
+	require.ErrorIs(t, err, services.ErrCorrectiveActionRequired)
+	mockRepo.AssertNotCalled(t, "CreateReading", mock.Anything, mock.Anything)
+	mockPublisher.AssertNotCalled(t, "PublishMessage", mock.Anything, mock.Anything)
+}
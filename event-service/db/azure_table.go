@@ -20,6 +20,7 @@ var Models = []string{
 	"events",
 	"persons",
 	"eventshifts",
+	"eventstaffing",
 }
 
 // InitAzureTables initializes Azure Table Storage connections for all models
@@ -3,11 +3,13 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/Catalin246/karma-kebab/models"
+	"github.com/Catalin246/karma-kebab/repositories"
 	"github.com/Catalin246/karma-kebab/services"
 
 	"github.com/google/uuid"
@@ -91,6 +93,12 @@ func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Validate the staffing plan (or build it from roleIDs)
+	if err := event.NormalizeStaffing(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	event.RowKey = uuid.New()
 
 	// Create event in the service
@@ -119,6 +127,12 @@ func (h *EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Validate the staffing plan (or build it from roleIDs)
+	if err := event.NormalizeStaffing(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	if err := h.service.Update(context.Background(), partitionKey, rowKey, event); err != nil {
 		http.Error(w, "Failed to update event: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -157,6 +171,10 @@ func (h *EventHandler) GetEventByShiftID(w http.ResponseWriter, r *http.Request)
 	shiftID := vars["shiftID"]
 
 	event, err := h.service.GetEventByShiftID(r.Context(), shiftID)
+	if errors.Is(err, repositories.ErrEventNotFound) {
+		http.Error(w, "No event found for given shiftId", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to retrieve events for given shiftId: "+err.Error(), http.StatusInternalServerError)
 		return
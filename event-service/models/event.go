@@ -18,17 +18,20 @@ const (
 
 // Event class
 type Event struct {
-	PartitionKey string      `json:"partitionKey"` // Azure Table Storage PartitionKey
-	RowKey       uuid.UUID   `json:"rowKey"`       // Azure Table Storage RowKey
-	StartTime    time.Time   `json:"startTime"`    // Event date and time
-	EndTime      time.Time   `json:"endTime"`      // Event date and time
-	Address      string      `json:"address"`      // Event address
-	Venue        string      `json:"venue"`        // Venue name
-	Description  string      `json:"description"`  // Description of the event
-	Money        float64     `json:"money"`        // Associated cost/money
-	Status       Status      `json:"status"`       // Event status
-	Person       Person      `json:"person"`       // Associated person
-	Note         string      `json:"note"`         // Additional notes
-	ShiftIDs     []uuid.UUID `json:"shiftIDs"`     // List of shift IDs (UUIDs)
-	RoleIDs      []int       `json:"roleIDs"`      // List of role IDs (ints)
+	PartitionKey string                `json:"partitionKey"` // Azure Table Storage PartitionKey
+	RowKey       uuid.UUID             `json:"rowKey"`       // Azure Table Storage RowKey
+	StartTime    time.Time             `json:"startTime"`    // Event date and time
+	EndTime      time.Time             `json:"endTime"`      // Event date and time
+	Address      string                `json:"address"`      // Event address
+	Venue        string                `json:"venue"`        // Venue name
+	Description  string                `json:"description"`  // Description of the event
+	Money        float64               `json:"money"`        // Associated cost/money
+	Status       Status                `json:"status"`       // Event status
+	Person       Person                `json:"person"`       // Associated person
+	Note         string                `json:"note"`         // Additional notes
+	ShiftIDs     []uuid.UUID           `json:"shiftIDs"`     // List of shift IDs (UUIDs)
+	RoleIDs      []int                 `json:"roleIDs"`      // Roles of the staffing plan (a plan of one person per role when only these are sent)
+	Staffing     []StaffingRequirement `json:"staffing"`     // Staffing plan: how many people of each role are needed, and when
+	Tags         []string              `json:"tags"`         // Event type tags, e.g. "festival", "outdoor", "corporate"
+	TruckID      string                `json:"truckID"`      // ID of the truck used at the event (truck-service)
 }
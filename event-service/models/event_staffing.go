@@ -0,0 +1,137 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Limits of a staffing plan
+const (
+	MaxStaffingCount        = 100 // the most people one requirement can ask for
+	MaxStaffingRequirements = 100 // the most requirements of a plan, so it is saved in one transaction
+)
+
+// ErrInvalidStaffing is returned when the staffing plan of an event is invalid
+var ErrInvalidStaffing = errors.New("invalid staffing plan")
+
+// StaffingRequirement is the number of people of one role an event needs, e.g. 3 cooks from 10:00 to 14:00
+type StaffingRequirement struct {
+	RoleID    int        `json:"roleID"`    // Role ID (ints, as in roleIDs)
+	Count     int        `json:"count"`     // Number of people needed
+	StartTime *time.Time `json:"startTime"` // Start of the role's time window (optional, the event's start by default)
+	EndTime   *time.Time `json:"endTime"`   // End of the role's time window (optional, the event's end by default)
+}
+
+// EventCreatedMessage is the body of the event.created message
+type EventCreatedMessage struct {
+	EventID   uuid.UUID             `json:"eventID"`
+	StartTime time.Time             `json:"startTime"`
+	EndTime   time.Time             `json:"endTime"`
+	RoleIDs   []int                 `json:"roleIDs"`  // roles of the staffing plan, for consumers that don't read it yet
+	Staffing  []StaffingRequirement `json:"staffing"` // one shift per person: Count shifts of each role
+}
+
+// NewEventCreatedMessage creates the event.created message of an event
+func NewEventCreatedMessage(event Event) EventCreatedMessage {
+	return EventCreatedMessage{
+		EventID:   event.RowKey,
+		StartTime: event.StartTime,
+		EndTime:   event.EndTime,
+		RoleIDs:   event.RoleIDs,
+		Staffing:  event.Staffing,
+	}
+}
+
+// window returns the time window of a requirement within the event. A side the requirement and the event leave open
+// is unbounded.
+func (s StaffingRequirement) window(event Event) (time.Time, time.Time) {
+	start, end := event.StartTime, event.EndTime
+	if s.StartTime != nil {
+		start = *s.StartTime
+	}
+	if s.EndTime != nil {
+		end = *s.EndTime
+	}
+	if end.IsZero() {
+		end = time.Unix(1<<62, 0)
+	}
+	return start, end
+}
+
+// NormalizeStaffing validates the staffing plan of an event and fills in RoleIDs with its roles. Events sent with only
+// roleIDs (the format before staffing plans) get one person per listed role. When neither is sent, Staffing stays nil
+// so an update keeps the stored plan.
+func (e *Event) NormalizeStaffing() error {
+	if e.Staffing == nil && len(e.RoleIDs) > 0 {
+		e.Staffing = staffingFromRoleIDs(e.RoleIDs)
+	}
+	if len(e.Staffing) > MaxStaffingRequirements {
+		return fmt.Errorf("%w: at most %d requirements are allowed", ErrInvalidStaffing, MaxStaffingRequirements)
+	}
+
+	for i, requirement := range e.Staffing {
+		if requirement.RoleID <= 0 {
+			return fmt.Errorf("%w: requirement %d has no valid roleID", ErrInvalidStaffing, i+1)
+		}
+		if requirement.Count < 1 || requirement.Count > MaxStaffingCount {
+			return fmt.Errorf("%w: the count of role %d must be between 1 and %d", ErrInvalidStaffing, requirement.RoleID, MaxStaffingCount)
+		}
+
+		start, end := requirement.window(*e)
+		if requirement.StartTime != nil || requirement.EndTime != nil {
+			if !start.Before(end) {
+				return fmt.Errorf("%w: the time window of role %d must end after it starts", ErrInvalidStaffing, requirement.RoleID)
+			}
+			if (!e.StartTime.IsZero() && start.Before(e.StartTime)) || (!e.EndTime.IsZero() && end.After(e.EndTime)) {
+				return fmt.Errorf("%w: the time window of role %d must be within the event", ErrInvalidStaffing, requirement.RoleID)
+			}
+		}
+
+		// a role can be listed more than once for different parts of the event, e.g. 2 cooks until 14:00 and 3 after
+		for _, other := range e.Staffing[:i] {
+			if other.RoleID != requirement.RoleID {
+				continue
+			}
+			otherStart, otherEnd := other.window(*e)
+			if start.Before(otherEnd) && otherStart.Before(end) {
+				return fmt.Errorf("%w: the time windows of role %d overlap", ErrInvalidStaffing, requirement.RoleID)
+			}
+		}
+	}
+
+	if e.Staffing != nil {
+		e.RoleIDs = StaffingRoleIDs(e.Staffing)
+	}
+	return nil
+}
+
+// staffingFromRoleIDs converts a list of roles to a staffing plan, counting roles that are listed more than once
+func staffingFromRoleIDs(roleIDs []int) []StaffingRequirement {
+	var staffing []StaffingRequirement
+	index := make(map[int]int)
+	for _, roleID := range roleIDs {
+		if i, ok := index[roleID]; ok {
+			staffing[i].Count++
+			continue
+		}
+		index[roleID] = len(staffing)
+		staffing = append(staffing, StaffingRequirement{RoleID: roleID, Count: 1})
+	}
+	return staffing
+}
+
+// StaffingRoleIDs returns the roles of a staffing plan, each once, in the order of the plan
+func StaffingRoleIDs(staffing []StaffingRequirement) []int {
+	var roleIDs []int
+	seen := make(map[int]bool)
+	for _, requirement := range staffing {
+		if !seen[requirement.RoleID] {
+			seen[requirement.RoleID] = true
+			roleIDs = append(roleIDs, requirement.RoleID)
+		}
+	}
+	return roleIDs
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -12,6 +13,12 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrEventNotFound is returned when no event is linked to a shift
+var ErrEventNotFound = errors.New("event not found")
+
+// table of the staffing plans of the events
+const staffingTableName = "eventstaffing"
+
 type EventRepository struct {
 	serviceClient *aztables.ServiceClient
 	tableName     string
@@ -45,6 +52,11 @@ func (r *EventRepository) Create(ctx context.Context, event models.Event) error
 		"LastName":     event.Person.LastName,
 		"Email":        event.Person.Email,
 		"Note":         event.Note,
+		"TruckID":      event.TruckID,
+	}
+
+	if err := addTags(eventEntity, event.Tags); err != nil {
+		return err
 	}
 
 	// Marshal the event entity to JSON for storage
@@ -53,9 +65,15 @@ func (r *EventRepository) Create(ctx context.Context, event models.Event) error
 		return fmt.Errorf("failed to marshal event entity: %v", err)
 	}
 
+	// Insert the staffing plan into its own table first, so an event is never stored without its plan
+	if err := r.saveStaffing(ctx, event.RowKey.String(), event.Staffing); err != nil {
+		return err
+	}
+
 	// Insert the event into the main Event table
 	_, err = tableClient.AddEntity(ctx, eventEntityBytes, nil)
 	if err != nil {
+		r.deleteStaffing(ctx, event.RowKey.String()) // best effort, the event doesn't exist
 		return fmt.Errorf("failed to insert event into Event table: %v", err)
 	}
 
@@ -115,6 +133,15 @@ func (r *EventRepository) GetByID(ctx context.Context, partitionKey, rowKey stri
 		Note: eventData["Note"].(string),
 	}
 
+	if err := parseTagsAndTruck(eventData, &event); err != nil {
+		return nil, err
+	}
+
+	// Fetch the staffing plan of the event
+	if err := r.attachStaffing(ctx, &event); err != nil {
+		return nil, err
+	}
+
 	// Fetch the shift IDs associated with the event
 	// The PartitionKey of the shift entities is the event's RowKey
 	filter := fmt.Sprintf("EventRowKey eq '%s'", rowKey) // using the event's RowKey as PartitionKey for shifts
@@ -225,6 +252,15 @@ func (r *EventRepository) GetAll(ctx context.Context, filter string) ([]models.E
 				Note: eventData["Note"].(string),
 			}
 
+			if err := parseTagsAndTruck(eventData, &event); err != nil {
+				return nil, err
+			}
+
+			// Fetch the staffing plan of the event
+			if err := r.attachStaffing(ctx, &event); err != nil {
+				return nil, err
+			}
+
 			// Fetch the shift IDs associated with the event (use RowKey of the event for PartitionKey in shifts)
 			filterShifts := fmt.Sprintf("EventRowKey eq '%s'", rowKeyUUID.String()) // use event's RowKey as PartitionKey for shift relationships
 			listOptionsShifts := &aztables.ListEntitiesOptions{
@@ -293,6 +329,11 @@ func (r *EventRepository) Update(ctx context.Context, partitionKey, rowKey strin
 		"LastName":     event.Person.LastName,
 		"Email":        event.Person.Email,
 		"Note":         event.Note,
+		"TruckID":      event.TruckID,
+	}
+
+	if err := addTags(entity, event.Tags); err != nil {
+		return err
 	}
 
 	// Marshal the updated event entity to JSON
@@ -307,6 +348,13 @@ func (r *EventRepository) Update(ctx context.Context, partitionKey, rowKey strin
 		return fmt.Errorf("failed to update event: %v", err)
 	}
 
+	// Replace the staffing plan when one was sent (nil keeps the stored plan)
+	if event.Staffing != nil {
+		if err := r.saveStaffing(ctx, rowKey, event.Staffing); err != nil {
+			return err
+		}
+	}
+
 	// Now insert the new shift relationships for the updated event
 	for _, shiftID := range event.ShiftIDs {
 		shiftEntity := map[string]interface{}{
@@ -341,7 +389,8 @@ func (r *EventRepository) Delete(ctx context.Context, partitionKey, rowKey strin
 		return fmt.Errorf("failed to delete event: %v", err)
 	}
 
-	return nil
+	// Delete the staffing plan of the event
+	return r.deleteStaffing(ctx, rowKey)
 }
 
 // GetEventByShiftID retrieves a single event by ShiftID
@@ -380,6 +429,10 @@ func (r *EventRepository) GetEventByShiftID(ctx context.Context, shiftID string)
 		}
 	}
 
+	if eventRowKey == "" {
+		return nil, ErrEventNotFound
+	}
+
 	// Now retrieve the event from the events table using the eventID
 	event, err := r.GetByID(ctx, eventRowKey, eventPartitionKey)
 	if err != nil {
@@ -392,3 +445,174 @@ func (r *EventRepository) GetEventByShiftID(ctx context.Context, shiftID string)
 	// Return the event
 	return event, err
 }
+
+// addTags stores the tags on the entity as a JSON string (table properties can't hold lists)
+func addTags(entity map[string]interface{}, tags []string) error {
+	if len(tags) == 0 {
+		entity["Tags"] = ""
+		return nil
+	}
+
+	tagBytes, err := json.Marshal(tags)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %v", err)
+	}
+	entity["Tags"] = string(tagBytes)
+
+	return nil
+}
+
+// parseTagsAndTruck reads the tags and truck of an event (events created before they existed have neither)
+func parseTagsAndTruck(eventData map[string]interface{}, event *models.Event) error {
+	if tagsJSON, ok := eventData["Tags"].(string); ok && tagsJSON != "" {
+		if err := json.Unmarshal([]byte(tagsJSON), &event.Tags); err != nil {
+			return fmt.Errorf("failed to parse event tags: %v", err)
+		}
+	}
+	event.TruckID, _ = eventData["TruckID"].(string)
+
+	return nil
+}
+
+// saveStaffing replaces the staffing plan of an event. The plan is stored in the eventstaffing table, partitioned by
+// the event's RowKey, one row per requirement in the order of the plan. The rows all share a partition, so the plan is
+// replaced in one transaction: its rows are overwritten and the rows of a longer old plan deleted.
+func (r *EventRepository) saveStaffing(ctx context.Context, eventRowKey string, staffing []models.StaffingRequirement) error {
+	tableClient := r.serviceClient.NewClient(staffingTableName)
+
+	entities, err := r.listStaffing(ctx, eventRowKey)
+	if err != nil {
+		return err
+	}
+
+	actions := make([]aztables.TransactionAction, 0, len(staffing))
+	for i, requirement := range staffing {
+		entity := map[string]interface{}{
+			"PartitionKey": eventRowKey,
+			"RowKey":       staffingRowKey(i), // keeps the order of the plan
+			"RoleID":       requirement.RoleID,
+			"Count":        requirement.Count,
+			"StartTime":    formatOptionalTime(requirement.StartTime),
+			"EndTime":      formatOptionalTime(requirement.EndTime),
+		}
+
+		entityBytes, err := json.Marshal(entity)
+		if err != nil {
+			return fmt.Errorf("failed to marshal staffing requirement: %v", err)
+		}
+
+		actions = append(actions, aztables.TransactionAction{ActionType: aztables.TransactionTypeInsertReplace, Entity: entityBytes})
+	}
+
+	for _, entity := range entities {
+		rowKey, _ := entity["RowKey"].(string)
+		if rowKey < staffingRowKey(len(staffing)) {
+			continue // overwritten above
+		}
+
+		entityBytes, err := json.Marshal(map[string]interface{}{"PartitionKey": eventRowKey, "RowKey": rowKey})
+		if err != nil {
+			return fmt.Errorf("failed to marshal staffing requirement: %v", err)
+		}
+
+		actions = append(actions, aztables.TransactionAction{ActionType: aztables.TransactionTypeDelete, Entity: entityBytes})
+	}
+
+	if len(actions) == 0 {
+		return nil
+	}
+
+	// the plan has at most models.MaxStaffingRequirements rows, so this fits in one transaction
+	if _, err := tableClient.SubmitTransaction(ctx, actions, nil); err != nil {
+		return fmt.Errorf("failed to save staffing plan of event %s: %v", eventRowKey, err)
+	}
+
+	return nil
+}
+
+// staffingRowKey returns the RowKey of the i-th requirement of a plan, which sorts in the order of the plan
+func staffingRowKey(i int) string {
+	return fmt.Sprintf("%03d", i)
+}
+
+// deleteStaffing removes the staffing plan of an event
+func (r *EventRepository) deleteStaffing(ctx context.Context, eventRowKey string) error {
+	return r.saveStaffing(ctx, eventRowKey, nil)
+}
+
+// attachStaffing reads the staffing plan of an event and the roles it needs (events created before staffing plans
+// have none)
+func (r *EventRepository) attachStaffing(ctx context.Context, event *models.Event) error {
+	entities, err := r.listStaffing(ctx, event.RowKey.String())
+	if err != nil {
+		return err
+	}
+
+	var staffing []models.StaffingRequirement
+	for _, entity := range entities {
+		requirement := models.StaffingRequirement{
+			RoleID: int(entity["RoleID"].(float64)),
+			Count:  int(entity["Count"].(float64)),
+		}
+		if requirement.StartTime, err = parseOptionalTime(entity["StartTime"]); err != nil {
+			return fmt.Errorf("failed to parse staffing start time: %v", err)
+		}
+		if requirement.EndTime, err = parseOptionalTime(entity["EndTime"]); err != nil {
+			return fmt.Errorf("failed to parse staffing end time: %v", err)
+		}
+		staffing = append(staffing, requirement)
+	}
+
+	event.Staffing = staffing
+	event.RoleIDs = models.StaffingRoleIDs(staffing)
+
+	return nil
+}
+
+// listStaffing returns the staffing rows of an event, in the order of the plan
+func (r *EventRepository) listStaffing(ctx context.Context, eventRowKey string) ([]map[string]interface{}, error) {
+	tableClient := r.serviceClient.NewClient(staffingTableName)
+
+	filter := fmt.Sprintf("PartitionKey eq '%s'", eventRowKey)
+	pager := tableClient.NewListEntitiesPager(&aztables.ListEntitiesOptions{Filter: &filter})
+
+	var entities []map[string]interface{}
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list staffing requirements: %v", err)
+		}
+
+		for _, entity := range page.Entities {
+			var staffingData map[string]interface{}
+			if err := json.Unmarshal(entity, &staffingData); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal staffing requirement: %v", err)
+			}
+			entities = append(entities, staffingData)
+		}
+	}
+
+	return entities, nil
+}
+
+// formatOptionalTime formats a time as RFC3339 ("" when it isn't set)
+func formatOptionalTime(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.Format(time.RFC3339)
+}
+
+// parseOptionalTime parses a time stored by formatOptionalTime
+func parseOptionalTime(value interface{}) (*time.Time, error) {
+	text, _ := value.(string)
+	if text == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
@@ -38,14 +38,7 @@ func (r *RabbitMQService) PublishMessage(queueName, message string) error {
 
 // PublishEventCreated publishes an event created message
 func (r *RabbitMQService) PublishEventCreated(ctx context.Context, event models.Event) error {
-	message := map[string]interface{}{
-		"roleIDs":   event.RoleIDs,
-		"eventID":   event.RowKey,
-		"startTime": event.StartTime,
-		"endTime":   event.EndTime,
-	}
-
-	messageBytes, err := json.Marshal(message)
+	messageBytes, err := json.Marshal(models.NewEventCreatedMessage(event))
 	if err != nil {
 		return err
 	}
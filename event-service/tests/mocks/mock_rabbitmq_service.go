@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/Catalin246/karma-kebab/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRabbitMQService struct {
+	mock.Mock
+}
+
+func (m *MockRabbitMQService) PublishMessage(queueName, message string) error {
+	return m.Called(queueName, message).Error(0)
+}
+
+func (m *MockRabbitMQService) PublishEventCreated(ctx context.Context, event models.Event) error {
+	return m.Called(ctx, event).Error(0)
+}
+
+func (m *MockRabbitMQService) PublishEventDeleted(ctx context.Context, eventID string, partitionKey string) error {
+	return m.Called(ctx, eventID, partitionKey).Error(0)
+}
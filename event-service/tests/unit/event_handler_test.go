@@ -9,8 +9,10 @@ import (
 
 	"github.com/Catalin246/karma-kebab/handlers"
 	"github.com/Catalin246/karma-kebab/models"
+	"github.com/Catalin246/karma-kebab/repositories"
 	"github.com/Catalin246/karma-kebab/tests/mocks"
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -76,3 +78,46 @@ func TestGetEvents(t *testing.T) {
 	// Check that the mock's expectations were met
 	mockService.AssertExpectations(t)
 }
+
+func TestGetEventByShiftID(t *testing.T) {
+	mockService := new(mocks.MockEventService)
+
+	shiftID := uuid.New().String()
+	event := &models.Event{
+		RowKey:  uuid.New(),
+		Venue:   "Beach",
+		Tags:    []string{"festival", "outdoor"},
+		TruckID: "truck-1",
+	}
+	mockService.On("GetEventByShiftID", mock.Anything, shiftID).Return(event, nil)
+
+	handler := handlers.NewEventHandler(mockService, nil)
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/events/"+shiftID, nil), map[string]string{"shiftID": shiftID})
+	rr := httptest.NewRecorder()
+
+	handler.GetEventByShiftID(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response models.Event
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, []string{"festival", "outdoor"}, response.Tags)
+	assert.Equal(t, "truck-1", response.TruckID)
+}
+
+func TestGetEventByShiftID_NotFound(t *testing.T) {
+	mockService := new(mocks.MockEventService)
+
+	shiftID := uuid.New().String()
+	mockService.On("GetEventByShiftID", mock.Anything, shiftID).Return(nil, repositories.ErrEventNotFound)
+
+	handler := handlers.NewEventHandler(mockService, nil)
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/events/"+shiftID, nil), map[string]string{"shiftID": shiftID})
+	rr := httptest.NewRecorder()
+
+	handler.GetEventByShiftID(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,149 @@
+package unit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Catalin246/karma-kebab/handlers"
+	"github.com/Catalin246/karma-kebab/models"
+	"github.com/Catalin246/karma-kebab/tests/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	eventStart = time.Date(2024, 7, 6, 10, 0, 0, 0, time.UTC)
+	eventEnd   = time.Date(2024, 7, 6, 22, 0, 0, 0, time.UTC)
+)
+
+func staffingTime(hour int) *time.Time {
+	value := time.Date(2024, 7, 6, hour, 0, 0, 0, time.UTC)
+	return &value
+}
+
+func TestNormalizeStaffing(t *testing.T) {
+	event := models.Event{StartTime: eventStart, EndTime: eventEnd, Staffing: []models.StaffingRequirement{
+		{RoleID: 1, Count: 2, EndTime: staffingTime(14)},
+		{RoleID: 2, Count: 2},
+		{RoleID: 1, Count: 3, StartTime: staffingTime(14)}, // more cooks in the evening
+		{RoleID: 3, Count: 1, StartTime: staffingTime(16), EndTime: staffingTime(20)},
+	}}
+
+	assert.NoError(t, event.NormalizeStaffing())
+	assert.Equal(t, []int{1, 2, 3}, event.RoleIDs)
+}
+
+func TestNormalizeStaffing_FromRoleIDs(t *testing.T) {
+	event := models.Event{StartTime: eventStart, EndTime: eventEnd, RoleIDs: []int{1, 2}}
+
+	assert.NoError(t, event.NormalizeStaffing())
+	assert.Equal(t, []models.StaffingRequirement{{RoleID: 1, Count: 1}, {RoleID: 2, Count: 1}}, event.Staffing)
+	assert.Equal(t, []int{1, 2}, event.RoleIDs)
+}
+
+func TestNormalizeStaffing_FromRoleIDsWithoutTimes(t *testing.T) {
+	// events sent before staffing plans had only roleIDs, with or without times
+	event := models.Event{RoleIDs: []int{1, 2, 1}}
+
+	assert.NoError(t, event.NormalizeStaffing())
+	assert.Equal(t, []models.StaffingRequirement{{RoleID: 1, Count: 2}, {RoleID: 2, Count: 1}}, event.Staffing)
+	assert.Equal(t, []int{1, 2}, event.RoleIDs)
+}
+
+func TestNormalizeStaffing_NotSent(t *testing.T) {
+	event := models.Event{StartTime: eventStart, EndTime: eventEnd}
+
+	assert.NoError(t, event.NormalizeStaffing())
+	assert.Nil(t, event.Staffing)
+	assert.Nil(t, event.RoleIDs)
+}
+
+func TestNormalizeStaffing_Invalid(t *testing.T) {
+	plans := map[string][]models.StaffingRequirement{
+		"no role":              {{Count: 1}},
+		"no count":             {{RoleID: 1}},
+		"too many":             {{RoleID: 1, Count: models.MaxStaffingCount + 1}},
+		"window ends too soon": {{RoleID: 1, Count: 1, StartTime: staffingTime(14), EndTime: staffingTime(12)}},
+		"outside the event":    {{RoleID: 1, Count: 1, EndTime: staffingTime(23)}},
+		"overlapping windows":  {{RoleID: 1, Count: 2, EndTime: staffingTime(15)}, {RoleID: 1, Count: 3, StartTime: staffingTime(14)}},
+	}
+
+	for name, staffing := range plans {
+		event := models.Event{StartTime: eventStart, EndTime: eventEnd, Staffing: staffing}
+		assert.True(t, errors.Is(event.NormalizeStaffing(), models.ErrInvalidStaffing), name)
+	}
+}
+
+func TestEventCreatedMessage(t *testing.T) {
+	event := models.Event{StartTime: eventStart, EndTime: eventEnd, Staffing: []models.StaffingRequirement{
+		{RoleID: 1, Count: 3, StartTime: staffingTime(12)},
+	}}
+	assert.NoError(t, event.NormalizeStaffing())
+
+	messageBytes, err := json.Marshal(models.NewEventCreatedMessage(event))
+	assert.NoError(t, err)
+
+	var message map[string]interface{}
+	assert.NoError(t, json.Unmarshal(messageBytes, &message))
+	assert.Equal(t, []interface{}{float64(1)}, message["roleIDs"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"roleID": float64(1), "count": float64(3), "startTime": "2024-07-06T12:00:00Z", "endTime": nil,
+	}}, message["staffing"])
+}
+
+func TestCreateEvent_PublishesStaffing(t *testing.T) {
+	mockService := new(mocks.MockEventService)
+	mockRabbitMQ := new(mocks.MockRabbitMQService)
+	handler := handlers.NewEventHandler(mockService, mockRabbitMQ)
+
+	hasStaffing := mock.MatchedBy(func(event models.Event) bool {
+		return len(event.Staffing) == 2 && event.Staffing[0].Count == 3 && len(event.RoleIDs) == 2
+	})
+	mockService.On("Create", mock.Anything, hasStaffing).Return(nil)
+	mockRabbitMQ.On("PublishEventCreated", mock.Anything, hasStaffing).Return(nil)
+
+	body := `{"startTime": "2024-07-06T10:00:00Z", "endTime": "2024-07-06T22:00:00Z",
+		"staffing": [{"roleID": 1, "count": 3}, {"roleID": 2, "count": 2, "startTime": "2024-07-06T17:00:00Z"}]}`
+	rr := httptest.NewRecorder()
+
+	handler.CreateEvent(rr, httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	mockService.AssertExpectations(t)
+	mockRabbitMQ.AssertExpectations(t)
+}
+
+func TestUpdateEvent_KeepsStaffingWhenNotSent(t *testing.T) {
+	mockService := new(mocks.MockEventService)
+	handler := handlers.NewEventHandler(mockService, nil)
+
+	// a nil plan tells the repository to keep the stored one
+	noStaffing := mock.MatchedBy(func(event models.Event) bool { return event.Staffing == nil })
+	mockService.On("Update", mock.Anything, mock.Anything, mock.Anything, noStaffing).Return(nil)
+
+	body := `{"startTime": "2024-07-06T10:00:00Z", "endTime": "2024-07-06T22:00:00Z", "venue": "Market square"}`
+	rr := httptest.NewRecorder()
+
+	handler.UpdateEvent(rr, httptest.NewRequest(http.MethodPut, "/events/Event/1", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateEvent_InvalidStaffing(t *testing.T) {
+	mockService := new(mocks.MockEventService)
+	handler := handlers.NewEventHandler(mockService, nil)
+
+	body := `{"startTime": "2024-07-06T10:00:00Z", "endTime": "2024-07-06T22:00:00Z", "staffing": [{"roleID": 1, "count": 0}]}`
+	rr := httptest.NewRecorder()
+
+	handler.CreateEvent(rr, httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}